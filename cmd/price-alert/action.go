@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// act runs the actions named for the alert ev, each in the background so a
+// slow command never holds up the ticks.
+func (m *monitor) act(actions []string, ev alert.Event) {
+	env := append(os.Environ(),
+		"PRICE_ALERT_EVENT="+ev.Kind.String(),
+		"PRICE_ALERT_RULE="+ev.Rule,
+		"PRICE_ALERT_SYMBOL="+ev.Symbol,
+		"PRICE_ALERT_PRICE="+strconv.FormatFloat(ev.Price, 'f', -1, 64),
+		"PRICE_ALERT_TEXT="+ev.Text)
+	for _, name := range actions {
+		go runAction(name, m.cfg.Actions[name], env)
+	}
+}
+
+// runAction runs a and logs its output if it fails.
+func runAction(name string, a config.ActionConfig, env []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, a.Command, a.Args...)
+	cmd.Env = env
+	out, err := cmd.CombinedOutput()
+	switch out = bytes.TrimSpace(out); {
+	case err != nil && len(out) > 0:
+		logging.Warnf("Action %s failed: %v: %s", name, err, out)
+	case err != nil:
+		logging.Warnf("Action %s failed: %v", name, err)
+	}
+}
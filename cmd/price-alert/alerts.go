@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// announce adds ev to the alert_path ring of its symbol's slot, the first
+// one for alerts of symbols without a slot, and wakes the readers:
+// "<seq>\0<severity>\0<event>\0<SYMBOL>\0<price>\0<text>\0", where seq
+// counts the slot's alerts since start, so a reader tells new alerts from
+// a price update and finds every one of a tick that raised several.
+// Alerts below alert_severity are left out, and a text too long for the
+// record is cut short.
+func (m *monitor) announce(ev alert.Event) {
+	if m.alerts == nil {
+		return
+	}
+	if sev, _ := alert.ParseSeverity(m.cfg.AlertSeverity); ev.Severity < sev {
+		return
+	}
+	slot := max(m.cfg.Slot(ev.Symbol), 0)
+	label, price := strings.ToUpper(ev.Symbol), strconv.FormatFloat(ev.Price, 'f', -1, 64)
+	if sc := m.cfg.Symbol(ev.Symbol); sc != nil {
+		label, price = sc.Label(), sc.Format(ev.Price)
+	}
+	if _, err := m.alerts.Write(slot, ev.Severity.String(), ev.Kind.String(), label, price, ev.Text); err != nil {
+		logging.Error("Alert region error: "+err.Error(), "symbol", ev.Symbol, "slot", slot)
+		return
+	}
+	if err := m.pub.Notify(slot); err != nil {
+		logging.Error("Publish error: "+err.Error(), "symbol", ev.Symbol, "slot", slot)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/ipc"
+)
+
+// TestAnnounceOneTick fires the two events one tick raises, a step and a
+// crossed target, and checks a reader woken after both still finds each.
+func TestAnnounceOneTick(t *testing.T) {
+	cfg := config.Default()
+	cfg.Symbols = []config.SymbolConfig{{Symbol: "btcusdt"}, {Symbol: "ethusdt"}}
+	path := filepath.Join(t.TempDir(), "alerts")
+	alerts, err := ipc.CreateAlerts(path, cfg.AlertSize, len(cfg.Symbols), ipc.DefaultPerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alerts.Close()
+	reader, err := ipc.OpenAlerts(path, cfg.AlertSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	m := &monitor{cfg: cfg, pub: ipc.Discard{}, alerts: alerts}
+	seen := reader.Head(1)
+
+	m.announce(alert.Event{Symbol: "ethusdt", Kind: alert.Up, Severity: alert.Warn, Price: 3512.5, Text: "up to 3512.50"})
+	m.announce(alert.Event{Symbol: "ethusdt", Kind: alert.Target, Severity: alert.Critical, Price: 3512.5, Text: "crossed 3510"})
+
+	var got [][]string
+	for _, f := range reader.Since(1, seen) {
+		got = append(got, f[1:])
+	}
+	want := [][]string{
+		{"warn", alert.Up.String(), "ETHUSDT", "3512.50", "up to 3512.50"},
+		{"critical", alert.Target.String(), "ETHUSDT", "3512.50", "crossed 3510"},
+	}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("Since(1, %d) = %q, want %q", seen, got, want)
+	}
+	if other := reader.Since(0, 0); len(other) != 0 {
+		t.Errorf("Since(0, 0) = %q, want no alerts", other)
+	}
+}
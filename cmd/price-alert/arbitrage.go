@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+)
+
+// arbitrage raises the alert of the aggregated symbol sc when its
+// cheapest and dearest markets have been arbitrage.percent apart for
+// arbitrage.for, e.g. "ETHUSDT markets 0.8 percent apart for 12s,
+// binance:ethusdt 3421 vs coinbase:eth-usd 3448": an opportunity, or one
+// feed gone bad.
+func (m *monitor) arbitrage(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	a, ok := m.aggs[sc.Symbol]
+	if !ok {
+		return
+	}
+	low, high, ok := a.Extremes(time.Now())
+	if !ok {
+		return
+	}
+	ev, lasted, ok := m.arbs.Update(sc.Symbol, low.Price, high.Price, t.Time, sc.Arbitrage.Percent, sc.Arbitrage.For)
+	if !ok {
+		return
+	}
+	ev.Time, ev.Source = t.Time, source
+	ev.Text = fmt.Sprintf("%s markets %s percent apart for %s, %s %s vs %s %s%s", sc.Label(), m.num.Format(ev.Level, 2), shortDuration(lasted.Round(time.Second)),
+		low.Market, sc.Display(m.num, low.Price), high.Market, sc.Display(m.num, high.Price), via)
+	m.notify(ev)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// ATH_BACKFILL is the number of monthly klines the all-time highs are
+// seeded from, the most Binance returns at once.
+const ATH_BACKFILL = 1000
+
+// athRecord is the state of a symbol kept in ath_file.
+type athRecord struct {
+	High    float64 `json:"high"`
+	Alerted float64 `json:"alerted,omitempty"` // the high last announced
+}
+
+// ath folds the price t of sc into its all-time high and announces a new
+// one, e.g. "ETHUSDT new all-time high: 4900", saving ath_file with it.
+func (m *monitor) ath(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	ev, ok := m.highs.Update(sc.Symbol, t.Price, m.step(sc))
+	if !ok {
+		return
+	}
+	ev.Time, ev.Source, ev.Size, ev.Spread = t.Time, source, t.Size, t.Ask-t.Bid
+	ev.Text = fmt.Sprintf("%s new all-time high: %s%s", sc.Label(), sc.DisplayAlert(m.num, t.Price), via)
+	m.notify(ev)
+	m.saveATH()
+}
+
+// backfillATH seeds the all-time highs of symbols with ath not seeded yet
+// from ath_file and their monthly klines, whichever is higher, so a restart
+// announces neither the high it was stopped at nor one it missed again. A
+// symbol whose first market is not on Binance, or whose klines fail, takes
+// the file's high or else its first price.
+func (m *monitor) backfillATH() {
+	if m.cfg.Source == config.SOURCE_REPLAY {
+		return
+	}
+	saved := m.loadATH()
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, sc := range m.cfg.Symbols {
+		if !sc.ATH {
+			continue
+		}
+		if _, _, ok := m.highs.Value(sc.Symbol); ok {
+			continue
+		}
+		if r, ok := saved[sc.Symbol]; ok {
+			m.highs.Seed(sc.Symbol, r.High, r.Alerted)
+		}
+		market := m.cfg.Markets(sc)[0]
+		fetch := klineFetcher(market.Exchange)
+		if fetch == nil {
+			continue
+		}
+		_, rest := m.cfg.Endpoints(market.Exchange)
+		klines, err := fetch(client, rest, market.Symbol, "1M", ATH_BACKFILL)
+		if err == nil && len(klines) == 0 {
+			err = errors.New("no klines")
+		}
+		if err != nil {
+			logging.Warn(fmt.Sprintf("%s all-time high backfill failed: %v", sc.Label(), err),
+				"event", "backfill", "symbol", sc.Symbol)
+			continue
+		}
+		high := 0.0
+		for _, k := range klines {
+			high = max(high, k.High)
+		}
+		m.highs.Seed(sc.Symbol, high, 0)
+		high, _, _ = m.highs.Value(sc.Symbol)
+		logging.Info(fmt.Sprintf("%s all-time high %s", sc.Label(), sc.Format(high)),
+			"event", "backfill", "symbol", sc.Symbol, "high", high)
+	}
+}
+
+// loadATH reads ath_file, nil when it is off or missing.
+func (m *monitor) loadATH() map[string]athRecord {
+	if m.cfg.ATHFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(m.cfg.ATHFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	var saved map[string]athRecord
+	if err == nil {
+		err = json.Unmarshal(data, &saved)
+	}
+	if err != nil {
+		logging.Warnf("ath_file: %v", err)
+		return nil
+	}
+	return saved
+}
+
+// saveATH replaces ath_file atomically with the all-time highs of the
+// symbols with ath, keeping those of symbols the config dropped.
+func (m *monitor) saveATH() {
+	if m.cfg.ATHFile == "" || m.cfg.Source == config.SOURCE_REPLAY {
+		return
+	}
+	saved := m.loadATH()
+	if saved == nil {
+		saved = make(map[string]athRecord)
+	}
+	for _, sc := range m.cfg.Symbols {
+		if high, alerted, ok := m.highs.Value(sc.Symbol); ok && sc.ATH {
+			saved[sc.Symbol] = athRecord{High: high, Alerted: alerted}
+		}
+	}
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		logging.Errorf("ath_file: %v", err)
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(m.cfg.ATHFile), ".ath-*")
+	if err != nil {
+		logging.Warnf("ath_file: %v", err)
+		return
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), m.cfg.ATHFile)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		logging.Warnf("ath_file: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// atr folds the price t of sc into its ATR candles and logs the step each
+// closed candle makes.
+func (m *monitor) atr(sc config.SymbolConfig, t feed.Tick) {
+	if !m.atrs.Update(sc.Symbol, t.Price, t.Time, config.KlineDuration(sc.ATR.Interval), sc.ATR.Period) {
+		return
+	}
+	value, _ := m.atrs.Value(sc.Symbol)
+	logging.Debug(fmt.Sprintf("%s step %s from a %s ATR of %s", sc.Label(), sc.Display(m.num, m.step(sc)), sc.ATR.Interval, sc.Display(m.num, value)),
+		"event", "atr", "symbol", sc.Symbol, "atr", value)
+}
+
+// backfillATR seeds the ATR candles of symbols with atr from the klines of
+// their first market, so their step follows volatility from the start. A
+// symbol whose first market is not on Binance, or whose klines fail,
+// builds its candles from its prices and keeps its fixed step until then.
+func (m *monitor) backfillATR() {
+	if m.cfg.Source == config.SOURCE_REPLAY {
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, sc := range m.cfg.Symbols {
+		if sc.ATR.Multiple == 0 {
+			continue
+		}
+		if _, ok := m.atrs.Value(sc.Symbol); ok {
+			continue
+		}
+		market := m.cfg.Markets(sc)[0]
+		fetch := klineFetcher(market.Exchange)
+		if fetch == nil {
+			continue
+		}
+		_, rest := m.cfg.Endpoints(market.Exchange)
+		// One more for the open kline, and one whose close starts the
+		// first true range
+		klines, err := fetch(client, rest, market.Symbol, sc.ATR.Interval, sc.ATR.Period+2)
+		if err != nil {
+			logging.Warn(fmt.Sprintf("%s ATR backfill failed, using the fixed step until %d candles closed: %v", sc.Label(), sc.ATR.Period, err),
+				"event", "backfill", "symbol", sc.Symbol)
+			continue
+		}
+		for _, k := range klines {
+			m.atrs.Add(sc.Symbol, k.OpenTime, k.High, k.Low, k.Close, sc.ATR.Period)
+		}
+		if value, ok := m.atrs.Value(sc.Symbol); ok {
+			logging.Info(fmt.Sprintf("%s step %s from a %s ATR of %s", sc.Label(), sc.Display(m.num, m.step(sc)), sc.ATR.Interval, sc.Display(m.num, value)),
+				"event", "atr", "symbol", sc.Symbol, "atr", value)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// averageList describes the moving averages of sc for the tracker.
+func averageList(sc config.SymbolConfig) []alert.Average {
+	list := make([]alert.Average, len(sc.MovingAverages.Averages))
+	for i, name := range sc.MovingAverages.Averages {
+		exponential, period, _ := config.MovingAverage(name)
+		list[i] = alert.Average{Name: name, Exponential: exponential, Period: period}
+	}
+	return list
+}
+
+// averages folds the price t of sc into its moving average candles and,
+// once one closes, updates the stats slot and raises the alerts of the
+// crossovers it made, e.g. "ETHUSDT crossed above EMA200 at 3412" or
+// "ETHUSDT EMA20 crossed below EMA50 at 3350".
+func (m *monitor) averages(slot int, sc config.SymbolConfig, t feed.Tick, source, via string) {
+	ma := sc.MovingAverages
+	if !m.avgs.Update(sc.Symbol, t.Price, t.Time, config.KlineDuration(ma.Interval), averageList(sc)) {
+		return
+	}
+	m.writeStats(slot, sc)
+	for _, cross := range ma.Crossovers {
+		x, y, _ := strings.Cut(cross, "/")
+		ev, ok := m.avgs.Cross(sc.Symbol, x, y)
+		if !ok {
+			continue
+		}
+		side := "above"
+		if ev.Change < 0 {
+			side = "below"
+		}
+		subject := sc.Label() + " " + strings.ToUpper(x)
+		if x == "price" {
+			subject = sc.Label()
+		}
+		ev.Time, ev.Source = t.Time, source
+		ev.Text = fmt.Sprintf("%s crossed %s %s at %s%s", subject, side, strings.ToUpper(y), sc.DisplayAlert(m.num, ev.Price), via)
+		m.notify(ev)
+	}
+}
+
+// backfillAverages seeds the moving averages of symbols with them from the
+// klines of their first market, so they have values and their crossovers
+// alert from the start. A symbol whose first market is not on Binance, or
+// whose klines fail, builds them from its prices instead.
+func (m *monitor) backfillAverages() {
+	if m.cfg.Source == config.SOURCE_REPLAY {
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, sc := range m.cfg.Symbols {
+		list := averageList(sc)
+		if len(list) == 0 {
+			continue
+		}
+		if _, ok := m.avgs.Close(sc.Symbol); ok {
+			continue
+		}
+		market := m.cfg.Markets(sc)[0]
+		fetch := klineFetcher(market.Exchange)
+		if fetch == nil {
+			continue
+		}
+		longest := 0
+		for _, a := range list {
+			longest = max(longest, a.Period)
+		}
+		_, rest := m.cfg.Endpoints(market.Exchange)
+		// One more for the open kline
+		klines, err := fetch(client, rest, market.Symbol, sc.MovingAverages.Interval, longest+1)
+		if err != nil {
+			logging.Warn(fmt.Sprintf("%s moving average backfill failed, building them from the prices: %v", sc.Label(), err),
+				"event", "backfill", "symbol", sc.Symbol)
+			continue
+		}
+		for _, k := range klines {
+			m.avgs.Add(sc.Symbol, k.OpenTime, k.Close, list)
+		}
+		// The sides the crossovers start from
+		for _, cross := range sc.MovingAverages.Crossovers {
+			x, y, _ := strings.Cut(cross, "/")
+			m.avgs.Cross(sc.Symbol, x, y)
+		}
+		var values []string
+		for _, a := range list {
+			if v, ok := m.avgs.Value(sc.Symbol, a.Name); ok {
+				values = append(values, a.Name+"="+sc.Format(v))
+			}
+		}
+		logging.Info(fmt.Sprintf("%s moving averages backfilled: %s", sc.Label(), strings.Join(values, " ")),
+			"event", "backfill", "symbol", sc.Symbol)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// klineFetcher returns the kline lookup of exchange, or nil when it has
+// none.
+func klineFetcher(exchange string) feed.KlineFetcher {
+	switch exchange {
+	case config.SOURCE_BINANCE:
+		return feed.FetchKlines
+	case config.SOURCE_BINANCE_FUTURES:
+		return feed.FetchFuturesKlines
+	}
+	return nil
+}
+
+// backfill seeds every symbol without a checkpoint yet from the klines of
+// cfg.Backfill, so its first tick already compares against history. A
+// symbol whose first market is not on Binance, or whose klines fail,
+// starts cold from its first tick as before. ATR steps are seeded first.
+func (m *monitor) backfill() {
+	m.backfillATR()
+	m.backfillAverages()
+	m.backfillRSI()
+	m.backfillMACD()
+	m.backfillBollinger()
+	m.backfillVolume()
+	m.backfillDays()
+	m.backfillATH()
+	m.backfillDailyChange()
+	m.backfillLevels()
+	m.backfillPivots()
+	m.backfillVolatility()
+	bf := m.cfg.Backfill
+	if bf.Window == 0 || m.cfg.Source == config.SOURCE_REPLAY {
+		return
+	}
+	// One more for the open kline, which ends the history
+	limit := int(bf.Window/config.KlineDuration(bf.Interval)) + 1
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, sc := range m.cfg.Symbols {
+		if _, ok := m.stepper.Checkpoint(sc.Symbol); ok {
+			continue
+		}
+		market := m.cfg.Markets(sc)[0]
+		fetch := klineFetcher(market.Exchange)
+		if fetch == nil {
+			continue
+		}
+		_, rest := m.cfg.Endpoints(market.Exchange)
+		klines, err := fetch(client, rest, market.Symbol, bf.Interval, limit)
+		if err == nil && len(klines) == 0 {
+			err = errors.New("no klines")
+		}
+		if err != nil {
+			logging.Warn(fmt.Sprintf("%s backfill failed, starting from the first tick: %v", sc.Label(), err),
+				"event", "backfill", "symbol", sc.Symbol)
+			continue
+		}
+		m.seed(sc, klines)
+	}
+}
+
+// seed sets the checkpoint and target side of sc from the latest close of
+// klines, announcing the start as the first tick would, and with 24h of
+// klines the 24h statistics its alerts_24h compare against.
+func (m *monitor) seed(sc config.SymbolConfig, klines []feed.Kline) {
+	last := klines[len(klines)-1]
+	if sc.CloseOnly && len(klines) > 1 {
+		last = klines[len(klines)-2] // alerts wait for closed candles
+	}
+	m.targets.Update(sc.Symbol, last.Close)
+	ev, _, _ := m.stepper.Update(sc.Symbol, last.Close, sc.Step, grid(sc))
+	ev.Time = time.Now()
+	ev.Text = fmt.Sprintf("%s starting price checkpoint: %s from %d %s klines", sc.Label(), sc.Display(m.num, last.Close), len(klines), m.cfg.Backfill.Interval)
+	m.notify(ev)
+
+	rules := sc.Alerts24h
+	from := time.Now().Add(-24 * time.Hour)
+	if (rules.ChangeStep == 0 && !rules.HighLow) || klines[0].OpenTime.After(from) {
+		return
+	}
+	var day *feed.Kline
+	for _, k := range klines {
+		switch {
+		case k.OpenTime.Before(from):
+		case day == nil:
+			day = &feed.Kline{Open: k.Open, High: k.High, Low: k.Low}
+		default:
+			day.High, day.Low = max(day.High, k.High), min(day.Low, k.Low)
+		}
+	}
+	if day == nil {
+		return // a kline longer than a day
+	}
+	m.daily.Update(sc.Symbol, last.Close, day.Open, day.High, day.Low, rules.ChangeStep, rules.HighLow, m.dayRearm(sc))
+	logging.Debug(fmt.Sprintf("%s 24h from klines: open %s high %s low %s", sc.Label(),
+		sc.Display(m.num, day.Open), sc.Display(m.num, day.High), sc.Display(m.num, day.Low)),
+		"event", "backfill", "symbol", sc.Symbol)
+}
+
+// backfillDays seeds the rolling 24h range of symbols with alerts_24h on
+// streams without 24h statistics from 24h of 5m klines of their first
+// market, so their alerts start at once. A symbol whose first market is
+// not on Binance, or whose klines fail, waits until its prices span 24h.
+func (m *monitor) backfillDays() {
+	if m.cfg.Source == config.SOURCE_REPLAY {
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, sc := range m.cfg.Symbols {
+		if !sc.Alerts24h.Enabled() || config.StatsStream(sc.Stream) {
+			continue
+		}
+		if _, _, _, full := m.days.Range(sc.Symbol, time.Now()); full {
+			continue
+		}
+		market := m.cfg.Markets(sc)[0]
+		fetch := klineFetcher(market.Exchange)
+		if fetch == nil {
+			continue
+		}
+		_, rest := m.cfg.Endpoints(market.Exchange)
+		// One more for the open kline
+		klines, err := fetch(client, rest, market.Symbol, "5m", int(24*time.Hour/alert.DAY_BUCKET)+1)
+		if err != nil {
+			logging.Warn(fmt.Sprintf("%s 24h backfill failed, building the range from the prices: %v", sc.Label(), err),
+				"event", "backfill", "symbol", sc.Symbol)
+			continue
+		}
+		for _, k := range klines {
+			m.days.Add(sc.Symbol, k.OpenTime, k.Open, k.High, k.Low, k.Close)
+		}
+		if open, high, low, full := m.days.Range(sc.Symbol, time.Now()); full {
+			m.daily.Update(sc.Symbol, klines[len(klines)-1].Close, open, high, low, sc.Alerts24h.ChangeStep, sc.Alerts24h.HighLow, m.dayRearm(sc))
+			logging.Debug(fmt.Sprintf("%s 24h from klines: open %s high %s low %s", sc.Label(),
+				sc.Display(m.num, open), sc.Display(m.num, high), sc.Display(m.num, low)),
+				"event", "backfill", "symbol", sc.Symbol)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+)
+
+// BASIS_EXCHANGE tags the ticks of the mark price streams of basis
+// contracts, which belong to no slot of their own.
+const BASIS_EXCHANGE = "basis"
+
+// BASIS_PERP_HORIZON is the time a perpetual's basis is annualized over:
+// one funding interval, within which funding pulls the mark back toward
+// spot. A delivery contract's is the time to its expiry, at least
+// BASIS_MIN_HORIZON.
+const (
+	BASIS_PERP_HORIZON = 8 * time.Hour
+	BASIS_MIN_HORIZON  = 24 * time.Hour
+)
+
+// basisState is the latest basis of a symbol, in percent of its price.
+type basisState struct {
+	mark       float64
+	pct        float64
+	annualized float64
+}
+
+// basisSource streams the mark prices of the basis contracts markets from
+// binance-futures.
+func basisSource(cfg config.Config, markets []string) feed.Source {
+	streams := make([]string, len(markets))
+	for i, market := range markets {
+		streams[i] = market + "@" + config.STREAM_MARK_PRICE_1S
+	}
+	return feed.Tagged{Source: exchangeSource(cfg, config.SOURCE_BINANCE_FUTURES, streams, nil), Exchange: BASIS_EXCHANGE}
+}
+
+// basis records the mark price t of a basis contract against the latest
+// price of each symbol tracking it, and raises their basis alerts, e.g.
+// "ETHUSDT basis 0.52%, mark 3015.60 over 3000.00, 571% annualized".
+// Nothing is compared before the symbol has a price.
+func (m *monitor) basis(t feed.Tick) {
+	for _, sc := range m.cfg.Symbols {
+		if !sc.Basis.Enabled() || sc.Basis.Market != t.Symbol {
+			continue
+		}
+		price, ok := m.last[sc.Symbol]
+		if !ok {
+			continue
+		}
+		pct := (t.Price/price.Price - 1) * 100
+		st := basisState{mark: t.Price, pct: pct, annualized: annualize(pct, sc.Basis.Market, t.Time)}
+		m.bases[sc.Symbol] = st
+		ev, ok := m.basisLevels.Update(sc.Symbol, st.pct, st.annualized, sc.Basis.Percent, sc.Basis.Annualized)
+		if !ok {
+			continue
+		}
+		side := "over"
+		if pct < 0 {
+			side = "under"
+		}
+		ev.Time, ev.Price = t.Time, price.Price
+		ev.Text = fmt.Sprintf("%s basis %s%%, mark %s %s %s, %s%% annualized", sc.Label(), m.num.Format(pct, 2),
+			sc.Display(m.num, t.Price), side, sc.Display(m.num, price.Price), m.num.Format(math.Round(st.annualized), 0))
+		m.notify(ev)
+	}
+}
+
+// annualize scales the basis pct of the contract market at now to a year:
+// over the time to expiry of a delivery contract such as btcusdt_251226,
+// which settles at 08:00 UTC, else over BASIS_PERP_HORIZON.
+func annualize(pct float64, market string, now time.Time) float64 {
+	horizon := BASIS_PERP_HORIZON
+	if _, date, ok := strings.Cut(market, "_"); ok {
+		if expiry, err := time.Parse("060102", date); err == nil {
+			horizon = max(expiry.Add(8*time.Hour).Sub(now), BASIS_MIN_HORIZON)
+		}
+	}
+	return pct * float64(365*24*time.Hour) / float64(horizon)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+)
+
+// baskets reprices the baskets and ratios holding symbol after t moved
+// it, once every component has a price, and handles each new price as a
+// tick of the derived symbol.
+func (m *monitor) baskets(symbol string, t feed.Tick) {
+	for _, b := range m.cfg.Symbols {
+		if !holds(b, symbol) {
+			continue
+		}
+		price, ok := m.derive(b)
+		if ok {
+			m.handle(feed.Tick{Symbol: b.Symbol, Exchange: config.SOURCE_BASKET, Price: price, Time: t.Time,
+				Degraded: t.Degraded, Backfilled: t.Backfilled})
+		}
+	}
+}
+
+// derive prices the basket or ratio b from the latest prices of its
+// components, false while one has none yet or a ratio's quote is 0.
+func (m *monitor) derive(b config.SymbolConfig) (float64, bool) {
+	if b.Ratio.Enabled() {
+		base, ok := m.last[b.Ratio.Base]
+		quote, have := m.last[b.Ratio.Quote]
+		if !ok || !have || quote.Price == 0 {
+			return 0, false
+		}
+		return base.Price / quote.Price, true
+	}
+	price := 0.0
+	for _, c := range b.Basket {
+		last, ok := m.last[c.Symbol]
+		if !ok {
+			return 0, false
+		}
+		price += c.Weight * last.Price
+	}
+	return price, true
+}
+
+// holds reports whether symbol is a component of the basket or ratio b.
+func holds(b config.SymbolConfig, symbol string) bool {
+	return slices.Contains(b.Components(), symbol)
+}
+
+// basketFormula renders the basket or ratio of s, e.g.
+// "0.7×ethusdt + 0.3×btcusdt" or "ethusdt/btcusdt".
+func basketFormula(s config.SymbolConfig) string {
+	if s.Ratio.Enabled() {
+		return s.Ratio.Base + "/" + s.Ratio.Quote
+	}
+	var b strings.Builder
+	for i, c := range s.Basket {
+		weight := c.Weight
+		switch {
+		case i > 0 && weight < 0:
+			b.WriteString(" - ")
+			weight = -weight
+		case i > 0:
+			b.WriteString(" + ")
+		}
+		fmt.Fprintf(&b, "%g×%s", weight, c.Symbol)
+	}
+	return b.String()
+}
+
+// basketInfo stands in for an exchange lookup of a basket, which has none.
+func basketInfo(client *http.Client, restEndpoint, symbol string) (feed.SymbolInfo, error) {
+	return feed.SymbolInfo{}, errors.New("baskets and ratios have no tick size; set step and precision")
+}
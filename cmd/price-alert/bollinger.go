@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// bollinger folds the price t of sc into its Bollinger candles and raises
+// its alerts when a closed one broke out of the bands, e.g. "ETHUSDT closed
+// above the upper Bollinger band 3480 at 3495", or squeezed them, e.g.
+// "ETHUSDT Bollinger squeeze, bandwidth 2.1% at 3450".
+func (m *monitor) bollinger(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	b := sc.Bollinger
+	for _, ev := range m.bands.Update(sc.Symbol, t.Price, t.Time, config.KlineDuration(b.Interval), b.Period, b.Deviations, b.Squeeze) {
+		ev.Time, ev.Source = t.Time, source
+		switch {
+		case ev.Kind == alert.Squeeze:
+			ev.Text = fmt.Sprintf("%s Bollinger squeeze, bandwidth %s%% at %s%s", sc.Label(), m.num.Format(ev.Level, 1), sc.DisplayAlert(m.num, ev.Price), via)
+		case ev.Change > 0:
+			ev.Text = fmt.Sprintf("%s closed above the upper Bollinger band %s at %s%s", sc.Label(), sc.Format(ev.Level), sc.DisplayAlert(m.num, ev.Price), via)
+		default:
+			ev.Text = fmt.Sprintf("%s closed below the lower Bollinger band %s at %s%s", sc.Label(), sc.Format(ev.Level), sc.DisplayAlert(m.num, ev.Price), via)
+		}
+		m.notify(ev)
+	}
+}
+
+// backfillBollinger seeds the Bollinger candles of symbols with bollinger
+// from the klines of their first market, so the bands are known from the
+// start; a close already outside them or a squeeze already on then waits
+// for the next one. A symbol whose first market is not on Binance, or
+// whose klines fail, builds its candles from its prices.
+func (m *monitor) backfillBollinger() {
+	if m.cfg.Source == config.SOURCE_REPLAY {
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, sc := range m.cfg.Symbols {
+		b := sc.Bollinger
+		if !b.Enabled() {
+			continue
+		}
+		if _, ok := m.bands.Value(sc.Symbol); ok {
+			continue
+		}
+		market := m.cfg.Markets(sc)[0]
+		fetch := klineFetcher(market.Exchange)
+		if fetch == nil {
+			continue
+		}
+		_, rest := m.cfg.Endpoints(market.Exchange)
+		// One more for the open kline
+		klines, err := fetch(client, rest, market.Symbol, b.Interval, b.Period+1)
+		if err != nil {
+			logging.Warn(fmt.Sprintf("%s Bollinger backfill failed, building the bands from the prices: %v", sc.Label(), err),
+				"event", "backfill", "symbol", sc.Symbol)
+			continue
+		}
+		for _, k := range klines {
+			m.bands.Add(sc.Symbol, k.OpenTime, k.Close, b.Period, b.Deviations, b.Squeeze)
+		}
+		if v, ok := m.bands.Value(sc.Symbol); ok {
+			logging.Info(fmt.Sprintf("%s %s Bollinger bands %s-%s", sc.Label(), b.Interval, sc.Format(v.Lower), sc.Format(v.Upper)),
+				"event", "backfill", "symbol", sc.Symbol, "lower", v.Lower, "upper", v.Upper)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// CANDLE_HEADER starts a new candle_file.
+const CANDLE_HEADER = "start,symbol,interval,open,high,low,close,volume\n"
+
+// candleIntervals returns the lengths of the candles intervals of sc and
+// of its volatility interval.
+func candleIntervals(sc config.SymbolConfig) []time.Duration {
+	intervals := make([]time.Duration, len(sc.Candles), len(sc.Candles)+1)
+	for i, interval := range sc.Candles {
+		intervals[i] = config.KlineDuration(interval)
+	}
+	if d := config.KlineDuration(sc.Volatility.Interval); sc.Volatility.Enabled() && !slices.Contains(intervals, d) {
+		intervals = append(intervals, d)
+	}
+	return intervals
+}
+
+// candle folds the price t of sc into its 1m candles, which its rules read,
+// and reports the closed candles of its candles intervals: to the debug
+// log, candle_file and the stats slot, and those of its volatility
+// interval to the volatility.
+func (m *monitor) candle(slot int, sc config.SymbolConfig, t feed.Tick, source, via string) {
+	closed := m.candles.Update(sc.Symbol, t.Price, t.Size, t.Time, candleIntervals(sc))
+	reported := false
+	for _, c := range closed {
+		if sc.Volatility.Enabled() && c.Interval == config.KlineDuration(sc.Volatility.Interval) {
+			m.volatility(sc, c, source, via)
+			reported = true
+		}
+		for _, interval := range sc.Candles {
+			if config.KlineDuration(interval) != c.Interval {
+				continue
+			}
+			if logging.Enabled(logging.DEBUG) {
+				logging.Debug(fmt.Sprintf("%s %s candle %s O %s H %s L %s C %s V %s", sc.Label(), interval, c.Start.UTC().Format("15:04"),
+					sc.Format(c.Open), sc.Format(c.High), sc.Format(c.Low), sc.Format(c.Close), m.num.Format(c.Volume, -1)),
+					"event", "candle", "symbol", sc.Symbol)
+			}
+			m.writeCandle(sc, interval, c)
+			reported = true
+		}
+	}
+	if reported {
+		m.writeStats(slot, sc)
+	}
+}
+
+// writeCandle appends c, a closed candle of interval of sc, to
+// candle_file, opening it first, with CANDLE_HEADER when it is new.
+func (m *monitor) writeCandle(sc config.SymbolConfig, interval string, c alert.Candle) {
+	if m.cfg.CandleFile == "" {
+		return
+	}
+	if m.candleOut == nil || m.candleOut.Name() != m.cfg.CandleFile {
+		m.closeCandles()
+		f, err := os.OpenFile(m.cfg.CandleFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			logging.Errorf("candle_file: %v", err)
+			return
+		}
+		if info, err := f.Stat(); err == nil && info.Size() == 0 {
+			f.WriteString(CANDLE_HEADER)
+		}
+		m.candleOut = f
+	}
+	_, err := fmt.Fprintf(m.candleOut, "%s,%s,%s,%s,%s,%s,%s,%s\n", c.Start.UTC().Format(time.RFC3339), sc.Symbol, interval,
+		sc.Format(c.Open), sc.Format(c.High), sc.Format(c.Low), sc.Format(c.Close), strconv.FormatFloat(c.Volume, 'f', -1, 64))
+	if err != nil {
+		logging.Errorf("candle_file: %v", err)
+	}
+}
+
+// closeCandles closes candle_file, if open.
+func (m *monitor) closeCandles() {
+	if m.candleOut != nil {
+		m.candleOut.Close()
+		m.candleOut = nil
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+)
+
+// chainlinkSource polls the answers of the chainlink exchange's feeds.
+func chainlinkSource(cfg config.Config, markets []string) feed.Source {
+	return &feed.Quotes{
+		Quote:        feed.ChainlinkQuoter(),
+		RESTEndpoint: cfg.Chainlink.RPCURL,
+		Symbols:      markets,
+		Interval:     cfg.Chainlink.Interval,
+	}
+}
+
+// divergence raises the divergence alert of sc for the price of t against
+// the latest price of divergence.from, e.g. "ETH/USD 0.62% below ETHUSDT,
+// 3012.10 vs 3030.80". Nothing is compared before from has a price.
+func (m *monitor) divergence(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	from := m.cfg.Symbol(sc.Divergence.From)
+	ref, ok := m.last[sc.Divergence.From]
+	if from == nil || !ok {
+		return
+	}
+	ev, ok := m.divergences.Update(sc.Symbol, t.Price, ref.Price, sc.Divergence.Percent)
+	if !ok {
+		return
+	}
+	side := "above"
+	if ev.Level < 0 {
+		side = "below"
+	}
+	ev.Time, ev.Source = t.Time, source
+	ev.Text = fmt.Sprintf("%s %s%% %s %s, %s vs %s%s", sc.Label(), m.num.Format(math.Abs(ev.Level), 2), side, from.Label(),
+		sc.Display(m.num, t.Price), from.Display(m.num, ref.Price), via)
+	m.notify(ev)
+}
+
+// checkChainlink reads the latest answer of feed, which proves both the
+// node and the feed.
+func checkChainlink(client *http.Client, cfg config.Config, feedSymbol string) (string, string) {
+	price, _, err := feed.ChainlinkQuoter()(client, cfg.Chainlink.RPCURL, "", feedSymbol)
+	if err != nil {
+		return CHECK_FAIL, err.Error()
+	}
+	return CHECK_OK, fmt.Sprintf("latest answer %g", price)
+}
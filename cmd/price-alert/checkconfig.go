@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/secrets"
+)
+
+// Check statuses reported by check-config.
+const (
+	CHECK_OK   = "OK"
+	CHECK_FAIL = "FAIL"
+	CHECK_SKIP = "SKIP"
+)
+
+// access(2) mode bits; the syscall package does not export them.
+const (
+	ACCESS_X_OK = 1
+	ACCESS_W_OK = 2
+)
+
+// checkResult is one line of the check-config report.
+type checkResult struct {
+	Check  string `json:"check"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// checkConfigCmd implements the check-config subcommand. It returns the
+// process exit code: 0 when every check passed, 1 otherwise.
+func checkConfigCmd(args []string) int {
+	fs := flag.NewFlagSet("check-config", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	loader := config.ParseFlags(fs, args)
+
+	var report []checkResult
+	add := func(check, status, detail string) {
+		report = append(report, checkResult{Check: check, Status: status, Detail: detail})
+	}
+
+	cfg, err := loader.Load()
+	if err != nil {
+		add("config", CHECK_FAIL, strings.ReplaceAll(err.Error(), "\n", "; "))
+	} else {
+		source := "defaults"
+		if loader.Path != "" {
+			source = loader.Path
+		}
+		if cfg.Profile != "" {
+			source += ", profile " + cfg.Profile
+		}
+		if cfg.Testnet {
+			source += ", testnet"
+		}
+		add("config", CHECK_OK, source)
+
+		client := &http.Client{Timeout: 10 * time.Second}
+		for _, s := range cfg.Symbols {
+			if cfg.Source == config.SOURCE_REPLAY {
+				add("symbol "+s.Symbol, CHECK_SKIP, "replay source")
+				continue
+			}
+			for _, market := range cfg.Markets(s) {
+				_, rest := cfg.Endpoints(market.Exchange)
+				var status, detail string
+				switch {
+				case market.Exchange == config.SOURCE_ETHEREUM:
+					status, detail = checkGas(client, cfg)
+				case market.Exchange == config.SOURCE_CHAINLINK:
+					status, detail = checkChainlink(client, cfg, market.Symbol)
+				case market.Exchange == config.SOURCE_UNISWAP:
+					status, detail = checkUniswap(client, cfg, market.Symbol)
+				case market.Exchange == config.SOURCE_CUSTOM:
+					status, detail = checkCustom(client, cfg, market.Symbol)
+				case market.Exchange == config.SOURCE_PLUGIN:
+					status, detail = checkPlugin(cfg, market.Symbol)
+				case market.Exchange == config.SOURCE_BASKET:
+					status, detail = CHECK_OK, basketFormula(s)
+				case cfg.Quotes(market.Exchange) != nil:
+					status, detail = checkQuote(client, cfg, market.Exchange, market.Symbol)
+				default:
+					status, detail = checkSymbol(client, infoFetcher(market.Exchange), rest, market.Symbol)
+				}
+				if market.Exchange != config.SOURCE_BINANCE {
+					detail = market.Exchange + ": " + detail
+				}
+				check := "symbol " + s.Symbol
+				if len(s.Markets) > 0 || len(s.Failover) > 0 {
+					// one row per market of an aggregated or failover symbol
+					check += "/" + market.Exchange
+					if market.Symbol != s.Symbol {
+						detail = market.Symbol + " on " + detail
+					}
+				}
+				add(check, status, detail)
+			}
+		}
+		if cfg.Source == config.SOURCE_REPLAY {
+			if _, err := os.Stat(cfg.Replay.File); err != nil {
+				add("replay.file", CHECK_FAIL, err.Error())
+			} else {
+				add("replay.file", CHECK_OK, cfg.Replay.File)
+			}
+		}
+		status, detail := checkWritable(cfg.SHMPath, false)
+		add("shm_path", status, detail)
+		if cfg.StatsPath != "" {
+			status, detail = checkWritable(cfg.StatsPath, false)
+			add("stats_path", status, detail)
+			need := 0
+			for _, s := range cfg.Symbols {
+				need = max(need, cfg.StatsRecordSize(s))
+			}
+			add("stats_size", CHECK_OK, fmt.Sprintf("records up to %d of %d bytes", need, cfg.StatsSize))
+		}
+		status, detail = checkWritable(cfg.PipePath, true)
+		add("pipe_path", status, detail)
+		if path := cfg.AlertRegion(); path != "" {
+			status, detail = checkWritable(path, false)
+			if status == CHECK_OK {
+				detail += ", " + cfg.AlertSeverity + " alerts and up"
+			}
+			add("alert_path", status, detail)
+		}
+		// Removed and recreated, replaced through a temporary file or
+		// rotated, so their directories must be writable too
+		for _, f := range []struct{ check, path string }{
+			{"control_socket", cfg.ControlPath()},
+			{"status_file", cfg.StatusFile},
+			{"ath_file", cfg.ATHFile},
+			{"log.file", cfg.Log.File},
+		} {
+			if f.path != "" {
+				status, detail = checkReplaceable(f.path)
+				add(f.check, status, detail)
+			}
+		}
+		if cfg.CandleFile != "" {
+			status, detail = checkWritable(cfg.CandleFile, false)
+			add("candle_file", status, detail)
+		}
+		if perm := cfg.Permissions; perm.Mode != "" || perm.Group != "" || perm.User != "" {
+			status, detail = checkPermissions(perm)
+			add("permissions", status, detail)
+		}
+		keyring := secrets.Keyring{Service: cfg.Secrets.KeyringService}
+		if keyring.Available() {
+			add("secrets", CHECK_OK, "providers "+newSecrets(cfg).Name())
+		} else {
+			add("secrets", CHECK_SKIP, "no keyring helper installed, using "+newSecrets(cfg)[1:].Name())
+		}
+		if cfg.UserData.APIKey != "" && cfg.Source != config.SOURCE_REPLAY {
+			if _, err := userDataKey(cfg); err != nil {
+				add("user_data", CHECK_FAIL, err.Error())
+			} else {
+				add("user_data", CHECK_OK, cfg.UserData.Exchange+" fills")
+			}
+		}
+		var names []string
+		for name := range cfg.Actions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if path, err := exec.LookPath(cfg.Actions[name].Command); err != nil {
+				add("action "+name, CHECK_FAIL, err.Error())
+			} else {
+				add("action "+name, CHECK_OK, "runs "+path)
+			}
+		}
+		if len(cfg.Notifiers) == 0 {
+			add("notifiers", CHECK_SKIP, "none configured")
+		}
+		for _, n := range cfg.Notifiers {
+			if path, err := exec.LookPath(cfg.Actions[n.Action].Command); err != nil {
+				add("notifier "+n.Action, CHECK_FAIL, err.Error())
+			} else {
+				add("notifier "+n.Action, CHECK_OK, n.Severity+" alerts and up, runs "+path)
+			}
+		}
+	}
+
+	failed := false
+	for _, r := range report {
+		failed = failed || r.Status == CHECK_FAIL
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CHECK\tSTATUS\tDETAIL")
+		for _, r := range report {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.Check, r.Status, r.Detail)
+		}
+		w.Flush()
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// checkPermissions resolves the IPC group and the user to drop to.
+func checkPermissions(p config.PermissionsConfig) (string, string) {
+	if _, err := ipcPerm(p); err != nil {
+		return CHECK_FAIL, err.Error()
+	}
+	var parts []string
+	if p.Mode != "" {
+		parts = append(parts, "mode "+p.Mode)
+	}
+	if p.Group != "" {
+		parts = append(parts, "group "+p.Group)
+	}
+	if p.User != "" {
+		u, err := user.Lookup(p.User)
+		if err != nil {
+			return CHECK_FAIL, "permissions.user: " + err.Error()
+		}
+		if os.Geteuid() != 0 && strconv.Itoa(os.Geteuid()) != u.Uid {
+			return CHECK_FAIL, "switching to " + p.User + " needs the writer started as root"
+		}
+		parts = append(parts, "drops to "+p.User)
+	}
+	return CHECK_OK, strings.Join(parts, ", ")
+}
+
+// checkSymbol looks the symbol up with its exchange's fetch and requires it
+// to be trading.
+func checkSymbol(client *http.Client, fetch feed.InfoFetcher, restEndpoint, symbol string) (string, string) {
+	info, err := fetch(client, restEndpoint, symbol)
+	if err != nil {
+		return CHECK_FAIL, err.Error()
+	}
+	if !info.Trading {
+		return CHECK_FAIL, "status " + info.Status
+	}
+	return CHECK_OK, fmt.Sprintf("%s, tick size %g", info.Status, info.TickSize)
+}
+
+// checkWritable verifies that path exists and is writable, or that it could
+// be created in its parent directory. With fifo set an existing file must be
+// a named pipe.
+func checkWritable(path string, fifo bool) (string, string) {
+	st, err := os.Stat(path)
+	switch {
+	case err == nil:
+		if fifo && st.Mode()&os.ModeNamedPipe == 0 {
+			return CHECK_FAIL, path + " exists but is not a named pipe"
+		}
+		if st.IsDir() {
+			return CHECK_FAIL, path + " is a directory"
+		}
+		if err := syscall.Access(path, ACCESS_W_OK); err != nil {
+			return CHECK_FAIL, fmt.Sprintf("%s: not writable: %v", path, err)
+		}
+		return CHECK_OK, path
+	case os.IsNotExist(err):
+		dir := filepath.Dir(path)
+		if err := syscall.Access(dir, ACCESS_W_OK|ACCESS_X_OK); err != nil {
+			return CHECK_FAIL, fmt.Sprintf("cannot create %s: %v", path, err)
+		}
+		return CHECK_OK, path + " (will be created)"
+	default:
+		return CHECK_FAIL, err.Error()
+	}
+}
+
+// checkReplaceable verifies, beyond checkWritable, that new files can be
+// created in the directory of path, for files that are replaced rather
+// than written in place.
+func checkReplaceable(path string) (string, string) {
+	status, detail := checkWritable(path, false)
+	if status != CHECK_OK {
+		return status, detail
+	}
+	if err := syscall.Access(filepath.Dir(path), ACCESS_W_OK|ACCESS_X_OK); err != nil {
+		return CHECK_FAIL, fmt.Sprintf("cannot replace %s: %v", path, err)
+	}
+	return status, detail
+}
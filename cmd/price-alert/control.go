@@ -0,0 +1,314 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/control"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+const controlHelp = `commands:
+  status                          show state of every symbol
+  pause | resume                  stop/restart processing ticks (connection stays up)
+  mute | unmute                   silence/restore alert notifications
+  snooze <duration> [rule]        silence all alerts, or a rule's, for a while
+  unsnooze [rule]                 end a snooze early
+  ack [symbol]                    acknowledge escalated alerts, counting them over
+  set-step <step>[%] [symbol]     change the alert step until the next reload
+  set-checkpoint <price> [symbol] move the checkpoint
+  target <price> [symbol]         alert once when the price crosses a level
+  repeat-target <price> [symbol]  alert on every crossing of a level, a step apart
+  untarget <price> [symbol]       drop the targets at a level
+  subscribe <symbol> [stream]     add a symbol until the next reload
+  unsubscribe <symbol>            drop a symbol until the next reload
+  subscriptions                   list the streams of the live connection
+  help                            this text`
+
+// exec runs one control command against the monitor state. It is called
+// from the loop goroutine, so it may touch the state freely.
+func (m *monitor) exec(args []string) string {
+	switch args[0] {
+	case "help":
+		return controlHelp
+	case "status":
+		return m.status()
+	case "pause":
+		m.paused = true
+		return "paused"
+	case "resume":
+		m.paused = false
+		clear(m.trades) // trades skipped while paused are no gap
+		return "resumed"
+	case "mute":
+		m.muted = true
+		return "muted"
+	case "unmute":
+		m.muted = false
+		return "unmuted"
+	case "snooze":
+		return m.snooze(args[1:])
+	case "unsnooze":
+		return m.unsnooze(args[1:])
+	case "ack":
+		return m.ack(args[1:])
+	case "set-step", "set-checkpoint", "target", "repeat-target", "untarget":
+		if len(args) < 2 || len(args) > 3 {
+			return "error: usage: " + args[0] + " <value> [symbol]"
+		}
+		number, percent := strings.CutSuffix(args[1], "%")
+		value, err := strconv.ParseFloat(number, 64)
+		if percent && (args[0] != "set-step" || value >= 100) {
+			return "error: only set-step takes a percentage, below 100%"
+		}
+		if err != nil || value <= 0 {
+			return "error: value must be a positive number"
+		}
+		sc, err := m.pickSymbol(args[2:])
+		if err != nil {
+			return "error: " + err.Error()
+		}
+		switch args[0] {
+		case "set-step":
+			if percent {
+				sc.StepPercent = value
+				return fmt.Sprintf("%s step %s%%", sc.Label(), strconv.FormatFloat(value, 'f', -1, 64))
+			}
+			sc.Step, sc.StepPercent = value, 0
+			return fmt.Sprintf("%s step %s", sc.Label(), sc.Format(value))
+		case "target":
+			m.targets.Add(sc.Symbol, value)
+			return fmt.Sprintf("%s target %s", sc.Label(), sc.Format(value))
+		case "repeat-target":
+			m.targets.Every(sc.Symbol, value, m.step(*sc))
+			return fmt.Sprintf("%s repeating target %s", sc.Label(), sc.Format(value))
+		case "untarget":
+			if !m.targets.Remove(sc.Symbol, value) {
+				return fmt.Sprintf("error: %s has no target at %s", sc.Label(), sc.Format(value))
+			}
+			return fmt.Sprintf("%s target %s removed", sc.Label(), sc.Format(value))
+		}
+		m.stepper.SetCheckpoint(sc.Symbol, value)
+		return fmt.Sprintf("%s checkpoint %s", sc.Label(), sc.Format(value))
+	}
+	return fmt.Sprintf("error: unknown command %q, try help", args[0])
+}
+
+// pickSymbol returns the named symbol, or the only configured one when no
+// name is given.
+func (m *monitor) pickSymbol(names []string) (*config.SymbolConfig, error) {
+	if len(names) == 0 {
+		if len(m.cfg.Symbols) != 1 {
+			return nil, fmt.Errorf("%d symbols configured, name one", len(m.cfg.Symbols))
+		}
+		return &m.cfg.Symbols[0], nil
+	}
+	sc := m.cfg.Symbol(names[0])
+	if sc == nil {
+		return nil, fmt.Errorf("unknown symbol %s", names[0])
+	}
+	return sc, nil
+}
+
+func (m *monitor) status() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "paused=%t muted=%t", m.paused, m.muted)
+	if m.cfg.QuietHours.Enabled() {
+		fmt.Fprintf(&b, " quiet=%t held=%d", m.cfg.QuietHours.Contains(time.Now()), len(m.held))
+	}
+	now := time.Now()
+	if m.snoozed.After(now) {
+		fmt.Fprintf(&b, " snoozed=%s", m.snoozed.Sub(now).Round(time.Second))
+	}
+	for _, name := range m.snoozedRules(now) {
+		fmt.Fprintf(&b, " snoozed[%s]=%s", name, m.snoozes[name].Sub(now).Round(time.Second))
+	}
+	b.WriteString("\n")
+	for _, sc := range m.cfg.Symbols {
+		fmt.Fprintf(&b, "%s step=%s", sc.Label(), sc.Format(m.step(sc)))
+		if sc.StepPercent > 0 {
+			fmt.Fprintf(&b, " step_percent=%g", sc.StepPercent)
+		}
+		if atr, ok := m.atrs.Value(sc.Symbol); ok && sc.ATR.Multiple > 0 {
+			fmt.Fprintf(&b, " atr=%s", sc.Format(atr))
+		}
+		for _, name := range sc.MovingAverages.Averages {
+			if v, ok := m.avgs.Value(sc.Symbol, name); ok {
+				fmt.Fprintf(&b, " %s=%s", name, sc.Format(v))
+			}
+		}
+		if v, ok := m.rsis.Value(sc.Symbol); ok && sc.RSI.Enabled() {
+			fmt.Fprintf(&b, " rsi=%.1f", v)
+		}
+		if v, signal, ok := m.macds.Value(sc.Symbol); ok && sc.MACD.Enabled() {
+			fmt.Fprintf(&b, " macd=%s/%s", sc.Format(v), sc.Format(signal))
+		}
+		if v, ok := m.bands.Value(sc.Symbol); ok && sc.Bollinger.Enabled() {
+			fmt.Fprintf(&b, " bollinger=%s-%s", sc.Format(v.Lower), sc.Format(v.Upper))
+		}
+		if v, _, ok := m.vwaps.Value(sc.Symbol); ok && sc.VWAP.Enabled() {
+			fmt.Fprintf(&b, " vwap=%s", sc.Format(v))
+		}
+		if open, _, ok := m.dailyChanges.Value(sc.Symbol); ok && sc.DailyChange.Enabled() {
+			if t, ok := m.last[sc.Symbol]; ok {
+				fmt.Fprintf(&b, " today=%+.2f%%", (t.Price-open)/open*100)
+			}
+		}
+		if v, avg, ok := m.volumes.Value(sc.Symbol); ok && sc.Volume.Enabled() {
+			fmt.Fprintf(&b, " volume=%s/%s", m.num.Format(v, 2), m.num.Format(avg, 2))
+		}
+		if buy, sell, ok := m.deltas.Value(sc.Symbol); ok && sc.VolumeDelta.Enabled() {
+			fmt.Fprintf(&b, " delta=%s", m.num.Format(buy-sell, 2))
+		}
+		if v, _, ok := m.highs.Value(sc.Symbol); ok && sc.ATH {
+			fmt.Fprintf(&b, " ath=%s", sc.Format(v))
+		}
+		if support, resistance, ok := m.swings.Value(sc.Symbol); ok && sc.Levels.Enabled() {
+			if support > 0 {
+				fmt.Fprintf(&b, " support=%s", sc.Format(support))
+			}
+			if resistance > 0 {
+				fmt.Fprintf(&b, " resistance=%s", sc.Format(resistance))
+			}
+		}
+		for _, window := range sc.Volatility.Windows {
+			if v, ok := m.vols.Value(sc.Symbol, window); ok {
+				fmt.Fprintf(&b, " vol_%s=%s%%", window, m.num.Format(v, 1))
+			}
+		}
+		for _, period := range sc.Pivots.Periods {
+			if levels, ok := m.points.Value(sc.Symbol, config.KlineDuration(period)); ok {
+				fmt.Fprintf(&b, " %s:", period)
+				for i, name := range sc.Pivots.Levels {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					fmt.Fprintf(&b, "%s=%s", name, sc.Format(levels[name]))
+				}
+			}
+		}
+		for _, name := range sc.Sessions {
+			if l, ok := m.sessions.Levels(sc.Symbol, name); ok && l.LastHigh > 0 {
+				fmt.Fprintf(&b, " %s=%s-%s", name, sc.Format(l.LastLow), sc.Format(l.LastHigh))
+			}
+		}
+		if cp, ok := m.stepper.Checkpoint(sc.Symbol); ok {
+			fmt.Fprintf(&b, " checkpoint=%s", sc.Format(cp))
+		}
+		if t, ok := m.last[sc.Symbol]; ok {
+			fmt.Fprintf(&b, " price=%s age=%s", sc.Format(t.Price), time.Since(t.Time).Round(time.Second))
+			if t.Ask > 0 {
+				fmt.Fprintf(&b, " bid=%s ask=%s", sc.Format(t.Bid), sc.Format(t.Ask))
+			}
+			if t.Day != nil {
+				fmt.Fprintf(&b, " 24h=%+.2f%% high=%s low=%s", t.Day.ChangePct(t.Price), sc.Format(t.Day.High), sc.Format(t.Day.Low))
+			}
+			if t.Degraded {
+				b.WriteString(" degraded")
+			}
+			if m.stale[sc.Symbol] {
+				b.WriteString(" stale")
+			}
+		}
+		if f, ok := m.fundings[sc.Symbol]; ok {
+			fmt.Fprintf(&b, " funding=%.4f%% next_funding=%s premium=%+.3f%%", f.rate, time.Until(f.next).Round(time.Minute), f.premium)
+		}
+		if bs, ok := m.bases[sc.Symbol]; ok {
+			fmt.Fprintf(&b, " basis=%+.3f%% annualized=%+.1f%%", bs.pct, bs.annualized)
+		}
+		if r, ok := m.correlations.Value(sc.Symbol); ok {
+			fmt.Fprintf(&b, " correlation=%.2f", r)
+		}
+		if levels := m.targets.Levels(sc.Symbol); len(levels) > 0 {
+			formatted := make([]string, len(levels))
+			for i, l := range levels {
+				formatted[i] = sc.Format(l)
+			}
+			fmt.Fprintf(&b, " targets=%s", strings.Join(formatted, ","))
+		}
+		if high, low, ok := m.trails.Extremes(sc.Symbol); ok && sc.Trailing.Enabled() {
+			fmt.Fprintf(&b, " trail_high=%s trail_low=%s", sc.Format(high), sc.Format(low))
+		}
+		if levels := m.targets.Repeating(sc.Symbol); len(levels) > 0 {
+			formatted := make([]string, len(levels))
+			for i, l := range levels {
+				formatted[i] = sc.Format(l)
+			}
+			fmt.Fprintf(&b, " repeating=%s", strings.Join(formatted, ","))
+		}
+		if source := m.source(sc); source != "" {
+			fmt.Fprintf(&b, " source=%s", source)
+		}
+		if w, ok := m.workers[sc.Symbol]; ok {
+			b.WriteString(w.status())
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// openControl starts the control socket when one is configured. Failing to
+// listen is not fatal: the monitor just runs without it.
+func (m *monitor) openControl() {
+	if m.cfg.ControlPath() == "" {
+		return
+	}
+	srv, err := control.Listen(m.cfg.ControlPath(), m.requests)
+	if err != nil {
+		logging.Warnf("Control socket disabled: %v", err)
+		return
+	}
+	m.control = srv
+}
+
+// isTerminal reports whether f is a tty. A plain character-device check
+// would also match /dev/null, which is stdin under systemd.
+func isTerminal(f *os.File) bool {
+	var t syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TCGETS, uintptr(unsafe.Pointer(&t)))
+	return errno == 0
+}
+
+// openREPL accepts the control commands on stdin when it is a terminal.
+func (m *monitor) openREPL() {
+	if !isTerminal(os.Stdin) {
+		return
+	}
+	logging.Infof("Interactive mode: type help for commands")
+	go control.Serve(os.Stdin, os.Stdout, "> ", m.requests)
+}
+
+// ctlCmd sends one command to a running writer's control socket.
+func ctlCmd(args []string) int {
+	fs := flag.NewFlagSet("ctl", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: price-alert ctl [flags] <command> [args]")
+		fmt.Fprintln(os.Stderr, controlHelp)
+		fs.PrintDefaults()
+	}
+	loader := config.ParseFlags(fs, args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	cfg, err := loader.Load()
+	if err != nil {
+		logging.Fatalf("config: %v", err)
+	}
+	if cfg.ControlPath() == "" {
+		fmt.Fprintln(os.Stderr, "control_socket is off")
+		return 1
+	}
+	if err := control.Send(cfg.ControlPath(), strings.Join(fs.Args(), " "), os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "ctl:", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+)
+
+// correlation samples the price of sc from t against the latest price of
+// correlation.with and raises its alert when the two stop moving together,
+// e.g. "ETHUSDT correlation with BTCUSDT broke down to 0.31 over 1h".
+// Nothing is sampled before with has a price.
+func (m *monitor) correlation(sc config.SymbolConfig, t feed.Tick) {
+	with := m.cfg.Symbol(sc.Correlation.With)
+	ref, ok := m.last[sc.Correlation.With]
+	if with == nil || !ok {
+		return
+	}
+	ev, ok := m.correlations.Update(sc.Symbol, t.Price, ref.Price, t.Time, sc.Correlation.Window, sc.Correlation.Below)
+	if !ok {
+		return
+	}
+	ev.Time = t.Time
+	ev.Text = fmt.Sprintf("%s correlation with %s broke down to %s over %v", sc.Label(), with.Label(), m.num.Format(ev.Level, 2), shortDuration(sc.Correlation.Window))
+	m.notify(ev)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/jsonpath"
+	"github.com/qqubb/tts_price_alert/internal/secrets"
+)
+
+// jsonEndpoints returns the custom sources of cfg by name, with their
+// headers as resolved by resolveCustomHeaders.
+func jsonEndpoints(cfg config.Config) map[string]feed.JSONEndpoint {
+	endpoints := make(map[string]feed.JSONEndpoint, len(cfg.Custom.Sources))
+	for name, src := range cfg.Custom.Sources {
+		path, _ := jsonpath.Parse(src.Path) // checked by Validate
+		endpoints[name] = feed.JSONEndpoint{URL: src.URL, Header: src.Header, Path: path}
+	}
+	return endpoints
+}
+
+// customSource polls the custom exchange's markets, each a JSON API.
+func customSource(cfg config.Config, markets []string) feed.Source {
+	return &feed.Quotes{
+		Quote:    feed.JSONQuoter(jsonEndpoints(cfg)),
+		Symbols:  markets,
+		Interval: cfg.Custom.Interval,
+	}
+}
+
+// resolveCustomHeaders resolves the header secrets of the custom sources
+// into cfg, skipping those already resolved.
+func resolveCustomHeaders(cfg *config.Config) error {
+	if cfg.Source == config.SOURCE_REPLAY {
+		return nil
+	}
+	for name, src := range cfg.Custom.Sources {
+		if src.Header != nil || len(src.Headers) == 0 {
+			continue
+		}
+		src.Header = make(map[string]string, len(src.Headers))
+		for k, v := range src.Headers {
+			value, err := secrets.Resolve(newSecrets(*cfg), v)
+			if err != nil {
+				return fmt.Errorf("custom: sources.%s: headers.%s: %w", name, k, err)
+			}
+			src.Header[k] = value
+		}
+		cfg.Custom.Sources[name] = src
+	}
+	return nil
+}
+
+// customInfo stands in for an exchange lookup of a custom source,
+// deriving its tick size from the current price as for coins.
+func customInfo(cfg config.Config) feed.InfoFetcher {
+	return func(client *http.Client, restEndpoint, symbol string) (feed.SymbolInfo, error) {
+		if err := resolveCustomHeaders(&cfg); err != nil {
+			return feed.SymbolInfo{}, err
+		}
+		price, err := feed.FetchJSONPrice(client, jsonEndpoints(cfg)[symbol])
+		if err != nil {
+			return feed.SymbolInfo{}, err
+		}
+		return feed.CoinInfo(symbol, price), nil
+	}
+}
+
+// checkCustom asks the custom source name for its price, which proves the
+// URL, its headers and the path.
+func checkCustom(client *http.Client, cfg config.Config, name string) (string, string) {
+	if err := resolveCustomHeaders(&cfg); err != nil {
+		return CHECK_FAIL, err.Error()
+	}
+	price, err := feed.FetchJSONPrice(client, jsonEndpoints(cfg)[name])
+	if err != nil {
+		return CHECK_FAIL, err.Error()
+	}
+	return CHECK_OK, fmt.Sprintf("%s = %g", cfg.Custom.Sources[name].Path, price)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// dailyChange raises the alert of sc when the price t passed a threshold
+// of its daily_change from the day's open, e.g. "ETHUSDT up 2 percent
+// today, at 3570, from 3500".
+func (m *monitor) dailyChange(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	d := sc.DailyChange
+	ev, pct, ok := m.dailyChanges.Update(sc.Symbol, t.Price, d.Day(t.Time), d.Percents)
+	if !ok {
+		return
+	}
+	dir := "up"
+	if ev.Change < 0 {
+		dir = "down"
+	}
+	ev.Time, ev.Source, ev.Size, ev.Spread = t.Time, source, t.Size, t.Ask-t.Bid
+	ev.Text = fmt.Sprintf("%s %s %s percent today, at %s, from %s%s", sc.Label(), dir, m.num.Format(pct, -1), sc.DisplayAlert(m.num, t.Price), sc.DisplayAlert(m.num, ev.Level), via)
+	m.notify(ev)
+}
+
+// backfillDailyChange opens the day of symbols with daily_change not opened
+// yet at the open of the 5m kline their anchor falls in, so a start in the
+// middle of the day still measures from its open. A symbol whose first
+// market is not on Binance, or whose klines fail, opens the day at its
+// first price.
+func (m *monitor) backfillDailyChange() {
+	if m.cfg.Source == config.SOURCE_REPLAY {
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, sc := range m.cfg.Symbols {
+		d := sc.DailyChange
+		if !d.Enabled() {
+			continue
+		}
+		if _, _, ok := m.dailyChanges.Value(sc.Symbol); ok {
+			continue
+		}
+		market := m.cfg.Markets(sc)[0]
+		fetch := klineFetcher(market.Exchange)
+		if fetch == nil {
+			continue
+		}
+		_, rest := m.cfg.Endpoints(market.Exchange)
+		day := d.Day(time.Now())
+		// The klines since the anchor and the one it falls in
+		klines, err := fetch(client, rest, market.Symbol, "5m", int(time.Since(day)/(5*time.Minute))+2)
+		if err != nil {
+			logging.Warn(fmt.Sprintf("%s daily change backfill failed, opening the day at the first price: %v", sc.Label(), err),
+				"event", "backfill", "symbol", sc.Symbol)
+			continue
+		}
+		for _, k := range klines {
+			if k.CloseTime.After(day) {
+				m.dailyChanges.Open(sc.Symbol, day, k.Open)
+				logging.Info(fmt.Sprintf("%s day opened at %s", sc.Label(), sc.Format(k.Open)),
+					"event", "backfill", "symbol", sc.Symbol, "open", k.Open)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+	"github.com/qqubb/tts_price_alert/internal/notify"
+)
+
+// DUP_KEEP is how long a run with dropped alerts waits for a repeat to
+// announce its count.
+const DUP_KEEP = 24 * time.Hour
+
+// dupRun is a run of identical alerts: when the first and the last one
+// announced came, and how many there were, dropped ones included.
+type dupRun struct {
+	first, shown time.Time
+	count        int
+}
+
+// dupKey identifies ev for dedup_window: its symbol, kind, rule and level,
+// or price for the kinds without one, rounded as its alert text shows it.
+func (m *monitor) dupKey(ev alert.Event) string {
+	level := ev.Level
+	if level == 0 {
+		level = ev.Price
+	}
+	rounded := strconv.FormatFloat(level, 'g', 6, 64)
+	if sc := m.cfg.Symbol(ev.Symbol); sc != nil {
+		rounded = sc.DisplayAlert(m.num, level)
+	}
+	return ev.Symbol + " " + ev.Kind.String() + " " + ev.Rule + " " + ev.Session + " " + rounded
+}
+
+// duplicate reports whether ev repeats an alert announced within
+// dedup_window, which it then only logs. The first repeat past the window
+// is announced with the count of the run, "ETHUSDT down to 3400, third
+// time in 10 minutes".
+func (m *monitor) duplicate(ev *alert.Event) bool {
+	window := m.cfg.DedupWindow
+	if window <= 0 {
+		return false
+	}
+	at := ev.Time
+	if at.IsZero() {
+		at = time.Now()
+	}
+	for key, run := range m.dups {
+		if since := at.Sub(run.shown); since >= window && run.count == 1 || since >= DUP_KEEP {
+			delete(m.dups, key)
+		}
+	}
+	key := m.dupKey(*ev)
+	run, ok := m.dups[key]
+	if !ok {
+		m.dups[key] = &dupRun{first: at, shown: at, count: 1}
+		return false
+	}
+	run.count++
+	if at.Sub(run.shown) < window {
+		logging.Info("[duplicate] "+ev.Text, notify.EventAttrs(*ev)...)
+		return true
+	}
+	if ev.Repeats == 0 { // an escalated alert already says how often
+		ev.Text += fmt.Sprintf(", %s time in %s", ordinal(run.count), spokenDuration(at.Sub(run.first)))
+	}
+	*run = dupRun{first: at, shown: at, count: 1}
+	return false
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+)
+
+// depth feeds a book snapshot of sc into its imbalance and alerts once
+// one side has outweighed the other depth.ratio times for depth.for, e.g.
+// "BTCUSDT book 3.2 to 1 bids over asks in the top 10 levels for 12s, at
+// 60,120", often ahead of a move that way.
+func (m *monitor) depth(sc config.SymbolConfig, t feed.Tick) {
+	if !sc.Depth.Enabled() {
+		return
+	}
+	ev, lasted, ok := m.depths.Update(sc.Symbol, t.Depth.Bids, t.Depth.Asks, t.Price, t.Time, sc.Depth.Ratio, sc.Depth.For)
+	if !ok {
+		return
+	}
+	ev.Time = t.Time
+	sides := "bids over asks"
+	if ev.Change < 0 {
+		sides = "asks over bids"
+	}
+	ev.Text = fmt.Sprintf("%s book %s to 1 %s in the top %d levels for %s, at %s", sc.Label(), m.num.Format(ev.Level, 1), sides, sc.Depth.Levels,
+		shortDuration(lasted.Round(time.Second)), sc.DisplayAlert(m.num, t.Price))
+	m.notify(ev)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+)
+
+// escalate counts ev towards escalation and, once it fired escalation.count
+// times within the window, announces it as a warning one severity up,
+// "Warning, ETHUSDT down to 3400, fifth time in 24 minutes". A step
+// streak already says how many came, so its text only gets the warning.
+func (m *monitor) escalate(ev *alert.Event) {
+	e := m.cfg.Escalation
+	if e.Count == 0 || ev.Kind == alert.Start {
+		return
+	}
+	counted := *ev
+	if counted.Time.IsZero() {
+		counted.Time = time.Now()
+	}
+	n, since := m.repeats.Update(counted, e.Window)
+	if n < e.Count {
+		return
+	}
+	ev.Repeats = n
+	ev.Severity = min(ev.Severity+1, alert.Critical)
+	ev.Text = "Warning, " + ev.Text
+	if ev.Streak == 0 {
+		ev.Text += fmt.Sprintf(", %s time in %s", ordinal(n), spokenDuration(counted.Time.Sub(since)))
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// failover picks the market a symbol with failover markets is priced from:
+// the first, in config.Markets order, that traded within
+// reconnect.failover_after. All of them stream all the time, so a backup
+// takes over without a dial and the primary is back as soon as it trades.
+type failover struct {
+	seen   []time.Time // last tick per market
+	active int
+}
+
+// newFailover starts with the primary active and counted as fresh, so a
+// backup that happens to trade first does not take over at startup.
+func newFailover(markets int, now time.Time) *failover {
+	f := &failover{seen: make([]time.Time, markets)}
+	f.seen[0] = now
+	return f
+}
+
+// pick records a tick from market i at now and reports whether it comes
+// from the active market, and whether the active market changed.
+func (f *failover) pick(i int, now time.Time, after time.Duration) (use, changed bool) {
+	f.seen[i] = now
+	active := i
+	for j, t := range f.seen[:i] {
+		if now.Sub(t) < after {
+			active = j
+			break
+		}
+	}
+	changed = active != f.active
+	f.active = active
+	return active == i, changed
+}
+
+// failover reports whether t comes from the active market of the failover
+// symbol sc, logging every switch, and returns that market's exchange.
+func (m *monitor) failover(sc config.SymbolConfig, t feed.Tick) (source string, ok bool) {
+	markets := m.cfg.Markets(sc)
+	i := slices.IndexFunc(markets, func(mk config.MarketConfig) bool {
+		return mk.Exchange == t.Exchange && mk.Symbol == t.Symbol
+	})
+	if i < 0 {
+		return "", false
+	}
+	now := time.Now()
+	f, found := m.failovers[sc.Symbol]
+	if !found {
+		f = newFailover(len(markets), now)
+		m.failovers[sc.Symbol] = f
+	}
+	prev := f.active
+	use, changed := f.pick(i, now, m.cfg.Reconnect.FailoverAfter)
+	active := markets[f.active]
+	if changed && f.active > prev {
+		logging.Warn(fmt.Sprintf("%s failing over to %s: %s silent for %v", sc.Label(), active.Exchange, markets[prev].Exchange, m.cfg.Reconnect.FailoverAfter),
+			"event", "failover", "symbol", sc.Symbol, "source", active.Exchange)
+	} else if changed {
+		logging.Info(fmt.Sprintf("%s back on %s", sc.Label(), active.Exchange),
+			"event", "failback", "symbol", sc.Symbol, "source", active.Exchange)
+	}
+	return active.Exchange, use
+}
+
+// activeMarket returns the index into config.Markets of the market sc is
+// priced from.
+func (m *monitor) activeMarket(sc config.SymbolConfig) int {
+	if f, ok := m.failovers[sc.Symbol]; ok {
+		return f.active
+	}
+	return 0
+}
+
+// source returns the exchange sc is currently priced from, or "" unless it
+// has failover markets.
+func (m *monitor) source(sc config.SymbolConfig) string {
+	if len(sc.Failover) == 0 || m.cfg.Source == config.SOURCE_REPLAY {
+		return ""
+	}
+	return m.cfg.Markets(sc)[m.activeMarket(sc)].Exchange
+}
+
+// via annotates an alert text of sc while a backup market prices it.
+func (m *monitor) via(sc config.SymbolConfig) string {
+	if m.source(sc) == "" || m.activeMarket(sc) == 0 {
+		return ""
+	}
+	return " via " + m.source(sc)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// fundingState is the latest funding of a perpetual, in percent, for the
+// status output and the stats region.
+type fundingState struct {
+	rate    float64
+	settled *float64 // the rate seen at the last settlement since startup
+	next    time.Time
+	premium float64 // of the mark over the index, 0 before an index is seen
+}
+
+// funding records the funding of sc reported by t and raises its funding
+// alerts, e.g. "BTCUSDT funding rose above 0.05 percent, at 0.0612
+// percent". A settlement is noticed when the next funding time moves on,
+// and the rate in force just before it is kept as the settled one.
+func (m *monitor) funding(slot int, sc config.SymbolConfig, t feed.Tick) {
+	rate := t.Funding.Rate * 100
+	st, seen := m.fundings[sc.Symbol]
+	if seen && t.Funding.Next.After(st.next) {
+		settled := st.rate
+		st.settled = &settled
+		logging.Info(fmt.Sprintf("%s funding settled at %s percent", sc.Label(), m.num.Format(settled, 4)),
+			"event", "funding_settled", "symbol", sc.Symbol, "rate", settled)
+	}
+	st.rate, st.next = rate, t.Funding.Next
+	if t.Funding.Index > 0 {
+		st.premium = (t.Price/t.Funding.Index - 1) * 100
+	}
+	m.fundings[sc.Symbol] = st
+	m.writeStats(slot, sc)
+
+	levels := sc.Funding.Levels
+	if sc.Funding.SignFlip {
+		levels = append(slices.Clone(levels), 0)
+	}
+	for _, ev := range m.fundingRates.Update(sc.Symbol, rate, levels) {
+		ev.Time, ev.Price = t.Time, t.Price
+		at := m.num.Format(rate, 4)
+		switch {
+		case ev.Level == 0 && rate < 0:
+			ev.Text = fmt.Sprintf("%s funding turned negative, at %s percent", sc.Label(), at)
+		case ev.Level == 0:
+			ev.Text = fmt.Sprintf("%s funding turned positive, at %s percent", sc.Label(), at)
+		case rate < ev.Level:
+			ev.Text = fmt.Sprintf("%s funding fell below %s percent, at %s percent", sc.Label(), m.num.Format(ev.Level, -1), at)
+		default:
+			ev.Text = fmt.Sprintf("%s funding rose above %s percent, at %s percent", sc.Label(), m.num.Format(ev.Level, -1), at)
+		}
+		m.notify(ev)
+	}
+}
+
+// premium raises the premium alert of sc when the mark price t trades far
+// from its index, e.g. "BTCUSDT premium 0.52% over index 64000.00" or
+// "BTCUSDT discount 0.61% under index 64000.00".
+func (m *monitor) premium(sc config.SymbolConfig, t feed.Tick) {
+	if sc.Premium.Percent <= 0 || t.Funding.Index <= 0 {
+		return
+	}
+	pct := (t.Price/t.Funding.Index - 1) * 100
+	ev, ok := m.premiums.Update(sc.Symbol, pct, sc.Premium.Percent)
+	if !ok {
+		return
+	}
+	name, side := "premium", "over"
+	if pct < 0 {
+		name, side = "discount", "under"
+	}
+	ev.Time, ev.Price = t.Time, t.Price
+	ev.Text = fmt.Sprintf("%s %s %s%% %s index %s", sc.Label(), name, m.num.Format(math.Abs(pct), 2), side, sc.Display(m.num, t.Funding.Index))
+	m.notify(ev)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// MAX_GAP_TRADES caps the aggregate trades backfilled for one gap, so a
+// long outage costs a few requests rather than a flood of stale alerts.
+const MAX_GAP_TRADES = 5 * feed.AGG_TRADES_LIMIT
+
+// lastTrade is the last trade seen for a symbol, on stream.
+type lastTrade struct {
+	stream string // exchange and stream type; trade and aggTrade IDs differ
+	id     int64
+	at     time.Time
+}
+
+// aggTradesFetcher returns the aggregate trades lookup of exchange, or nil
+// when it has none.
+func aggTradesFetcher(exchange string) feed.AggTradesFetcher {
+	switch exchange {
+	case config.SOURCE_BINANCE:
+		return feed.FetchAggTrades
+	case config.SOURCE_BINANCE_FUTURES:
+		return feed.FetchFuturesAggTrades
+	}
+	return nil
+}
+
+// fillGap handles the trades the stream of t skipped since the previous
+// one before t itself. Trade IDs run without holes while a connection is
+// up, so a jump means a reconnect missed trades; they are fetched from the
+// aggTrades endpoint and handled as Backfilled ticks, so crossed targets
+// and steps still alert. It blocks the loop for the requests, once per
+// reconnect and symbol.
+func (m *monitor) fillGap(sc config.SymbolConfig, t feed.Tick) {
+	stream := t.Exchange + " " + sc.Stream
+	prev, seen := m.trades[sc.Symbol]
+	m.trades[sc.Symbol] = lastTrade{stream: stream, id: t.TradeID, at: t.Time}
+	fetch := aggTradesFetcher(t.Exchange)
+	if !seen || prev.stream != stream || t.TradeID <= prev.id+1 || fetch == nil {
+		return
+	}
+	missed := t.TradeID - prev.id - 1
+	agg := sc.Stream == config.STREAM_AGG_TRADE
+	_, rest := m.cfg.Endpoints(t.Exchange)
+	client := &http.Client{Timeout: 5 * time.Second}
+	// aggTrade IDs are the stream's own; a trade stream's are found by
+	// time and then the trade IDs each aggregate covers
+	fromID, start := int64(0), prev.at
+	if agg {
+		fromID = prev.id + 1
+	}
+	var ticks []feed.Tick
+	var err error
+fetch:
+	for len(ticks) < MAX_GAP_TRADES {
+		var trades []feed.AggTrade
+		if trades, err = fetch(client, rest, t.Symbol, fromID, start); err != nil {
+			break
+		}
+		for _, a := range trades {
+			switch {
+			case agg && a.ID >= t.TradeID, !agg && a.FirstID >= t.TradeID:
+				break fetch
+			case !agg && a.LastID <= prev.id:
+				continue
+			}
+			ticks = append(ticks, feed.Tick{Symbol: t.Symbol, Price: a.Price, Size: a.Qty, Side: a.Side, Time: a.Time,
+				Exchange: t.Exchange, TradeID: a.ID, Backfilled: true})
+		}
+		if len(trades) < feed.AGG_TRADES_LIMIT {
+			break
+		}
+		fromID = trades[len(trades)-1].ID + 1
+	}
+	switch {
+	case err != nil:
+		logging.Warn(fmt.Sprintf("%s missed %d trades over a reconnect, backfilled %d: %v", sc.Label(), missed, len(ticks), err),
+			"event", "trade_gap", "symbol", sc.Symbol, "missed", missed)
+	case len(ticks) >= MAX_GAP_TRADES:
+		logging.Warn(fmt.Sprintf("%s missed %d trades over a reconnect, backfilled the first %d aggregates", sc.Label(), missed, len(ticks)),
+			"event", "trade_gap", "symbol", sc.Symbol, "missed", missed)
+	default:
+		logging.Info(fmt.Sprintf("%s missed %d trades over a reconnect, backfilled", sc.Label(), missed),
+			"event", "trade_gap", "symbol", sc.Symbol, "missed", missed)
+	}
+	for _, bt := range ticks {
+		m.handle(bt)
+	}
+}
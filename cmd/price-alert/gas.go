@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+)
+
+// gasSource polls the base fee of the ethereum exchange's markets, which
+// are labels: every one reads the same node.
+func gasSource(cfg config.Config, markets []string) feed.Source {
+	return &feed.Quotes{
+		Quote:        feed.FetchBaseFee,
+		RESTEndpoint: cfg.Ethereum.RPCURL,
+		Symbols:      markets,
+		Interval:     cfg.Ethereum.Interval,
+	}
+}
+
+// gas raises the gas alerts of sc for the base fee of t, e.g. "GAS under
+// 10 gwei, at 8.42 gwei".
+func (m *monitor) gas(sc config.SymbolConfig, t feed.Tick) {
+	for _, ev := range m.gasLevels.Update(sc.Symbol, t.Price, sc.Gas.Below) {
+		ev.Time = t.Time
+		ev.Text = fmt.Sprintf("%s under %s gwei, at %s gwei", sc.Label(), m.num.Format(ev.Level, -1), m.num.Format(t.Price, 2))
+		m.notify(ev)
+	}
+}
+
+// checkGas asks the node for the base fee.
+func checkGas(client *http.Client, cfg config.Config) (string, string) {
+	fee, _, err := feed.FetchBaseFee(client, cfg.Ethereum.RPCURL, "", "")
+	if err != nil {
+		return CHECK_FAIL, err.Error()
+	}
+	return CHECK_OK, fmt.Sprintf("base fee %.2f gwei", fee)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// levels folds the price t of sc into its support and resistance candles
+// and announces a level the price nears or breaks, e.g. "ETHUSDT
+// approaching the high of 4 days ago at 3680, at 3671".
+func (m *monitor) levels(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	l := sc.Levels
+	ev, at, ok := m.swings.Update(sc.Symbol, t.Price, t.Time, config.KlineDuration(l.Interval), l.Lookback, l.Swing, l.Approach)
+	if !ok {
+		return
+	}
+	ev.Time, ev.Source, ev.Size, ev.Spread = t.Time, source, t.Size, t.Ask-t.Bid
+	verb, extreme := "approaching", "high"
+	if ev.Kind == alert.Breakout {
+		verb = "broke"
+	}
+	if ev.Side == "support" {
+		extreme = "low"
+	}
+	ev.Text = fmt.Sprintf("%s %s the %s of %s at %s, at %s%s", sc.Label(), verb, extreme, spokenAgo(t.Time.Sub(at)),
+		sc.DisplayAlert(m.num, ev.Level), sc.DisplayAlert(m.num, t.Price), via)
+	m.notify(ev)
+}
+
+// spokenAgo reads the age d of a level out loud, "5 hours ago", or in days
+// past two.
+func spokenAgo(d time.Duration) string {
+	if d < 48*time.Hour {
+		return spokenDuration(d) + " ago"
+	}
+	return strconv.Itoa(int(d.Round(24*time.Hour)/(24*time.Hour))) + " days ago"
+}
+
+// backfillLevels seeds the candles of symbols with levels from the klines
+// of their first market, so the swings of the lookback are known from the
+// start. A symbol whose first market is not on Binance, or whose klines
+// fail, finds its levels in the candles built from its prices.
+func (m *monitor) backfillLevels() {
+	if m.cfg.Source == config.SOURCE_REPLAY {
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, sc := range m.cfg.Symbols {
+		l := sc.Levels
+		if !l.Enabled() {
+			continue
+		}
+		if _, _, ok := m.swings.Value(sc.Symbol); ok {
+			continue
+		}
+		market := m.cfg.Markets(sc)[0]
+		fetch := klineFetcher(market.Exchange)
+		if fetch == nil {
+			continue
+		}
+		_, rest := m.cfg.Endpoints(market.Exchange)
+		// One more for the open kline
+		klines, err := fetch(client, rest, market.Symbol, l.Interval, l.Lookback+1)
+		if err != nil {
+			logging.Warn(fmt.Sprintf("%s levels backfill failed, finding them from the prices: %v", sc.Label(), err),
+				"event", "backfill", "symbol", sc.Symbol)
+			continue
+		}
+		for _, k := range klines {
+			m.swings.Add(sc.Symbol, k.OpenTime, k.High, k.Low, l.Lookback, l.Swing)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+)
+
+// liquidation adds a forced order of sc to its window and alerts once the
+// window's notional reaches liquidations.threshold, e.g. "BTCUSDT
+// 2,500,000 liquidated in 1m, 80% longs, at 60,120".
+func (m *monitor) liquidation(sc config.SymbolConfig, t feed.Tick) {
+	rules := sc.Liquidations
+	if rules.Threshold == 0 {
+		return
+	}
+	long := t.Liquidation.Side == "SELL"
+	ev, longs, ok := m.liqs.Add(sc.Symbol, t.Price, t.Size, long, t.Time, rules.Window, rules.Threshold)
+	if !ok {
+		return
+	}
+	ev.Time, ev.Size = t.Time, t.Size
+	side, share := "longs", longs/ev.Level
+	if share < 0.5 {
+		side, share = "shorts", 1-share
+	}
+	ev.Text = fmt.Sprintf("%s %s liquidated in %s, %s%% %s, at %s", sc.Label(), m.num.Format(ev.Level, 0), shortDuration(rules.Window),
+		m.num.Format(math.Round(share*100), 0), side, sc.DisplayAlert(m.num, t.Price))
+	m.notify(ev)
+}
+
+// shortDuration drops the zero minutes and seconds time.Duration.String
+// spells out, "5m" rather than "5m0s".
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
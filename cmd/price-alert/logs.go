@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// logFile is the open log.file, kept across reloads.
+var logFile *logging.RotatingFile
+
+// setupLogging applies the log settings; reload calls it again so level,
+// format and file changes take effect without a restart. An unchanged
+// log.file is reopened, which also picks up a file moved by logrotate.
+func setupLogging(lc config.LogConfig) {
+	level, err := logging.ParseLevel(lc.Level)
+	if err != nil {
+		level = logging.INFO
+	}
+	var out io.Writer = os.Stderr
+	old := logFile
+	logFile = nil
+	if lc.File != "" {
+		maxSize := int64(lc.MaxSize) << 20
+		if old != nil && old.Path == lc.File {
+			logFile, old = old, nil
+			logFile.SetLimits(maxSize, lc.MaxBackups, lc.Compress)
+			err = logFile.Reopen()
+		} else {
+			logFile, err = logging.OpenRotatingFile(lc.File, maxSize, lc.MaxBackups, lc.Compress)
+		}
+		if err != nil {
+			logging.Errorf("log.file: %v, logging to stderr", err)
+			logFile = nil
+		} else {
+			out = logFile
+		}
+	}
+	format := lc.Format
+	if format == logging.FORMAT_AUTO {
+		format = logging.FORMAT_CONSOLE
+		if logFile == nil && logging.StderrIsJournal() {
+			format = logging.FORMAT_JOURNAL
+		}
+	}
+	color := lc.Color == config.COLOR_ALWAYS
+	if lc.Color == config.COLOR_AUTO {
+		_, noColor := os.LookupEnv("NO_COLOR")
+		color = !noColor && out == io.Writer(os.Stderr) && isTerminal(os.Stderr)
+	}
+	logging.Setup(logging.Options{Level: level, Format: format, Color: color, Output: out})
+	if old != nil {
+		old.Close()
+	}
+}
+
+// closeLogging waits for a pending log compression and closes log.file.
+func closeLogging() {
+	if logFile != nil {
+		logFile.Close()
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// MACD_BACKFILL is the most klines backfillMACD fetches, Binance's limit.
+const MACD_BACKFILL = 1000
+
+// macd folds the price t of sc into its MACD candles and raises the alerts
+// of its crosses when a closed candle took the MACD across its signal line
+// or zero, e.g. "ETHUSDT MACD crossed above signal at 3450 (MACD 12.4)".
+func (m *monitor) macd(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	mc := sc.MACD
+	for _, ev := range m.macds.Update(sc.Symbol, t.Price, t.Time, config.KlineDuration(mc.Interval), mc.Fast, mc.Slow, mc.Signal) {
+		line, dir := "signal", "above"
+		switch {
+		case ev.Kind == alert.MACDSignal && !slices.Contains(mc.Crosses, config.MACD_SIGNAL_CROSS):
+			continue
+		case ev.Kind == alert.MACDZero && !slices.Contains(mc.Crosses, config.MACD_ZERO_CROSS):
+			continue
+		case ev.Kind == alert.MACDZero:
+			line = "zero"
+			if ev.Level < 0 {
+				dir = "below"
+			}
+		case ev.Change < 0:
+			dir = "below"
+		}
+		ev.Time, ev.Source = t.Time, source
+		ev.Text = fmt.Sprintf("%s MACD crossed %s %s at %s (MACD %s)%s", sc.Label(), dir, line, sc.DisplayAlert(m.num, ev.Price), sc.Format(ev.Level), via)
+		m.notify(ev)
+	}
+}
+
+// backfillMACD seeds the MACD candles of symbols with macd from the klines
+// of their first market, over three times the slow and signal periods so
+// the EMAs have settled. A symbol whose first market is not on Binance, or
+// whose klines fail, builds its candles from its prices.
+func (m *monitor) backfillMACD() {
+	if m.cfg.Source == config.SOURCE_REPLAY {
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, sc := range m.cfg.Symbols {
+		mc := sc.MACD
+		if !mc.Enabled() {
+			continue
+		}
+		if _, _, ok := m.macds.Value(sc.Symbol); ok {
+			continue
+		}
+		market := m.cfg.Markets(sc)[0]
+		fetch := klineFetcher(market.Exchange)
+		if fetch == nil {
+			continue
+		}
+		_, rest := m.cfg.Endpoints(market.Exchange)
+		klines, err := fetch(client, rest, market.Symbol, mc.Interval, min(3*(mc.Slow+mc.Signal)+1, MACD_BACKFILL))
+		if err != nil {
+			logging.Warn(fmt.Sprintf("%s MACD backfill failed, building it from the prices: %v", sc.Label(), err),
+				"event", "backfill", "symbol", sc.Symbol)
+			continue
+		}
+		for _, k := range klines {
+			m.macds.Add(sc.Symbol, k.OpenTime, k.Close, mc.Fast, mc.Slow, mc.Signal)
+		}
+		if v, signal, ok := m.macds.Value(sc.Symbol); ok {
+			logging.Info(fmt.Sprintf("%s %s MACD %s, signal %s", sc.Label(), mc.Interval, sc.Format(v), sc.Format(signal)),
+				"event", "backfill", "symbol", sc.Symbol, "macd", v, "signal", signal)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Command price-alert streams exchange prices into shared memory, signals
+// readers over a named pipe and announces step moves.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// command is one price-alert subcommand. run receives the arguments after
+// the subcommand name and returns the process exit code.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+var commands = []command{
+	{"run", "stream prices to SHM and raise alerts (default)", runCmd},
+	{"supervise", "like run, with one connection and backoff per symbol", superviseCmd},
+	{"reader", "print prices from SHM as the writer signals them", readerCmd},
+	{"replay", "feed recorded ticks through the alert pipeline", replayCmd},
+	{"check-config", "validate the configuration before deploying", checkConfigCmd},
+	{"ctl", "send a command to a running writer's control socket", ctlCmd},
+	{"secret", "store or look up credentials in the OS keyring", secretCmd},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: price-alert [command] [flags]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun 'price-alert <command> -h' for the flags of a command.")
+}
+
+func main() {
+	args := os.Args[1:]
+	name := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		usage()
+		return
+	}
+	for _, c := range commands {
+		if c.name == name {
+			code := c.run(args)
+			closeLogging()
+			os.Exit(code)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
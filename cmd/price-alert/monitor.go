@@ -0,0 +1,1465 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/aggregate"
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/control"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/ipc"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+	"github.com/qqubb/tts_price_alert/internal/notify"
+	"github.com/qqubb/tts_price_alert/internal/numfmt"
+	"github.com/qqubb/tts_price_alert/internal/sdnotify"
+)
+
+// monitor owns the pipeline state: it writes every tick to its SHM slot,
+// signals readers and turns step moves into alerts. All of its state is
+// touched from the loop goroutine only.
+type monitor struct {
+	cfg     config.Config
+	loader  *config.Loader
+	pub     ipc.Publisher
+	lock    *ipc.Lock   // nil in dry runs
+	stats   *ipc.SHM    // the stats_path region; nil when off or in dry runs
+	alerts  *ipc.Alerts // the alert_path region; nil when off or in dry runs
+	stepper *alert.Stepper
+	atrs    *alert.ATRs // candles of symbols with atr steps
+	streaks *alert.Streaks
+	repeats *alert.Repeats // alerts counted for escalation
+	swings  *alert.Levels  // support and resistance of symbols with levels
+	points  *alert.Pivots  // pivots of symbols with pivots
+	avgs    *alert.MovingAverages
+	rsis    *alert.RSIs
+	macds   *alert.MACDs
+	bands   *alert.BollingerBands
+	vwaps   *alert.VWAPs
+	volumes *alert.Volumes
+	deltas  *alert.VolumeDeltas
+	arbs    *alert.Arbitrages
+	pegs    *alert.Pegs
+	spreads *alert.Spreads
+	depths  *alert.Depths
+	targets *alert.Targets
+	rounds  *alert.RoundNumbers
+	trails  *alert.Trailing
+	speeds  *alert.Velocities
+	candles *alert.Candles // of symbols with candles, volatility or rules
+	vols    *alert.Volatilities
+	rules   *alert.Rules
+	daily   *alert.Daily
+	days    *alert.DayRanges // of symbols with alerts_24h on other streams
+	highs   *alert.AllTimeHighs
+	liqs    *alert.Liquidations
+	// fundingRates raises funding alerts; fundings holds the latest
+	// funding of each perpetual's mark price stream.
+	fundingRates *alert.Funding
+	fundings     map[string]fundingState
+	premiums     *alert.Premiums
+	oiMoves      *alert.OpenInterestMoves
+	gasLevels    *alert.Gas
+	divergences  *alert.Divergences
+	basisLevels  *alert.Bases
+	bases        map[string]basisState // the latest basis of symbols tracking one
+	correlations *alert.Correlations
+	sessions     *alert.Sessions // highs and lows of the sessions of symbols
+	dailyChanges *alert.DailyChanges
+	notifier     notify.Notifier
+	num          numfmt.Locale // cfg.Locale, for log lines and alert texts
+
+	// last holds the latest tick per symbol, for status output.
+	last map[string]feed.Tick
+	// trades holds the last trade ID per symbol, for fillGap.
+	trades map[string]lastTrade
+	// recent holds the last prices of symbols with outliers.
+	recent map[string]*recentPrices
+	// heard holds when each symbol's live stream last delivered a price
+	// and stale the symbols marked stale since; dialled is when the run
+	// connection was last dialled.
+	heard   map[string]time.Time
+	stale   map[string]bool
+	dialled time.Time
+	// aggs holds the composite price state of symbols with markets.
+	aggs map[string]*aggregate.Aggregator
+	// failovers tracks the active market of symbols with failover markets.
+	failovers map[string]*failover
+	// paused drops ticks entirely; muted only silences notifications.
+	paused, muted bool
+
+	control  *control.Server
+	requests chan control.Request // fed by the control socket and the REPL
+	// methods changes the streams of the live binance and binance-futures
+	// connections under run.
+	methods map[string]*feed.BinanceMethods
+	// apiKey is the resolved user_data.api_key; empty when the user data
+	// stream is off.
+	apiKey string
+
+	// workers is the per-symbol connection state under supervise; nil
+	// when one connection carries every symbol.
+	workers map[string]*worker
+
+	// ready is set once a price reached SHM on the current connection and
+	// systemd was told; repeating READY=1 after a reconnect is harmless.
+	ready bool
+	// watchdog is systemd's WatchdogSec (0 when off); pings are only sent
+	// from handle, so a stalled feed stops them.
+	watchdog time.Duration
+	lastPing time.Time
+
+	// started and reconnects feed the status_file; statusC ticks every
+	// status_interval and is nil when the file is off.
+	started    time.Time
+	reconnects int
+	statusC    <-chan time.Time
+
+	// pollC ticks every reconnect.poll_interval and is nil when polling is
+	// off; polled brings a round of REST prices back, polling is set while
+	// one is out and down while the run connection is lost.
+	pollC         <-chan time.Time
+	polled        chan []feed.Tick
+	polling, down bool
+	// oiC ticks every OPEN_INTEREST_INTERVAL and is nil for a replay;
+	// oiPolled brings a round of open interest readings back, oiPolling is
+	// set while one is out.
+	oiC       <-chan time.Time
+	oiPolled  chan []openInterest
+	oiPolling bool
+	// reloaded brings a config loaded after SIGHUP back, nil when it
+	// failed; reloading is set while one is out, and rehup when another
+	// SIGHUP came meanwhile.
+	reloaded         chan *config.Config
+	reloading, rehup bool
+	// clockC ticks every second for the stale_after checks and the end of
+	// quiet hours, and is nil for a replay.
+	clockC <-chan time.Time
+	// candleOut is candle_file while open.
+	candleOut *os.File
+	// held are the alerts queued during quiet_hours, oldest first.
+	held []heldAlert
+	// snoozed is when the snooze of all alerts ends, snoozes those of
+	// single rules by name.
+	snoozed time.Time
+	snoozes map[string]time.Time
+	// dups are the runs of identical alerts within dedup_window, by key.
+	dups map[string]*dupRun
+}
+
+// resolve fills in per-symbol defaults from the exchange tick sizes.
+func resolve(cfg *config.Config) {
+	lookups := make(map[string]config.TickLookup)
+	cfg.ResolveDefaults(func(symbol string) (float64, int, error) {
+		// An aggregated symbol takes the tick size of its first market
+		market := cfg.Markets(*cfg.Symbol(symbol))[0]
+		lookup, ok := lookups[market.Exchange]
+		if !ok {
+			_, rest := cfg.Endpoints(market.Exchange)
+			fetch := infoFetcher(market.Exchange)
+			switch market.Exchange {
+			case config.SOURCE_COINS:
+				fetch = coinInfo(*cfg)
+			case config.SOURCE_CUSTOM:
+				fetch = customInfo(*cfg)
+			}
+			lookup = feed.TickLookup(fetch, rest)
+			lookups[market.Exchange] = lookup
+		}
+		return lookup(market.Symbol)
+	})
+}
+
+// infoFetcher returns the market lookup of exchange.
+func infoFetcher(exchange string) feed.InfoFetcher {
+	switch exchange {
+	case config.SOURCE_COINBASE:
+		return feed.FetchCoinbaseProduct
+	case config.SOURCE_KRAKEN:
+		return feed.FetchKrakenPair
+	case config.SOURCE_BYBIT:
+		return feed.BybitInstrument("spot")
+	case config.SOURCE_BYBIT_LINEAR:
+		return feed.BybitInstrument("linear")
+	case config.SOURCE_OKX:
+		return feed.FetchOKXInstrument
+	case config.SOURCE_STOCKS:
+		return feed.StockInfo
+	case config.SOURCE_FOREX:
+		return feed.ForexInfo
+	case config.SOURCE_ETHEREUM:
+		return feed.GasInfo
+	case config.SOURCE_CHAINLINK:
+		return feed.ChainlinkInfo
+	case config.SOURCE_UNISWAP:
+		return feed.UniswapInfo
+	case config.SOURCE_PLUGIN:
+		return pluginInfo
+	case config.SOURCE_BASKET:
+		return basketInfo
+	case config.SOURCE_BINANCE_FUTURES:
+		return feed.FetchFuturesSymbolInfo
+	}
+	return feed.FetchSymbolInfo
+}
+
+// slot returns the SHM slot t belongs to, or -1. Live ticks are matched by
+// exchange and market, so an aggregated symbol's markets share its slot.
+func (m *monitor) slot(t feed.Tick) int {
+	if t.Exchange == "" {
+		return m.cfg.Slot(t.Symbol)
+	}
+	return m.cfg.MarketSlot(t.Exchange, t.Symbol)
+}
+
+// aggregate folds a market tick of the aggregated symbol sc into its
+// composite price and returns that as a tick of sc.
+func (m *monitor) aggregate(sc config.SymbolConfig, t feed.Tick) feed.Tick {
+	a, ok := m.aggs[sc.Symbol]
+	if !ok {
+		a = aggregate.New(sc.Aggregate.Method, sc.Aggregate.Window)
+		m.aggs[sc.Symbol] = a
+	}
+	price := a.Add(t.Exchange+":"+t.Symbol, t.Price, t.Size, time.Now())
+	return feed.Tick{Symbol: sc.Symbol, Price: price, Size: t.Size, Time: t.Time}
+}
+
+// handle processes one tick. Each symbol owns the SHM slot matching its
+// position in the config.
+func (m *monitor) handle(t feed.Tick) {
+	if t.Fill != nil {
+		m.fill(t) // not a price, so it has no slot
+		return
+	}
+	if t.Exchange == BASIS_EXCHANGE {
+		if !m.paused {
+			m.basis(t) // a contract's mark price, not a symbol's price
+		}
+		return
+	}
+	slot := m.slot(t)
+	if slot < 0 {
+		return
+	}
+	m.keepalive()
+	if m.paused {
+		return
+	}
+	sc := m.cfg.Symbols[slot]
+	if t.Liquidation != nil {
+		m.liquidation(sc, t)
+		return
+	}
+	if t.Depth != nil {
+		m.depth(sc, t)
+		return
+	}
+	if t.Funding != nil {
+		m.funding(slot, sc, t)
+		m.premium(sc, t)
+		if !strings.HasPrefix(sc.Stream, config.STREAM_MARK_PRICE) {
+			return // the extra mark price stream of funding and premium alerts
+		}
+	}
+	if t.TradeID > 0 && !t.Backfilled && len(sc.Markets) == 0 && len(sc.Failover) == 0 {
+		m.fillGap(sc, t)
+	}
+	source, via := "", ""
+	switch {
+	case t.Backfilled:
+		via = " (backfilled)"
+	case t.Degraded:
+		// Polled from the first market only, so neither aggregated nor a
+		// failover candidate
+		t.Symbol, source, via = sc.Symbol, config.REST_SOURCE, " (degraded)"
+	case len(sc.Markets) > 0 && t.Exchange != "":
+		t = m.aggregate(sc, t)
+	case len(sc.Failover) > 0 && t.Exchange != "":
+		var ok bool
+		if source, ok = m.failover(sc, t); !ok {
+			return // a standby market
+		}
+		t.Symbol, via = sc.Symbol, m.via(sc)
+	}
+	// A bad print neither counts as a sign of life nor brings a stale
+	// feed back
+	if sc.Outliers.Percent > 0 && m.outlier(sc, t) {
+		return
+	}
+	if !t.Degraded && !t.Backfilled {
+		m.heard[sc.Symbol] = time.Now()
+		if m.stale[sc.Symbol] {
+			m.fresh(sc, t, source, via)
+		}
+	}
+	if m.down && !t.Degraded {
+		m.down = false
+		logging.Info("Stream back, polling stopped", "event", "recovered")
+	}
+	m.last[t.Symbol] = t
+	defer m.baskets(sc.Symbol, t)
+	label := sc.Label()
+
+	if t.Day != nil {
+		m.writeStats(slot, sc)
+	}
+	if err := m.pub.Publish(slot, sc.Format(t.Price), label, source); err != nil {
+		logging.Error("Publish error: "+err.Error(), "symbol", t.Symbol, "slot", slot)
+	} else if !m.ready && !t.Degraded {
+		m.ready = true
+		m.sdNotify(sdnotify.READY + "\n" + sdnotify.Status("streaming "+strings.Join(m.cfg.SymbolNames(), ",")))
+	}
+
+	if t.Day != nil {
+		m.dailyAlerts(sc, t, source, via)
+	} else if sc.Alerts24h.Enabled() {
+		m.rollingDay(sc, t, source, via)
+	}
+	if len(sc.Gas.Below) > 0 {
+		m.gas(sc, t)
+	}
+	if sc.Divergence.Percent > 0 {
+		m.divergence(sc, t, source, via)
+	}
+	if sc.Correlation.With != "" {
+		m.correlation(sc, t)
+	}
+	if sc.ATR.Multiple > 0 {
+		m.atr(sc, t)
+	}
+	if len(sc.MovingAverages.Averages) > 0 {
+		m.averages(slot, sc, t, source, via)
+	}
+	if sc.RSI.Enabled() {
+		m.rsi(sc, t, source, via)
+	}
+	if sc.MACD.Enabled() {
+		m.macd(sc, t, source, via)
+	}
+	if sc.Bollinger.Enabled() {
+		m.bollinger(sc, t, source, via)
+	}
+	if sc.VWAP.Enabled() {
+		m.vwap(slot, sc, t, source, via)
+	}
+	if sc.CloseOnly && !t.Closed {
+		return // published, but alerts wait for the candle to close
+	}
+	for _, ev := range m.targets.Update(t.Symbol, t.Price) {
+		ev.Time, ev.Source, ev.Size, ev.Spread = t.Time, source, t.Size, t.Ask-t.Bid
+		ev.Text = fmt.Sprintf("%s crossed %s%s", label, sc.DisplayAlert(m.num, ev.Level), via)
+		m.notify(ev)
+	}
+	if sc.RoundNumbers > 0 {
+		if ev, ok := m.rounds.Update(t.Symbol, t.Price, sc.RoundNumbers); ok {
+			side := "above"
+			if ev.Change < 0 {
+				side = "below"
+			}
+			ev.Time, ev.Source, ev.Size, ev.Spread = t.Time, source, t.Size, t.Ask-t.Bid
+			ev.Text = fmt.Sprintf("%s %s %s%s", label, side, sc.DisplayAlert(m.num, ev.Level), via)
+			m.notify(ev)
+		}
+	}
+	if sc.Trailing.Enabled() {
+		m.trail(sc, t, source, via)
+	}
+	if sc.Arbitrage.Percent > 0 && !t.Degraded {
+		m.arbitrage(sc, t, source, via)
+	}
+	if sc.Peg.Enabled() {
+		m.peg(sc, t, source, via)
+	}
+	if sc.Spread.Enabled() && t.Bid > 0 && t.Ask > 0 {
+		m.spread(sc, t, source, via)
+	}
+	if sc.Whales.Notional > 0 {
+		m.whale(sc, t, source, via)
+	}
+	if sc.Volume.Enabled() {
+		m.volume(sc, t, source, via)
+	}
+	if sc.VolumeDelta.Enabled() {
+		m.volumeDelta(sc, t, source, via)
+	}
+	if sc.Velocity.Enabled() {
+		m.velocity(sc, t, source, via)
+	}
+	if len(sc.Sessions) > 0 {
+		m.sessionBreaks(slot, sc, t, source, via)
+	}
+	if sc.DailyChange.Enabled() {
+		m.dailyChange(sc, t, source, via)
+	}
+	if sc.ATH {
+		m.ath(sc, t, source, via)
+	}
+	if sc.Levels.Enabled() {
+		m.levels(sc, t, source, via)
+	}
+	if sc.Pivots.Enabled() {
+		m.pivots(sc, t, source, via)
+	}
+	if len(sc.Candles) > 0 || sc.Volatility.Enabled() || len(sc.Rules) > 0 {
+		m.candle(slot, sc, t, source, via)
+	}
+	if len(sc.Rules) > 0 {
+		m.rule(sc, t, source, via)
+	}
+
+	ev, change, ok := m.stepper.Update(t.Symbol, t.Price, m.step(sc), grid(sc))
+	if !ok {
+		if logging.Enabled(logging.DEBUG) {
+			logging.Debug(fmt.Sprintf("%s tick %s Δ %s", label, sc.Display(m.num, t.Price), sc.Display(m.num, change)),
+				"event", "tick", "symbol", t.Symbol, "price", t.Price, "delta", change,
+				"latency_ms", time.Since(t.Time).Milliseconds())
+		}
+		return
+	}
+	ev.Time, ev.Source, ev.Size, ev.Spread = t.Time, source, t.Size, t.Ask-t.Bid
+	switch ev.Kind {
+	case alert.Start:
+		ev.Text = fmt.Sprintf("%s starting price checkpoint: %s%s", label, sc.Display(m.num, t.Price), via)
+	case alert.Up:
+		ev.Text = fmt.Sprintf("%s up to %s%s", label, sc.DisplayAlert(m.num, t.Price), via)
+	case alert.Down:
+		ev.Text = fmt.Sprintf("%s down to %s%s", label, sc.DisplayAlert(m.num, t.Price), via)
+	}
+	if sc.Streak.Count > 0 {
+		m.streak(sc, &ev, via)
+	}
+	m.notify(ev)
+}
+
+// trail raises the trailing alert of sc when t retraced from its extreme,
+// e.g. "ETHUSDT fell 3.1% from its high of 3450 to 3343".
+func (m *monitor) trail(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	tr := sc.Trailing
+	ev, ok := m.trails.Update(t.Symbol, t.Price, tr.Amount, tr.Percent, tr.From != config.TRAILING_LOW, tr.From != config.TRAILING_HIGH)
+	if !ok {
+		return
+	}
+	moved, extreme := "fell", "high"
+	if ev.Change > 0 {
+		moved, extreme = "rose", "low"
+	}
+	by := sc.Display(m.num, math.Abs(ev.Change))
+	if tr.Percent > 0 {
+		by = m.num.Format(math.Abs(ev.Change/ev.Level*100), 1) + "%"
+	}
+	ev.Time, ev.Source, ev.Size, ev.Spread = t.Time, source, t.Size, t.Ask-t.Bid
+	ev.Text = fmt.Sprintf("%s %s %s from its %s of %s to %s%s", sc.Label(), moved, by, extreme,
+		sc.DisplayAlert(m.num, ev.Level), sc.DisplayAlert(m.num, t.Price), via)
+	m.notify(ev)
+}
+
+// velocity raises the velocity alert of sc when t moved fast, e.g.
+// "ETHUSDT up 32.50 in 41s, to 3482".
+func (m *monitor) velocity(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	v := sc.Velocity
+	ev, took, ok := m.speeds.Update(t.Symbol, t.Price, t.Time, v.Amount, v.Percent, v.Window)
+	if !ok {
+		return
+	}
+	dir := "up"
+	if ev.Change < 0 {
+		dir = "down"
+	}
+	by := sc.Display(m.num, math.Abs(ev.Change))
+	if v.Percent > 0 {
+		by = m.num.Format(math.Abs(ev.Change/ev.Level*100), 1) + "%"
+	}
+	ev.Time, ev.Source, ev.Size, ev.Spread = t.Time, source, t.Size, t.Ask-t.Bid
+	ev.Text = fmt.Sprintf("%s %s %s in %s, to %s%s", sc.Label(), dir, by, shortDuration(took.Round(time.Second)), sc.DisplayAlert(m.num, t.Price), via)
+	m.notify(ev)
+}
+
+// rule raises the alerts of the rules of sc that t made true, as
+// "ETHUSDT dip at 3480" or their own text, and runs their actions.
+func (m *monitor) rule(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	conditions := make([]alert.Condition, len(sc.Rules))
+	for i, r := range sc.Rules {
+		conditions[i] = alert.Condition{Name: r.Name, When: r.Expr, Candle: r.On == config.RULE_CANDLE, Cooldown: r.Cooldown,
+			Rearm: r.Rearm, For: r.For, Candles: r.Candles, Confirm: r.Confirm}
+	}
+	for _, ev := range m.rules.Update(t.Symbol, t.Price, t.Bid, t.Ask, t.Time, conditions) {
+		price := sc.DisplayAlert(m.num, ev.Price)
+		ev.Time, ev.Source, ev.Size, ev.Spread = t.Time, source, t.Size, t.Ask-t.Bid
+		ev.Text = fmt.Sprintf("%s %s at %s%s", sc.Label(), ev.Rule, price, via)
+		i := slices.IndexFunc(sc.Rules, func(r config.RuleConfig) bool { return r.Name == ev.Rule })
+		r := sc.Rules[i]
+		ev.Severity, _ = alert.ParseSeverity(r.Severity)
+		if r.Text != "" {
+			ev.Text = strings.ReplaceAll(r.Text, "{price}", price)
+		}
+		if r.Quiet {
+			logging.Info("[quiet] "+ev.Text, notify.EventAttrs(ev)...)
+		} else {
+			m.notify(ev)
+		}
+		m.act(r.Actions, ev)
+	}
+}
+
+// configureTargets registers the targets of each symbol's config, replacing
+// the previous config's and keeping those added over the control socket.
+func (m *monitor) configureTargets() {
+	for _, sc := range m.cfg.Symbols {
+		rearm := sc.Targets.Rearm
+		if rearm == 0 {
+			rearm = m.step(sc)
+		}
+		m.targets.Configure(sc.Symbol, sc.Targets.Once, sc.Targets.Every, rearm)
+	}
+}
+
+// step returns the alert step of sc: a multiple of its ATR once known,
+// else the step at its current checkpoint.
+func (m *monitor) step(sc config.SymbolConfig) float64 {
+	if atr, ok := m.atrs.Value(sc.Symbol); ok && sc.ATR.Multiple > 0 {
+		return sc.ATR.Multiple * atr
+	}
+	checkpoint, _ := m.stepper.Checkpoint(sc.Symbol)
+	return sc.StepAt(checkpoint)
+}
+
+// grid returns the checkpoint grid of sc: rounding from 0, or from origin
+// when anchored, snapping every checkpoint unless sc resets to the price.
+func grid(sc config.SymbolConfig) alert.Grid {
+	g := alert.Grid{Size: sc.Rounding, Snap: sc.Reset != config.RESET_PRICE}
+	if sc.Reset == config.RESET_ANCHORED {
+		g.Origin = sc.Origin
+	}
+	return g
+}
+
+// keepalive pings the systemd watchdog at half its timeout, as
+// sd_watchdog_enabled(3) recommends.
+func (m *monitor) keepalive() {
+	if m.watchdog == 0 {
+		return
+	}
+	if now := time.Now(); now.Sub(m.lastPing) >= m.watchdog/2 {
+		m.lastPing = now
+		m.sdNotify(sdnotify.WATCHDOG)
+	}
+}
+
+// sdNotify reports state to systemd; outside a unit it does nothing.
+func (m *monitor) sdNotify(state string) {
+	if _, err := sdnotify.Notify(state); err != nil {
+		logging.Warnf("sd_notify: %v", err)
+	}
+}
+
+// notify delivers ev unless it is snoozed, repeats an alert within
+// dedup_window or falls in quiet hours.
+func (m *monitor) notify(ev alert.Event) {
+	if ev.Severity == 0 {
+		ev.Severity = ev.Kind.Severity()
+	}
+	m.release()
+	if m.snoozedAt(ev, time.Now()) {
+		logging.Info("[snoozed] "+ev.Text, notify.EventAttrs(ev)...)
+		return
+	}
+	m.escalate(&ev)
+	if m.duplicate(&ev) {
+		return
+	}
+	if m.quiet(ev) {
+		m.hold(ev)
+		return
+	}
+	m.deliver(ev)
+}
+
+// deliver logs ev, writes it to alert_path for the speaking reader and
+// runs the notifiers of its severity unless alerts are muted.
+func (m *monitor) deliver(ev alert.Event) {
+	if m.muted {
+		logging.Info("[muted] "+ev.Text, notify.EventAttrs(ev)...)
+		return
+	}
+	if err := m.notifier.Notify(ev); err != nil {
+		logging.Errorf("Notify error: %v", err)
+	}
+	m.announce(ev)
+	var actions []string
+	for _, n := range m.cfg.Notifiers {
+		if sev, _ := alert.ParseSeverity(n.Severity); ev.Severity >= sev {
+			actions = append(actions, n.Action)
+		}
+	}
+	if ev.Repeats > 0 {
+		for _, name := range m.cfg.Escalation.Actions {
+			if !slices.Contains(actions, name) {
+				actions = append(actions, name)
+			}
+		}
+	}
+	m.act(actions, ev)
+}
+
+// reload re-reads the configuration in the background, as resolving its
+// tick sizes and markets waits for the exchanges, and hands it to the loop
+// on reloaded. A SIGHUP arriving meanwhile reloads again once it is in.
+func (m *monitor) reload() {
+	if m.reloading {
+		m.rehup = true
+		return
+	}
+	m.reloading = true
+	cur := m.cfg
+	go func() {
+		if next, ok := m.loadConfig(cur); ok {
+			m.reloaded <- &next
+		} else {
+			m.reloaded <- nil
+		}
+	}()
+}
+
+// loadConfig loads and resolves the next configuration, carrying over the
+// secrets of cur, and reports false on a failure it has logged.
+func (m *monitor) loadConfig(cur config.Config) (config.Config, bool) {
+	next, err := m.loader.Load()
+	if err != nil {
+		logging.Errorf("Reload failed, keeping current config: %v", err)
+		return next, false
+	}
+	// Resolved before privileges were dropped, and used by resolve
+	next.Stocks.Key, next.Forex.Key, next.Coins.Key = cur.Stocks.Key, cur.Forex.Key, cur.Coins.Key
+	for name, src := range next.Custom.Sources {
+		if old, ok := cur.Custom.Sources[name]; ok && maps.Equal(old.Headers, src.Headers) {
+			src.Header = old.Header
+			next.Custom.Sources[name] = src
+		}
+	}
+	for name, p := range next.Plugins {
+		if old, ok := cur.Plugins[name]; ok && maps.Equal(old.Env, p.Env) {
+			p.Environ = old.Environ
+			next.Plugins[name] = p
+		}
+	}
+	// New header and env secrets may be out of reach now
+	if err := resolveCustomHeaders(&next); err != nil {
+		logging.Errorf("Reload failed, keeping current config: %v (new header secrets need a restart)", err)
+		return next, false
+	}
+	if err := resolvePluginEnv(&next); err != nil {
+		logging.Errorf("Reload failed, keeping current config: %v (new env secrets need a restart)", err)
+		return next, false
+	}
+	resolve(&next)
+	return next, true
+}
+
+// applyReload switches to the config a reload brought back, nil after a
+// failed one, and reports whether the stream has to be resubscribed. The
+// SHM and pipe stay mapped, so path changes are ignored until the next
+// restart.
+func (m *monitor) applyReload(loaded *config.Config) (resubscribe bool) {
+	m.reloading = false
+	if m.rehup {
+		m.rehup = false
+		defer m.reload()
+	}
+	if loaded == nil {
+		return false
+	}
+	next, cur := *loaded, m.cfg
+	if next.SHMPath != cur.SHMPath || next.PipePath != cur.PipePath || next.StatsPath != cur.StatsPath || next.AlertPath != cur.AlertPath ||
+		next.BufferSize != cur.BufferSize || next.StatsSize != cur.StatsSize || next.AlertSize != cur.AlertSize || next.Slots != cur.Slots {
+		logging.Warnf("Reload: SHM/pipe/stats/alert path and size changes need a restart, ignoring them")
+		next.SHMPath, next.PipePath, next.StatsPath, next.AlertPath = cur.SHMPath, cur.PipePath, cur.StatsPath, cur.AlertPath
+		next.BufferSize, next.StatsSize, next.AlertSize, next.Slots = cur.BufferSize, cur.StatsSize, cur.AlertSize, cur.Slots
+		if len(next.Symbols) > next.Slots {
+			logging.Errorf("Reload failed, keeping current config: more symbols than SHM slots")
+			return false
+		}
+		for _, sc := range next.Symbols {
+			if need := next.StatsRecordSize(sc); next.StatsPath != "" && need > next.StatsSize {
+				logging.Errorf("Reload failed, keeping current config: %s needs %d-byte stats slots, stats_size is %d", sc.Label(), need, next.StatsSize)
+				return false
+			}
+		}
+	}
+	if next.ControlSocket != cur.ControlSocket || next.PIDFile != cur.PIDFile ||
+		next.StatusFile != cur.StatusFile || next.StatusInterval != cur.StatusInterval ||
+		next.Reconnect.PollInterval != cur.Reconnect.PollInterval || next.UserData != cur.UserData ||
+		next.Stocks.APIKey != cur.Stocks.APIKey || next.Forex.APIKey != cur.Forex.APIKey || next.Coins.APIKey != cur.Coins.APIKey {
+		logging.Warnf("Reload: control_socket/pid_file/status_file/poll_interval/user_data/api_key changes need a restart, ignoring them")
+		next.ControlSocket, next.PIDFile = cur.ControlSocket, cur.PIDFile
+		next.StatusFile, next.StatusInterval = cur.StatusFile, cur.StatusInterval
+		next.Reconnect.PollInterval = cur.Reconnect.PollInterval
+		next.UserData = cur.UserData
+		next.Stocks.APIKey, next.Forex.APIKey, next.Coins.APIKey = cur.Stocks.APIKey, cur.Forex.APIKey, cur.Coins.APIKey
+	}
+	m.cfg = next
+	m.num, _ = numfmt.Parse(next.Locale)
+	for symbol := range m.aggs {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		if s == nil || s.Aggregate != old.Aggregate || !slices.Equal(next.Markets(*s), cur.Markets(*old)) {
+			delete(m.aggs, symbol)
+		}
+	}
+	// Symbols left without rules drop their state, and without candles,
+	// volatility or rules their candles
+	m.rules.Retain(func(symbol string) bool {
+		s := next.Symbol(symbol)
+		return s != nil && len(s.Rules) > 0
+	})
+	m.candles.Retain(func(symbol string) bool {
+		s := next.Symbol(symbol)
+		return s != nil && (len(s.Candles) > 0 || s.Volatility.Enabled() || len(s.Rules) > 0)
+	})
+	// A new volatility interval or windows starts the returns over
+	m.vols.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && s.Volatility.Interval == old.Volatility.Interval &&
+			slices.Equal(s.Volatility.Windows, old.Volatility.Windows)
+	})
+	// New trailing, velocity or volume delta settings arm afresh
+	m.deltas.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && s.VolumeDelta == old.VolumeDelta
+	})
+	m.speeds.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && s.Velocity == old.Velocity
+	})
+	m.trails.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && s.Trailing == old.Trailing
+	})
+	// So do new moving average intervals and averages
+	m.avgs.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && s.MovingAverages.Interval == old.MovingAverages.Interval &&
+			slices.Equal(s.MovingAverages.Averages, old.MovingAverages.Averages)
+	})
+	// New session lists start their levels over
+	m.sessions.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && slices.Equal(s.Sessions, old.Sessions)
+	})
+	// A new daily_change anchor or thresholds opens the day afresh
+	m.dailyChanges.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && s.DailyChange.Equal(old.DailyChange)
+	})
+	// An ath turned off forgets its high, reseeded when turned on again
+	m.highs.Retain(func(symbol string) bool {
+		s := next.Symbol(symbol)
+		return s != nil && s.ATH
+	})
+	// A vwap turned off drops its session
+	m.vwaps.Retain(func(symbol string) bool {
+		s := next.Symbol(symbol)
+		return s != nil && s.VWAP.Enabled()
+	})
+	// A new ATR, RSI, MACD, Bollinger or volume interval or period starts
+	// the candles over
+	m.volumes.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && s.Volume.Interval == old.Volume.Interval && s.Volume.Period == old.Volume.Period
+	})
+	m.bands.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && s.Bollinger.Interval == old.Bollinger.Interval && s.Bollinger.Period == old.Bollinger.Period
+	})
+	m.macds.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && s.MACD.Interval == old.MACD.Interval && s.MACD.Fast == old.MACD.Fast &&
+			s.MACD.Slow == old.MACD.Slow && s.MACD.Signal == old.MACD.Signal
+	})
+	m.rsis.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && s.RSI.Interval == old.RSI.Interval && s.RSI.Period == old.RSI.Period
+	})
+	m.atrs.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && s.ATR.Interval == old.ATR.Interval && s.ATR.Period == old.ATR.Period
+	})
+	// New arbitrage settings or markets arm afresh
+	m.arbs.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && s.Arbitrage == old.Arbitrage && slices.Equal(next.Markets(*s), cur.Markets(*old))
+	})
+	// A new peg judges the price afresh
+	m.pegs.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && s.Peg == old.Peg
+	})
+	// A new spread window learns the normal spread afresh
+	m.spreads.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && s.Spread == old.Spread && s.Stream == old.Stream
+	})
+	// A new depth ratio or hold arms afresh
+	m.depths.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && s.Depth == old.Depth
+	})
+	// New levels settings find the swings afresh, reseeded from klines
+	m.swings.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && s.Levels == old.Levels
+	})
+	// New pivots periods or levels arm afresh
+	m.points.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && slices.Equal(s.Pivots.Periods, old.Pivots.Periods) &&
+			slices.Equal(s.Pivots.Levels, old.Pivots.Levels) && s.Pivots.Rearm == old.Pivots.Rearm
+	})
+	// A new partner or window starts the samples over
+	m.correlations.Retain(func(symbol string) bool {
+		s, old := next.Symbol(symbol), cur.Symbol(symbol)
+		return s != nil && old != nil && s.Correlation == old.Correlation
+	})
+	for symbol := range m.failovers {
+		if s := next.Symbol(symbol); s == nil || !slices.Equal(next.Markets(*s), cur.Markets(*cur.Symbol(symbol))) {
+			delete(m.failovers, symbol)
+		}
+	}
+	setupLogging(next.Log)
+	m.retain(next)
+	m.reslot(cur)
+	m.configureTargets()
+	m.backfill() // symbols the reload added
+	logging.Infof("Config reloaded: %d symbol(s)", len(next.Symbols))
+	return streamChanged(cur, next)
+}
+
+// reslot empties the slots whose symbol changed from cur to the current
+// config, as a removed symbol moves every later one down a slot: a slot
+// taken over is marked stale under its new symbol until that one's next
+// price, a freed one cleared, and either loses its alerts, so readers
+// never act on the record of the symbol that left it.
+func (m *monitor) reslot(cur config.Config) {
+	for slot := range max(len(cur.Symbols), len(m.cfg.Symbols)) {
+		var sc *config.SymbolConfig
+		if slot < len(m.cfg.Symbols) {
+			sc = &m.cfg.Symbols[slot]
+		}
+		if sc != nil && slot < len(cur.Symbols) && cur.Symbols[slot].Symbol == sc.Symbol {
+			continue
+		}
+		if m.alerts != nil {
+			m.alerts.ClearSlot(slot)
+		}
+		label := ""
+		if sc != nil {
+			label = sc.Label()
+			m.writeStats(slot, *sc)
+		} else if m.stats != nil {
+			m.stats.ClearSlot(slot)
+		}
+		// An empty price reads as stale, and with an empty symbol as unused
+		if err := m.pub.Publish(slot, "", label, ""); err != nil {
+			logging.Error("Publish error: "+err.Error(), "slot", slot)
+		}
+	}
+}
+
+// retain drops the alert state and last tick of symbols next no longer
+// has.
+func (m *monitor) retain(next config.Config) {
+	keep := func(symbol string) bool { return next.Slot(symbol) >= 0 }
+	m.stepper.Retain(keep)
+	m.days.Retain(keep)
+	m.highs.Retain(keep)
+	m.dailyChanges.Retain(keep)
+	m.sessions.Retain(keep)
+	m.streaks.Retain(keep)
+	m.repeats.Retain(keep)
+	m.swings.Retain(keep)
+	m.points.Retain(keep)
+	m.atrs.Retain(keep)
+	m.avgs.Retain(keep)
+	m.rsis.Retain(keep)
+	m.macds.Retain(keep)
+	m.bands.Retain(keep)
+	m.vwaps.Retain(keep)
+	m.volumes.Retain(keep)
+	m.deltas.Retain(keep)
+	m.arbs.Retain(keep)
+	m.pegs.Retain(keep)
+	m.spreads.Retain(keep)
+	m.depths.Retain(keep)
+	m.targets.Retain(keep)
+	m.rounds.Retain(keep)
+	m.trails.Retain(keep)
+	m.speeds.Retain(keep)
+	m.rules.Retain(keep)
+	m.candles.Retain(keep)
+	m.vols.Retain(keep)
+	m.daily.Retain(keep)
+	m.liqs.Retain(keep)
+	m.fundingRates.Retain(keep)
+	m.premiums.Retain(keep)
+	m.oiMoves.Retain(keep)
+	m.gasLevels.Retain(keep)
+	m.divergences.Retain(keep)
+	m.basisLevels.Retain(keep)
+	m.correlations.Retain(keep)
+	for symbol := range m.bases {
+		if s := next.Symbol(symbol); s == nil || !s.Basis.Enabled() {
+			delete(m.bases, symbol)
+		}
+	}
+	for symbol := range m.fundings {
+		if !keep(symbol) {
+			delete(m.fundings, symbol)
+		}
+	}
+	for symbol := range m.last {
+		if !keep(symbol) {
+			delete(m.last, symbol)
+		}
+	}
+	for symbol := range m.trades {
+		if !keep(symbol) {
+			delete(m.trades, symbol)
+		}
+	}
+	for symbol := range m.heard {
+		if !keep(symbol) {
+			delete(m.heard, symbol)
+			delete(m.stale, symbol)
+		}
+	}
+	for symbol := range m.recent {
+		if s := next.Symbol(symbol); s == nil || s.Outliers.Percent == 0 {
+			delete(m.recent, symbol)
+		}
+	}
+}
+
+// streamChanged reports whether moving from a to b needs a new source.
+func streamChanged(a, b config.Config) bool {
+	if a.Source != b.Source || a.Replay != b.Replay || len(a.Symbols) != len(b.Symbols) {
+		return true
+	}
+	for i := range a.Symbols {
+		if streamKey(a, a.Symbols[i]) != streamKey(b, b.Symbols[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// streamKey identifies the connections s is streamed over; a change means
+// resubscribing.
+func streamKey(cfg config.Config, s config.SymbolConfig) string {
+	key := s.Symbol + " " + s.Stream
+	if s.Liquidations.Threshold > 0 {
+		key += " forceOrder"
+	}
+	if s.Depth.Enabled() {
+		key += " " + depthStream(s)
+	}
+	if markStream(s) {
+		key += " markPrice"
+	}
+	if s.Basis.Enabled() {
+		key += " basis=" + s.Basis.Market
+	}
+	for _, m := range cfg.Markets(s) {
+		ws, _ := cfg.Endpoints(m.Exchange)
+		key += " " + m.Symbol + "@" + m.Exchange + "=" + ws
+		if q := cfg.Quotes(m.Exchange); q != nil {
+			key += fmt.Sprintf("%s,%s,%v", q.Provider, q.RESTEndpoint, q.Interval)
+		}
+		switch m.Exchange {
+		case config.SOURCE_ETHEREUM:
+			key += fmt.Sprintf("%s,%v", cfg.Ethereum.RPCURL, cfg.Ethereum.Interval)
+		case config.SOURCE_CHAINLINK:
+			key += fmt.Sprintf("%s,%v", cfg.Chainlink.RPCURL, cfg.Chainlink.Interval)
+		case config.SOURCE_UNISWAP:
+			key += fmt.Sprintf("%s,%v", cfg.Uniswap.RPCURL, cfg.Uniswap.Interval)
+		case config.SOURCE_CUSTOM:
+			src := cfg.Custom.Sources[m.Symbol]
+			key += fmt.Sprintf("%s,%s,%v,%v", src.URL, src.Path, src.Headers, cfg.Custom.Interval)
+		case config.SOURCE_PLUGIN:
+			name, _ := config.PluginMarket(m.Symbol)
+			p := cfg.Plugins[name]
+			key += fmt.Sprintf("%s,%q,%v", p.Command, p.Args, p.Env)
+		}
+	}
+	return key
+}
+
+// newSource builds the price source selected by cfg. Binance connections
+// attach to methods, keyed by exchange, for runtime subscriptions.
+func newSource(cfg config.Config, methods map[string]*feed.BinanceMethods) feed.Source {
+	if cfg.Source == config.SOURCE_REPLAY {
+		return &feed.Replay{Path: cfg.Replay.File, Speed: cfg.Replay.Speed}
+	}
+	return marketsSource(cfg, cfg.Symbols, methods)
+}
+
+// marketsSource streams the markets of symbols: one connection per
+// exchange, merged into one tick stream. With failover markets each
+// connection reconnects on its own, so the backups keep streaming while
+// the primary is down.
+func marketsSource(cfg config.Config, symbols []config.SymbolConfig, methods map[string]*feed.BinanceMethods) feed.Source {
+	var exchanges, basis []string
+	markets := make(map[string][]string)
+	standby := false
+	for _, s := range symbols {
+		standby = standby || len(s.Failover) > 0
+		if s.Basis.Enabled() {
+			basis = append(basis, s.Basis.Market)
+		}
+		for _, m := range cfg.Markets(s) {
+			if m.Exchange == config.SOURCE_BASKET {
+				continue // priced from the other symbols' ticks
+			}
+			if _, ok := markets[m.Exchange]; !ok {
+				exchanges = append(exchanges, m.Exchange)
+			}
+			markets[m.Exchange] = append(markets[m.Exchange], symbolStreams(s, m)...)
+		}
+	}
+	if len(exchanges) == 1 && len(basis) == 0 {
+		return exchangeSource(cfg, exchanges[0], markets[exchanges[0]], methods[exchanges[0]])
+	}
+	var multi feed.Multi
+	for _, exchange := range exchanges {
+		src := exchangeSource(cfg, exchange, markets[exchange], methods[exchange])
+		if standby {
+			src = feed.Retry{Source: src, Name: exchange, InitialBackoff: cfg.Reconnect.InitialBackoff, MaxBackoff: cfg.Reconnect.MaxBackoff}
+		}
+		multi = append(multi, src)
+	}
+	if len(basis) > 0 {
+		src := basisSource(cfg, basis)
+		if standby {
+			src = feed.Retry{Source: src, Name: BASIS_EXCHANGE, InitialBackoff: cfg.Reconnect.InitialBackoff, MaxBackoff: cfg.Reconnect.MaxBackoff}
+		}
+		multi = append(multi, src)
+	}
+	if len(multi) == 1 {
+		return multi[0]
+	}
+	return multi
+}
+
+// streamName is the name market m of s is subscribed under: on Binance
+// the symbol with its stream type unless that is a plain trade stream.
+func streamName(s config.SymbolConfig, m config.MarketConfig) string {
+	switch m.Exchange {
+	case config.SOURCE_BINANCE, config.SOURCE_BINANCE_FUTURES:
+		if s.Stream != "" && s.Stream != config.STREAM_TRADE {
+			return m.Symbol + "@" + s.Stream
+		}
+	}
+	return m.Symbol
+}
+
+// symbolStreams lists what market m of s is subscribed to: its streamName,
+// with liquidation alerts its forceOrder stream, with depth alerts its
+// partial depth stream and with funding or premium alerts a mark price
+// stream unless it already prices by the mark.
+func symbolStreams(s config.SymbolConfig, m config.MarketConfig) []string {
+	streams := []string{streamName(s, m)}
+	if s.Liquidations.Threshold > 0 {
+		streams = append(streams, m.Symbol+"@forceOrder")
+	}
+	if s.Depth.Enabled() {
+		streams = append(streams, m.Symbol+"@"+depthStream(s))
+	}
+	if markStream(s) {
+		streams = append(streams, m.Symbol+"@"+config.STREAM_MARK_PRICE)
+	}
+	return streams
+}
+
+// depthStream names the partial depth stream of s, e.g. depth10.
+func depthStream(s config.SymbolConfig) string {
+	return fmt.Sprintf("depth%d", s.Depth.Levels)
+}
+
+// markStream reports whether s needs a mark price stream of its own for
+// its funding or premium alerts.
+func markStream(s config.SymbolConfig) bool {
+	return (s.Funding.Enabled() || s.Premium.Percent > 0) && !strings.HasPrefix(s.Stream, config.STREAM_MARK_PRICE)
+}
+
+// exchangeSource streams markets from one exchange, tagging the ticks with
+// its name. methods is only used by Binance and may be nil.
+func exchangeSource(cfg config.Config, exchange string, markets []string, methods *feed.BinanceMethods) feed.Source {
+	ws, _ := cfg.Endpoints(exchange)
+	ping := cfg.Reconnect.PingPeriod
+	var src feed.Source
+	switch exchange {
+	case config.SOURCE_COINBASE:
+		src = &feed.Coinbase{Endpoint: ws, Symbols: markets, PingPeriod: ping}
+	case config.SOURCE_KRAKEN:
+		src = &feed.Kraken{Endpoint: ws, Symbols: markets, PingPeriod: ping}
+	case config.SOURCE_BYBIT, config.SOURCE_BYBIT_LINEAR:
+		src = &feed.Bybit{Endpoint: ws, Symbols: markets, PingPeriod: ping}
+	case config.SOURCE_OKX:
+		src = &feed.OKX{Endpoint: ws, Symbols: markets, PingPeriod: ping}
+	case config.SOURCE_STOCKS, config.SOURCE_FOREX, config.SOURCE_COINS:
+		src = quotesSource(cfg, exchange, markets)
+	case config.SOURCE_ETHEREUM:
+		src = gasSource(cfg, markets)
+	case config.SOURCE_CHAINLINK:
+		src = chainlinkSource(cfg, markets)
+	case config.SOURCE_UNISWAP:
+		src = uniswapSource(cfg, markets)
+	case config.SOURCE_CUSTOM:
+		src = customSource(cfg, markets)
+	case config.SOURCE_PLUGIN:
+		src = pluginSource(cfg, markets)
+	case config.SOURCE_BINANCE_FUTURES:
+		src = feed.SplitBinance(feed.Binance{Endpoint: ws, Symbols: markets, PingPeriod: ping, Methods: methods}, feed.BINANCE_FUTURES_MAX_STREAMS)
+	default:
+		src = feed.SplitBinance(feed.Binance{Endpoint: ws, Symbols: markets, PingPeriod: ping, Methods: methods}, feed.BINANCE_MAX_STREAMS)
+	}
+	return feed.Tagged{Source: src, Exchange: exchange}
+}
+
+// loop runs the configured source and handles its ticks. SIGHUP reloads the
+// config and resubscribes when the stream changed. A failed live source is
+// restarted with exponential backoff; loop returns once a replay ends, or
+// with a nil error after SIGINT/SIGTERM has closed the source.
+func (m *monitor) loop() error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	backoff := m.cfg.Reconnect.InitialBackoff
+	for {
+		ctx, cancel := context.WithCancel(context.Background())
+		// Unbuffered, so every tick is handled before Run returns
+		ticks := make(chan feed.Tick)
+		errc := make(chan error, 1)
+		src := newSource(m.cfg, m.methods)
+		if ud := m.userDataSource(); ud != nil {
+			src = feed.Multi{src, ud}
+		}
+		m.dialled = time.Now()
+		go func() { errc <- src.Run(ctx, ticks) }()
+
+		var err error
+		resubscribe := false
+	wait:
+		for {
+			select {
+			case t := <-ticks:
+				m.handle(t)
+			case req := <-m.requests:
+				m.serve(req)
+			case <-m.statusC:
+				m.writeStatus("running")
+			case <-m.pollC:
+				m.poll()
+			case polled := <-m.polled:
+				m.handlePolled(polled)
+			case <-m.oiC:
+				m.pollOpenInterest()
+			case readings := <-m.oiPolled:
+				m.handleOpenInterest(readings)
+			case <-m.clockC:
+				m.checkStale()
+				m.release()
+			case err = <-errc:
+				break wait
+			case sig := <-stop:
+				logging.Infof("Received %v, shutting down", sig)
+				m.sdNotify(sdnotify.STOPPING)
+				cancel()
+				<-errc
+				return nil
+			case <-hup:
+				m.reload()
+			case next := <-m.reloaded:
+				if resubscribe = m.applyReload(next); resubscribe {
+					cancel()
+					<-errc
+					break wait
+				}
+			}
+		}
+		cancel()
+
+		if resubscribe {
+			logging.Infof("Resubscribing: %v", m.cfg.SymbolNames())
+			backoff = m.cfg.Reconnect.InitialBackoff
+			continue
+		}
+		if m.cfg.Source == config.SOURCE_REPLAY {
+			return err
+		}
+		if err != nil {
+			logging.Warnf("Client error: %v", err)
+		}
+		logging.Warn(fmt.Sprintf("Reconnecting in %v...", backoff), "event", "reconnect", "backoff", backoff)
+		m.sdNotify(sdnotify.Status(fmt.Sprintf("reconnecting in %v", backoff)))
+		m.ready = false
+		m.reconnects++
+		m.streamLost()
+		if !m.sleep(backoff, hup, stop) {
+			return nil
+		}
+		backoff *= 2
+		if backoff > m.cfg.Reconnect.MaxBackoff {
+			backoff = m.cfg.Reconnect.MaxBackoff
+		}
+	}
+}
+
+// sleep waits for d while still applying reloads, stale checks and the end
+// of quiet hours; the stream is about to be redialled anyway, so no
+// resubscribe is needed. It reports false if a stop signal arrived
+// instead.
+func (m *monitor) sleep(d time.Duration, hup, stop <-chan os.Signal) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			return true
+		case sig := <-stop:
+			logging.Infof("Received %v, shutting down", sig)
+			m.sdNotify(sdnotify.STOPPING)
+			return false
+		case req := <-m.requests:
+			m.serve(req)
+		case <-m.statusC:
+			m.writeStatus("running")
+		case <-m.pollC:
+			m.poll()
+		case polled := <-m.polled:
+			m.handlePolled(polled)
+		case <-m.oiC:
+			m.pollOpenInterest()
+		case readings := <-m.oiPolled:
+			m.handleOpenInterest(readings)
+		case <-m.clockC:
+			m.checkStale()
+			m.release()
+		case <-hup:
+			m.reload()
+		case next := <-m.reloaded:
+			m.applyReload(next)
+		}
+	}
+}
+
+// openMonitor maps the SHM region and opens the pipe for cfg, then drops to
+// permissions.user if one is set. Opening the pipe blocks until a reader
+// attaches. With dryRun set no IPC objects are
+// created and updates are only logged.
+func openMonitor(cfg config.Config, loader *config.Loader, dryRun bool) (*monitor, error) {
+	perm, err := ipcPerm(cfg.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	// Looked up while the keyring and secrets dir are still readable
+	apiKey, err := userDataKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveQuoteKeys(&cfg); err != nil {
+		return nil, err
+	}
+	if err := resolveCustomHeaders(&cfg); err != nil {
+		return nil, err
+	}
+	if err := resolvePluginEnv(&cfg); err != nil {
+		return nil, err
+	}
+	var pub ipc.Publisher = ipc.Discard{}
+	var lock *ipc.Lock
+	if dryRun {
+		logging.Infof("Dry run: not writing %s or %s", cfg.SHMPath, cfg.PipePath)
+	} else {
+		// Two writers on one region would interleave their records, and
+		// one starting up would clear the other's alerts
+		paths := []string{ipc.LockPath(cfg.SHMPath)}
+		if path := cfg.AlertRegion(); path != "" {
+			paths = append(paths, ipc.LockPath(path))
+		}
+		if lock, err = ipc.AcquireLock(paths...); err != nil {
+			return nil, err
+		}
+	}
+	// Written before the FIFO open, which blocks until a reader attaches
+	if cfg.PIDFile != "" {
+		if err := ipc.WritePIDFile(cfg.PIDFile); err != nil {
+			logging.Warnf("PID file: %v", err)
+		}
+	}
+	// Created before the FIFO open, so a reader woken by it can map it
+	var alerts *ipc.Alerts
+	if path := cfg.AlertRegion(); !dryRun && path != "" {
+		// Cleared, as a crashed writer's last alerts are not news to the
+		// next reader
+		if alerts, err = ipc.CreateAlerts(path, cfg.AlertSize, cfg.Slots, perm); err != nil {
+			if cfg.PIDFile != "" {
+				os.Remove(cfg.PIDFile)
+			}
+			lock.Release()
+			return nil, fmt.Errorf("alert_path: %w", err)
+		}
+	}
+	if !dryRun {
+		w, err := ipc.OpenWriter(cfg.SHMPath, cfg.PipePath, cfg.BufferSize, cfg.Slots, perm)
+		if err != nil {
+			if alerts != nil {
+				alerts.Close()
+			}
+			if cfg.PIDFile != "" {
+				os.Remove(cfg.PIDFile)
+			}
+			lock.Release()
+			return nil, err
+		}
+		pub = w
+	}
+	var stats *ipc.SHM
+	if !dryRun && cfg.StatsPath != "" {
+		if stats, err = ipc.CreateSHM(cfg.StatsPath, cfg.StatsSize, cfg.Slots, perm); err != nil {
+			pub.Close()
+			if alerts != nil {
+				alerts.Close()
+			}
+			if cfg.PIDFile != "" {
+				os.Remove(cfg.PIDFile)
+			}
+			lock.Release()
+			return nil, fmt.Errorf("stats_path: %w", err)
+		}
+	}
+	candles := alert.NewCandles()
+	m := &monitor{
+		cfg:          cfg,
+		loader:       loader,
+		pub:          pub,
+		lock:         lock,
+		stats:        stats,
+		alerts:       alerts,
+		stepper:      alert.NewStepper(),
+		atrs:         alert.NewATRs(),
+		streaks:      alert.NewStreaks(),
+		repeats:      alert.NewRepeats(),
+		swings:       alert.NewLevels(),
+		points:       alert.NewPivots(),
+		targets:      alert.NewTargets(),
+		rounds:       alert.NewRoundNumbers(),
+		trails:       alert.NewTrailing(),
+		speeds:       alert.NewVelocities(),
+		candles:      candles,
+		vols:         alert.NewVolatilities(),
+		rules:        alert.NewRules(candles),
+		avgs:         alert.NewMovingAverages(),
+		rsis:         alert.NewRSIs(),
+		macds:        alert.NewMACDs(),
+		bands:        alert.NewBollingerBands(),
+		vwaps:        alert.NewVWAPs(),
+		volumes:      alert.NewVolumes(),
+		deltas:       alert.NewVolumeDeltas(),
+		arbs:         alert.NewArbitrages(),
+		pegs:         alert.NewPegs(),
+		spreads:      alert.NewSpreads(),
+		depths:       alert.NewDepths(),
+		daily:        alert.NewDaily(),
+		days:         alert.NewDayRanges(),
+		highs:        alert.NewAllTimeHighs(),
+		sessions:     alert.NewSessions(),
+		dailyChanges: alert.NewDailyChanges(),
+		liqs:         alert.NewLiquidations(),
+		fundingRates: alert.NewFunding(),
+		fundings:     make(map[string]fundingState),
+		premiums:     alert.NewPremiums(),
+		oiMoves:      alert.NewOpenInterestMoves(),
+		gasLevels:    alert.NewGas(),
+		divergences:  alert.NewDivergences(),
+		basisLevels:  alert.NewBases(),
+		bases:        make(map[string]basisState),
+		correlations: alert.NewCorrelations(),
+		notifier:     notify.Console{},
+		dups:         make(map[string]*dupRun),
+		snoozes:      make(map[string]time.Time),
+		last:         make(map[string]feed.Tick),
+		trades:       make(map[string]lastTrade),
+		recent:       make(map[string]*recentPrices),
+		heard:        make(map[string]time.Time),
+		stale:        make(map[string]bool),
+		aggs:         make(map[string]*aggregate.Aggregator),
+		failovers:    make(map[string]*failover),
+		requests:     make(chan control.Request),
+		methods: map[string]*feed.BinanceMethods{
+			config.SOURCE_BINANCE:         {},
+			config.SOURCE_BINANCE_FUTURES: {},
+		},
+		apiKey:   apiKey,
+		polled:   make(chan []feed.Tick, 1),
+		oiPolled: make(chan []openInterest, 1),
+		reloaded: make(chan *config.Config, 1),
+		watchdog: sdnotify.WatchdogInterval(),
+		started:  time.Now(),
+	}
+	m.num, _ = numfmt.Parse(cfg.Locale)
+	m.statusC = m.statusTicker()
+	m.pollC = m.pollTicker()
+	m.oiC = m.oiTicker()
+	m.clockC = m.clockTicker()
+	m.configureTargets()
+	m.openControl()
+	if u := cfg.Permissions.User; u != "" {
+		if err := dropPrivileges(u, handover(cfg, m.cfg.ControlPath()), handoverDirs(cfg)); err != nil {
+			m.Close()
+			return nil, err
+		}
+		logging.Infof("Running as %s", u)
+	}
+	m.openREPL()
+	return m, nil
+}
+
+// Close tears the pipeline down; the returned error is from the publisher,
+// which marks the SHM slots stale before closing.
+func (m *monitor) Close() error {
+	if m.control != nil {
+		m.control.Close()
+	}
+	m.writeStatus("stopped")
+	m.saveATH()
+	m.closeCandles()
+	err := m.pub.Close()
+	if m.stats != nil {
+		// Cleared like the price slots, so no reader trusts frozen stats
+		m.stats.Clear()
+		err = errors.Join(err, m.stats.Sync(), m.stats.Close())
+	}
+	if m.alerts != nil {
+		m.alerts.Clear()
+		err = errors.Join(err, m.alerts.Sync(), m.alerts.Close())
+	}
+	if m.cfg.PIDFile != "" {
+		os.Remove(m.cfg.PIDFile)
+	}
+	if m.lock != nil {
+		m.lock.Release()
+	}
+	return err
+}
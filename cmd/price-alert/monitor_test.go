@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/control"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/ipc"
+)
+
+// records is a Publisher keeping the price and symbol last written to each
+// slot, "" for a stale price.
+type records map[int][2]string
+
+func (r records) Publish(slot int, price, symbol, _ string) error {
+	r[slot] = [2]string{price, symbol}
+	return nil
+}
+
+func (r records) MarkStale(slot int) error {
+	r[slot] = [2]string{"", r[slot][1]}
+	return nil
+}
+
+func (records) Notify(int) error { return nil }
+func (records) Close() error     { return nil }
+
+// testMonitor returns a replay monitor of symbols, each with a step of 10
+// and 2 decimals, publishing into the returned records and an alert region
+// of its own.
+func testMonitor(t *testing.T, symbols ...string) (*monitor, records) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Source, cfg.ControlSocket, cfg.AlertSeverity = config.SOURCE_REPLAY, "off", "info"
+	precision := 2
+	cfg.Symbols = nil
+	for _, s := range symbols {
+		cfg.Symbols = append(cfg.Symbols, config.SymbolConfig{Symbol: s, Step: 10, Rounding: 10, Precision: &precision})
+	}
+	m, err := openMonitor(cfg, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.alerts, err = ipc.CreateAlerts(filepath.Join(t.TempDir(), "alerts"), cfg.AlertSize, cfg.Slots, ipc.DefaultPerm); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.alerts.Close() })
+	pub := records{}
+	m.pub = pub
+	return m, pub
+}
+
+// ctl runs one control command on m and returns its reply.
+func ctl(m *monitor, line string) string {
+	reqs := make(chan control.Request)
+	var out strings.Builder
+	go func() {
+		control.Serve(strings.NewReader(line+"\n"), &out, "", reqs)
+		close(reqs)
+	}()
+	for req := range reqs {
+		m.serve(req)
+	}
+	return strings.TrimSpace(out.String())
+}
+
+func tick(symbol string, price float64) feed.Tick {
+	return feed.Tick{Symbol: symbol, Price: price, Time: time.Now()}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// openInterest is one polled open interest reading of a symbol.
+type openInterest struct {
+	symbol string
+	oi     float64
+	at     time.Time
+}
+
+// oiTicker returns the open interest poll ticker channel, or nil when the
+// source is a replay.
+func (m *monitor) oiTicker() <-chan time.Time {
+	if m.cfg.Source == config.SOURCE_REPLAY {
+		return nil
+	}
+	return time.NewTicker(config.OPEN_INTEREST_INTERVAL).C
+}
+
+// pollOpenInterest fetches the open interest of every symbol with
+// open_interest alerts. Like poll, the requests run in the background, one
+// round at a time, and the readings come back on m.oiPolled.
+func (m *monitor) pollOpenInterest() {
+	if m.oiPolling {
+		return
+	}
+	var symbols []config.SymbolConfig
+	for _, sc := range m.cfg.Symbols {
+		if sc.OpenInterest.Change > 0 {
+			symbols = append(symbols, sc)
+		}
+	}
+	if len(symbols) == 0 {
+		return
+	}
+	m.oiPolling = true
+	cfg := m.cfg
+	go func() {
+		client := &http.Client{Timeout: config.OPEN_INTEREST_INTERVAL}
+		_, rest := cfg.Endpoints(config.SOURCE_BINANCE_FUTURES)
+		var readings []openInterest
+		for _, sc := range symbols {
+			market := cfg.Markets(sc)[0].Symbol
+			oi, err := feed.FetchOpenInterest(client, rest, market)
+			if err != nil {
+				logging.Warn(fmt.Sprintf("%s open interest poll error: %v", sc.Label(), err),
+					"event", "poll", "symbol", sc.Symbol, "exchange", config.SOURCE_BINANCE_FUTURES)
+				continue
+			}
+			readings = append(readings, openInterest{symbol: sc.Symbol, oi: oi, at: time.Now()})
+		}
+		m.oiPolled <- readings
+	}()
+}
+
+// handleOpenInterest feeds a round of readings, with each symbol's latest
+// price, into the open interest alerts, e.g. "BTCUSDT open interest up
+// 3.2% in 5m as price fell 150, at 60,120".
+func (m *monitor) handleOpenInterest(readings []openInterest) {
+	m.oiPolling = false
+	if m.paused {
+		return
+	}
+	for _, r := range readings {
+		sc := m.cfg.Symbol(r.symbol)
+		if sc == nil || sc.OpenInterest.Change == 0 {
+			continue // removed by a reload while the requests were out
+		}
+		last, ok := m.last[sc.Symbol]
+		if !ok {
+			continue // no price to compare with yet
+		}
+		rules := sc.OpenInterest
+		ev, since, ok := m.oiMoves.Add(sc.Symbol, r.oi, last.Price, r.at, rules.Window, rules.Change, m.step(*sc))
+		if !ok {
+			continue
+		}
+		ev.Time = r.at
+		oiDir, priceDir := "up", "rose"
+		if ev.Level < 0 {
+			oiDir = "down"
+		}
+		if ev.Change < 0 {
+			priceDir = "fell"
+		}
+		ev.Text = fmt.Sprintf("%s open interest %s %s%% in %s as price %s %s, at %s", sc.Label(), oiDir,
+			m.num.Format(math.Abs(ev.Level), 1), shortDuration(since.Round(time.Second)), priceDir,
+			sc.DisplayAlert(m.num, math.Abs(ev.Change)), sc.DisplayAlert(m.num, last.Price))
+		m.notify(ev)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// recentPrices are the last prices of a symbol, dropped ones included.
+type recentPrices struct {
+	prices  []float64 // oldest first, at most outliers.samples
+	dropped int       // since startup
+}
+
+// outlier reports whether the price t of sc is a bad print to drop: more
+// than outliers.percent from the median of the last outliers.samples
+// prices. It logs each one and judges nothing until half the samples are
+// in.
+func (m *monitor) outlier(sc config.SymbolConfig, t feed.Tick) bool {
+	r, ok := m.recent[sc.Symbol]
+	if !ok {
+		r = &recentPrices{}
+		m.recent[sc.Symbol] = r
+	}
+	var median float64
+	if n := len(r.prices); n > sc.Outliers.Samples/2 {
+		sorted := slices.Clone(r.prices)
+		slices.Sort(sorted)
+		median = sorted[n/2]
+		if n%2 == 0 {
+			median = (sorted[n/2-1] + sorted[n/2]) / 2
+		}
+	}
+	r.prices = append(r.prices, t.Price)
+	if len(r.prices) > sc.Outliers.Samples {
+		r.prices = r.prices[len(r.prices)-sc.Outliers.Samples:]
+	}
+	if median == 0 {
+		return false
+	}
+	off := math.Abs(t.Price-median) / median * 100
+	if off <= sc.Outliers.Percent {
+		return false
+	}
+	r.dropped++
+	logging.Warn(fmt.Sprintf("%s dropped outlier %s, %s%% from the median %s", sc.Label(), sc.Display(m.num, t.Price), m.num.Format(off, 2), sc.Display(m.num, median)),
+		"event", "outlier", "symbol", sc.Symbol, "price", t.Price, "median", median)
+	return true
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+)
+
+// peg raises the alerts of the stablecoin pair sc straying from its peg,
+// e.g. "USDCUSDT depeg 0.62 percent below 1.0000, at 0.9938", repeated while it
+// stays out, and "USDCUSDT back on peg at 0.9998" once it returns.
+func (m *monitor) peg(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	ev, back, ok := m.pegs.Update(sc.Symbol, t.Price, t.Time, sc.Peg.Target, sc.Peg.Band, sc.Peg.Repeat)
+	if !ok {
+		return
+	}
+	ev.Time, ev.Source, ev.Size, ev.Spread = t.Time, source, t.Size, t.Ask-t.Bid
+	if back {
+		ev.Text = fmt.Sprintf("%s back on peg at %s%s", sc.Label(), sc.Display(m.num, t.Price), via)
+	} else {
+		side := "above"
+		if ev.Change < 0 {
+			side = "below"
+		}
+		ev.Text = fmt.Sprintf("%s depeg %s percent %s %s, at %s%s", sc.Label(), m.num.Format(math.Abs(ev.Level), 2), side,
+			sc.Display(m.num, sc.Peg.Target), sc.Display(m.num, t.Price), via)
+	}
+	m.notify(ev)
+}
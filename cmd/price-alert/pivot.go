@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// PIVOT_PERIOD_NAMES read the pivots periods out loud.
+var PIVOT_PERIOD_NAMES = map[string]string{"1d": "daily", "1w": "weekly"}
+
+// pivots folds the price t of sc into its pivot periods and announces each
+// level it touched or crossed, e.g. "ETHUSDT crossed the daily R1 at 3520, at 3523".
+func (m *monitor) pivots(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	for _, period := range sc.Pivots.Periods {
+		for _, ev := range m.points.Update(sc.Symbol, t.Price, t.Time, config.KlineDuration(period), sc.Pivots.Rearm, sc.Pivots.Levels) {
+			ev.Time, ev.Source, ev.Size, ev.Spread = t.Time, source, t.Size, t.Ask-t.Bid
+			verb := "crossed"
+			if ev.Price == ev.Level {
+				verb = "touched"
+			}
+			ev.Text = fmt.Sprintf("%s %s the %s %s at %s, at %s%s", sc.Label(), verb, PIVOT_PERIOD_NAMES[period], ev.Pivot,
+				sc.DisplayAlert(m.num, ev.Level), sc.DisplayAlert(m.num, t.Price), via)
+			m.notify(ev)
+		}
+	}
+}
+
+// backfillPivots seeds the pivots of symbols with pivots from the last
+// closed kline of each period of their first market, so the levels are
+// known from the start. A symbol whose first market is not on Binance, or
+// whose klines fail, has its first levels once a period closed on its
+// prices.
+func (m *monitor) backfillPivots() {
+	if m.cfg.Source == config.SOURCE_REPLAY {
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, sc := range m.cfg.Symbols {
+		if !sc.Pivots.Enabled() {
+			continue
+		}
+		market := m.cfg.Markets(sc)[0]
+		fetch := klineFetcher(market.Exchange)
+		if fetch == nil {
+			continue
+		}
+		_, rest := m.cfg.Endpoints(market.Exchange)
+		for _, period := range sc.Pivots.Periods {
+			if _, ok := m.points.Value(sc.Symbol, config.KlineDuration(period)); ok {
+				continue
+			}
+			// The closed kline and the open one
+			klines, err := fetch(client, rest, market.Symbol, period, 2)
+			if err == nil && len(klines) < 2 {
+				err = fmt.Errorf("%d klines", len(klines))
+			}
+			if err != nil {
+				logging.Warn(fmt.Sprintf("%s %s pivots backfill failed, waiting for a period to close: %v", sc.Label(), period, err),
+					"event", "backfill", "symbol", sc.Symbol)
+				continue
+			}
+			prev, open := klines[0], klines[1]
+			m.points.Seed(sc.Symbol, config.KlineDuration(period), prev.High, prev.Low, prev.Close, open.OpenTime, open.High, open.Low, open.Close)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"sort"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/secrets"
+)
+
+// pluginSource runs the plugins the plugin exchange's markets belong to,
+// each with the symbols it knows them by.
+func pluginSource(cfg config.Config, markets []string) feed.Source {
+	var names []string
+	symbols := make(map[string][]string)
+	for _, market := range markets {
+		name, symbol := config.PluginMarket(market)
+		if _, ok := symbols[name]; !ok {
+			names = append(names, name)
+		}
+		symbols[name] = append(symbols[name], symbol)
+	}
+	var multi feed.Multi
+	for _, name := range names {
+		p := cfg.Plugins[name]
+		env := make([]string, 0, len(p.Environ))
+		for k, v := range p.Environ {
+			env = append(env, k+"="+v)
+		}
+		sort.Strings(env)
+		multi = append(multi, &feed.Plugin{Name: name, Command: p.Command, Args: p.Args, Env: env, Symbols: symbols[name]})
+	}
+	if len(multi) == 1 {
+		return multi[0]
+	}
+	return multi
+}
+
+// resolvePluginEnv resolves the env secrets of the plugins into cfg,
+// skipping those already resolved.
+func resolvePluginEnv(cfg *config.Config) error {
+	if cfg.Source == config.SOURCE_REPLAY {
+		return nil
+	}
+	for name, p := range cfg.Plugins {
+		if p.Environ != nil || len(p.Env) == 0 {
+			continue
+		}
+		p.Environ = make(map[string]string, len(p.Env))
+		for k, v := range p.Env {
+			value, err := secrets.Resolve(newSecrets(*cfg), v)
+			if err != nil {
+				return fmt.Errorf("plugins: %s: env.%s: %w", name, k, err)
+			}
+			p.Environ[k] = value
+		}
+		cfg.Plugins[name] = p
+	}
+	return nil
+}
+
+// pluginInfo stands in for an exchange lookup of a plugin market, which
+// has none.
+func pluginInfo(client *http.Client, restEndpoint, symbol string) (feed.SymbolInfo, error) {
+	return feed.SymbolInfo{}, errors.New("plugins have no tick size; set step and precision")
+}
+
+// checkPlugin finds the command of the plugin market belongs to, which is
+// as far as a check can go without running it.
+func checkPlugin(cfg config.Config, market string) (string, string) {
+	name, _ := config.PluginMarket(market)
+	path, err := exec.LookPath(cfg.Plugins[name].Command)
+	if err != nil {
+		return CHECK_FAIL, err.Error()
+	}
+	return CHECK_OK, name + " runs " + path
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// priceFetcher returns the REST ticker lookup of an exchange, nil for a
+// plugin or basket.
+func priceFetcher(exchange string) feed.PriceFetcher {
+	switch exchange {
+	case config.SOURCE_PLUGIN, config.SOURCE_BASKET:
+		return nil
+	case config.SOURCE_COINBASE:
+		return feed.FetchCoinbasePrice
+	case config.SOURCE_KRAKEN:
+		return feed.FetchKrakenPrice
+	case config.SOURCE_BYBIT:
+		return feed.BybitPrice("spot")
+	case config.SOURCE_BYBIT_LINEAR:
+		return feed.BybitPrice("linear")
+	case config.SOURCE_OKX:
+		return feed.FetchOKXPrice
+	case config.SOURCE_BINANCE_FUTURES:
+		return feed.FetchFuturesPrice
+	}
+	return feed.FetchBinancePrice
+}
+
+// pollTicker returns the reconnect.poll_interval ticker channel, or nil
+// when polling is off or the source is a replay.
+func (m *monitor) pollTicker() <-chan time.Time {
+	if m.cfg.Reconnect.PollInterval == 0 || m.cfg.Source == config.SOURCE_REPLAY {
+		return nil
+	}
+	return time.NewTicker(m.cfg.Reconnect.PollInterval).C
+}
+
+// streamDown reports whether sc currently has no live stream: under run
+// after the connection failed until it delivers again, under supervise while
+// its worker is backing off or redialling.
+func (m *monitor) streamDown(sc config.SymbolConfig) bool {
+	if m.workers == nil {
+		return m.down
+	}
+	w, ok := m.workers[sc.Symbol]
+	return ok && (w.state == WORKER_BACKOFF || w.state == WORKER_CONNECTING && w.restarts > 0)
+}
+
+// poll fetches the REST price of every symbol whose stream is down, from
+// its first market; the polled exchanges (stocks, forex, ethereum, coins,
+// chainlink, uniswap and custom), plugins and baskets, which have no REST
+// ticker, are skipped. The requests run in the background, one round at a time,
+// and the prices come back on m.polled.
+func (m *monitor) poll() {
+	if m.polling || m.pollC == nil {
+		return
+	}
+	var markets []config.MarketConfig
+	for _, sc := range m.cfg.Symbols {
+		if mk := m.cfg.Markets(sc)[0]; m.streamDown(sc) && !config.Polled(mk.Exchange) && priceFetcher(mk.Exchange) != nil {
+			markets = append(markets, mk)
+		}
+	}
+	if len(markets) == 0 {
+		return
+	}
+	m.polling = true
+	cfg := m.cfg
+	go func() {
+		client := &http.Client{Timeout: cfg.Reconnect.PollInterval}
+		var ticks []feed.Tick
+		for _, mk := range markets {
+			_, rest := cfg.Endpoints(mk.Exchange)
+			price, err := priceFetcher(mk.Exchange)(client, rest, mk.Symbol)
+			if err != nil {
+				logging.Warn(fmt.Sprintf("%s REST poll error: %v", strings.ToUpper(mk.Symbol), err),
+					"event", "poll", "symbol", mk.Symbol, "exchange", mk.Exchange)
+				continue
+			}
+			ticks = append(ticks, feed.Tick{Symbol: mk.Symbol, Exchange: mk.Exchange, Price: price, Time: time.Now(), Degraded: true})
+		}
+		m.polled <- ticks
+	}()
+}
+
+// handlePolled feeds a round of polled prices into the pipeline, skipping
+// symbols whose stream came back while the requests were out.
+func (m *monitor) handlePolled(ticks []feed.Tick) {
+	m.polling = false
+	for _, t := range ticks {
+		if slot := m.slot(t); slot >= 0 && m.streamDown(m.cfg.Symbols[slot]) {
+			m.handle(t)
+		}
+	}
+}
+
+// streamLost enters degraded mode under run: every symbol is polled until
+// the next connection delivers.
+func (m *monitor) streamLost() {
+	if m.down || m.pollC == nil {
+		return
+	}
+	m.down = true
+	logging.Warn(fmt.Sprintf("Stream down, polling REST every %v", m.cfg.Reconnect.PollInterval), "event", "degraded")
+	m.poll()
+}
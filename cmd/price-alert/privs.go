@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"syscall"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/ipc"
+)
+
+// ipcPerm resolves the configured mode and group for the IPC objects.
+func ipcPerm(p config.PermissionsConfig) (ipc.Perm, error) {
+	perm := ipc.DefaultPerm
+	mode, ok, err := p.FileMode()
+	if err != nil {
+		return perm, err
+	}
+	if ok {
+		perm.Mode = mode
+	}
+	if p.Group != "" {
+		g, err := user.LookupGroup(p.Group)
+		if err != nil {
+			if g, err = user.LookupGroupId(p.Group); err != nil {
+				return perm, fmt.Errorf("permissions.group: unknown group %s", p.Group)
+			}
+		}
+		if perm.GID, err = strconv.Atoi(g.Gid); err != nil {
+			return perm, err
+		}
+	}
+	return perm, nil
+}
+
+// dropPrivileges switches the process to the named user and its groups.
+// Files in handover, such as the control socket, are chowned to the user
+// first so it can still remove or write them; missing ones are skipped.
+// The user has to be able to write the directories in dirs, where files
+// are created or renamed later, or nothing is switched. Already running as
+// the user is not an error.
+func dropPrivileges(name string, handover, dirs []string) error {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return fmt.Errorf("permissions.user: %w", err)
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return err
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return err
+	}
+	if os.Geteuid() == uid {
+		return nil
+	}
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("permissions.user %s: the writer has to be started as root to switch users", name)
+	}
+	var groups []int
+	ids, err := u.GroupIds()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if g, err := strconv.Atoi(id); err == nil {
+			groups = append(groups, g)
+		}
+	}
+	for _, dir := range dirs {
+		if !writableBy(dir, uid, append(groups, gid)) {
+			return fmt.Errorf("permissions.user %s cannot write %s", name, dir)
+		}
+	}
+	for _, path := range handover {
+		if path == "" {
+			continue
+		}
+		if err := os.Lchown(path, uid, gid); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	// Groups before gid before uid: each step needs the privileges the
+	// next one drops
+	if err := syscall.Setgroups(groups); err != nil {
+		return fmt.Errorf("setgroups: %w", err)
+	}
+	if err := syscall.Setgid(gid); err != nil {
+		return fmt.Errorf("setgid: %w", err)
+	}
+	if err := syscall.Setuid(uid); err != nil {
+		return fmt.Errorf("setuid: %w", err)
+	}
+	return nil
+}
+
+// writableBy reports whether the user uid in groups may create files in
+// dir, going by its owner and mode bits.
+func writableBy(dir string, uid int, groups []int) bool {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	mode := info.Mode().Perm()
+	switch {
+	case int(st.Uid) == uid:
+		return mode&0o300 == 0o300
+	case slices.Contains(groups, int(st.Gid)):
+		return mode&0o030 == 0o030
+	default:
+		return mode&0o003 == 0o003
+	}
+}
+
+// handover lists the files that dropPrivileges chowns: the control socket
+// and those of cfg the writer keeps writing or removes on shutdown.
+func handover(cfg config.Config, control string) []string {
+	return []string{control, cfg.PIDFile, cfg.Log.File, cfg.StatusFile, cfg.ATHFile, cfg.CandleFile}
+}
+
+// handoverDirs lists the directories the user has to write after
+// dropPrivileges: those of status_file and ath_file, replaced through a
+// temporary file, of log.file, which rotation renames and recreates, and
+// of a candle_file that doesn't exist yet.
+func handoverDirs(cfg config.Config) []string {
+	var dirs []string
+	for _, path := range []string{cfg.StatusFile, cfg.ATHFile, cfg.Log.File} {
+		if path != "" {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+	}
+	if cfg.CandleFile != "" {
+		if _, err := os.Stat(cfg.CandleFile); os.IsNotExist(err) {
+			dirs = append(dirs, filepath.Dir(cfg.CandleFile))
+		}
+	}
+	return dirs
+}
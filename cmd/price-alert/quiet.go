@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+	"github.com/qqubb/tts_price_alert/internal/notify"
+)
+
+// MAX_HELD caps the alerts queued over one night of quiet hours, dropping
+// the oldest, so a busy market does not flood the morning.
+const MAX_HELD = 50
+
+// heldAlert is an alert queued during quiet hours and when it was.
+type heldAlert struct {
+	ev alert.Event
+	at time.Time
+}
+
+// quiet reports whether ev falls in the quiet hours and is not critical.
+func (m *monitor) quiet(ev alert.Event) bool {
+	return m.cfg.QuietHours.Contains(time.Now()) && ev.Severity < alert.Critical
+}
+
+// hold logs ev as held back, queueing it if quiet_hours.queue is set.
+func (m *monitor) hold(ev alert.Event) {
+	logging.Info("[quiet] "+ev.Text, notify.EventAttrs(ev)...)
+	if !m.cfg.QuietHours.Queue {
+		return
+	}
+	if len(m.held) == MAX_HELD {
+		m.held = m.held[1:]
+	}
+	m.held = append(m.held, heldAlert{ev, time.Now()})
+}
+
+// release delivers the queued alerts once the quiet hours are over, each
+// marked with when it was held.
+func (m *monitor) release() {
+	if len(m.held) == 0 || m.cfg.QuietHours.Contains(time.Now()) {
+		return
+	}
+	held := m.held
+	m.held = nil
+	loc := m.cfg.QuietHours.Location
+	if loc == nil { // quiet_hours removed by a reload
+		loc = time.Local
+	}
+	logging.Infof("Quiet hours over, delivering %d held alerts", len(held))
+	for _, h := range held {
+		h.ev.Text += fmt.Sprintf(" (held from %s)", h.at.In(loc).Format("15:04"))
+		m.deliver(h.ev)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/secrets"
+)
+
+// quoter returns the quote lookup of a provider for the stocks, forex or
+// coins exchange.
+func quoter(exchange, provider string) feed.Quoter {
+	switch {
+	case exchange == config.SOURCE_COINS:
+		return feed.CachedQuoter(coinsFetcher(provider), nil, 0)
+	case provider == config.QUOTES_TWELVE_DATA:
+		return feed.FetchTwelveDataPrice
+	case provider == config.QUOTES_ALPHA_VANTAGE && exchange == config.SOURCE_FOREX:
+		return feed.FetchAlphaVantageRate
+	case provider == config.QUOTES_ALPHA_VANTAGE:
+		return feed.FetchAlphaVantageQuote
+	case exchange == config.SOURCE_FOREX:
+		return feed.FetchPolygonForex
+	}
+	return feed.FetchPolygonTrade
+}
+
+// coinsFetcher returns the price lookup of a coins.provider aggregator.
+func coinsFetcher(provider string) feed.BatchFetcher {
+	if provider == config.QUOTES_COINMARKETCAP {
+		return feed.FetchCoinMarketCapPrices
+	}
+	return feed.FetchCoinGeckoPrices
+}
+
+// quotesSource polls markets of the stocks, forex or coins exchange.
+func quotesSource(cfg config.Config, exchange string, markets []string) feed.Source {
+	q := cfg.Quotes(exchange)
+	src := &feed.Quotes{
+		Quote:        quoter(exchange, q.Provider),
+		Session:      feed.USSession,
+		Market:       "US market",
+		RESTEndpoint: q.RESTEndpoint,
+		APIKey:       q.Key,
+		Symbols:      markets,
+		Interval:     q.Interval,
+	}
+	switch exchange {
+	case config.SOURCE_FOREX:
+		src.Session, src.Market = feed.ForexSession, "Forex market"
+	case config.SOURCE_COINS:
+		// One request per round for all markets; half an interval keeps
+		// a round from straddling two
+		src.Quote = feed.CachedQuoter(coinsFetcher(q.Provider), markets, q.Interval/2)
+		src.Session = nil // coins trade around the clock
+	}
+	return src
+}
+
+// coinInfo stands in for an exchange lookup of a coin, deriving its tick
+// size from the coin's current price.
+func coinInfo(cfg config.Config) feed.InfoFetcher {
+	return func(client *http.Client, restEndpoint, symbol string) (feed.SymbolInfo, error) {
+		if err := resolveQuoteKeys(&cfg); err != nil {
+			return feed.SymbolInfo{}, err
+		}
+		q := cfg.Quotes(config.SOURCE_COINS)
+		price, _, err := quoter(config.SOURCE_COINS, q.Provider)(client, restEndpoint, q.Key, symbol)
+		if err != nil {
+			return feed.SymbolInfo{}, err
+		}
+		return feed.CoinInfo(symbol, price), nil
+	}
+}
+
+// resolveQuoteKeys resolves the API keys of the stocks, forex and coins
+// providers into cfg; a key is left empty when it is not configured.
+func resolveQuoteKeys(cfg *config.Config) error {
+	for _, exchange := range []string{config.SOURCE_STOCKS, config.SOURCE_FOREX, config.SOURCE_COINS} {
+		q := cfg.Quotes(exchange)
+		if q.APIKey == "" || q.Key != "" || cfg.Source == config.SOURCE_REPLAY {
+			continue
+		}
+		key, err := secrets.Resolve(newSecrets(*cfg), q.APIKey)
+		if err != nil {
+			return fmt.Errorf("%s: api_key: %w", exchange, err)
+		}
+		q.Key = key
+	}
+	return nil
+}
+
+// checkQuote asks the provider of exchange for a quote of symbol, which
+// proves both the key and the symbol.
+func checkQuote(client *http.Client, cfg config.Config, exchange, symbol string) (string, string) {
+	if err := resolveQuoteKeys(&cfg); err != nil {
+		return CHECK_FAIL, err.Error()
+	}
+	q := cfg.Quotes(exchange)
+	price, _, err := quoter(exchange, q.Provider)(client, q.RESTEndpoint, q.Key, symbol)
+	if err != nil {
+		return CHECK_FAIL, err.Error()
+	}
+	return CHECK_OK, fmt.Sprintf("%s, last %g", q.Provider, price)
+}
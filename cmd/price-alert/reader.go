@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/ipc"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+	"github.com/qqubb/tts_price_alert/internal/numfmt"
+)
+
+// readerCmd is a minimal Go counterpart of tts_shm_reader.py: it blocks on
+// the pipe and prints the slot each notification points at, or the alert
+// when the notification was for a new one in alert_path.
+func readerCmd(args []string) int {
+	fs := flag.NewFlagSet("reader", flag.ExitOnError)
+	loader := config.ParseFlags(fs, args)
+	cfg, err := loader.Load()
+	if err != nil {
+		logging.Fatalf("config: %v", err)
+	}
+	setupLogging(cfg.Log)
+	num, _ := numfmt.Parse(cfg.Locale)
+
+	shm, err := ipc.OpenSHM(cfg.SHMPath, cfg.BufferSize)
+	if err != nil {
+		logging.Fatalf("shared memory not found, is the writer running? %v", err)
+	}
+	defer shm.Close()
+	pipe, err := ipc.OpenPipeReader(cfg.PipePath)
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+	defer pipe.Close()
+	// The writer maps alert_path before it opens the pipe; alerts written
+	// before the reader attached are not announced
+	seen := make(map[int]uint64)
+	var alerts *ipc.Alerts
+	if path := cfg.AlertRegion(); path != "" {
+		if alerts, err = ipc.OpenAlerts(path, cfg.AlertSize); err != nil {
+			logging.Warnf("alert_path: %v, printing prices only", err)
+		} else {
+			defer alerts.Close()
+			for i := 0; i < alerts.Slots(); i++ {
+				seen[i] = alerts.Head(i)
+			}
+		}
+	}
+
+	buf := make([]byte, 1)
+	for {
+		// Block until the writer signals; the byte is the slot index + 1
+		if _, err := pipe.Read(buf); err != nil {
+			if err == io.EOF {
+				logging.Infof("Writer closed the pipe")
+				return 0
+			}
+			logging.Fatalf("%v", err)
+		}
+		slot := int(buf[0]) - 1
+		if alerts != nil {
+			if fresh := alerts.Since(slot, seen[slot]); len(fresh) > 0 {
+				for _, fields := range fresh {
+					if len(fields) == 6 {
+						fmt.Println("[ALERT]", fields[5])
+					}
+				}
+				seen[slot], _ = strconv.ParseUint(fresh[len(fresh)-1][0], 10, 64)
+				continue
+			}
+		}
+		price, symbol, source, err := shm.Read(slot)
+		if err != nil {
+			continue
+		}
+		if source != "" {
+			fmt.Println(symbol, display(num, price), source)
+		} else {
+			fmt.Println(symbol, display(num, price))
+		}
+	}
+}
+
+// display reformats a plain SHM price in num, keeping its decimals.
+func display(num numfmt.Locale, price string) string {
+	v, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		return price
+	}
+	decimals := 0
+	if _, frac, ok := strings.Cut(price, "."); ok {
+		decimals = len(frac)
+	}
+	return num.Format(v, decimals)
+}
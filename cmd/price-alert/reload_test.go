@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+)
+
+// TestReloadSlots reloads a config without the first of three symbols and
+// checks the slots as TestUnsubscribeSlots does.
+func TestReloadSlots(t *testing.T) {
+	m, pub := testMonitor(t, "btcusdt", "ethusdt", "solusdt")
+	dir := t.TempDir()
+	replay := filepath.Join(dir, "ticks.csv")
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(replay, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	yaml := "source: replay\ncontrol_socket: off\nreplay: {file: " + replay + "}\n" +
+		"symbols:\n  - {symbol: ethusdt, step: 10, precision: 2}\n  - {symbol: solusdt, step: 10, precision: 2}\n"
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m.loader = &config.Loader{Path: path}
+	m.handle(tick("btcusdt", 60000))
+	m.handle(tick("ethusdt", 3500))
+	m.handle(tick("solusdt", 150))
+
+	m.reload()
+	m.applyReload(<-m.reloaded)
+	if got := m.cfg.SymbolNames(); len(got) != 2 {
+		t.Fatalf("symbols after reload = %v, want ethusdt and solusdt", got)
+	}
+	m.handle(tick("ethusdt", 3501))
+
+	tests := []struct {
+		slot          int
+		price, symbol string
+	}{
+		{0, "3501.00", "ETHUSDT"},
+		{1, "", "SOLUSDT"},
+		{2, "", ""},
+	}
+	for _, tt := range tests {
+		if got := pub[tt.slot]; got != [2]string{tt.price, tt.symbol} {
+			t.Errorf("slot %d = %q, want %q", tt.slot, got, [2]string{tt.price, tt.symbol})
+		}
+		if alerts := m.alerts.Since(tt.slot, 0); len(alerts) != 0 {
+			t.Errorf("slot %d alerts = %q, want none", tt.slot, alerts)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// replayCmd feeds a recorded tick file through the same SHM, pipe and alert
+// pipeline as run, then exits.
+func replayCmd(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	file := fs.String("file", "", "CSV file of time,symbol,price lines to replay (default replay.file)")
+	speed := fs.Float64("speed", -1, "playback speed factor; 0 replays without delays (default replay.speed)")
+	dryRun := fs.Bool("dry-run", false, "evaluate alerts and log only; create no SHM file or FIFO")
+	loader := config.ParseFlags(fs, args)
+	loader.Override(func(c *config.Config) {
+		c.Source = config.SOURCE_REPLAY
+		if *file != "" {
+			c.Replay.File = *file
+		}
+		if *speed >= 0 {
+			c.Replay.Speed = *speed
+		}
+	})
+	cfg, err := loader.Load()
+	if err != nil {
+		logging.Fatalf("config: %v", err)
+	}
+	setupLogging(cfg.Log)
+	resolve(&cfg)
+
+	m, err := openMonitor(cfg, loader, *dryRun)
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+
+	code := 0
+	if err := m.loop(); err != nil {
+		logging.Errorf("Replay error: %v", err)
+		code = 1
+	}
+	if err := m.Close(); err != nil {
+		logging.Errorf("Shutdown error: %v", err)
+		code = 1
+	}
+	return code
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// rsi folds the price t of sc into its RSI candles and raises its alert
+// when a closed one took the RSI overbought or oversold, e.g. "ETHUSDT RSI
+// 72.4, overbought at 3450".
+func (m *monitor) rsi(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	r := sc.RSI
+	ev, ok := m.rsis.Update(sc.Symbol, t.Price, t.Time, config.KlineDuration(r.Interval), r.Period, r.Overbought, r.Oversold, r.Rearm)
+	if !ok {
+		return
+	}
+	state := "overbought"
+	if ev.Level <= r.Oversold {
+		state = "oversold"
+	}
+	ev.Time, ev.Source = t.Time, source
+	ev.Text = fmt.Sprintf("%s RSI %s, %s at %s%s", sc.Label(), m.num.Format(ev.Level, 1), state, sc.DisplayAlert(m.num, ev.Price), via)
+	m.notify(ev)
+}
+
+// backfillRSI seeds the RSI candles of symbols with rsi from the klines of
+// their first market, so the RSI is known from the start; one already
+// overbought or oversold then waits for its re-arm. A symbol whose first
+// market is not on Binance, or whose klines fail, builds its candles from
+// its prices.
+func (m *monitor) backfillRSI() {
+	if m.cfg.Source == config.SOURCE_REPLAY {
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, sc := range m.cfg.Symbols {
+		r := sc.RSI
+		if !r.Enabled() {
+			continue
+		}
+		if _, ok := m.rsis.Value(sc.Symbol); ok {
+			continue
+		}
+		market := m.cfg.Markets(sc)[0]
+		fetch := klineFetcher(market.Exchange)
+		if fetch == nil {
+			continue
+		}
+		_, rest := m.cfg.Endpoints(market.Exchange)
+		// One more for the open kline, and one whose close the first
+		// change is taken from
+		klines, err := fetch(client, rest, market.Symbol, r.Interval, r.Period+2)
+		if err != nil {
+			logging.Warn(fmt.Sprintf("%s RSI backfill failed, building it from the prices: %v", sc.Label(), err),
+				"event", "backfill", "symbol", sc.Symbol)
+			continue
+		}
+		for _, k := range klines {
+			m.rsis.Add(sc.Symbol, k.OpenTime, k.Close, r.Period, r.Overbought, r.Oversold, r.Rearm)
+		}
+		if v, ok := m.rsis.Value(sc.Symbol); ok {
+			logging.Info(fmt.Sprintf("%s %s RSI %s", sc.Label(), r.Interval, m.num.Format(v, 1)),
+				"event", "backfill", "symbol", sc.Symbol, "rsi", v)
+		}
+	}
+}
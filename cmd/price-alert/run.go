@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// runCmd streams the configured symbols from the configured source until
+// SIGINT/SIGTERM, or until a replay source is exhausted. It exits 0 after a
+// clean shutdown and 1 if the source or the teardown failed.
+func runCmd(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "evaluate alerts and log only; create no SHM file or FIFO")
+	loader := config.ParseFlags(fs, args)
+	cfg, err := loader.Load()
+	if err != nil {
+		logging.Fatalf("config: %v", err)
+	}
+	setupLogging(cfg.Log)
+	resolve(&cfg)
+
+	m, err := openMonitor(cfg, loader, *dryRun)
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+	m.backfill()
+
+	if cfg.Profile != "" {
+		logging.Infof("Profile: %s", cfg.Profile)
+	}
+	code := 0
+	if err := m.loop(); err != nil {
+		logging.Errorf("Source error: %v", err)
+		code = 1
+	}
+	if err := m.Close(); err != nil {
+		logging.Errorf("Shutdown error: %v", err)
+		code = 1
+	}
+	return code
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+	"github.com/qqubb/tts_price_alert/internal/secrets"
+)
+
+// newSecrets builds the secret provider chain for cfg.
+func newSecrets(cfg config.Config) secrets.Chain {
+	return secrets.New(cfg.Secrets.KeyringService, config.SECRET_ENV_PREFIX, cfg.Secrets.Dir)
+}
+
+// secretCmd manages credentials in the OS keyring:
+//
+//	price-alert secret set <name>    read the value from stdin and store it
+//	price-alert secret check <name>  report which provider resolves it
+func secretCmd(args []string) int {
+	fs := flag.NewFlagSet("secret", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: price-alert secret [flags] set|check <name>")
+		fs.PrintDefaults()
+	}
+	loader := config.ParseFlags(fs, args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	cfg, err := loader.Load()
+	if err != nil {
+		logging.Fatalf("config: %v", err)
+	}
+	action, name := fs.Arg(0), fs.Arg(1)
+
+	switch action {
+	case "set":
+		fmt.Fprintf(os.Stderr, "Value for %s: ", name)
+		value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		value = strings.TrimRight(value, "\r\n")
+		if value == "" {
+			fmt.Fprintln(os.Stderr, "\nno value read:", err)
+			return 1
+		}
+		keyring := secrets.Keyring{Service: cfg.Secrets.KeyringService}
+		if err := keyring.Store(name, value); err != nil {
+			fmt.Fprintln(os.Stderr, "store failed:", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "stored %s in the keyring; reference it as %s%s\n", name, secrets.REF_PREFIX, name)
+		return 0
+	case "check":
+		for _, p := range newSecrets(cfg) {
+			if _, err := p.Lookup(name); err == nil {
+				fmt.Printf("%s: found in %s\n", name, p.Name())
+				return 0
+			}
+		}
+		fmt.Printf("%s: not found\n", name)
+		return 1
+	}
+	fs.Usage()
+	return 2
+}
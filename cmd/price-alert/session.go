@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+)
+
+// sessionBreaks folds the price t of sc into the highs and lows of its
+// sessions, updates the stats slot and raises the alerts of the levels of
+// the last sessions it broke, e.g. "ETHUSDT broke the asia session high of
+// 3480, at 3485".
+func (m *monitor) sessionBreaks(slot int, sc config.SymbolConfig, t feed.Tick, source, via string) {
+	for _, name := range sc.Sessions {
+		start, end := m.cfg.Sessions[name].Last(t.Time)
+		for _, ev := range m.sessions.Update(sc.Symbol, name, t.Price, t.Time, start, end) {
+			side := "high"
+			if ev.Change < 0 {
+				side = "low"
+			}
+			ev.Time, ev.Source, ev.Size, ev.Spread = t.Time, source, t.Size, t.Ask-t.Bid
+			ev.Text = fmt.Sprintf("%s broke the %s session %s of %s, at %s%s", sc.Label(), name, side, sc.DisplayAlert(m.num, ev.Level), sc.DisplayAlert(m.num, t.Price), via)
+			m.notify(ev)
+		}
+	}
+	m.writeStats(slot, sc)
+}
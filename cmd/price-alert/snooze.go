@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+)
+
+// snoozedAt reports whether ev falls in a snooze of all alerts or of its
+// rule at now.
+func (m *monitor) snoozedAt(ev alert.Event, now time.Time) bool {
+	if now.Before(m.snoozed) {
+		return true
+	}
+	return ev.Rule != "" && now.Before(m.snoozes[ev.Rule])
+}
+
+// snoozedRules returns the names of the rules snoozed at now, sorted, and
+// forgets the snoozes that ended.
+func (m *monitor) snoozedRules(now time.Time) []string {
+	var names []string
+	for name, until := range m.snoozes {
+		if !now.Before(until) {
+			delete(m.snoozes, name)
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// snooze runs "snooze <duration> [rule]": alerts, or those of the rule on
+// any symbol, are only logged for the duration.
+func (m *monitor) snooze(args []string) string {
+	if len(args) == 0 {
+		return "error: usage: snooze <duration> [rule]"
+	}
+	d, err := time.ParseDuration(args[0])
+	if err != nil || d <= 0 {
+		return "error: duration must be positive, such as 30m"
+	}
+	until := time.Now().Add(d)
+	if len(args) == 1 {
+		m.snoozed = until
+		return fmt.Sprintf("snoozed until %s", until.Format("15:04:05"))
+	}
+	name := strings.Join(args[1:], " ")
+	if !m.hasRule(name) {
+		return fmt.Sprintf("error: no rule named %q", name)
+	}
+	m.snoozes[name] = until
+	return fmt.Sprintf("rule %s snoozed until %s", name, until.Format("15:04:05"))
+}
+
+// unsnooze runs "unsnooze [rule]", ending the snooze of all alerts or of
+// the rule.
+func (m *monitor) unsnooze(args []string) string {
+	if len(args) == 0 {
+		m.snoozed = time.Time{}
+		return "unsnoozed"
+	}
+	name := strings.Join(args, " ")
+	if _, ok := m.snoozes[name]; !ok {
+		return fmt.Sprintf("error: rule %s is not snoozed", name)
+	}
+	delete(m.snoozes, name)
+	return "rule " + name + " unsnoozed"
+}
+
+// ack runs "ack [symbol]": the escalated alerts of the symbol, or of all
+// of them, count their repeats over, so they escalate again only after
+// another escalation.count.
+func (m *monitor) ack(args []string) string {
+	if m.cfg.Escalation.Count == 0 {
+		return "error: escalation is off"
+	}
+	symbol, label := "", "all symbols"
+	if len(args) > 0 {
+		sc, err := m.pickSymbol(args)
+		if err != nil {
+			return "error: " + err.Error()
+		}
+		symbol, label = sc.Symbol, sc.Label()
+	}
+	n := m.repeats.Acknowledge(symbol, m.cfg.Escalation.Count)
+	if n == 0 {
+		return "nothing to acknowledge for " + label
+	}
+	return fmt.Sprintf("acknowledged %d escalated alerts of %s", n, label)
+}
+
+// hasRule reports whether a symbol has a rule named name.
+func (m *monitor) hasRule(name string) bool {
+	for _, sc := range m.cfg.Symbols {
+		for _, r := range sc.Rules {
+			if r.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
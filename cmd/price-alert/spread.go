@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+)
+
+// spread raises the alert of sc when the bid-ask spread of its quote t has
+// stayed spread.multiple times normal or wider for spread.for, e.g.
+// "ETHUSDT spread 0.12 percent for 15s, 4.0 times normal, at 3420": the
+// book thinning out, often ahead of a violent move.
+func (m *monitor) spread(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	ev, lasted, ok := m.spreads.Update(sc.Symbol, t.Bid, t.Ask, t.Time, sc.Spread.Multiple, sc.Spread.For, sc.Spread.Window)
+	if !ok {
+		return
+	}
+	normal, _ := m.spreads.Value(sc.Symbol)
+	ev.Time, ev.Source, ev.Spread = t.Time, source, t.Ask-t.Bid
+	ev.Text = fmt.Sprintf("%s spread %s percent for %s, %s times normal, at %s%s", sc.Label(), m.num.Format(ev.Level, 2), shortDuration(lasted.Round(time.Second)),
+		m.num.Format(ev.Level/normal, 1), sc.DisplayAlert(m.num, t.Price), via)
+	m.notify(ev)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// clockTicker returns the ticker channel of the stale_after checks and the
+// end of quiet hours, or nil for a replay, whose prices arrive as fast as
+// they are read.
+func (m *monitor) clockTicker() <-chan time.Time {
+	if m.cfg.Source == config.SOURCE_REPLAY {
+		return nil
+	}
+	return time.NewTicker(time.Second).C
+}
+
+// liveSince returns when the stream of sc was last heard from: its last
+// price, or the connection if none came on it yet. It is false while the
+// stream is down, which reconnects and polling already cover.
+func (m *monitor) liveSince(sc config.SymbolConfig) (time.Time, bool) {
+	since := m.dialled
+	if m.workers != nil {
+		w, ok := m.workers[sc.Symbol]
+		if !ok || w.state == WORKER_BACKOFF {
+			return time.Time{}, false
+		}
+		since = w.since
+	} else if m.down {
+		return time.Time{}, false
+	}
+	if at, ok := m.heard[sc.Symbol]; ok && at.After(since) {
+		since = at
+	}
+	return since, true
+}
+
+// checkStale raises the stale alert of each symbol whose connected stream
+// has delivered no price for its stale_after, e.g. "ETHUSDT feed stale, no
+// price for 30s", and empties its SHM record, so readers stop trusting a
+// price that no longer moves. A silent stall keeps the socket open and
+// never reaches the reconnect.
+func (m *monitor) checkStale() {
+	if m.paused {
+		return
+	}
+	now := time.Now()
+	for slot, sc := range m.cfg.Symbols {
+		if sc.StaleAfter == 0 || m.stale[sc.Symbol] {
+			continue
+		}
+		since, ok := m.liveSince(sc)
+		if !ok || now.Sub(since) < sc.StaleAfter {
+			continue
+		}
+		m.stale[sc.Symbol] = true
+		if err := m.pub.MarkStale(slot); err != nil {
+			logging.Error("Publish error: "+err.Error(), "symbol", sc.Symbol, "slot", slot)
+		}
+		silent := now.Sub(since).Round(time.Second)
+		ev := alert.Event{Symbol: sc.Symbol, Kind: alert.Stale, Level: silent.Seconds(), Time: now}
+		if t, ok := m.last[sc.Symbol]; ok {
+			ev.Price = t.Price
+		}
+		ev.Text = fmt.Sprintf("%s feed stale, no price for %s", sc.Label(), shortDuration(silent))
+		m.notify(ev)
+	}
+}
+
+// fresh announces the first price of a stale symbol, e.g. "ETHUSDT feed
+// back, at 3420"; publishing it fills the SHM record again.
+func (m *monitor) fresh(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	delete(m.stale, sc.Symbol)
+	ev := alert.Event{Symbol: sc.Symbol, Kind: alert.Stale, Price: t.Price, Time: t.Time, Source: source, Size: t.Size}
+	ev.Text = fmt.Sprintf("%s feed back, at %s%s", sc.Label(), sc.DisplayAlert(m.num, t.Price), via)
+	m.notify(ev)
+}
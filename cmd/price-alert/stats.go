@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// writeStats fills the stats_path slot of sc with its latest 24h
+// statistics and funding: "<SYMBOL>\0<open>\0<high>\0<low>\0<volume>\0
+// <quote_volume>\0<change_pct>\0<funding_pct>\0<settled_pct>\0
+// <next_funding_ms>\0", then one field per moving average in config
+// order, the VWAP of symbols with vwap, the high and low of the last of
+// each of its sessions, the open, high, low, close and volume of the last
+// closed candle of each of its candles intervals and the volatility of
+// each of its volatility windows, leaving the fields it has no data for
+// empty. It runs
+// before the price is published, so a reader woken for the slot finds both
+// up to date.
+func (m *monitor) writeStats(slot int, sc config.SymbolConfig) {
+	if m.stats == nil {
+		return
+	}
+	fields := make([]string, 10, 11+len(sc.MovingAverages.Averages)+2*len(sc.Sessions)+5*len(sc.Candles)+len(sc.Volatility.Windows))
+	fields[0] = sc.Label()
+	if t, ok := m.last[sc.Symbol]; ok && t.Day != nil {
+		d := t.Day
+		fields[1], fields[2], fields[3] = sc.Format(d.Open), sc.Format(d.High), sc.Format(d.Low)
+		fields[4], fields[5] = strconv.FormatFloat(d.Volume, 'f', -1, 64), strconv.FormatFloat(d.QuoteVolume, 'f', -1, 64)
+		fields[6] = strconv.FormatFloat(d.ChangePct(t.Price), 'f', 2, 64)
+	}
+	if f, ok := m.fundings[sc.Symbol]; ok {
+		fields[7] = strconv.FormatFloat(f.rate, 'f', 4, 64)
+		if f.settled != nil {
+			fields[8] = strconv.FormatFloat(*f.settled, 'f', 4, 64)
+		}
+		fields[9] = strconv.FormatInt(f.next.UnixMilli(), 10)
+	}
+	for _, name := range sc.MovingAverages.Averages {
+		v, ok := m.avgs.Value(sc.Symbol, name)
+		if !ok {
+			fields = append(fields, "")
+			continue
+		}
+		fields = append(fields, sc.Format(v))
+	}
+	if sc.VWAP.Enabled() {
+		v, _, ok := m.vwaps.Value(sc.Symbol)
+		if !ok {
+			fields = append(fields, "")
+		} else {
+			fields = append(fields, sc.Format(v))
+		}
+	}
+	for _, name := range sc.Sessions {
+		l, _ := m.sessions.Levels(sc.Symbol, name)
+		if l.LastHigh == 0 {
+			fields = append(fields, "", "")
+			continue
+		}
+		fields = append(fields, sc.Format(l.LastHigh), sc.Format(l.LastLow))
+	}
+	for _, interval := range sc.Candles {
+		c, ok := m.candles.Last(sc.Symbol, config.KlineDuration(interval))
+		if !ok {
+			fields = append(fields, "", "", "", "", "")
+			continue
+		}
+		fields = append(fields, sc.Format(c.Open), sc.Format(c.High), sc.Format(c.Low), sc.Format(c.Close), strconv.FormatFloat(c.Volume, 'f', -1, 64))
+	}
+	for _, window := range sc.Volatility.Windows {
+		v, ok := m.vols.Value(sc.Symbol, window)
+		if !ok {
+			fields = append(fields, "")
+			continue
+		}
+		fields = append(fields, strconv.FormatFloat(v, 'f', 2, 64))
+	}
+	err := m.stats.WriteFields(slot, fields...)
+	if err != nil {
+		logging.Error("Stats error: "+err.Error(), "symbol", sc.Symbol, "slot", slot)
+	}
+}
+
+// rollingDay folds t into the rolling 24h range of sc, whose stream has no
+// 24h statistics, and raises its alerts_24h events from that range once it
+// spans 24h.
+func (m *monitor) rollingDay(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	open, high, low, full := m.days.Update(sc.Symbol, t.Price, t.Time)
+	if !full {
+		// Record the range so far, so the first full one compares against it
+		m.daily.Update(sc.Symbol, t.Price, open, high, low, sc.Alerts24h.ChangeStep, sc.Alerts24h.HighLow, m.dayRearm(sc))
+		return
+	}
+	t.Day = &feed.Stats{Open: open, High: high, Low: low}
+	m.dailyAlerts(sc, t, source, via)
+}
+
+// dayRearm returns how far beyond the last alerted 24h extreme of sc a new
+// one alerts.
+func (m *monitor) dayRearm(sc config.SymbolConfig) float64 {
+	if sc.Alerts24h.Rearm > 0 {
+		return sc.Alerts24h.Rearm
+	}
+	return m.step(sc)
+}
+
+// dailyAlerts raises the alerts_24h events of sc for t.
+func (m *monitor) dailyAlerts(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	rules := sc.Alerts24h
+	if rules.ChangeStep == 0 && !rules.HighLow {
+		return
+	}
+	d := t.Day
+	for _, ev := range m.daily.Update(sc.Symbol, t.Price, d.Open, d.High, d.Low, rules.ChangeStep, rules.HighLow, m.dayRearm(sc)) {
+		ev.Time, ev.Source = t.Time, source
+		switch ev.Kind {
+		case alert.Change24h:
+			dir := "up"
+			if ev.Level < 0 {
+				dir = "down"
+			}
+			ev.Text = fmt.Sprintf("%s %s %s percent in 24 hours, at %s%s", sc.Label(), dir, m.num.Format(math.Abs(ev.Level), -1), sc.DisplayAlert(m.num, t.Price), via)
+		case alert.High24h:
+			ev.Text = fmt.Sprintf("%s new 24 hour high: %s%s", sc.Label(), sc.DisplayAlert(m.num, t.Price), via)
+		case alert.Low24h:
+			ev.Text = fmt.Sprintf("%s new 24 hour low: %s%s", sc.Label(), sc.DisplayAlert(m.num, t.Price), via)
+		}
+		m.notify(ev)
+	}
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// statusFile is the JSON document written to status_file.
+type statusFile struct {
+	State      string         `json:"state"` // running or stopped
+	Time       time.Time      `json:"time"`
+	Started    time.Time      `json:"started"`
+	UptimeSec  int64          `json:"uptime_seconds"`
+	PID        int            `json:"pid"`
+	Profile    string         `json:"profile,omitempty"`
+	Source     string         `json:"source"`
+	Paused     bool           `json:"paused"`
+	Muted      bool           `json:"muted"`
+	Quiet      bool           `json:"quiet"`
+	Held       int            `json:"held"`
+	Snoozed    *time.Time     `json:"snoozed_until,omitempty"`
+	Snoozes    []snoozeStatus `json:"snoozed_rules,omitempty"`
+	Reconnects int            `json:"reconnects"`
+	Symbols    []symbolStatus `json:"symbols"`
+}
+
+type snoozeStatus struct {
+	Rule  string    `json:"rule"`
+	Until time.Time `json:"until"`
+}
+
+type symbolStatus struct {
+	Symbol     string         `json:"symbol"`
+	Exchange   string         `json:"exchange,omitempty"` // live, unaggregated symbols only
+	Price      *float64       `json:"price,omitempty"`
+	Checkpoint *float64       `json:"checkpoint,omitempty"`
+	Step       float64        `json:"step"`
+	Targets    []float64      `json:"targets,omitempty"`
+	LastTick   *time.Time     `json:"last_tick,omitempty"`
+	AgeSec     *float64       `json:"age_seconds,omitempty"`
+	LastQty    float64        `json:"last_qty,omitempty"` // quantity of the last tick, if reported
+	Bid        float64        `json:"bid,omitempty"`      // bookTicker symbols only
+	Ask        float64        `json:"ask,omitempty"`
+	Spread     float64        `json:"spread,omitempty"`
+	Day        *dayStatus     `json:"24h,omitempty"`      // miniTicker and ticker symbols only
+	Funding    *fundingStatus `json:"funding,omitempty"`  // perpetuals with a mark price stream only
+	Basis      *basisStatus   `json:"basis,omitempty"`    // symbols with basis only
+	Degraded   bool           `json:"degraded,omitempty"` // price polled from REST
+	Stale      bool           `json:"stale,omitempty"`    // past stale_after
+	Worker     string         `json:"worker,omitempty"`   // supervise only
+	Restarts   int            `json:"restarts,omitempty"`
+	Source     string         `json:"source,omitempty"`    // failover symbols only: the active exchange
+	Method     string         `json:"aggregate,omitempty"` // aggregated symbols only
+	Markets    []marketStatus `json:"markets,omitempty"`
+	Corr       *float64       `json:"correlation,omitempty"`
+	StepPct    float64        `json:"step_percent,omitempty"`
+	ATR        float64        `json:"atr,omitempty"` // the step's, once known
+	Repeating  []float64      `json:"repeating_targets,omitempty"`
+	// Averages are the moving averages with a value, by name.
+	Averages map[string]float64 `json:"moving_averages,omitempty"`
+	RSI      *float64           `json:"rsi,omitempty"`
+	MACD     *macdStatus        `json:"macd,omitempty"`
+	Bands    *bandsStatus       `json:"bollinger,omitempty"`
+	VWAP     *vwapStatus        `json:"vwap,omitempty"`
+	ATH      *float64           `json:"ath,omitempty"`
+	Normal   *float64           `json:"normal_spread_pct,omitempty"`
+	Dropped  int                `json:"outliers_dropped,omitempty"`
+	Volume   *volumeStatus      `json:"volume,omitempty"`
+	Delta    *deltaStatus       `json:"volume_delta,omitempty"`
+	Depth    *depthStatus       `json:"depth,omitempty"`
+	Levels   *levelsStatus      `json:"levels,omitempty"`
+	Today    *todayStatus       `json:"daily_change,omitempty"`
+	// Pivots are the pivot levels by period and name, once a period closed.
+	Pivots map[string]map[string]float64 `json:"pivots,omitempty"`
+	// Candles are the last closed candles by interval.
+	Candles map[string]candleStatus `json:"candles,omitempty"`
+	// Volatility is the realized volatility in percent by window.
+	Volatility map[string]float64 `json:"volatility,omitempty"`
+	// Sessions are the levels of the sessions with a price, by name.
+	Sessions map[string]sessionStatus `json:"sessions,omitempty"`
+}
+
+// sessionStatus is the high and low of a session so far and of the last
+// one that ended, once known.
+type sessionStatus struct {
+	High     float64 `json:"high,omitempty"`
+	Low      float64 `json:"low,omitempty"`
+	LastHigh float64 `json:"last_high,omitempty"`
+	LastLow  float64 `json:"last_low,omitempty"`
+}
+
+// volumeStatus is the volume of the open candle of a symbol with volume
+// and the average of the last ones, once they have closed.
+type volumeStatus struct {
+	Current float64 `json:"current"`
+	Average float64 `json:"average"`
+}
+
+// depthStatus is the notional of each side of the last book snapshot of a
+// symbol with depth.
+type depthStatus struct {
+	Bids float64 `json:"bids"`
+	Asks float64 `json:"asks"`
+}
+
+// candleStatus is the last closed candle of an interval of a symbol with
+// candles.
+type candleStatus struct {
+	Start  time.Time `json:"start"`
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"`
+}
+
+// levelsStatus is the nearest support below and resistance above the
+// price of a symbol with levels, omitted for a side without one.
+type levelsStatus struct {
+	Support    float64 `json:"support,omitempty"`
+	Resistance float64 `json:"resistance,omitempty"`
+}
+
+// deltaStatus is the volume takers bought and sold within the window of a
+// symbol with volume_delta.
+type deltaStatus struct {
+	Buy   float64 `json:"buy"`
+	Sell  float64 `json:"sell"`
+	Delta float64 `json:"delta"`
+}
+
+// todayStatus is the day of a symbol with daily_change.
+type todayStatus struct {
+	Day       time.Time `json:"day"` // when it started
+	Open      float64   `json:"open"`
+	ChangePct float64   `json:"change_pct"`
+}
+
+// vwapStatus is the session VWAP of a symbol with vwap.
+type vwapStatus struct {
+	Value     float64 `json:"value"`
+	Deviation float64 `json:"deviation"`
+}
+
+// bandsStatus are the Bollinger bands of a symbol with bollinger, once
+// its period of candles has closed.
+type bandsStatus struct {
+	Lower        float64 `json:"lower"`
+	Middle       float64 `json:"middle"`
+	Upper        float64 `json:"upper"`
+	BandwidthPct float64 `json:"bandwidth_pct"`
+}
+
+// macdStatus is the MACD of a symbol with macd, once its signal line is
+// set.
+type macdStatus struct {
+	Value  float64 `json:"value"`
+	Signal float64 `json:"signal"`
+}
+
+// dayStatus is the rolling 24h window of a ticker-streamed symbol.
+type dayStatus struct {
+	Open        float64 `json:"open"`
+	High        float64 `json:"high"`
+	Low         float64 `json:"low"`
+	Volume      float64 `json:"volume"`
+	QuoteVolume float64 `json:"quote_volume"`
+	ChangePct   float64 `json:"change_pct"`
+}
+
+// fundingStatus is the funding of a perpetual, in percent.
+type fundingStatus struct {
+	RatePct    float64   `json:"rate_pct"`
+	SettledPct *float64  `json:"settled_pct,omitempty"` // once a settlement was seen
+	Next       time.Time `json:"next_funding"`
+	PremiumPct float64   `json:"premium_pct"` // of the mark over the index
+}
+
+// basisStatus is the basis of a futures contract over a symbol's price,
+// in percent of the price.
+type basisStatus struct {
+	Market        string  `json:"market"`
+	Mark          float64 `json:"mark"`
+	Pct           float64 `json:"basis_pct"`
+	AnnualizedPct float64 `json:"annualized_pct"`
+}
+
+// marketStatus is the latest trade of one market of an aggregated symbol.
+type marketStatus struct {
+	Market string  `json:"market"` // exchange:symbol
+	Price  float64 `json:"price"`
+	AgeSec float64 `json:"age_seconds"`
+}
+
+// statusTicker returns the status_file ticker channel, or nil when the file
+// is disabled so the select case never fires.
+func (m *monitor) statusTicker() <-chan time.Time {
+	if m.cfg.StatusFile == "" {
+		return nil
+	}
+	return time.NewTicker(m.cfg.StatusInterval).C
+}
+
+// writeStatus replaces status_file atomically, so readers never see a
+// partial document.
+func (m *monitor) writeStatus(state string) {
+	if m.cfg.StatusFile == "" {
+		return
+	}
+	now := time.Now()
+	st := statusFile{
+		State:      state,
+		Time:       now,
+		Started:    m.started,
+		UptimeSec:  int64(now.Sub(m.started).Seconds()),
+		PID:        os.Getpid(),
+		Profile:    m.cfg.Profile,
+		Source:     m.cfg.Source,
+		Paused:     m.paused,
+		Muted:      m.muted,
+		Quiet:      m.cfg.QuietHours.Contains(now),
+		Held:       len(m.held),
+		Reconnects: m.reconnects,
+		Symbols:    []symbolStatus{},
+	}
+	if m.snoozed.After(now) {
+		until := m.snoozed
+		st.Snoozed = &until
+	}
+	for _, name := range m.snoozedRules(now) {
+		st.Snoozes = append(st.Snoozes, snoozeStatus{name, m.snoozes[name]})
+	}
+	for _, sc := range m.cfg.Symbols {
+		ss := symbolStatus{Symbol: sc.Symbol, Step: m.step(sc), StepPct: sc.StepPercent, Targets: m.targets.Levels(sc.Symbol),
+			Repeating: m.targets.Repeating(sc.Symbol)}
+		if m.cfg.Source != config.SOURCE_REPLAY && len(sc.Markets) == 0 {
+			ss.Exchange = m.cfg.ExchangeOf(sc)
+		}
+		if cp, ok := m.stepper.Checkpoint(sc.Symbol); ok {
+			ss.Checkpoint = &cp
+		}
+		if t, ok := m.last[sc.Symbol]; ok {
+			age := now.Sub(t.Time).Seconds()
+			ss.Price, ss.LastTick, ss.AgeSec, ss.Degraded = &t.Price, &t.Time, &age, t.Degraded
+			ss.LastQty, ss.Bid, ss.Ask, ss.Spread = t.Size, t.Bid, t.Ask, t.Ask-t.Bid
+			if d := t.Day; d != nil {
+				ss.Day = &dayStatus{Open: d.Open, High: d.High, Low: d.Low, Volume: d.Volume, QuoteVolume: d.QuoteVolume, ChangePct: d.ChangePct(t.Price)}
+			}
+		}
+		ss.Stale = m.stale[sc.Symbol]
+		if r, ok := m.recent[sc.Symbol]; ok {
+			ss.Dropped = r.dropped
+		}
+		if v, ok := m.spreads.Value(sc.Symbol); ok && sc.Spread.Enabled() {
+			ss.Normal = &v
+		}
+		if atr, ok := m.atrs.Value(sc.Symbol); ok && sc.ATR.Multiple > 0 {
+			ss.ATR = atr
+		}
+		for _, name := range sc.MovingAverages.Averages {
+			if v, ok := m.avgs.Value(sc.Symbol, name); ok {
+				if ss.Averages == nil {
+					ss.Averages = make(map[string]float64)
+				}
+				ss.Averages[name] = v
+			}
+		}
+		if v, ok := m.rsis.Value(sc.Symbol); ok && sc.RSI.Enabled() {
+			ss.RSI = &v
+		}
+		if v, signal, ok := m.macds.Value(sc.Symbol); ok && sc.MACD.Enabled() {
+			ss.MACD = &macdStatus{Value: v, Signal: signal}
+		}
+		if v, ok := m.bands.Value(sc.Symbol); ok && sc.Bollinger.Enabled() {
+			ss.Bands = &bandsStatus{Lower: v.Lower, Middle: v.Middle, Upper: v.Upper, BandwidthPct: v.Bandwidth()}
+		}
+		if v, dev, ok := m.vwaps.Value(sc.Symbol); ok && sc.VWAP.Enabled() {
+			ss.VWAP = &vwapStatus{Value: v, Deviation: dev}
+		}
+		if v, _, ok := m.highs.Value(sc.Symbol); ok && sc.ATH {
+			ss.ATH = &v
+		}
+		if v, avg, ok := m.volumes.Value(sc.Symbol); ok && sc.Volume.Enabled() {
+			ss.Volume = &volumeStatus{Current: v, Average: avg}
+		}
+		if buy, sell, ok := m.deltas.Value(sc.Symbol); ok && sc.VolumeDelta.Enabled() {
+			ss.Delta = &deltaStatus{Buy: buy, Sell: sell, Delta: buy - sell}
+		}
+		if bids, asks, ok := m.depths.Value(sc.Symbol); ok && sc.Depth.Enabled() {
+			ss.Depth = &depthStatus{Bids: bids, Asks: asks}
+		}
+		if support, resistance, ok := m.swings.Value(sc.Symbol); ok && sc.Levels.Enabled() {
+			ss.Levels = &levelsStatus{Support: support, Resistance: resistance}
+		}
+		for _, interval := range sc.Candles {
+			if c, ok := m.candles.Last(sc.Symbol, config.KlineDuration(interval)); ok {
+				if ss.Candles == nil {
+					ss.Candles = make(map[string]candleStatus)
+				}
+				ss.Candles[interval] = candleStatus{Start: c.Start, Open: c.Open, High: c.High, Low: c.Low, Close: c.Close, Volume: c.Volume}
+			}
+		}
+		for _, window := range sc.Volatility.Windows {
+			if v, ok := m.vols.Value(sc.Symbol, window); ok {
+				if ss.Volatility == nil {
+					ss.Volatility = make(map[string]float64)
+				}
+				ss.Volatility[window] = v
+			}
+		}
+		for _, period := range sc.Pivots.Periods {
+			if levels, ok := m.points.Value(sc.Symbol, config.KlineDuration(period)); ok {
+				if ss.Pivots == nil {
+					ss.Pivots = make(map[string]map[string]float64)
+				}
+				ss.Pivots[period] = make(map[string]float64)
+				for _, name := range sc.Pivots.Levels {
+					ss.Pivots[period][name] = levels[name]
+				}
+			}
+		}
+		if open, day, ok := m.dailyChanges.Value(sc.Symbol); ok && sc.DailyChange.Enabled() {
+			ss.Today = &todayStatus{Day: day, Open: open}
+			if ss.Price != nil {
+				ss.Today.ChangePct = (*ss.Price - open) / open * 100
+			}
+		}
+		for _, name := range sc.Sessions {
+			if l, ok := m.sessions.Levels(sc.Symbol, name); ok {
+				if ss.Sessions == nil {
+					ss.Sessions = make(map[string]sessionStatus)
+				}
+				ss.Sessions[name] = sessionStatus{High: l.High, Low: l.Low, LastHigh: l.LastHigh, LastLow: l.LastLow}
+			}
+		}
+		if f, ok := m.fundings[sc.Symbol]; ok {
+			ss.Funding = &fundingStatus{RatePct: f.rate, SettledPct: f.settled, Next: f.next, PremiumPct: f.premium}
+		}
+		if b, ok := m.bases[sc.Symbol]; ok {
+			ss.Basis = &basisStatus{Market: sc.Basis.Market, Mark: b.mark, Pct: b.pct, AnnualizedPct: b.annualized}
+		}
+		if r, ok := m.correlations.Value(sc.Symbol); ok {
+			ss.Corr = &r
+		}
+		ss.Source = m.source(sc)
+		if len(sc.Markets) > 0 {
+			ss.Method = sc.Aggregate.Method
+			if a, ok := m.aggs[sc.Symbol]; ok {
+				for _, q := range a.Quotes() {
+					ss.Markets = append(ss.Markets, marketStatus{Market: q.Market, Price: q.Price, AgeSec: now.Sub(q.Time).Seconds()})
+				}
+			}
+		}
+		if w, ok := m.workers[sc.Symbol]; ok {
+			ss.Worker, ss.Restarts = w.state, w.restarts
+			st.Reconnects += w.restarts
+		}
+		st.Symbols = append(st.Symbols, ss)
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		logging.Errorf("status_file: %v", err)
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(m.cfg.StatusFile), ".status-*")
+	if err != nil {
+		logging.Warnf("status_file: %v", err)
+		return
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		os.Chmod(tmp.Name(), 0644)
+		err = os.Rename(tmp.Name(), m.cfg.StatusFile)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		logging.Warnf("status_file: %v", err)
+	}
+}
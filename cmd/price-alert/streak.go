@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/config"
+)
+
+// ORDINALS spell out the first steps of a streak; later ones get digits.
+var ORDINALS = []string{"first", "second", "third", "fourth", "fifth", "sixth", "seventh", "eighth", "ninth", "tenth"}
+
+// streak counts the step event ev of sc into its streak and, once the
+// streak reached streak.count, escalates its text, e.g. "ETHUSDT third
+// step down in 20 minutes, to 3400".
+func (m *monitor) streak(sc config.SymbolConfig, ev *alert.Event, via string) {
+	n, since := m.streaks.Update(*ev, sc.Streak.Window)
+	if n < sc.Streak.Count {
+		return
+	}
+	ev.Streak = n
+	ev.Text = fmt.Sprintf("%s %s step %s in %s, to %s%s", sc.Label(), ordinal(n), ev.Kind, spokenDuration(ev.Time.Sub(since)),
+		sc.DisplayAlert(m.num, ev.Price), via)
+}
+
+// ordinal spells out n as an ordinal, "third" or "12th".
+func ordinal(n int) string {
+	if n >= 1 && n <= len(ORDINALS) {
+		return ORDINALS[n-1]
+	}
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// spokenDuration reads d out loud in whole minutes, or hours past two.
+func spokenDuration(d time.Duration) string {
+	switch minutes := int(d.Round(time.Minute) / time.Minute); {
+	case minutes < 1:
+		return "under a minute"
+	case minutes == 1:
+		return "1 minute"
+	case minutes < 120:
+		return strconv.Itoa(minutes) + " minutes"
+	default:
+		return strconv.Itoa(int(d.Round(time.Hour)/time.Hour)) + " hours"
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/control"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// serve answers req. The config changes of subscribe and unsubscribe are
+// made on the loop goroutine, but their websocket calls wait for Binance in
+// the background so ticks keep flowing meanwhile.
+func (m *monitor) serve(req control.Request) {
+	switch req.Args[0] {
+	case "subscribe":
+		m.subscribe(req)
+	case "unsubscribe":
+		m.unsubscribe(req)
+	case "subscriptions":
+		m.subscriptions(req)
+	default:
+		req.Reply(m.exec(req.Args))
+	}
+}
+
+// subscribe adds a symbol, priced from the default exchange, until the next
+// reload. Under run it joins the live Binance connection; under supervise
+// it gets a worker of its own.
+func (m *monitor) subscribe(req control.Request) {
+	args := req.Args[1:]
+	if len(args) < 1 || len(args) > 2 {
+		req.Reply("error: usage: subscribe <symbol> [stream]")
+		return
+	}
+	if m.cfg.Source == config.SOURCE_REPLAY {
+		req.Reply("error: a replay cannot subscribe")
+		return
+	}
+	next := m.cfg
+	sc := config.SymbolConfig{Symbol: args[0]}
+	if len(args) == 2 {
+		sc.Stream = args[1]
+	}
+	next.Symbols = append(slices.Clone(next.Symbols), sc)
+	if err := next.Validate(); err != nil {
+		req.Reply("error: " + err.Error())
+		return
+	}
+	sc = next.Symbols[len(next.Symbols)-1]
+	market := next.Markets(sc)[0]
+	methods := m.methods[market.Exchange]
+	if m.workers == nil && (methods == nil || !m.streams(market.Exchange)) {
+		req.Reply(fmt.Sprintf("error: no %s connection to subscribe on; add %s to the config and reload", market.Exchange, sc.Symbol))
+		return
+	}
+	resolve(&next)
+	m.cfg = next
+	logging.Info(fmt.Sprintf("Subscribed %s", sc.Label()), "event", "subscribe", "symbol", sc.Symbol)
+	if m.workers != nil {
+		req.Reply("subscribed " + sc.Label())
+		return
+	}
+	stream := streamName(sc, market)
+	go func() {
+		err := methods.Subscribe(context.Background(), stream)
+		switch {
+		case err == nil:
+			req.Reply("subscribed " + sc.Label())
+		case errors.Is(err, feed.ErrNotConnected):
+			req.Reply("subscribed " + sc.Label() + " on the next connection")
+		default:
+			req.Reply(fmt.Sprintf("error: %s: %v; it stays configured until unsubscribe or reload", stream, err))
+		}
+	}()
+}
+
+// streams reports whether the run connection streams from exchange.
+func (m *monitor) streams(exchange string) bool {
+	for _, s := range m.cfg.Symbols {
+		for _, mk := range m.cfg.Markets(s) {
+			if mk.Exchange == exchange {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unsubscribe drops a single-market symbol until the next reload.
+func (m *monitor) unsubscribe(req control.Request) {
+	if len(req.Args) != 2 {
+		req.Reply("error: usage: unsubscribe <symbol>")
+		return
+	}
+	sc := m.cfg.Symbol(strings.ToLower(req.Args[1]))
+	switch {
+	case sc == nil:
+		req.Reply("error: unknown symbol " + req.Args[1])
+		return
+	case len(m.cfg.Symbols) == 1:
+		req.Reply("error: " + sc.Label() + " is the only symbol")
+		return
+	case len(m.cfg.Markets(*sc)) > 1:
+		req.Reply("error: " + sc.Label() + " has several markets; remove it from the config and reload")
+		return
+	}
+	removed := *sc
+	market := m.cfg.Markets(removed)[0]
+	cur, next := m.cfg, m.cfg
+	next.Symbols = slices.DeleteFunc(slices.Clone(next.Symbols), func(s config.SymbolConfig) bool {
+		return s.Symbol == removed.Symbol
+	})
+	m.cfg = next
+	m.retain(next)
+	m.reslot(cur)
+	logging.Info(fmt.Sprintf("Unsubscribed %s", removed.Label()), "event", "unsubscribe", "symbol", removed.Symbol)
+	methods := m.methods[market.Exchange]
+	if m.workers != nil || methods == nil || m.cfg.Source == config.SOURCE_REPLAY {
+		req.Reply("unsubscribed " + removed.Label())
+		return
+	}
+	streams := symbolStreams(removed, market)
+	go func() {
+		for _, stream := range streams {
+			err := methods.Unsubscribe(context.Background(), stream)
+			if err != nil && !errors.Is(err, feed.ErrNotConnected) {
+				// Its ticks no longer have a slot, so they are dropped
+				req.Reply(fmt.Sprintf("unsubscribed %s; %s stays on the connection: %v", removed.Label(), stream, err))
+				return
+			}
+		}
+		req.Reply("unsubscribed " + removed.Label())
+	}()
+}
+
+// subscriptions lists the streams every live Binance connection reports,
+// or the workers under supervise.
+func (m *monitor) subscriptions(req control.Request) {
+	if m.workers != nil {
+		var lines []string
+		for _, s := range m.cfg.Symbols {
+			if w, ok := m.workers[s.Symbol]; ok {
+				lines = append(lines, s.Label()+w.status())
+			}
+		}
+		req.Reply(strings.Join(lines, "\n"))
+		return
+	}
+	var exchanges []string
+	for exchange := range m.methods {
+		if m.streams(exchange) {
+			exchanges = append(exchanges, exchange)
+		}
+	}
+	if len(exchanges) == 0 {
+		req.Reply("error: no Binance connection")
+		return
+	}
+	slices.Sort(exchanges)
+	methods := m.methods
+	go func() {
+		var lines []string
+		for _, exchange := range exchanges {
+			streams, err := methods[exchange].List(context.Background())
+			if err != nil {
+				lines = append(lines, fmt.Sprintf("%s: error: %v", exchange, err))
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%s: %s", exchange, strings.Join(streams, " ")))
+		}
+		req.Reply(strings.Join(lines, "\n"))
+	}()
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestUnsubscribeSlots unsubscribes the first of three symbols, which moves
+// the others down a slot, and checks no slot keeps a record of the symbol
+// that held it before.
+func TestUnsubscribeSlots(t *testing.T) {
+	m, pub := testMonitor(t, "btcusdt", "ethusdt", "solusdt")
+	m.handle(tick("btcusdt", 60000))
+	m.handle(tick("ethusdt", 3500))
+	m.handle(tick("solusdt", 150))
+	if got := m.alerts.Head(0); got == 0 {
+		t.Fatalf("Head(0) = 0 before unsubscribe, want the BTCUSDT start")
+	}
+
+	if got, want := ctl(m, "unsubscribe btcusdt"), "unsubscribed BTCUSDT"; got != want {
+		t.Fatalf("unsubscribe = %q, want %q", got, want)
+	}
+	m.handle(tick("ethusdt", 3501))
+
+	tests := []struct {
+		slot          int
+		price, symbol string
+	}{
+		{0, "3501.00", "ETHUSDT"},
+		{1, "", "SOLUSDT"}, // stale until its next price
+		{2, "", ""},        // freed
+	}
+	for _, tt := range tests {
+		if got := pub[tt.slot]; got != [2]string{tt.price, tt.symbol} {
+			t.Errorf("slot %d = %q, want %q", tt.slot, got, [2]string{tt.price, tt.symbol})
+		}
+		if alerts := m.alerts.Since(tt.slot, 0); len(alerts) != 0 {
+			t.Errorf("slot %d alerts = %q, want none", tt.slot, alerts)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+	"github.com/qqubb/tts_price_alert/internal/sdnotify"
+)
+
+// Worker states reported by status.
+const (
+	WORKER_CONNECTING = "connecting"
+	WORKER_STREAMING  = "streaming"
+	WORKER_BACKOFF    = "backoff"
+)
+
+// worker is the supervisor's view of one symbol's connection. It is only
+// touched from the loop goroutine; the connection itself reports through
+// workerEvent.
+type worker struct {
+	symbol   string
+	key      string // streamKey, to spot an exchange or endpoint change
+	state    string
+	restarts int
+	lastErr  error
+	since    time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// workerEvent is a state change sent from a worker goroutine to the loop.
+type workerEvent struct {
+	symbol   string
+	state    string
+	restarts int
+	err      error
+	retry    time.Duration // backoff before the next attempt
+}
+
+// superviseCmd works like run but gives every symbol its own connection: a
+// symbol whose stream keeps failing backs off on its own while the others
+// keep publishing into the shared SHM region.
+func superviseCmd(args []string) int {
+	fs := flag.NewFlagSet("supervise", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "evaluate alerts and log only; create no SHM file or FIFO")
+	loader := config.ParseFlags(fs, args)
+	cfg, err := loader.Load()
+	if err != nil {
+		logging.Fatalf("config: %v", err)
+	}
+	setupLogging(cfg.Log)
+	if cfg.Source == config.SOURCE_REPLAY {
+		logging.Fatalf("supervise needs a live source; use replay for recordings")
+	}
+	resolve(&cfg)
+
+	m, err := openMonitor(cfg, loader, *dryRun)
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+	m.backfill()
+	if cfg.Profile != "" {
+		logging.Infof("Profile: %s", cfg.Profile)
+	}
+	m.supervise()
+	if err := m.Close(); err != nil {
+		logging.Errorf("Shutdown error: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// supervise is loop with one worker per symbol. It returns after SIGINT or
+// SIGTERM once every worker has stopped.
+func (m *monitor) supervise() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	ticks := make(chan feed.Tick)
+	events := make(chan workerEvent)
+	m.workers = make(map[string]*worker)
+	m.syncWorkers(ticks, events)
+	if ud := m.userDataSource(); ud != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go ud.Run(ctx, ticks)
+	}
+	for {
+		select {
+		case t := <-ticks:
+			m.workerTick(t)
+		case ev := <-events:
+			m.workerChanged(ev)
+		case req := <-m.requests:
+			m.serve(req)
+			m.syncWorkers(ticks, events) // after a subscribe or unsubscribe
+		case <-m.statusC:
+			m.writeStatus("running")
+		case <-m.pollC:
+			m.poll()
+		case polled := <-m.polled:
+			m.handlePolled(polled)
+		case <-m.oiC:
+			m.pollOpenInterest()
+		case readings := <-m.oiPolled:
+			m.handleOpenInterest(readings)
+		case <-m.clockC:
+			m.checkStale()
+			m.release()
+		case <-hup:
+			m.reload()
+		case next := <-m.reloaded:
+			if m.applyReload(next) {
+				m.syncWorkers(ticks, events)
+			}
+		case sig := <-stop:
+			logging.Infof("Received %v, shutting down", sig)
+			m.sdNotify(sdnotify.STOPPING)
+			for _, w := range m.workers {
+				m.stopWorker(w, ticks, events)
+			}
+			return
+		}
+	}
+}
+
+// syncWorkers starts and stops workers to match m.cfg. Only symbols that
+// were added or removed, or moved to another exchange or endpoint, are
+// touched. Baskets and ratios have no connection of their own.
+func (m *monitor) syncWorkers(ticks chan feed.Tick, events chan workerEvent) {
+	for symbol, w := range m.workers {
+		if s := m.cfg.Symbol(symbol); s == nil || streamKey(m.cfg, *s) != w.key {
+			m.stopWorker(w, ticks, events)
+			delete(m.workers, symbol)
+		}
+	}
+	for _, s := range m.cfg.Symbols {
+		symbol := s.Symbol
+		if _, ok := m.workers[symbol]; ok || s.Derived() {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		w := &worker{symbol: symbol, key: streamKey(m.cfg, s), state: WORKER_CONNECTING, since: time.Now(), cancel: cancel, done: make(chan struct{})}
+		m.workers[symbol] = w
+		src, rc := marketsSource(m.cfg, []config.SymbolConfig{s}, nil), m.cfg.Reconnect
+		go func() {
+			defer close(w.done)
+			runWorker(ctx, symbol, src, rc, ticks, events)
+		}()
+	}
+}
+
+// stopWorker cancels w and waits for it, still handling whatever it and the
+// other workers send meanwhile so none of them blocks on an unbuffered
+// channel.
+func (m *monitor) stopWorker(w *worker, ticks chan feed.Tick, events chan workerEvent) {
+	w.cancel()
+	for {
+		select {
+		case <-w.done:
+			return
+		case t := <-ticks:
+			m.workerTick(t)
+		case ev := <-events:
+			m.workerChanged(ev)
+		}
+	}
+}
+
+// workerTick handles t and marks its worker as streaming: a connection only
+// counts as up once prices flow.
+func (m *monitor) workerTick(t feed.Tick) {
+	if t.Fill != nil || t.Exchange == BASIS_EXCHANGE {
+		m.handle(t)
+		return
+	}
+	slot := m.slot(t)
+	if slot < 0 {
+		return
+	}
+	m.handle(t)
+	if w, ok := m.workers[m.cfg.Symbols[slot].Symbol]; ok && w.state != WORKER_STREAMING {
+		w.state, w.since = WORKER_STREAMING, time.Now()
+		m.workersChanged()
+	}
+}
+
+// workerChanged records a state change reported by a worker goroutine.
+func (m *monitor) workerChanged(ev workerEvent) {
+	w, ok := m.workers[ev.symbol]
+	if !ok {
+		return
+	}
+	wasStreaming := w.state == WORKER_STREAMING
+	w.state, w.restarts, w.since = ev.state, ev.restarts, time.Now()
+	if ev.err != nil {
+		w.lastErr = ev.err
+		logging.Warn(fmt.Sprintf("%s worker error: %v, reconnecting in %v", strings.ToUpper(ev.symbol), ev.err, ev.retry),
+			"event", "reconnect", "symbol", ev.symbol, "restarts", ev.restarts, "backoff", ev.retry)
+		m.poll()
+	}
+	if wasStreaming {
+		m.workersChanged()
+	}
+}
+
+// workersChanged logs how many workers are streaming; it is called
+// whenever that number changes.
+func (m *monitor) workersChanged() {
+	streaming := 0
+	for _, w := range m.workers {
+		if w.state == WORKER_STREAMING {
+			streaming++
+		}
+	}
+	logging.Infof("Workers: %d/%d streaming", streaming, len(m.workers))
+	m.sdNotify(sdnotify.Status(fmt.Sprintf("%d/%d symbols streaming", streaming, len(m.workers))))
+}
+
+// status formats w for the status command.
+func (w *worker) status() string {
+	s := fmt.Sprintf(" worker=%s for=%s restarts=%d", w.state, time.Since(w.since).Round(time.Second), w.restarts)
+	if w.lastErr != nil {
+		s += fmt.Sprintf(" last_error=%q", w.lastErr.Error())
+	}
+	return s
+}
+
+// runWorker streams one symbol until ctx is cancelled, restarting the
+// connection with its own exponential backoff. A connection that stayed
+// up longer than the maximum backoff starts the next one from the initial
+// delay again.
+func runWorker(ctx context.Context, symbol string, src feed.Source, rc config.ReconnectConfig, ticks chan<- feed.Tick, events chan<- workerEvent) {
+	report := func(ev workerEvent) bool {
+		ev.symbol = symbol
+		select {
+		case events <- ev:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	backoff := rc.InitialBackoff
+	for restarts := 0; ; restarts++ {
+		if !report(workerEvent{state: WORKER_CONNECTING, restarts: restarts}) {
+			return
+		}
+		started := time.Now()
+		err := runSafely(ctx, src, ticks)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = errors.New("stream ended")
+		}
+		if time.Since(started) > rc.MaxBackoff {
+			backoff = rc.InitialBackoff
+		}
+		if !report(workerEvent{state: WORKER_BACKOFF, restarts: restarts, err: err, retry: backoff}) {
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(backoff*2, rc.MaxBackoff)
+	}
+}
+
+// runSafely runs src, turning a panic into an error so that a crashing
+// stream only restarts its own worker.
+func runSafely(ctx context.Context, src feed.Source, ticks chan<- feed.Tick) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return src.Run(ctx, ticks)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+)
+
+// uniswapSource polls the prices of the uniswap exchange's pools.
+func uniswapSource(cfg config.Config, markets []string) feed.Source {
+	return &feed.Quotes{
+		Quote:        feed.UniswapQuoter(),
+		RESTEndpoint: cfg.Uniswap.RPCURL,
+		Symbols:      markets,
+		Interval:     cfg.Uniswap.Interval,
+	}
+}
+
+// checkUniswap reads the price of pool, which proves both the node and the
+// pool.
+func checkUniswap(client *http.Client, cfg config.Config, pool string) (string, string) {
+	price, _, err := feed.UniswapQuoter()(client, cfg.Uniswap.RPCURL, "", pool)
+	if err != nil {
+		return CHECK_FAIL, err.Error()
+	}
+	return CHECK_OK, fmt.Sprintf("pool price %g", price)
+}
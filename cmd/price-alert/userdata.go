@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/secrets"
+)
+
+// userDataKey resolves the user_data API key of cfg; it is empty when the
+// stream is off.
+func userDataKey(cfg config.Config) (string, error) {
+	if cfg.UserData.APIKey == "" || cfg.Source == config.SOURCE_REPLAY {
+		return "", nil
+	}
+	key, err := secrets.Resolve(newSecrets(cfg), cfg.UserData.APIKey)
+	if err != nil {
+		return "", fmt.Errorf("user_data: api_key: %w", err)
+	}
+	return key, nil
+}
+
+// userDataSource streams the account's fills, reconnecting on its own so
+// that it neither takes the price connection down nor waits for it. It is
+// nil when user_data is off.
+func (m *monitor) userDataSource() feed.Source {
+	if m.apiKey == "" {
+		return nil
+	}
+	exchange := m.cfg.UserData.Exchange
+	ws, rest := m.cfg.Endpoints(exchange)
+	return feed.Retry{
+		Source: &feed.UserData{
+			Endpoint:     ws,
+			RESTEndpoint: rest,
+			APIKey:       m.apiKey,
+			Futures:      exchange == config.SOURCE_BINANCE_FUTURES,
+			PingPeriod:   m.cfg.Reconnect.PingPeriod,
+		},
+		Name:           exchange + " user data",
+		InitialBackoff: m.cfg.Reconnect.InitialBackoff,
+		MaxBackoff:     m.cfg.Reconnect.MaxBackoff,
+	}
+}
+
+// fill announces an execution of one of the account's orders, e.g.
+// "ETHUSDT limit buy filled at 3050.00". Partial fills are only announced
+// with user_data.partial_fills.
+func (m *monitor) fill(t feed.Tick) {
+	f := t.Fill
+	partial := f.Status == "PARTIALLY_FILLED"
+	if partial && !m.cfg.UserData.PartialFills {
+		return
+	}
+	label := strings.ToUpper(t.Symbol)
+	price, qty := m.num.Format(t.Price, -1), m.num.Format(f.Filled, -1)
+	if sc := m.cfg.Symbol(t.Symbol); sc != nil {
+		price = sc.Display(m.num, t.Price)
+	}
+	order := strings.ToLower(strings.ReplaceAll(f.Type+" "+f.Side, "_", " "))
+	ev := alert.Event{Symbol: t.Symbol, Kind: alert.Fill, Price: t.Price, Time: t.Time, Size: t.Size}
+	if partial {
+		ev.Text = fmt.Sprintf("%s %s partially filled, %s of %s at %s", label, order, qty, m.num.Format(f.OrderQty, -1), price)
+	} else {
+		ev.Text = fmt.Sprintf("%s %s filled at %s", label, order, price)
+	}
+	m.notify(ev)
+}
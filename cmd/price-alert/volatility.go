@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// volWindows describes the volatility windows of sc for the tracker.
+func volWindows(sc config.SymbolConfig) []alert.VolWindow {
+	windows := make([]alert.VolWindow, len(sc.Volatility.Windows))
+	for i, name := range sc.Volatility.Windows {
+		windows[i] = alert.VolWindow{Name: name, Length: config.KlineDuration(name)}
+	}
+	return windows
+}
+
+// volatility folds c, a closed volatility candle of sc, into its realized
+// volatility and announces the windows that spiked or collapsed, e.g.
+// "ETHUSDT 1h volatility spiked to 92 percent, above 80, at 3400".
+func (m *monitor) volatility(sc config.SymbolConfig, c alert.Candle, source, via string) {
+	v := sc.Volatility
+	for _, ev := range m.vols.Add(sc.Symbol, c.Start, c.Close, c.Interval, volWindows(sc), v.Above, v.Below) {
+		ev.Time, ev.Source = c.Start.Add(c.Interval), source
+		if ev.Kind == alert.VolSpike {
+			ev.Text = fmt.Sprintf("%s %s volatility spiked to %s percent, above %s, at %s%s", sc.Label(), ev.Window,
+				m.num.Format(ev.Level, 0), m.num.Format(v.Above, -1), sc.DisplayAlert(m.num, c.Close), via)
+		} else {
+			ev.Text = fmt.Sprintf("%s %s volatility collapsed to %s percent, below %s, at %s%s", sc.Label(), ev.Window,
+				m.num.Format(ev.Level, 0), m.num.Format(v.Below, -1), sc.DisplayAlert(m.num, c.Close), via)
+		}
+		m.notify(ev)
+	}
+}
+
+// backfillVolatility seeds the volatility of symbols with volatility from
+// the closed klines of their first market spanning the longest window, so
+// the values are known from the start and one already past a threshold
+// does not alert. Other symbols have their values once their candles span
+// a window.
+func (m *monitor) backfillVolatility() {
+	if m.cfg.Source == config.SOURCE_REPLAY {
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, sc := range m.cfg.Symbols {
+		v := sc.Volatility
+		if !v.Enabled() {
+			continue
+		}
+		market := m.cfg.Markets(sc)[0]
+		fetch := klineFetcher(market.Exchange)
+		if fetch == nil {
+			continue
+		}
+		_, rest := m.cfg.Endpoints(market.Exchange)
+		interval, longest := config.KlineDuration(v.Interval), time.Duration(0)
+		for _, w := range volWindows(sc) {
+			longest = max(longest, w.Length)
+		}
+		// One more for the first return's base and one for the open kline
+		klines, err := fetch(client, rest, market.Symbol, v.Interval, int(longest/interval)+2)
+		if err != nil {
+			logging.Warn(fmt.Sprintf("%s volatility backfill failed, waiting for the candles: %v", sc.Label(), err),
+				"event", "backfill", "symbol", sc.Symbol)
+			continue
+		}
+		for i, k := range klines {
+			if i < len(klines)-1 {
+				m.vols.Add(sc.Symbol, k.OpenTime, k.Close, interval, volWindows(sc), v.Above, v.Below)
+			}
+		}
+	}
+}
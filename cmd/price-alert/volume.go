@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// volume adds the trade t of sc to its volume candle and raises its alert
+// when the candle's volume reached volume.multiple times the average, e.g.
+// "ETHUSDT 1m volume 4.2 times average, at 3420". Polled prices carry no
+// volume and are left out.
+func (m *monitor) volume(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	if t.Degraded {
+		return
+	}
+	v := sc.Volume
+	ev, ok := m.volumes.Update(sc.Symbol, t.Price, t.Size, t.Time, config.KlineDuration(v.Interval), v.Period, v.Multiple)
+	if !ok {
+		return
+	}
+	ev.Time, ev.Source, ev.Size, ev.Spread = t.Time, source, t.Size, t.Ask-t.Bid
+	ev.Text = fmt.Sprintf("%s %s volume %s times average, at %s%s", sc.Label(), v.Interval, m.num.Format(ev.Change/ev.Level, 1), sc.DisplayAlert(m.num, t.Price), via)
+	m.notify(ev)
+}
+
+// backfillVolume seeds the volume candles of symbols with volume from the
+// klines of their first market, so the average is known from the start; an
+// open candle already above the multiple then waits for the next one. A
+// symbol whose first market is not on Binance, or whose klines fail, builds
+// its candles from its trades.
+func (m *monitor) backfillVolume() {
+	if m.cfg.Source == config.SOURCE_REPLAY {
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, sc := range m.cfg.Symbols {
+		v := sc.Volume
+		if !v.Enabled() {
+			continue
+		}
+		if _, _, ok := m.volumes.Value(sc.Symbol); ok {
+			continue
+		}
+		market := m.cfg.Markets(sc)[0]
+		fetch := klineFetcher(market.Exchange)
+		if fetch == nil {
+			continue
+		}
+		_, rest := m.cfg.Endpoints(market.Exchange)
+		// One more for the open kline
+		klines, err := fetch(client, rest, market.Symbol, v.Interval, v.Period+1)
+		if err != nil {
+			logging.Warn(fmt.Sprintf("%s volume backfill failed, building it from the trades: %v", sc.Label(), err),
+				"event", "backfill", "symbol", sc.Symbol)
+			continue
+		}
+		for _, k := range klines {
+			m.volumes.Add(sc.Symbol, k.OpenTime, k.Volume, k.Close, config.KlineDuration(v.Interval), v.Period, v.Multiple)
+		}
+		if _, avg, ok := m.volumes.Value(sc.Symbol); ok {
+			logging.Info(fmt.Sprintf("%s %s average volume %s", sc.Label(), v.Interval, m.num.Format(avg, 2)),
+				"event", "backfill", "symbol", sc.Symbol, "average", avg)
+		}
+	}
+}
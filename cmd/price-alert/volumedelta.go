@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+)
+
+// volumeDelta adds the trade t of sc to its volume delta window and raises
+// its alert when takers on one side dominate it, e.g. "ETHUSDT 5m order
+// flow 82 percent net buying, delta 640, at 3420". Only trades reporting
+// their taker count.
+func (m *monitor) volumeDelta(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	if t.Side == "" {
+		return
+	}
+	d := sc.VolumeDelta
+	ev, ok := m.deltas.Update(sc.Symbol, t.Price, t.Size, t.Side == feed.SIDE_BUY, t.Time, d.Window, d.Imbalance, d.MinVolume)
+	if !ok {
+		return
+	}
+	side := "buying"
+	if ev.Change < 0 {
+		side = "selling"
+	}
+	ev.Time, ev.Source, ev.Size, ev.Spread = t.Time, source, t.Size, t.Ask-t.Bid
+	ev.Text = fmt.Sprintf("%s %s order flow %s percent net %s, delta %s, at %s%s", sc.Label(), shortDuration(d.Window), m.num.Format(math.Abs(ev.Level), 0), side,
+		m.num.Format(ev.Change, 2), sc.DisplayAlert(m.num, t.Price), via)
+	m.notify(ev)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+)
+
+// vwap folds the tick t of sc into its session VWAP, updates the stats slot
+// and raises its alert when the price strayed from the VWAP, e.g. "ETHUSDT
+// 2.1 percent above VWAP 3380 at 3451". Polled prices carry no volume and
+// are left out.
+func (m *monitor) vwap(slot int, sc config.SymbolConfig, t feed.Tick, source, via string) {
+	if t.Degraded {
+		return
+	}
+	v := sc.VWAP
+	ev, ok := m.vwaps.Update(sc.Symbol, t.Price, t.Size, v.Session(t.Time), v.Percent, v.Deviations)
+	m.writeStats(slot, sc)
+	if !ok {
+		return
+	}
+	side := "above"
+	if ev.Change < 0 {
+		side = "below"
+	}
+	ev.Time, ev.Source = t.Time, source
+	ev.Text = fmt.Sprintf("%s %s percent %s VWAP %s at %s%s", sc.Label(), m.num.Format(math.Abs(ev.Change)/ev.Level*100, 1), side, sc.Format(ev.Level), sc.DisplayAlert(m.num, ev.Price), via)
+	m.notify(ev)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/config"
+	"github.com/qqubb/tts_price_alert/internal/feed"
+)
+
+// whale alerts on the trade t of sc when its notional reaches
+// whales.notional, e.g. "ETHUSDT single 2,000,000 market sell at 3,420",
+// and runs the whales actions. Only trades reporting their taker carry a
+// side, so candles, quotes and polled prices never alert.
+func (m *monitor) whale(sc config.SymbolConfig, t feed.Tick, source, via string) {
+	notional := t.Price * t.Size
+	if t.Side == "" || notional < sc.Whales.Notional {
+		return
+	}
+	ev := alert.Event{Symbol: sc.Symbol, Kind: alert.Whale, Price: t.Price, Level: notional, Side: t.Side,
+		Time: t.Time, Source: source, Size: t.Size, Spread: t.Ask - t.Bid}
+	ev.Text = fmt.Sprintf("%s single %s market %s at %s%s", sc.Label(), m.num.Format(notional, 0), t.Side, sc.DisplayAlert(m.num, t.Price), via)
+	m.notify(ev)
+	m.act(sc.Whales.Actions, ev)
+}
@@ -0,0 +1,8 @@
+module github.com/qqubb/tts_price_alert
+
+go 1.22
+
+require (
+	github.com/gorilla/websocket v1.5.3
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,123 @@
+// Package aggregate combines the trades of one asset on several exchanges
+// into a composite price, so a single bad print does not move alerts.
+package aggregate
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Composite methods.
+const (
+	MEDIAN = "median" // median of each market's latest price
+	VWAP   = "vwap"   // volume-weighted average of every trade in the window
+)
+
+// Quote is the latest trade seen on one market.
+type Quote struct {
+	Market string
+	Price  float64
+	Time   time.Time // arrival, not exchange, time
+}
+
+type trade struct {
+	price, size float64
+	at          time.Time
+}
+
+// Aggregator computes the composite price of one symbol. Only trades that
+// arrived within Window count, so a market whose stream stalled drops out.
+type Aggregator struct {
+	Method string
+	Window time.Duration
+
+	quotes map[string]Quote
+	trades []trade // VWAP only, oldest first
+}
+
+func New(method string, window time.Duration) *Aggregator {
+	return &Aggregator{Method: method, Window: window, quotes: make(map[string]Quote)}
+}
+
+// Add records a trade of size at price on market, arriving at now, and
+// returns the new composite price.
+func (a *Aggregator) Add(market string, price, size float64, now time.Time) float64 {
+	a.quotes[market] = Quote{Market: market, Price: price, Time: now}
+	cutoff := now.Add(-a.Window)
+	if a.Method == VWAP {
+		a.trades = append(a.trades, trade{price, size, now})
+		i := 0
+		for i < len(a.trades) && a.trades[i].at.Before(cutoff) {
+			i++
+		}
+		a.trades = a.trades[i:]
+		return a.vwap()
+	}
+	return a.median(cutoff)
+}
+
+// median is the median of the fresh quotes; with an even count it is the
+// mean of the middle two.
+func (a *Aggregator) median(cutoff time.Time) float64 {
+	var prices []float64
+	for _, q := range a.quotes {
+		if !q.Time.Before(cutoff) {
+			prices = append(prices, q.Price)
+		}
+	}
+	slices.Sort(prices)
+	n := len(prices)
+	if n%2 == 1 {
+		return prices[n/2]
+	}
+	return (prices[n/2-1] + prices[n/2]) / 2
+}
+
+// vwap is the volume-weighted mean of the window's trades, or their plain
+// mean when no source reported sizes.
+func (a *Aggregator) vwap() float64 {
+	var sum, volume float64
+	for _, t := range a.trades {
+		sum += t.price * t.size
+		volume += t.size
+	}
+	if volume > 0 {
+		return sum / volume
+	}
+	sum = 0
+	for _, t := range a.trades {
+		sum += t.price
+	}
+	return sum / float64(len(a.trades))
+}
+
+// Quotes returns the latest quote of every market seen, sorted by market.
+func (a *Aggregator) Quotes() []Quote {
+	out := make([]Quote, 0, len(a.quotes))
+	for _, q := range a.quotes {
+		out = append(out, q)
+	}
+	slices.SortFunc(out, func(x, y Quote) int { return strings.Compare(x.Market, y.Market) })
+	return out
+}
+
+// Extremes returns the cheapest and dearest quotes that arrived within
+// Window of now, false with fewer than two markets among them.
+func (a *Aggregator) Extremes(now time.Time) (low, high Quote, ok bool) {
+	cutoff := now.Add(-a.Window)
+	n := 0
+	for _, q := range a.quotes {
+		if q.Time.Before(cutoff) {
+			continue
+		}
+		if n == 0 || q.Price < low.Price {
+			low = q
+		}
+		if n == 0 || q.Price > high.Price {
+			high = q
+		}
+		n++
+	}
+	return low, high, n >= 2
+}
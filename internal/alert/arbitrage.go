@@ -0,0 +1,61 @@
+package alert
+
+import "time"
+
+// ARBITRAGE_REARM is the fraction of its percent an arbitrage spread has
+// to narrow to before lasting at the percent again alerts again.
+const ARBITRAGE_REARM = 0.5
+
+// Arbitrages raises an event when the markets of a symbol stay apart.
+type Arbitrages struct {
+	symbols map[string]*arbitrage
+}
+
+type arbitrage struct {
+	since time.Time // the spread reached the percent, zero while below
+	fired bool      // alerted and not yet re-armed
+}
+
+func NewArbitrages() *Arbitrages {
+	return &Arbitrages{symbols: make(map[string]*arbitrage)}
+}
+
+// Update feeds the lowest and highest market prices of symbol at time at.
+// It returns an event with the spread, high over low in percent, as Level
+// and high minus low as Change, and how long the spread has lasted, once
+// it has been percent or more for hold; it alerts again only after the
+// spread has narrowed to ARBITRAGE_REARM times percent.
+func (a *Arbitrages) Update(symbol string, low, high float64, at time.Time, percent float64, hold time.Duration) (Event, time.Duration, bool) {
+	s, ok := a.symbols[symbol]
+	if !ok {
+		s = &arbitrage{}
+		a.symbols[symbol] = s
+	}
+	spread := (high/low - 1) * 100
+	if spread <= percent*ARBITRAGE_REARM {
+		s.fired = false
+	}
+	if spread < percent {
+		s.since = time.Time{}
+		return Event{}, 0, false
+	}
+	if s.since.IsZero() {
+		s.since = at
+	}
+	lasted := at.Sub(s.since)
+	if s.fired || lasted < hold {
+		return Event{}, 0, false
+	}
+	s.fired = true
+	return Event{Symbol: symbol, Kind: Arbitrage, Price: high, Change: high - low, Level: spread}, lasted, true
+}
+
+// Retain drops when the markets drifted apart, and whether that alerted,
+// of the symbols for which keep returns false.
+func (a *Arbitrages) Retain(keep func(symbol string) bool) {
+	for symbol := range a.symbols {
+		if !keep(symbol) {
+			delete(a.symbols, symbol)
+		}
+	}
+}
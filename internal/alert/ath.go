@@ -0,0 +1,69 @@
+package alert
+
+// AllTimeHighs keeps the highest price ever seen of each symbol and raises
+// an event when the price sets a new one.
+type AllTimeHighs struct {
+	symbols map[string]*ath
+}
+
+type ath struct {
+	high    float64 // the all-time high
+	alerted float64 // the high last alerted, 0 before one
+}
+
+func NewAllTimeHighs() *AllTimeHighs {
+	return &AllTimeHighs{symbols: make(map[string]*ath)}
+}
+
+// Update feeds a price of symbol; the first one of a symbol not seeded only
+// sets its high. It returns an event with the previous high as Level when
+// the price is above it and, after an alert, rearm or more above the high
+// that alerted.
+func (a *AllTimeHighs) Update(symbol string, price, rearm float64) (Event, bool) {
+	s, ok := a.symbols[symbol]
+	if !ok {
+		a.symbols[symbol] = &ath{high: price}
+		return Event{}, false
+	}
+	if price <= s.high {
+		return Event{}, false
+	}
+	prev := s.high
+	s.high = price
+	if s.alerted > 0 && price < s.alerted+rearm {
+		return Event{}, false
+	}
+	s.alerted = price
+	return Event{Symbol: symbol, Kind: ATH, Price: price, Change: price - prev, Level: prev}, true
+}
+
+// Seed raises the high of symbol to high and the high last alerted to
+// alerted, such as ones kept across restarts or from historical klines.
+func (a *AllTimeHighs) Seed(symbol string, high, alerted float64) {
+	s, ok := a.symbols[symbol]
+	if !ok {
+		s = &ath{}
+		a.symbols[symbol] = s
+	}
+	s.high = max(s.high, high)
+	s.alerted = max(s.alerted, alerted)
+}
+
+// Value returns the all-time high of symbol and the high last alerted,
+// false before a price.
+func (a *AllTimeHighs) Value(symbol string) (high, alerted float64, ok bool) {
+	if s, ok := a.symbols[symbol]; ok {
+		return s.high, s.alerted, true
+	}
+	return 0, 0, false
+}
+
+// Retain drops the all-time high and the high last alerted of the symbols
+// for which keep returns false.
+func (a *AllTimeHighs) Retain(keep func(symbol string) bool) {
+	for symbol := range a.symbols {
+		if !keep(symbol) {
+			delete(a.symbols, symbol)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package alert
+
+import (
+	"math"
+	"time"
+)
+
+// ATRs keeps the average true range of each symbol over candles built from
+// its prices, for steps that scale with volatility.
+type ATRs struct {
+	symbols map[string]*atr
+}
+
+type atr struct {
+	start            time.Time // of the open candle
+	high, low, close float64
+	prev             float64 // close of the last closed candle, 0 before one
+	ranges           []float64
+	value            float64 // Wilder's average once len(ranges) reached the period
+}
+
+func NewATRs() *ATRs {
+	return &ATRs{symbols: make(map[string]*atr)}
+}
+
+// Update feeds a price of symbol at time at into its open candle of
+// interval. It returns true when the price opened a new candle and the
+// closed one moved the average true range over period candles.
+func (a *ATRs) Update(symbol string, price float64, at time.Time, interval time.Duration, period int) bool {
+	return a.Add(symbol, at.Truncate(interval), price, price, price, period)
+}
+
+// Add feeds a candle of symbol opening at start, such as a historical
+// kline; one opening before the open candle is ignored, one opening with it
+// is merged into it. It returns true like Update.
+func (a *ATRs) Add(symbol string, start time.Time, high, low, close float64, period int) bool {
+	s, ok := a.symbols[symbol]
+	switch {
+	case !ok:
+		a.symbols[symbol] = &atr{start: start, high: high, low: low, close: close}
+		return false
+	case start.Before(s.start):
+		return false
+	case start.Equal(s.start):
+		s.high, s.low, s.close = max(s.high, high), min(s.low, low), close
+		return false
+	}
+	tr := s.high - s.low
+	if s.prev > 0 {
+		tr = max(tr, math.Abs(s.high-s.prev), math.Abs(s.low-s.prev))
+	}
+	s.prev = s.close
+	s.start, s.high, s.low, s.close = start, high, low, close
+	if len(s.ranges) < period {
+		s.ranges = append(s.ranges, tr)
+		if len(s.ranges) < period {
+			return false
+		}
+		sum := 0.0
+		for _, r := range s.ranges {
+			sum += r
+		}
+		s.value = sum / float64(period)
+		return true
+	}
+	s.value = (s.value*float64(period-1) + tr) / float64(period)
+	return true
+}
+
+// Value returns the average true range of symbol, false before period
+// candles have closed.
+func (a *ATRs) Value(symbol string) (float64, bool) {
+	if s, ok := a.symbols[symbol]; ok && s.value > 0 {
+		return s.value, true
+	}
+	return 0, false
+}
+
+// Retain drops the open candle, the true ranges and their average of the
+// symbols for which keep returns false.
+func (a *ATRs) Retain(keep func(symbol string) bool) {
+	for symbol := range a.symbols {
+		if !keep(symbol) {
+			delete(a.symbols, symbol)
+		}
+	}
+}
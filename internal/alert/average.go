@@ -0,0 +1,140 @@
+package alert
+
+import "time"
+
+// Average is a moving average of candle closes: an exponential one, whose
+// weight falls off with age, or a simple mean of the last Period.
+type Average struct {
+	Name        string
+	Exponential bool
+	Period      int
+}
+
+// MovingAverages keeps moving averages over candles built from the prices
+// of each symbol and raises an event when the close or one average crosses
+// another.
+type MovingAverages struct {
+	symbols map[string]*averages
+}
+
+type averages struct {
+	start  time.Time // of the open candle
+	close  float64   // of the open candle
+	closes []float64 // of the closed candles, as many as the longest period
+	count  int       // candles closed
+	values map[string]float64
+	sides  map[string]float64 // x minus y of each crossover at its last check
+}
+
+func NewMovingAverages() *MovingAverages {
+	return &MovingAverages{symbols: make(map[string]*averages)}
+}
+
+// Update feeds a price of symbol at time at into its open candle of
+// interval. It returns true when the price opened a new candle and the
+// closed one moved the averages.
+func (m *MovingAverages) Update(symbol string, price float64, at time.Time, interval time.Duration, list []Average) bool {
+	return m.Add(symbol, at.Truncate(interval), price, list)
+}
+
+// Add feeds a candle of symbol opening at start, such as a historical
+// kline; one opening before the open candle is ignored, one opening with it
+// replaces its close. It returns true like Update.
+func (m *MovingAverages) Add(symbol string, start time.Time, close float64, list []Average) bool {
+	s, ok := m.symbols[symbol]
+	switch {
+	case !ok:
+		m.symbols[symbol] = &averages{start: start, close: close, values: make(map[string]float64), sides: make(map[string]float64)}
+		return false
+	case start.Before(s.start):
+		return false
+	case start.Equal(s.start):
+		s.close = close
+		return false
+	}
+	keep := 0
+	for _, a := range list {
+		keep = max(keep, a.Period)
+	}
+	s.closes = append(s.closes, s.close)
+	if len(s.closes) > keep {
+		s.closes = s.closes[len(s.closes)-keep:]
+	}
+	s.count++
+	for _, a := range list {
+		v, seeded := s.values[a.Name]
+		switch {
+		case s.count < a.Period:
+		case a.Exponential && seeded:
+			k := 2 / float64(a.Period+1)
+			s.values[a.Name] = s.close*k + v*(1-k)
+		default:
+			// An EMA starts from the SMA of its first period
+			sum := 0.0
+			for _, c := range s.closes[len(s.closes)-a.Period:] {
+				sum += c
+			}
+			s.values[a.Name] = sum / float64(a.Period)
+		}
+	}
+	s.start, s.close = start, close
+	return true
+}
+
+// Value returns the average name of symbol, false before its period of
+// candles has closed.
+func (m *MovingAverages) Value(symbol, name string) (float64, bool) {
+	if s, ok := m.symbols[symbol]; ok {
+		v, ok := s.values[name]
+		return v, ok
+	}
+	return 0, false
+}
+
+// Close returns the close of the last closed candle of symbol.
+func (m *MovingAverages) Close(symbol string) (float64, bool) {
+	if s, ok := m.symbols[symbol]; ok && len(s.closes) > 0 {
+		return s.closes[len(s.closes)-1], true
+	}
+	return 0, false
+}
+
+// Cross checks whether x crossed y, each the name of an average or "price"
+// for the close, since the previous check, and returns an event with the
+// value of y as Level and x minus y as Change. A side without a value yet
+// checks nothing.
+func (m *MovingAverages) Cross(symbol, x, y string) (Event, bool) {
+	s, ok := m.symbols[symbol]
+	if !ok || len(s.closes) == 0 {
+		return Event{}, false
+	}
+	value := func(name string) (float64, bool) {
+		if name == "price" {
+			return s.closes[len(s.closes)-1], true
+		}
+		v, ok := s.values[name]
+		return v, ok
+	}
+	vx, okx := value(x)
+	vy, oky := value(y)
+	if !okx || !oky || vx == vy {
+		return Event{}, false
+	}
+	key := x + "/" + y
+	prev, seen := s.sides[key]
+	s.sides[key] = vx - vy
+	if !seen || (prev > 0) == (vx > vy) {
+		return Event{}, false
+	}
+	return Event{Symbol: symbol, Kind: Crossover, Price: s.closes[len(s.closes)-1], Change: vx - vy, Level: vy}, true
+}
+
+// Retain drops the closes, the averages and the crossover sides of the
+// symbols for which keep returns false.
+func (m *MovingAverages) Retain(keep func(symbol string) bool) {
+	for symbol := range m.symbols {
+		if !keep(symbol) {
+			delete(m.symbols, symbol)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package alert
+
+import "math"
+
+// BASIS_REARM is the fraction of its thresholds a basis has to narrow to
+// before reaching them again alerts again.
+const BASIS_REARM = 0.5
+
+// Bases raises an event when the basis of a futures contract over a
+// symbol's price blows out.
+type Bases struct {
+	// fired records the symbols alerted and not yet re-armed.
+	fired map[string]bool
+}
+
+func NewBases() *Bases {
+	return &Bases{fired: make(map[string]bool)}
+}
+
+// Update feeds the basis of symbol in percent of its price, plain and
+// annualized, and returns an event when either reaches its threshold,
+// either way, with the plain basis as Level; a threshold of 0 is off. It
+// alerts again only after both have narrowed to BASIS_REARM times their
+// thresholds.
+func (b *Bases) Update(symbol string, pct, annualized, percent, annualizedPercent float64) (Event, bool) {
+	over := func(v, limit, scale float64) bool { return limit > 0 && math.Abs(v) >= limit*scale }
+	switch {
+	case !over(pct, percent, BASIS_REARM) && !over(annualized, annualizedPercent, BASIS_REARM):
+		delete(b.fired, symbol)
+	case (over(pct, percent, 1) || over(annualized, annualizedPercent, 1)) && !b.fired[symbol]:
+		b.fired[symbol] = true
+		return Event{Symbol: symbol, Kind: Basis, Level: pct}, true
+	}
+	return Event{}, false
+}
+
+// Retain forgets the basis alerts waiting to re-arm of the symbols for
+// which keep returns false.
+func (b *Bases) Retain(keep func(symbol string) bool) {
+	for symbol := range b.fired {
+		if !keep(symbol) {
+			delete(b.fired, symbol)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package alert
+
+import (
+	"math"
+	"time"
+)
+
+// Bands are the Bollinger bands of a symbol: Middle, the mean of the last
+// closes, and Lower and Upper a number of standard deviations from it.
+type Bands struct {
+	Lower, Middle, Upper float64
+}
+
+// Bandwidth returns the width of b in percent of its middle.
+func (b Bands) Bandwidth() float64 {
+	if b.Middle == 0 {
+		return 0
+	}
+	return (b.Upper - b.Lower) / b.Middle * 100
+}
+
+// BollingerBands keeps the Bollinger bands of each symbol over candles
+// built from its prices and raises an event when a candle closes outside
+// them or they contract into a squeeze.
+type BollingerBands struct {
+	symbols map[string]*bollinger
+}
+
+type bollinger struct {
+	start   time.Time // of the open candle
+	close   float64   // of the open candle
+	closes  []float64 // of the closed candles, at most the period
+	bands   Bands
+	ok      bool // bands are set
+	outside bool // the last close was outside the bands
+	squeeze bool // the bandwidth is below the squeeze
+}
+
+func NewBollingerBands() *BollingerBands {
+	return &BollingerBands{symbols: make(map[string]*bollinger)}
+}
+
+// Update feeds a price of symbol at time at into its open candle of
+// interval and, when it opened a new one, moves the bands by the closed
+// candle, like Add.
+func (b *BollingerBands) Update(symbol string, price float64, at time.Time, interval time.Duration, period int, deviations, squeeze float64) []Event {
+	return b.Add(symbol, at.Truncate(interval), price, period, deviations, squeeze)
+}
+
+// Add feeds a candle of symbol opening at start, such as a historical
+// kline; one opening before the open candle is ignored, one opening with it
+// replaces its close. Once period candles have closed it returns a
+// Bollinger event, with the band as Level and the close minus it as Change,
+// when a candle closes outside the bands after one inside, and with squeeze
+// set a Squeeze event, with the bandwidth as Level, when the bandwidth
+// falls below squeeze after being at or above it. The bands a close is
+// compared with include it.
+func (b *BollingerBands) Add(symbol string, start time.Time, close float64, period int, deviations, squeeze float64) []Event {
+	s, ok := b.symbols[symbol]
+	switch {
+	case !ok:
+		b.symbols[symbol] = &bollinger{start: start, close: close}
+		return nil
+	case start.Before(s.start):
+		return nil
+	case start.Equal(s.start):
+		s.close = close
+		return nil
+	}
+	closed := s.close
+	s.start, s.close = start, close
+	s.closes = append(s.closes, closed)
+	if len(s.closes) > period {
+		s.closes = s.closes[len(s.closes)-period:]
+	}
+	if len(s.closes) < period {
+		return nil
+	}
+	mean, variance := 0.0, 0.0
+	for _, c := range s.closes {
+		mean += c / float64(period)
+	}
+	for _, c := range s.closes {
+		variance += (c - mean) * (c - mean) / float64(period)
+	}
+	dev := deviations * math.Sqrt(variance)
+	s.bands, s.ok = Bands{Lower: mean - dev, Middle: mean, Upper: mean + dev}, true
+	var events []Event
+	band := s.bands.Upper
+	if closed < s.bands.Middle {
+		band = s.bands.Lower
+	}
+	outside := closed > s.bands.Upper || closed < s.bands.Lower
+	if outside && !s.outside {
+		events = append(events, Event{Symbol: symbol, Kind: Bollinger, Price: closed, Change: closed - band, Level: band})
+	}
+	s.outside = outside
+	if squeeze > 0 {
+		width := s.bands.Bandwidth()
+		if width < squeeze && !s.squeeze {
+			events = append(events, Event{Symbol: symbol, Kind: Squeeze, Price: closed, Level: width})
+		}
+		s.squeeze = width < squeeze
+	}
+	return events
+}
+
+// Value returns the bands of symbol, false before period candles have
+// closed.
+func (b *BollingerBands) Value(symbol string) (Bands, bool) {
+	if s, ok := b.symbols[symbol]; ok && s.ok {
+		return s.bands, true
+	}
+	return Bands{}, false
+}
+
+// Retain drops the closes and bands, and whether the last close was
+// outside them or in a squeeze, of the symbols for which keep returns false.
+func (b *BollingerBands) Retain(keep func(symbol string) bool) {
+	for symbol := range b.symbols {
+		if !keep(symbol) {
+			delete(b.symbols, symbol)
+		}
+	}
+}
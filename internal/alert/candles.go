@@ -0,0 +1,161 @@
+package alert
+
+import (
+	"slices"
+	"time"
+)
+
+// CANDLE_HISTORY is how far back Candles keeps the 1m candles of a symbol,
+// enough for the longest window of a rule variable.
+const CANDLE_HISTORY = 24 * time.Hour
+
+// Candle is an OHLCV candle of Interval opening at Start.
+type Candle struct {
+	Start                          time.Time
+	Interval                       time.Duration
+	Open, High, Low, Close, Volume float64
+	Turnover                       float64 // price times size, summed
+}
+
+// add folds a price and size into c.
+func (c *Candle) add(price, size float64) {
+	c.High, c.Low, c.Close, c.Volume = max(c.High, price), min(c.Low, price), price, c.Volume+size
+	c.Turnover += price * size
+}
+
+// merge folds the later candle d into c.
+func (c *Candle) merge(d Candle) {
+	c.High, c.Low, c.Close, c.Volume = max(c.High, d.High), min(c.Low, d.Low), d.Close, c.Volume+d.Volume
+	c.Turnover += d.Turnover
+}
+
+// Candles builds the 1m candles of each symbol from its ticks, keeping
+// CANDLE_HISTORY of them, and resamples the closed ones into candles of
+// longer intervals, which open at multiples of their interval since the
+// zero time, so hours on the hour and days at midnight UTC.
+type Candles struct {
+	symbols map[string]*candleState
+}
+
+type candleState struct {
+	minutes []Candle // the closed ones, oldest first, then the open one
+	// open and last are the open and the last closed candle of each
+	// longer interval
+	open, last map[time.Duration]Candle
+}
+
+func NewCandles() *Candles {
+	return &Candles{symbols: make(map[string]*candleState)}
+}
+
+// Update feeds a tick of symbol and returns the candles it closed, the 1m
+// one first and then those of intervals, in their order. A closed minute
+// goes into the candle of each longer interval, which closes with the
+// first tick past its end; a minute without ticks leaves no candle, so a
+// quiet market has gaps rather than flat candles.
+func (c *Candles) Update(symbol string, price, size float64, at time.Time, intervals []time.Duration) []Candle {
+	s, ok := c.symbols[symbol]
+	if !ok {
+		s = &candleState{open: make(map[time.Duration]Candle), last: make(map[time.Duration]Candle)}
+		c.symbols[symbol] = s
+	}
+	for iv := range s.open {
+		if !slices.Contains(intervals, iv) {
+			delete(s.open, iv)
+			delete(s.last, iv)
+		}
+	}
+	var closed []Candle
+	start := at.Truncate(time.Minute)
+	n := len(s.minutes)
+	switch {
+	case n > 0 && !start.After(s.minutes[n-1].Start):
+		s.minutes[n-1].add(price, size)
+		return nil
+	case n > 0:
+		minute := s.minutes[n-1]
+		closed = append(closed, minute)
+		for _, iv := range intervals {
+			if iv <= time.Minute {
+				continue
+			}
+			if o, ok := s.open[iv]; ok && o.Start.Equal(minute.Start.Truncate(iv)) {
+				o.merge(minute)
+				s.open[iv] = o
+			} else {
+				minute.Start, minute.Interval = minute.Start.Truncate(iv), iv
+				s.open[iv] = minute
+			}
+		}
+		for len(s.minutes) > 1 && !s.minutes[1].Start.After(start.Add(-CANDLE_HISTORY-time.Minute)) {
+			s.minutes = s.minutes[1:]
+		}
+	}
+	for _, iv := range intervals {
+		if o, ok := s.open[iv]; ok && at.Truncate(iv).After(o.Start) {
+			closed = append(closed, o)
+			s.last[iv] = o
+			delete(s.open, iv)
+		}
+	}
+	s.minutes = append(s.minutes, Candle{Start: start, Interval: time.Minute, Open: price, High: price, Low: price, Close: price, Volume: size, Turnover: price * size})
+	if len(closed) > 0 {
+		s.last[time.Minute] = closed[0]
+	}
+	return closed
+}
+
+// Minutes returns the 1m candles of symbol, oldest first, the last one
+// still open. The slice is only valid until the next Update.
+func (c *Candles) Minutes(symbol string) []Candle {
+	if s, ok := c.symbols[symbol]; ok {
+		return s.minutes
+	}
+	return nil
+}
+
+// Last returns the last closed candle of symbol of interval, 1m or one of
+// those it was updated with.
+func (c *Candles) Last(symbol string, interval time.Duration) (Candle, bool) {
+	s, ok := c.symbols[symbol]
+	if !ok {
+		return Candle{}, false
+	}
+	last, ok := s.last[interval]
+	return last, ok
+}
+
+// Resample returns the candle of interval that closed by at from minutes,
+// 1m candles oldest first; false when minutes start after it opened or
+// hold none of it.
+func Resample(minutes []Candle, interval time.Duration, at time.Time) (Candle, bool) {
+	end := at.Truncate(interval)
+	start := end.Add(-interval)
+	if len(minutes) == 0 || minutes[0].Start.After(start) {
+		return Candle{}, false
+	}
+	var c Candle
+	for _, m := range minutes {
+		switch {
+		case m.Start.Before(start):
+		case !m.Start.Before(end):
+			return c, c.Interval > 0
+		case c.Interval == 0:
+			c = m
+			c.Start, c.Interval = start, interval
+		default:
+			c.merge(m)
+		}
+	}
+	return c, c.Interval > 0
+}
+
+// Retain drops the 1m candles, and the open and last candle of each longer
+// interval, of the symbols for which keep returns false.
+func (c *Candles) Retain(keep func(symbol string) bool) {
+	for symbol := range c.symbols {
+		if !keep(symbol) {
+			delete(c.symbols, symbol)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package alert
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCandlesUpdate(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(min, sec int) time.Time {
+		return t0.Add(time.Duration(min)*time.Minute + time.Duration(sec)*time.Second)
+	}
+	candle := func(min int, iv time.Duration, open, high, low, close, volume float64) Candle {
+		return Candle{Start: at(min, 0), Interval: iv, Open: open, High: high, Low: low, Close: close, Volume: volume}
+	}
+	tests := []struct {
+		at          time.Time
+		price, size float64
+		want        []Candle
+	}{
+		{at(0, 10), 100, 1, nil},
+		{at(0, 50), 102, 1, nil},
+		{at(1, 30), 101, 2, []Candle{candle(0, time.Minute, 100, 102, 100, 102, 2)}},
+		// 00:02 has no ticks, and leaves no candle
+		{at(3, 10), 105, 1, []Candle{candle(1, time.Minute, 101, 101, 101, 101, 2)}},
+		{at(5, 5), 99, 1, []Candle{
+			candle(3, time.Minute, 105, 105, 105, 105, 1),
+			candle(0, 5*time.Minute, 100, 105, 100, 105, 5),
+		}},
+		{at(5, 40), 98, 1, nil},
+		// Six quiet minutes close 00:05 and leave no 1m or 5m candle for
+		// 00:06 to 00:11
+		{at(12, 0), 110, 1, []Candle{
+			candle(5, time.Minute, 99, 99, 98, 98, 2),
+			candle(5, 5*time.Minute, 99, 99, 98, 98, 2),
+		}},
+		{at(13, 0), 111, 1, []Candle{candle(12, time.Minute, 110, 110, 110, 110, 1)}},
+	}
+	c := NewCandles()
+	intervals := []time.Duration{time.Minute, 5 * time.Minute}
+	for _, tt := range tests {
+		got := c.Update("ethusdt", tt.price, tt.size, tt.at, intervals)
+		if len(got) != len(tt.want) {
+			t.Errorf("Update(%v) = %v, want %v", tt.at.Format(time.TimeOnly), got, tt.want)
+			continue
+		}
+		for i := range got {
+			got[i].Turnover = 0
+			if got[i] != tt.want[i] {
+				t.Errorf("Update(%v) candle %d = %+v, want %+v", tt.at.Format(time.TimeOnly), i, got[i], tt.want[i])
+			}
+		}
+	}
+	if last, ok := c.Last("ethusdt", 5*time.Minute); !ok || !last.Start.Equal(at(5, 0)) {
+		t.Errorf("Last(5m) = %+v, %v, want the 00:05 candle", last, ok)
+	}
+	if got := len(c.Minutes("ethusdt")); got != 6 {
+		t.Errorf("len(Minutes) = %d, want 6: 00:00, 00:01, 00:03, 00:05, 00:12 and the open 00:13", got)
+	}
+}
@@ -0,0 +1,110 @@
+package alert
+
+import (
+	"math"
+	"time"
+)
+
+// CORRELATION_SAMPLES is how many returns a correlation window is cut
+// into; CORRELATION_REARM is how far above its threshold a correlation
+// has to recover before breaking down again alerts again.
+const (
+	CORRELATION_SAMPLES = 30
+	CORRELATION_REARM   = 0.1
+)
+
+// Correlations keeps the rolling correlation of each symbol's returns with
+// those of a reference symbol and raises an event when it breaks down.
+type Correlations struct {
+	symbols map[string]*correlation
+}
+
+type correlation struct {
+	next       time.Time // when the next sample is due
+	price, ref float64   // at the last sample
+	returns    [][2]float64
+	fired      bool
+	value      float64
+	ok         bool // value is set
+}
+
+func NewCorrelations() *Correlations {
+	return &Correlations{symbols: make(map[string]*correlation)}
+}
+
+// Update feeds the prices of symbol and its reference at time at. Every
+// window/CORRELATION_SAMPLES it takes the log returns of both since the
+// last sample, and once it has CORRELATION_SAMPLES of them it returns an
+// event when their Pearson correlation falls below below, with the
+// correlation as Level. It alerts again only after the correlation has
+// recovered to below+CORRELATION_REARM.
+func (c *Correlations) Update(symbol string, price, ref float64, at time.Time, window time.Duration, below float64) (Event, bool) {
+	s, ok := c.symbols[symbol]
+	if !ok {
+		c.symbols[symbol] = &correlation{next: at.Add(window / CORRELATION_SAMPLES), price: price, ref: ref}
+		return Event{}, false
+	}
+	if at.Before(s.next) {
+		return Event{}, false
+	}
+	s.returns = append(s.returns, [2]float64{math.Log(price / s.price), math.Log(ref / s.ref)})
+	if len(s.returns) > CORRELATION_SAMPLES {
+		s.returns = s.returns[1:]
+	}
+	s.next, s.price, s.ref = at.Add(window/CORRELATION_SAMPLES), price, ref
+	if len(s.returns) < CORRELATION_SAMPLES {
+		return Event{}, false
+	}
+	s.value, s.ok = pearson(s.returns)
+	switch {
+	case !s.ok:
+	case s.value >= below+CORRELATION_REARM:
+		s.fired = false
+	case s.value < below && !s.fired:
+		s.fired = true
+		return Event{Symbol: symbol, Kind: Correlation, Price: price, Level: s.value}, true
+	}
+	return Event{}, false
+}
+
+// Value returns the latest correlation of symbol, false before a full
+// window or when either side stood still throughout.
+func (c *Correlations) Value(symbol string) (float64, bool) {
+	if s, ok := c.symbols[symbol]; ok && s.ok {
+		return s.value, true
+	}
+	return 0, false
+}
+
+// pearson is the correlation of the pairs, false when either side has no
+// variance.
+func pearson(pairs [][2]float64) (float64, bool) {
+	n := float64(len(pairs))
+	var sx, sy float64
+	for _, p := range pairs {
+		sx += p[0]
+		sy += p[1]
+	}
+	mx, my := sx/n, sy/n
+	var cov, vx, vy float64
+	for _, p := range pairs {
+		dx, dy := p[0]-mx, p[1]-my
+		cov += dx * dy
+		vx += dx * dx
+		vy += dy * dy
+	}
+	if vx == 0 || vy == 0 {
+		return 0, false
+	}
+	return cov / math.Sqrt(vx*vy), true
+}
+
+// Retain drops the sampled returns and the correlation of the symbols for
+// which keep returns false.
+func (c *Correlations) Retain(keep func(symbol string) bool) {
+	for symbol := range c.symbols {
+		if !keep(symbol) {
+			delete(c.symbols, symbol)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package alert
+
+import "math"
+
+// Daily raises events from an exchange's rolling 24h statistics: when the
+// change from the 24h open reaches another multiple of a percentage step,
+// and when the price sets a new 24h high or low.
+type Daily struct {
+	last map[string]day
+}
+
+type day struct {
+	band      int // change from the open in whole steps, towards zero
+	high, low float64
+	// alertedHigh and alertedLow are the extremes last alerted on, 0 when
+	// none is or the window dropped it
+	alertedHigh, alertedLow float64
+}
+
+func NewDaily() *Daily {
+	return &Daily{last: make(map[string]day)}
+}
+
+// Update feeds the price and 24h open, high and low of symbol. changeStep
+// is in percent, 0 disables change events; highLow enables high and low
+// events, a new extreme alerting only once it is rearm beyond the one
+// last alerted on. The first update only records the statistics.
+func (d *Daily) Update(symbol string, price, open, high, low, changeStep float64, highLow bool, rearm float64) []Event {
+	prev, seen := d.last[symbol]
+	cur := day{high: high, low: low, alertedHigh: prev.alertedHigh, alertedLow: prev.alertedLow}
+	if high < cur.alertedHigh {
+		cur.alertedHigh = 0
+	}
+	if low > cur.alertedLow {
+		cur.alertedLow = 0
+	}
+	if changeStep > 0 && open > 0 {
+		cur.band = int(math.Trunc((price - open) / open * 100 / changeStep))
+	}
+	if !seen {
+		d.last[symbol] = cur
+		return nil
+	}
+	var events []Event
+	// Only moves further from the open alert, so a price hovering at a
+	// multiple does not repeat it
+	if cur.band != prev.band && cur.band != 0 && (abs(cur.band) > abs(prev.band) || cur.band*prev.band < 0) {
+		events = append(events, Event{Symbol: symbol, Kind: Change24h, Price: price, Change: price - open, Level: float64(cur.band) * changeStep})
+	}
+	// The window also drops old extremes; only a price at the extreme is
+	// a new one
+	if highLow && high > prev.high && price >= high && (cur.alertedHigh == 0 || high >= cur.alertedHigh+rearm) {
+		cur.alertedHigh = high
+		events = append(events, Event{Symbol: symbol, Kind: High24h, Price: price, Change: price - open, Level: high})
+	}
+	if highLow && low < prev.low && price <= low && (cur.alertedLow == 0 || low <= cur.alertedLow-rearm) {
+		cur.alertedLow = low
+		events = append(events, Event{Symbol: symbol, Kind: Low24h, Price: price, Change: price - open, Level: low})
+	}
+	d.last[symbol] = cur
+	return events
+}
+
+// Retain drops the 24h change band and the extremes alerted of the symbols
+// for which keep returns false.
+func (d *Daily) Retain(keep func(symbol string) bool) {
+	for symbol := range d.last {
+		if !keep(symbol) {
+			delete(d.last, symbol)
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
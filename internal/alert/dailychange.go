@@ -0,0 +1,75 @@
+package alert
+
+import "time"
+
+// DailyChanges keeps the open of the current day of each symbol and raises
+// an event when the price has moved a threshold percentage from it.
+type DailyChanges struct {
+	symbols map[string]*dailyChange
+}
+
+type dailyChange struct {
+	day      time.Time // start of the day
+	open     float64
+	up, down int // thresholds alerted today on each side
+}
+
+func NewDailyChanges() *DailyChanges {
+	return &DailyChanges{symbols: make(map[string]*dailyChange)}
+}
+
+// Update feeds a price of symbol on the day starting at day, the first one
+// of a day not opened by Open opening it. It returns an event with the open
+// as Level and the price minus it as Change, and the threshold passed, when
+// the price is the next of the ascending percents or more above or below
+// the open; passing several at once alerts the largest, and each alerts
+// once a day.
+func (d *DailyChanges) Update(symbol string, price float64, day time.Time, percents []float64) (Event, float64, bool) {
+	s, ok := d.symbols[symbol]
+	if !ok || !s.day.Equal(day) {
+		s = &dailyChange{day: day, open: price}
+		d.symbols[symbol] = s
+	}
+	pct := (price - s.open) / s.open * 100
+	n := 0
+	for n < len(percents) && (pct >= percents[n] || -pct >= percents[n]) {
+		n++
+	}
+	switch {
+	case pct > 0 && n > s.up:
+		s.up = n
+	case pct < 0 && n > s.down:
+		s.down = n
+	default:
+		return Event{}, 0, false
+	}
+	return Event{Symbol: symbol, Kind: DailyChange, Price: price, Change: price - s.open, Level: s.open}, percents[n-1], true
+}
+
+// Open sets the open of symbol on the day starting at day, such as from a
+// historical kline, unless that day has already opened.
+func (d *DailyChanges) Open(symbol string, day time.Time, open float64) {
+	if s, ok := d.symbols[symbol]; ok && s.day.Equal(day) {
+		return
+	}
+	d.symbols[symbol] = &dailyChange{day: day, open: open}
+}
+
+// Value returns the open of the current day of symbol and the day it
+// started, false before a price.
+func (d *DailyChanges) Value(symbol string) (open float64, day time.Time, ok bool) {
+	if s, ok := d.symbols[symbol]; ok {
+		return s.open, s.day, true
+	}
+	return 0, time.Time{}, false
+}
+
+// Retain drops the day's open and the thresholds alerted since of the
+// symbols for which keep returns false.
+func (d *DailyChanges) Retain(keep func(symbol string) bool) {
+	for symbol := range d.symbols {
+		if !keep(symbol) {
+			delete(d.symbols, symbol)
+		}
+	}
+}
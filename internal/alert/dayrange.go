@@ -0,0 +1,77 @@
+package alert
+
+import "time"
+
+// DAY_BUCKET is the resolution of the rolling 24h range DayRanges keeps.
+const DAY_BUCKET = 5 * time.Minute
+
+// DayRanges keeps the rolling 24h open, high and low of symbols whose stream
+// does not report them, in buckets of DAY_BUCKET.
+type DayRanges struct {
+	symbols map[string][]bucket
+}
+
+type bucket struct {
+	start                  time.Time
+	open, high, low, close float64
+}
+
+func NewDayRanges() *DayRanges {
+	return &DayRanges{symbols: make(map[string][]bucket)}
+}
+
+// Update feeds a price of symbol at time at and returns the open, high and
+// low of the 24h before it; full is false while the buckets seen reach
+// back less than 24h.
+func (d *DayRanges) Update(symbol string, price float64, at time.Time) (open, high, low float64, full bool) {
+	d.Add(symbol, at.Truncate(DAY_BUCKET), price, price, price, price)
+	return d.Range(symbol, at)
+}
+
+// Add feeds a bucket of symbol opening at start, such as a historical
+// kline of DAY_BUCKET; one opening before the last is ignored, one opening
+// with it widens it.
+func (d *DayRanges) Add(symbol string, start time.Time, open, high, low, close float64) {
+	buckets := d.symbols[symbol]
+	n := len(buckets)
+	switch {
+	case n > 0 && start.Before(buckets[n-1].start):
+		return
+	case n > 0 && start.Equal(buckets[n-1].start):
+		b := &buckets[n-1]
+		b.high, b.low, b.close = max(b.high, high), min(b.low, low), close
+		return
+	}
+	buckets = append(buckets, bucket{start: start, open: open, high: high, low: low, close: close})
+	// The oldest bucket kept is the one the 24h start in
+	for len(buckets) > 1 && !buckets[1].start.After(start.Add(-24*time.Hour)) {
+		buckets = buckets[1:]
+	}
+	d.symbols[symbol] = buckets
+}
+
+// Range returns the open, high and low of symbol over the 24h before at,
+// like Update.
+func (d *DayRanges) Range(symbol string, at time.Time) (open, high, low float64, full bool) {
+	buckets := d.symbols[symbol]
+	from := at.Add(-24 * time.Hour)
+	for _, b := range buckets {
+		switch {
+		case !b.start.Add(DAY_BUCKET).After(from):
+		case open == 0:
+			open, high, low = b.open, b.high, b.low
+		default:
+			high, low = max(high, b.high), min(low, b.low)
+		}
+	}
+	return open, high, low, open != 0 && !buckets[0].start.After(from)
+}
+
+// Retain drops the 24h buckets of the symbols for which keep returns false.
+func (d *DayRanges) Retain(keep func(symbol string) bool) {
+	for symbol := range d.symbols {
+		if !keep(symbol) {
+			delete(d.symbols, symbol)
+		}
+	}
+}
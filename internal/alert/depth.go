@@ -0,0 +1,80 @@
+package alert
+
+import "time"
+
+// Depths raises an event when one side of a symbol's order book stays
+// heavier than the other by a ratio.
+type Depths struct {
+	symbols map[string]*depth
+}
+
+type depth struct {
+	bids, asks float64   // of the last snapshot
+	bidHeavy   bool      // the side since is for
+	since      time.Time // a side reached the ratio, zero while below
+	fired      bool      // alerted and not yet re-armed
+}
+
+func NewDepths() *Depths {
+	return &Depths{symbols: make(map[string]*depth)}
+}
+
+// Update feeds the bid and ask notional of a book snapshot of symbol at
+// time at, priced at price. It returns an event with the heavier side over
+// the lighter as Level and bids minus asks as Change, and how long the
+// side has outweighed the other, once it has been ratio times or more for
+// hold; it alerts again only after the ratio has come back under halfway
+// to 1 or the other side took over.
+func (d *Depths) Update(symbol string, bids, asks, price float64, at time.Time, ratio float64, hold time.Duration) (Event, time.Duration, bool) {
+	s, ok := d.symbols[symbol]
+	if !ok {
+		s = &depth{}
+		d.symbols[symbol] = s
+	}
+	s.bids, s.asks = bids, asks
+	if bids <= 0 || asks <= 0 {
+		return Event{}, 0, false
+	}
+	bidHeavy, r := bids >= asks, bids/asks
+	if !bidHeavy {
+		r = asks / bids
+	}
+	if r <= (1+ratio)/2 || bidHeavy != s.bidHeavy {
+		s.fired = false
+	}
+	if r < ratio || bidHeavy != s.bidHeavy {
+		s.since = time.Time{}
+	}
+	s.bidHeavy = bidHeavy
+	if r < ratio {
+		return Event{}, 0, false
+	}
+	if s.since.IsZero() {
+		s.since = at
+	}
+	lasted := at.Sub(s.since)
+	if s.fired || lasted < hold {
+		return Event{}, 0, false
+	}
+	s.fired = true
+	return Event{Symbol: symbol, Kind: Depth, Price: price, Change: bids - asks, Level: r}, lasted, true
+}
+
+// Value returns the bid and ask notional of the last snapshot of symbol,
+// false before one.
+func (d *Depths) Value(symbol string) (bids, asks float64, ok bool) {
+	if s, ok := d.symbols[symbol]; ok {
+		return s.bids, s.asks, true
+	}
+	return 0, 0, false
+}
+
+// Retain drops the last book snapshot, and since when one side outweighs
+// the other, of the symbols for which keep returns false.
+func (d *Depths) Retain(keep func(symbol string) bool) {
+	for symbol := range d.symbols {
+		if !keep(symbol) {
+			delete(d.symbols, symbol)
+		}
+	}
+}
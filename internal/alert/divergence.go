@@ -0,0 +1,44 @@
+package alert
+
+import "math"
+
+// DIVERGENCE_REARM is the fraction of its percent a divergence has to
+// narrow to before reaching the percent again alerts again.
+const DIVERGENCE_REARM = 0.5
+
+// Divergences raises an event when a symbol's price drifts apart from a
+// reference price.
+type Divergences struct {
+	// fired records the symbols alerted and not yet re-armed.
+	fired map[string]bool
+}
+
+func NewDivergences() *Divergences {
+	return &Divergences{fired: make(map[string]bool)}
+}
+
+// Update feeds the price of symbol and the reference price ref, and
+// returns an event when the gap between them reaches percent of ref,
+// either way, with the signed gap in percent as Level. It alerts again
+// only after the gap has narrowed to DIVERGENCE_REARM times percent.
+func (d *Divergences) Update(symbol string, price, ref, percent float64) (Event, bool) {
+	gap := (price/ref - 1) * 100
+	switch {
+	case math.Abs(gap) <= percent*DIVERGENCE_REARM:
+		delete(d.fired, symbol)
+	case math.Abs(gap) >= percent && !d.fired[symbol]:
+		d.fired[symbol] = true
+		return Event{Symbol: symbol, Kind: Divergence, Price: price, Level: gap}, true
+	}
+	return Event{}, false
+}
+
+// Retain forgets the divergence alerts waiting to re-arm of the symbols for
+// which keep returns false.
+func (d *Divergences) Retain(keep func(symbol string) bool) {
+	for symbol := range d.fired {
+		if !keep(symbol) {
+			delete(d.fired, symbol)
+		}
+	}
+}
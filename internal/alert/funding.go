@@ -0,0 +1,47 @@
+package alert
+
+// Funding raises an event whenever a perpetual's predicted funding rate
+// crosses one of a symbol's levels; a sign flip is a crossing of 0.
+type Funding struct {
+	// above records per symbol and level which side of it the rate was
+	// last seen on.
+	above map[string]map[float64]bool
+}
+
+func NewFunding() *Funding {
+	return &Funding{above: make(map[string]map[float64]bool)}
+}
+
+// Update feeds the funding rate of symbol, in percent, and returns an
+// event per level crossed since the rate was last off it, with the level
+// as Level. A rate exactly on a level (funding often rests at its 0.01%
+// baseline) crosses nothing, and a level's first rate is only recorded.
+func (f *Funding) Update(symbol string, rate float64, levels []float64) []Event {
+	sides, ok := f.above[symbol]
+	if !ok {
+		sides = make(map[float64]bool)
+		f.above[symbol] = sides
+	}
+	var events []Event
+	for _, level := range levels {
+		if rate == level {
+			continue
+		}
+		above := rate > level
+		if was, seen := sides[level]; seen && was != above {
+			events = append(events, Event{Symbol: symbol, Kind: FundingRate, Level: level})
+		}
+		sides[level] = above
+	}
+	return events
+}
+
+// Retain forgets, for the symbols for which keep returns false, which side
+// of each level the funding rate was last on.
+func (f *Funding) Retain(keep func(symbol string) bool) {
+	for symbol := range f.above {
+		if !keep(symbol) {
+			delete(f.above, symbol)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package alert
+
+// GAS_REARM is the factor above a level the base fee has to rise to before
+// falling below that level alerts again, so a fee hovering on it stays
+// quiet.
+const GAS_REARM = 1.2
+
+// Gas raises an event when a gas price falls below one of a symbol's levels.
+type Gas struct {
+	// fired records per symbol the levels alerted and not yet re-armed.
+	fired map[string]map[float64]bool
+}
+
+func NewGas() *Gas {
+	return &Gas{fired: make(map[string]map[float64]bool)}
+}
+
+// Update feeds the gas price of symbol, in gwei, and returns an event per
+// level it is below that was not alerted since the price last rose to
+// GAS_REARM times that level, with the level as Level. Levels start armed,
+// so a price already below one at startup alerts.
+func (g *Gas) Update(symbol string, price float64, levels []float64) []Event {
+	fired, ok := g.fired[symbol]
+	if !ok {
+		fired = make(map[float64]bool)
+		g.fired[symbol] = fired
+	}
+	var events []Event
+	for _, level := range levels {
+		switch {
+		case price >= level*GAS_REARM:
+			delete(fired, level)
+		case price < level && !fired[level]:
+			fired[level] = true
+			events = append(events, Event{Symbol: symbol, Kind: GasBelow, Price: price, Level: level})
+		}
+	}
+	return events
+}
+
+// Retain forgets the gas levels alerted and waiting to re-arm of the
+// symbols for which keep returns false.
+func (g *Gas) Retain(keep func(symbol string) bool) {
+	for symbol := range g.fired {
+		if !keep(symbol) {
+			delete(g.fired, symbol)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package alert
+
+import (
+	"math"
+	"time"
+)
+
+// Levels finds the support and resistance of each symbol, the swing highs
+// and lows of its recent candles, and raises an event when the price
+// approaches or breaks one.
+type Levels struct {
+	symbols map[string]*levels
+}
+
+type levels struct {
+	open        candle   // the candle the prices go into
+	candles     []candle // closed, oldest first
+	highs, lows []swing  // unbroken swing levels
+	// near holds the levels approached, until the price is twice the
+	// approach away again
+	near  map[float64]bool
+	price float64 // the last one
+}
+
+// candle is the range of a candle and when it opened.
+type candle struct {
+	start     time.Time
+	high, low float64
+}
+
+// swing is a swing high or low and when its candle opened.
+type swing struct {
+	price float64
+	at    time.Time
+}
+
+func NewLevels() *Levels {
+	return &Levels{symbols: make(map[string]*levels)}
+}
+
+// Add feeds a candle of symbol opening at start, such as a historical
+// kline; one opening before the open candle is ignored, one opening with it
+// is merged into it. Closing a candle finds the swings of 2*n+1 candles
+// among the last lookback.
+func (l *Levels) Add(symbol string, start time.Time, high, low float64, lookback, n int) {
+	s, ok := l.symbols[symbol]
+	switch {
+	case !ok:
+		l.symbols[symbol] = &levels{open: candle{start: start, high: high, low: low}, near: make(map[float64]bool)}
+		return
+	case start.Before(s.open.start):
+		return
+	case start.Equal(s.open.start):
+		s.open.high, s.open.low = max(s.open.high, high), min(s.open.low, low)
+		return
+	}
+	s.candles = append(s.candles, s.open)
+	if len(s.candles) > lookback {
+		s.candles = s.candles[len(s.candles)-lookback:]
+	}
+	s.open = candle{start: start, high: high, low: low}
+	s.highs, s.lows = s.swings(n)
+}
+
+// swings returns the swing highs and lows of the closed candles that no
+// later one went past; the open candle's prices break them in Update.
+func (s *levels) swings(n int) (highs, lows []swing) {
+	c := s.candles
+	for i := n; i < len(c)-n; i++ {
+		isHigh, isLow := true, true
+		for j := i - n; j < len(c); j++ {
+			switch {
+			case j == i:
+			case j < i:
+				isHigh = isHigh && c[j].high < c[i].high
+				isLow = isLow && c[j].low > c[i].low
+			default:
+				isHigh = isHigh && c[j].high <= c[i].high
+				isLow = isLow && c[j].low >= c[i].low
+			}
+		}
+		if isHigh {
+			highs = append(highs, swing{c[i].high, c[i].start})
+		}
+		if isLow {
+			lows = append(lows, swing{c[i].low, c[i].start})
+		}
+	}
+	return highs, lows
+}
+
+// Update feeds a price of symbol at time at into its open candle of
+// interval. It returns an event, with the level as Level and Side support
+// or resistance, when the price went past a level, a Breakout that drops
+// the level, or came within approach percent of the nearest one on a side,
+// an Approach, which that level raises again only once the price has been
+// twice as far; and when the level's candle opened.
+func (l *Levels) Update(symbol string, price float64, at time.Time, interval time.Duration, lookback, n int, approach float64) (Event, time.Time, bool) {
+	l.Add(symbol, at.Truncate(interval), price, price, lookback, n)
+	s := l.symbols[symbol]
+	s.price = price
+	for i, sw := range s.highs {
+		if price > sw.price {
+			s.highs = append(s.highs[:i:i], s.highs[i+1:]...)
+			delete(s.near, sw.price)
+			return Event{Symbol: symbol, Kind: Breakout, Price: price, Change: price - sw.price, Level: sw.price, Side: "resistance"}, sw.at, true
+		}
+	}
+	for i, sw := range s.lows {
+		if price < sw.price {
+			s.lows = append(s.lows[:i:i], s.lows[i+1:]...)
+			delete(s.near, sw.price)
+			return Event{Symbol: symbol, Kind: Breakout, Price: price, Change: price - sw.price, Level: sw.price, Side: "support"}, sw.at, true
+		}
+	}
+	distance := func(level float64) float64 { return math.Abs(price-level) / level * 100 }
+	for level := range s.near {
+		if distance(level) > 2*approach {
+			delete(s.near, level)
+		}
+	}
+	for _, side := range []string{"resistance", "support"} {
+		swings := s.highs
+		if side == "support" {
+			swings = s.lows
+		}
+		sw, ok := nearest(swings, price)
+		if ok && distance(sw.price) <= approach && !s.near[sw.price] {
+			s.near[sw.price] = true
+			return Event{Symbol: symbol, Kind: Approach, Price: price, Change: price - sw.price, Level: sw.price, Side: side}, sw.at, true
+		}
+	}
+	return Event{}, time.Time{}, false
+}
+
+// nearest returns the level closest to price, the latest of equal ones.
+func nearest(swings []swing, price float64) (best swing, ok bool) {
+	for _, sw := range swings {
+		if !ok || math.Abs(sw.price-price) <= math.Abs(best.price-price) {
+			best, ok = sw, true
+		}
+	}
+	return best, ok
+}
+
+// Value returns the nearest support below and resistance above the last
+// price of symbol, 0 for a side without one.
+func (l *Levels) Value(symbol string) (support, resistance float64, ok bool) {
+	s, ok := l.symbols[symbol]
+	if !ok {
+		return 0, 0, false
+	}
+	if sw, ok := nearest(s.lows, s.price); ok {
+		support = sw.price
+	}
+	if sw, ok := nearest(s.highs, s.price); ok {
+		resistance = sw.price
+	}
+	return support, resistance, true
+}
+
+// Retain drops the candles, the swing levels and the levels approached of
+// the symbols for which keep returns false.
+func (l *Levels) Retain(keep func(symbol string) bool) {
+	for symbol := range l.symbols {
+		if !keep(symbol) {
+			delete(l.symbols, symbol)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package alert
+
+import "time"
+
+// Liquidations sums the notional of each symbol's liquidations over a
+// rolling window and raises an event when the sum reaches a threshold. It
+// then stays quiet until the sum has fallen below the threshold again, so
+// one cascade alerts once.
+type Liquidations struct {
+	symbols map[string]*cascade
+}
+
+type cascade struct {
+	orders  []liquidation // oldest first
+	alerted bool
+}
+
+type liquidation struct {
+	at       time.Time
+	notional float64
+	long     bool
+}
+
+func NewLiquidations() *Liquidations {
+	return &Liquidations{symbols: make(map[string]*cascade)}
+}
+
+// Add records a liquidation of qty at price for symbol; long marks a long
+// position closed. ok is true when the notional within window up to at
+// just reached threshold. The event's Level is that notional and longs the
+// part of it from long positions.
+func (l *Liquidations) Add(symbol string, price, qty float64, long bool, at time.Time, window time.Duration, threshold float64) (ev Event, longs float64, ok bool) {
+	c, found := l.symbols[symbol]
+	if !found {
+		c = &cascade{}
+		l.symbols[symbol] = c
+	}
+	c.orders = append(c.orders, liquidation{at: at, notional: price * qty, long: long})
+	keep := 0
+	for keep < len(c.orders) && at.Sub(c.orders[keep].at) >= window {
+		keep++
+	}
+	c.orders = c.orders[keep:]
+	var total float64
+	for _, o := range c.orders {
+		total += o.notional
+		if o.long {
+			longs += o.notional
+		}
+	}
+	if total < threshold {
+		c.alerted = false
+		return Event{}, 0, false
+	}
+	if c.alerted {
+		return Event{}, 0, false
+	}
+	c.alerted = true
+	return Event{Symbol: symbol, Kind: Liquidation, Price: price, Level: total}, longs, true
+}
+
+// Retain drops the liquidations in the window, and whether they alerted,
+// of the symbols for which keep returns false.
+func (l *Liquidations) Retain(keep func(symbol string) bool) {
+	for symbol := range l.symbols {
+		if !keep(symbol) {
+			delete(l.symbols, symbol)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package alert
+
+import "time"
+
+// MACDs keeps the MACD of each symbol over candles built from its prices
+// and raises an event when it crosses its signal line or zero.
+type MACDs struct {
+	symbols map[string]*macd
+}
+
+type macd struct {
+	start      time.Time // of the open candle
+	close      float64   // of the open candle
+	count      int       // candles closed
+	fast, slow float64   // EMAs of the closes, each seeded from its SMA
+	sum        float64   // closes, then MACDs, summed while seeding
+	value      float64   // fast minus slow once count reached the slow period
+	signal     float64   // EMA of the MACDs
+	signals    int       // MACDs seen
+	ok         bool      // value and signal are set
+}
+
+func NewMACDs() *MACDs {
+	return &MACDs{symbols: make(map[string]*macd)}
+}
+
+// Update feeds a price of symbol at time at into its open candle of
+// interval and, when it opened a new one, moves the MACD by the closed
+// candle, like Add.
+func (m *MACDs) Update(symbol string, price float64, at time.Time, interval time.Duration, fast, slow, signal int) []Event {
+	return m.Add(symbol, at.Truncate(interval), price, fast, slow, signal)
+}
+
+// Add feeds a candle of symbol opening at start, such as a historical
+// kline; one opening before the open candle is ignored, one opening with it
+// replaces its close. Once the signal line is set it returns a MACDSignal
+// event when a closed candle took the MACD across it and a MACDZero one
+// when it took it across zero, each with the MACD as Level and the MACD
+// minus the signal line as Change.
+func (m *MACDs) Add(symbol string, start time.Time, close float64, fast, slow, signal int) []Event {
+	s, ok := m.symbols[symbol]
+	switch {
+	case !ok:
+		m.symbols[symbol] = &macd{start: start, close: close}
+		return nil
+	case start.Before(s.start):
+		return nil
+	case start.Equal(s.start):
+		s.close = close
+		return nil
+	}
+	closed := s.close
+	s.start, s.close = start, close
+	s.count++
+	ema := func(prev float64, period int) float64 {
+		k := 2 / float64(period+1)
+		return closed*k + prev*(1-k)
+	}
+	switch {
+	case s.count <= slow:
+		// Both EMAs start from the SMA of their period, the fast one over
+		// the last fast closes of the first slow ones
+		s.sum += closed
+		if s.count > slow-fast {
+			s.fast += closed / float64(fast)
+		}
+		if s.count < slow {
+			return nil
+		}
+		s.slow, s.sum = s.sum/float64(slow), 0
+	default:
+		s.fast, s.slow = ema(s.fast, fast), ema(s.slow, slow)
+	}
+	prev, hist := s.value, s.value-s.signal
+	s.value = s.fast - s.slow
+	s.signals++
+	switch {
+	case s.signals < signal:
+		s.sum += s.value
+		return nil
+	case s.signals == signal:
+		s.signal, s.sum, s.ok = (s.sum+s.value)/float64(signal), 0, true
+		return nil
+	}
+	k := 2 / float64(signal+1)
+	s.signal = s.value*k + s.signal*(1-k)
+	var events []Event
+	if h := s.value - s.signal; h != 0 && hist != 0 && (h > 0) != (hist > 0) {
+		events = append(events, Event{Symbol: symbol, Kind: MACDSignal, Price: closed, Change: h, Level: s.value})
+	}
+	if s.value != 0 && prev != 0 && (s.value > 0) != (prev > 0) {
+		events = append(events, Event{Symbol: symbol, Kind: MACDZero, Price: closed, Change: s.value - s.signal, Level: s.value})
+	}
+	return events
+}
+
+// Value returns the MACD and signal line of symbol, false before they are
+// set.
+func (m *MACDs) Value(symbol string) (value, signal float64, ok bool) {
+	if s, ok := m.symbols[symbol]; ok && s.ok {
+		return s.value, s.signal, true
+	}
+	return 0, 0, false
+}
+
+// Retain drops the EMAs, the MACD and its signal line of the symbols for
+// which keep returns false.
+func (m *MACDs) Retain(keep func(symbol string) bool) {
+	for symbol := range m.symbols {
+		if !keep(symbol) {
+			delete(m.symbols, symbol)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package alert
+
+import (
+	"math"
+	"time"
+)
+
+// OpenInterestMoves keeps each symbol's open interest samples over a
+// rolling window and raises an event when, against an earlier sample, the
+// open interest moved by a percentage while the price moved a step.
+type OpenInterestMoves struct {
+	symbols map[string][]oiSample // oldest first
+}
+
+type oiSample struct {
+	at        time.Time
+	oi, price float64
+}
+
+func NewOpenInterestMoves() *OpenInterestMoves {
+	return &OpenInterestMoves{symbols: make(map[string][]oiSample)}
+}
+
+// Add records open interest oi and price at time at for symbol. ok is true
+// when, against the oldest sample within window that qualifies, oi moved by
+// at least change percent and price by at least step. The event's Level is
+// the open interest change in percent and Change the price move; since
+// tells how long ago the sample was. The samples before this one are then
+// dropped, so a move alerts once.
+func (o *OpenInterestMoves) Add(symbol string, oi, price float64, at time.Time, window time.Duration, change, step float64) (ev Event, since time.Duration, ok bool) {
+	samples := o.symbols[symbol]
+	keep := 0
+	for keep < len(samples) && at.Sub(samples[keep].at) > window {
+		keep++
+	}
+	samples = samples[keep:]
+	cur := oiSample{at: at, oi: oi, price: price}
+	for _, s := range samples {
+		pct := (oi/s.oi - 1) * 100
+		move := price - s.price
+		if math.Abs(pct) >= change && math.Abs(move) >= step {
+			o.symbols[symbol] = []oiSample{cur}
+			return Event{Symbol: symbol, Kind: OpenInterest, Price: price, Change: move, Level: pct}, at.Sub(s.at), true
+		}
+	}
+	o.symbols[symbol] = append(samples, cur)
+	return Event{}, 0, false
+}
+
+// Retain drops the open interest samples of the symbols for which keep
+// returns false.
+func (o *OpenInterestMoves) Retain(keep func(symbol string) bool) {
+	for symbol := range o.symbols {
+		if !keep(symbol) {
+			delete(o.symbols, symbol)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package alert
+
+import (
+	"math"
+	"time"
+)
+
+// Pegs raises events while a stablecoin's price strays from its peg.
+type Pegs struct {
+	symbols map[string]*peg
+}
+
+type peg struct {
+	off   bool      // strayed and not back yet
+	depth int       // bands out at the last alert
+	last  time.Time // of the last alert
+}
+
+func NewPegs() *Pegs {
+	return &Pegs{symbols: make(map[string]*peg)}
+}
+
+// Update feeds a price of symbol at time at. It returns an event with the
+// deviation from target in percent as Level and the price minus target as
+// Change when the price strays band percent or more from target, each
+// further band from it and every repeat while it stays out; back is set on
+// the event returned once the price has come back within half of band.
+func (p *Pegs) Update(symbol string, price float64, at time.Time, target, band float64, repeat time.Duration) (ev Event, back, ok bool) {
+	s, found := p.symbols[symbol]
+	if !found {
+		s = &peg{}
+		p.symbols[symbol] = s
+	}
+	dev := (price/target - 1) * 100
+	ev = Event{Symbol: symbol, Kind: Depeg, Price: price, Change: price - target, Level: dev}
+	depth := int(math.Abs(dev) / band)
+	switch {
+	case !s.off && depth >= 1:
+		s.off = true
+	case s.off && math.Abs(dev) <= band/2:
+		s.off = false
+		return ev, true, true
+	case s.off && (depth > s.depth || at.Sub(s.last) >= repeat):
+	default:
+		return Event{}, false, false
+	}
+	s.depth, s.last = max(depth, 1), at
+	return ev, false, true
+}
+
+// Retain drops whether the price is off its peg, how many bands, and when
+// it last alerted, of the symbols for which keep returns false.
+func (p *Pegs) Retain(keep func(symbol string) bool) {
+	for symbol := range p.symbols {
+		if !keep(symbol) {
+			delete(p.symbols, symbol)
+		}
+	}
+}
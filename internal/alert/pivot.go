@@ -0,0 +1,127 @@
+package alert
+
+import (
+	"math"
+	"time"
+)
+
+// Pivots keeps the classic pivot points of each symbol, from the high, low
+// and close of the previous day or week, and raises an event when the
+// price touches or crosses one.
+type Pivots struct {
+	symbols map[string]map[time.Duration]*pivots // by symbol and period
+}
+
+type pivots struct {
+	start            time.Time // of the current period
+	high, low, close float64   // of the current period so far
+	// levels are those of the previous period by name, nil before one
+	// closed; disarmed holds the ones crossed until the price has been
+	// the rearm distance away
+	levels   map[string]float64
+	disarmed map[string]bool
+	price    float64 // the last one, 0 before one
+}
+
+func NewPivots() *Pivots {
+	return &Pivots{symbols: make(map[string]map[time.Duration]*pivots)}
+}
+
+// PivotLevels returns the classic pivot point P of a period's high, low
+// and close and its resistances R1 to R3 and supports S1 to S3.
+func PivotLevels(high, low, close float64) map[string]float64 {
+	p := (high + low + close) / 3
+	return map[string]float64{
+		"P":  p,
+		"R1": 2*p - low,
+		"S1": 2*p - high,
+		"R2": p + (high - low),
+		"S2": p - (high - low),
+		"R3": high + 2*(p-low),
+		"S3": low - 2*(high-p),
+	}
+}
+
+func (p *Pivots) state(symbol string, period time.Duration) *pivots {
+	periods, ok := p.symbols[symbol]
+	if !ok {
+		periods = make(map[time.Duration]*pivots)
+		p.symbols[symbol] = periods
+	}
+	s, ok := periods[period]
+	if !ok {
+		s = &pivots{disarmed: make(map[string]bool)}
+		periods[period] = s
+	}
+	return s
+}
+
+// Seed sets the pivots of symbol for period from the previous period's
+// high, low and close, such as a closed kline, and the current period
+// starting at start from its prices so far, such as the open kline.
+func (p *Pivots) Seed(symbol string, period time.Duration, prevHigh, prevLow, prevClose float64, start time.Time, high, low, close float64) {
+	s := p.state(symbol, period)
+	s.levels = PivotLevels(prevHigh, prevLow, prevClose)
+	s.start, s.high, s.low, s.close = start, high, low, close
+}
+
+// Update feeds a price of symbol at time at into its current period, the
+// periods starting at multiples of period since the zero time, so days at
+// midnight UTC and weeks on Mondays. It returns an event, with the level
+// as Level and its name as Pivot, for each level of names the price
+// touched or crossed since the last one; a level crossed alerts again only once the
+// price has been rearm percent away from it. A new period sets the levels
+// from the one that ended.
+func (p *Pivots) Update(symbol string, price float64, at time.Time, period time.Duration, rearm float64, names []string) []Event {
+	s := p.state(symbol, period)
+	switch start := at.Truncate(period); {
+	case s.high == 0:
+		s.start, s.high, s.low = start, price, price
+	case start.After(s.start):
+		s.levels = PivotLevels(s.high, s.low, s.close)
+		clear(s.disarmed)
+		s.start, s.high, s.low = start, price, price
+	}
+	s.high, s.low, s.close = max(s.high, price), min(s.low, price), price
+	prev := s.price
+	s.price = price
+	var events []Event
+	for _, name := range names {
+		level, ok := s.levels[name]
+		if !ok {
+			continue
+		}
+		if s.disarmed[name] {
+			if math.Abs(price-level)/level*100 >= rearm {
+				delete(s.disarmed, name)
+			}
+			continue
+		}
+		// A touch lands on the level, a cross goes past it
+		if prev == 0 || prev == level || (prev-level)*(price-level) > 0 {
+			continue
+		}
+		s.disarmed[name] = true
+		events = append(events, Event{Symbol: symbol, Kind: Pivot, Price: price, Change: price - level, Level: level, Pivot: name})
+	}
+	return events
+}
+
+// Value returns the pivot levels of symbol for period by name, false
+// before a period closed.
+func (p *Pivots) Value(symbol string, period time.Duration) (map[string]float64, bool) {
+	if s, ok := p.symbols[symbol][period]; ok && s.levels != nil {
+		return s.levels, true
+	}
+	return nil, false
+}
+
+// Retain drops the period ranges, pivot levels and disarmed levels of the
+// symbols for which keep returns false.
+func (p *Pivots) Retain(keep func(symbol string) bool) {
+	for symbol := range p.symbols {
+		if !keep(symbol) {
+			delete(p.symbols, symbol)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package alert
+
+import "math"
+
+// PREMIUM_REARM is the fraction of its threshold a premium has to narrow to
+// before reaching the threshold again alerts again.
+const PREMIUM_REARM = 0.5
+
+// Premiums raises an event when a perpetual's mark price trades far above
+// or below its index.
+type Premiums struct {
+	// fired records the symbols alerted and not yet re-armed.
+	fired map[string]bool
+}
+
+func NewPremiums() *Premiums {
+	return &Premiums{fired: make(map[string]bool)}
+}
+
+// Update feeds the premium of symbol's mark over its index, in percent of
+// the index, and returns an event when it reaches percent either way, with
+// the premium as Level. It alerts again only after the premium has
+// narrowed to PREMIUM_REARM times percent.
+func (p *Premiums) Update(symbol string, pct, percent float64) (Event, bool) {
+	switch {
+	case math.Abs(pct) < percent*PREMIUM_REARM:
+		delete(p.fired, symbol)
+	case math.Abs(pct) >= percent && !p.fired[symbol]:
+		p.fired[symbol] = true
+		return Event{Symbol: symbol, Kind: Premium, Level: pct}, true
+	}
+	return Event{}, false
+}
+
+// Retain forgets the premium alerts waiting to re-arm of the symbols for
+// which keep returns false.
+func (p *Premiums) Retain(keep func(symbol string) bool) {
+	for symbol := range p.fired {
+		if !keep(symbol) {
+			delete(p.fired, symbol)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package alert
+
+import "time"
+
+// Repeats counts how often the same alert of each symbol fired lately,
+// the same being of one kind, rule and session at any level.
+type Repeats struct {
+	symbols map[string]map[string][]time.Time // symbol, key, firing times
+}
+
+func NewRepeats() *Repeats {
+	return &Repeats{symbols: make(map[string]map[string][]time.Time)}
+}
+
+// Update counts ev and returns how many times it fired within window up to
+// it, itself included, and when the first of them was.
+func (r *Repeats) Update(ev Event, window time.Duration) (n int, since time.Time) {
+	keys, ok := r.symbols[ev.Symbol]
+	if !ok {
+		keys = make(map[string][]time.Time)
+		r.symbols[ev.Symbol] = keys
+	}
+	key := ev.Kind.String() + " " + ev.Rule + " " + ev.Session
+	times := append(keys[key], ev.Time)
+	for ev.Time.Sub(times[0]) > window {
+		times = times[1:]
+	}
+	keys[key] = times
+	return len(times), times[0]
+}
+
+// Acknowledge starts the counts over of the alerts of symbol, or of every
+// symbol for "", that fired at least count times, and returns how many.
+func (r *Repeats) Acknowledge(symbol string, count int) int {
+	acked := 0
+	for sym, keys := range r.symbols {
+		if symbol != "" && sym != symbol {
+			continue
+		}
+		for key, times := range keys {
+			if len(times) >= count {
+				delete(keys, key)
+				acked++
+			}
+		}
+	}
+	return acked
+}
+
+// Retain drops the firing times counted for the alerts of the symbols for
+// which keep returns false.
+func (r *Repeats) Retain(keep func(symbol string) bool) {
+	for symbol := range r.symbols {
+		if !keep(symbol) {
+			delete(r.symbols, symbol)
+		}
+	}
+}
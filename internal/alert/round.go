@@ -0,0 +1,67 @@
+package alert
+
+import "math"
+
+// ROUND_REARM is the fraction of the increment the price has to move away
+// from a round level before crossing it again alerts again.
+const ROUND_REARM = 0.25
+
+// RoundNumbers raises an event when the price of a symbol crosses a
+// multiple of its increment, such as every 100.
+type RoundNumbers struct {
+	symbols map[string]*roundNumber
+}
+
+type roundNumber struct {
+	prev  float64
+	level float64 // the last level alerted
+	armed bool    // the price has left level since
+	fired bool    // level is set
+}
+
+func NewRoundNumbers() *RoundNumbers {
+	return &RoundNumbers{symbols: make(map[string]*roundNumber)}
+}
+
+// Update feeds a price for symbol and returns an event when it crossed a
+// multiple of increment since the previous price, with the level as Level;
+// a move across several reports only the furthest. A level just alerted
+// alerts again only after the price has been ROUND_REARM increments away
+// from it.
+func (r *RoundNumbers) Update(symbol string, price, increment float64) (Event, bool) {
+	s, ok := r.symbols[symbol]
+	if !ok {
+		r.symbols[symbol] = &roundNumber{prev: price}
+		return Event{}, false
+	}
+	prev := s.prev
+	s.prev = price
+	if s.fired && math.Abs(price-s.level) >= increment*ROUND_REARM {
+		s.armed = true
+	}
+	var level float64
+	crossed := false
+	switch {
+	case price > prev:
+		level = math.Floor(price/increment) * increment
+		crossed = level > prev
+	case price < prev:
+		level = math.Ceil(price/increment) * increment
+		crossed = level < prev
+	}
+	if !crossed || s.fired && level == s.level && !s.armed {
+		return Event{}, false
+	}
+	s.level, s.fired, s.armed = level, true, false
+	return Event{Symbol: symbol, Kind: RoundNumber, Price: price, Change: price - prev, Level: level}, true
+}
+
+// Retain drops the round number last alerted, and whether it re-armed, of
+// the symbols for which keep returns false.
+func (r *RoundNumbers) Retain(keep func(symbol string) bool) {
+	for symbol := range r.symbols {
+		if !keep(symbol) {
+			delete(r.symbols, symbol)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package alert
+
+import "time"
+
+// RSIs keeps the relative strength index of each symbol over candles built
+// from its prices and raises an event when it turns overbought or
+// oversold.
+type RSIs struct {
+	symbols map[string]*rsi
+}
+
+type rsi struct {
+	start      time.Time // of the open candle
+	close      float64   // of the open candle
+	prev       float64   // close of the last closed candle, 0 before one
+	changes    int       // closes compared so far
+	gain, loss float64   // Wilder's averages once changes reached the period
+	value      float64
+	ok         bool // value is set
+	high, low  bool // an overbought or oversold event waits for its re-arm
+}
+
+func NewRSIs() *RSIs {
+	return &RSIs{symbols: make(map[string]*rsi)}
+}
+
+// Update feeds a price of symbol at time at into its open candle of
+// interval and, when it opened a new one, moves the RSI over period by the
+// closed candle, like Add.
+func (r *RSIs) Update(symbol string, price float64, at time.Time, interval time.Duration, period int, overbought, oversold, rearm float64) (Event, bool) {
+	return r.Add(symbol, at.Truncate(interval), price, period, overbought, oversold, rearm)
+}
+
+// Add feeds a candle of symbol opening at start, such as a historical
+// kline; one opening before the open candle is ignored, one opening with it
+// replaces its close. Once the RSI has period changes it returns an event
+// with the RSI as Level when a closed candle took it to overbought or
+// above, or to oversold or below; it alerts that way again only after the
+// RSI has been rearm back inside.
+func (r *RSIs) Add(symbol string, start time.Time, close float64, period int, overbought, oversold, rearm float64) (Event, bool) {
+	s, ok := r.symbols[symbol]
+	switch {
+	case !ok:
+		r.symbols[symbol] = &rsi{start: start, close: close}
+		return Event{}, false
+	case start.Before(s.start):
+		return Event{}, false
+	case start.Equal(s.start):
+		s.close = close
+		return Event{}, false
+	}
+	closed := s.close
+	s.start, s.close = start, close
+	if s.prev == 0 {
+		s.prev = closed
+		return Event{}, false
+	}
+	change := closed - s.prev
+	s.prev = closed
+	gain, loss := max(change, 0), max(-change, 0)
+	s.changes++
+	if s.changes <= period {
+		// Simple averages until the first period of changes
+		s.gain += gain / float64(period)
+		s.loss += loss / float64(period)
+		if s.changes < period {
+			return Event{}, false
+		}
+	} else {
+		s.gain = (s.gain*float64(period-1) + gain) / float64(period)
+		s.loss = (s.loss*float64(period-1) + loss) / float64(period)
+	}
+	switch s.ok = true; {
+	case s.loss > 0:
+		s.value = 100 - 100/(1+s.gain/s.loss)
+	case s.gain > 0:
+		s.value = 100
+	default:
+		s.value = 50 // not a move in a period
+	}
+	if s.high && s.value <= overbought-rearm {
+		s.high = false
+	}
+	if s.low && s.value >= oversold+rearm {
+		s.low = false
+	}
+	switch {
+	case s.value >= overbought && !s.high:
+		s.high = true
+	case s.value <= oversold && !s.low:
+		s.low = true
+	default:
+		return Event{}, false
+	}
+	return Event{Symbol: symbol, Kind: RSI, Price: closed, Change: change, Level: s.value}, true
+}
+
+// Value returns the RSI of symbol, false before period candles have closed.
+func (r *RSIs) Value(symbol string) (float64, bool) {
+	if s, ok := r.symbols[symbol]; ok && s.ok {
+		return s.value, true
+	}
+	return 0, false
+}
+
+// Retain drops the average gain and loss, the RSI and the events waiting
+// to re-arm of the symbols for which keep returns false.
+func (r *RSIs) Retain(keep func(symbol string) bool) {
+	for symbol := range r.symbols {
+		if !keep(symbol) {
+			delete(r.symbols, symbol)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package alert
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/expr"
+)
+
+// Condition is an expression rule of a symbol.
+type Condition struct {
+	Name string
+	When *expr.Expr
+	// Candle evaluates When as each 1m candle closes, with its close as
+	// the price, rather than on every tick.
+	Candle   bool
+	Cooldown time.Duration
+	// Rearm, when set, is the hysteresis of the condition: after an event
+	// it is met again only once When was false with the price at least
+	// Rearm away from the event's.
+	Rearm float64
+	// For and Candles make When hold for at least that long, and at that
+	// many evaluations in a row, before the condition is met.
+	For     time.Duration
+	Candles int
+	// Confirm, a multiple of 1m, holds the event of a met condition back
+	// until the candle of that length closes, and drops it unless When
+	// still holds at the close.
+	Confirm time.Duration
+}
+
+// Rules evaluates the conditions of each symbol with rules over its 1m
+// candles and raises an event when the expression of one turns true.
+type Rules struct {
+	candles *Candles
+	symbols map[string]*ruleState
+}
+
+type ruleState struct {
+	// candles are the 1m candles the variables are taken from, the last
+	// one open on ticks
+	candles    []Candle
+	minute     time.Time // the start of the open candle, zero before one
+	bid, ask   float64   // the latest quotes, 0 before one
+	conditions map[string]*conditionState
+}
+
+type conditionState struct {
+	since  time.Time // When has held since, zero while it does not
+	streak int       // evaluations in a row When held
+	met    bool
+	fired  time.Time // of the last event, zero before one
+	price  float64   // of the last event
+	// disarmed is set by an event with Rearm and cleared once When was
+	// false Rearm away from its price.
+	disarmed bool
+	// pending is the close of the candle a met condition waits for with
+	// Confirm, zero while none does.
+	pending time.Time
+}
+
+// NewRules returns Rules taking the 1m candles of each symbol from
+// candles.
+func NewRules(candles *Candles) *Rules {
+	return &Rules{candles: candles, symbols: make(map[string]*ruleState)}
+}
+
+// Update feeds a tick of symbol, with its quotes when it has them, once
+// the candles have, and returns an event with the condition's name as
+// Rule for every condition that became met, or was confirmed, unless it
+// alerted within its cooldown. A variable over more history than seen has
+// no value: the comparison it is part of is false, unless an && or ||
+// decided without it.
+func (r *Rules) Update(symbol string, price, bid, ask float64, at time.Time, conditions []Condition) []Event {
+	s, ok := r.symbols[symbol]
+	if !ok {
+		s = &ruleState{conditions: make(map[string]*conditionState)}
+		r.symbols[symbol] = s
+	}
+	minutes := r.candles.Minutes(symbol)
+	if len(minutes) == 0 {
+		return nil
+	}
+	var events []Event
+	n := len(minutes)
+	if open := minutes[n-1].Start; open.After(s.minute) {
+		if !s.minute.IsZero() && n > 1 {
+			s.candles = minutes[:n-1]
+			last := s.candles[n-2]
+			events = s.confirm(events, symbol, conditions, last.Close, last.Start.Add(time.Minute))
+			events = s.evaluate(events, symbol, conditions, true, last.Close, last.Start.Add(time.Minute))
+		}
+		s.minute = open
+	}
+	s.candles = minutes
+	if bid > 0 && ask > 0 {
+		s.bid, s.ask = bid, ask
+	}
+	return s.evaluate(events, symbol, conditions, false, price, at)
+}
+
+// evaluate appends the events of the conditions evaluated as candles
+// close (closed) or on ticks to events.
+func (s *ruleState) evaluate(events []Event, symbol string, conditions []Condition, closed bool, price float64, at time.Time) []Event {
+	for _, c := range conditions {
+		if c.Candle != closed {
+			continue
+		}
+		cs, ok := s.conditions[c.Name]
+		if !ok {
+			cs = &conditionState{}
+			s.conditions[c.Name] = cs
+		}
+		v, err := c.When.Eval(func(name string) (float64, error) { return s.lookup(name, price, at) })
+		if err != nil || v == 0 {
+			cs.since, cs.streak, cs.met = time.Time{}, 0, false
+			if cs.disarmed && math.Abs(price-cs.price) >= c.Rearm {
+				cs.disarmed = false
+			}
+			continue
+		}
+		if cs.since.IsZero() {
+			cs.since = at
+		}
+		cs.streak++
+		if cs.met || at.Sub(cs.since) < c.For || cs.streak < c.Candles {
+			continue
+		}
+		cs.met = true
+		if cs.disarmed || !cs.fired.IsZero() && at.Sub(cs.fired) < c.Cooldown {
+			continue
+		}
+		// A candle condition evaluated at the close it waits for needs no
+		// confirming
+		if c.Confirm > 0 && !(closed && at.Truncate(c.Confirm).Equal(at)) {
+			cs.pending = at.Truncate(c.Confirm).Add(c.Confirm)
+			continue
+		}
+		cs.fire(c, price, at)
+		events = append(events, Event{Symbol: symbol, Kind: Rule, Price: price, Rule: c.Name})
+	}
+	return events
+}
+
+// fire records an event of c at price and time at.
+func (cs *conditionState) fire(c Condition, price float64, at time.Time) {
+	cs.fired, cs.price, cs.disarmed = at, price, c.Rearm > 0
+}
+
+// confirm appends the events of the conditions waiting for a candle that
+// closed by at, with price as the close, whose When still holds.
+func (s *ruleState) confirm(events []Event, symbol string, conditions []Condition, price float64, at time.Time) []Event {
+	for _, c := range conditions {
+		cs, ok := s.conditions[c.Name]
+		if !ok || cs.pending.IsZero() || cs.pending.After(at) {
+			continue
+		}
+		cs.pending = time.Time{}
+		if v, err := c.When.Eval(func(name string) (float64, error) { return s.lookup(name, price, at) }); err == nil && v != 0 {
+			cs.fire(c, price, at)
+			events = append(events, Event{Symbol: symbol, Kind: Rule, Price: price, Rule: c.Name})
+		}
+	}
+	return events
+}
+
+// lookup returns the value of the variable name at price and time at.
+func (s *ruleState) lookup(name string, price float64, at time.Time) (float64, error) {
+	base, window, _ := expr.Variable(name)
+	switch base {
+	case "price":
+		return price, nil
+	case "hour":
+		return float64(at.Local().Hour()), nil
+	case "minute":
+		return float64(at.Local().Minute()), nil
+	case "weekday":
+		return float64(at.Local().Weekday()), nil
+	case "bid", "ask", "spread":
+		switch {
+		case s.ask == 0:
+			return 0, expr.ErrNoData
+		case base == "bid":
+			return s.bid, nil
+		case base == "ask":
+			return s.ask, nil
+		}
+		return s.ask - s.bid, nil
+	}
+	if c, ok := strings.CutPrefix(base, "candle_"); ok {
+		// The last closed candle of the window's length
+		candle, ok := Resample(s.candles, window, at)
+		if !ok {
+			return 0, expr.ErrNoData
+		}
+		return map[string]float64{"open": candle.Open, "high": candle.High, "low": candle.Low, "close": candle.Close, "volume": candle.Volume}[c], nil
+	}
+	// The window needs the candle that closed by its start, whose close
+	// a change is measured from
+	from := at.Add(-window)
+	closed := from.Add(-time.Minute)
+	first := 0
+	for first+1 < len(s.candles) && !s.candles[first+1].Start.After(closed) {
+		first++
+	}
+	if len(s.candles) == 0 || s.candles[first].Start.After(closed) {
+		return 0, expr.ErrNoData
+	}
+	high, low, volume, turnover := price, price, 0.0, 0.0
+	for _, c := range s.candles[first:] {
+		if c.Start.Add(time.Minute).After(from) {
+			high, low, volume, turnover = max(high, c.High), min(low, c.Low), volume+c.Volume, turnover+c.Turnover
+		}
+	}
+	switch base {
+	case "change":
+		ref := s.candles[first].Close
+		if ref == 0 {
+			return 0, expr.ErrNoData
+		}
+		return (price - ref) / ref * 100, nil
+	case "high":
+		return high, nil
+	case "low":
+		return low, nil
+	case "volume":
+		return volume, nil
+	case "vwap":
+		if volume == 0 {
+			return 0, expr.ErrNoData
+		}
+		return turnover / volume, nil
+	}
+	// avg_volume: the mean volume per window over all history
+	total := 0.0
+	for _, c := range s.candles {
+		total += c.Volume
+	}
+	// Shorter history would scale a partial minute up to a whole window
+	span := at.Sub(s.candles[0].Start)
+	if span < window {
+		return 0, expr.ErrNoData
+	}
+	return total * float64(window) / float64(span), nil
+}
+
+// Retain drops the candles, quotes and rule conditions of the symbols for
+// which keep returns false.
+func (r *Rules) Retain(keep func(symbol string) bool) {
+	for symbol := range r.symbols {
+		if !keep(symbol) {
+			delete(r.symbols, symbol)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package alert
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/expr"
+)
+
+func TestRulesConfirm(t *testing.T) {
+	type tick struct {
+		sec   int // since 00:00
+		price float64
+		fires bool
+	}
+	tests := []struct {
+		name    string
+		confirm time.Duration
+		ticks   []tick
+	}{
+		{"unconfirmed", 0, []tick{
+			{10, 3501, true},
+			{40, 3502, false},
+		}},
+		{"held at the close", time.Minute, []tick{
+			{10, 3501, false},
+			{40, 3502, false},
+			{65, 3503, true}, // closes 00:00 at 3502
+		}},
+		{"dropped at the close", time.Minute, []tick{
+			{10, 3501, false},
+			{40, 3490, false},
+			{65, 3510, false}, // closes 00:00 at 3490, met again for 00:01
+			{121, 3511, true}, // closes 00:01 at 3510
+		}},
+		{"dropped at the 5m close", 5 * time.Minute, []tick{
+			{60, 3501, false},
+			{200, 3505, false},
+			{270, 3490, false},
+			{310, 3480, false}, // closes 00:04 at 3490
+			{330, 3480, false},
+		}},
+	}
+	when, err := expr.Parse("price > 3500")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		candles := NewCandles()
+		r := NewRules(candles)
+		conditions := []Condition{{Name: "above", When: when, Confirm: tt.confirm}}
+		var got, want []bool
+		for _, tk := range tt.ticks {
+			at := t0.Add(time.Duration(tk.sec) * time.Second)
+			candles.Update("ethusdt", tk.price, 1, at, nil)
+			events := r.Update("ethusdt", tk.price, 0, 0, at, conditions)
+			got, want = append(got, len(events) > 0), append(want, tk.fires)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: events per tick = %v, want %v", tt.name, got, want)
+		}
+	}
+}
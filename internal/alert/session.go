@@ -0,0 +1,102 @@
+package alert
+
+import "time"
+
+// SessionLevels are the high and low of a session of a symbol so far, and
+// of the last one that ended, 0 before a price.
+type SessionLevels struct {
+	High, Low         float64
+	LastHigh, LastLow float64
+}
+
+// Sessions keeps the high and low of the trading sessions of each symbol
+// and raises an event when the price breaks those of the last session that
+// ended.
+type Sessions struct {
+	symbols map[string]map[string]*session
+}
+
+type session struct {
+	start, end time.Time // of the session tracked
+	levels     SessionLevels
+	// partial marks a session whose start was not seen, whose extremes
+	// are not its own
+	partial, ended      bool
+	brokeHigh, brokeLow bool // of the last session
+}
+
+func NewSessions() *Sessions {
+	return &Sessions{symbols: make(map[string]map[string]*session)}
+}
+
+// Update feeds a price of symbol at time at in the session name, the latest
+// one starting by at, from start to end. Once a session has ended, a price
+// above its high or below its low returns an event with the session as
+// Session and the level as Level, once per level; a session whose start
+// was missed sets no levels.
+func (s *Sessions) Update(symbol, name string, price float64, at, start, end time.Time) []Event {
+	sessions, ok := s.symbols[symbol]
+	if !ok {
+		sessions = make(map[string]*session)
+		s.symbols[symbol] = sessions
+	}
+	ss, ok := sessions[name]
+	if !ok {
+		ss = &session{start: start, end: end, partial: at.Sub(start) > time.Minute}
+		sessions[name] = ss
+	}
+	if !start.Equal(ss.start) {
+		ss.close()
+		ss.start, ss.end, ss.partial, ss.ended = start, end, false, false
+		ss.levels.High, ss.levels.Low = 0, 0
+	}
+	if !at.Before(ss.end) {
+		ss.close()
+	} else if ss.levels.High == 0 {
+		ss.levels.High, ss.levels.Low = price, price
+	} else {
+		ss.levels.High, ss.levels.Low = max(ss.levels.High, price), min(ss.levels.Low, price)
+	}
+	var events []Event
+	if l := ss.levels.LastHigh; l > 0 && price > l && !ss.brokeHigh {
+		ss.brokeHigh = true
+		events = append(events, Event{Symbol: symbol, Kind: SessionBreak, Price: price, Change: price - l, Level: l, Session: name})
+	}
+	if l := ss.levels.LastLow; l > 0 && price < l && !ss.brokeLow {
+		ss.brokeLow = true
+		events = append(events, Event{Symbol: symbol, Kind: SessionBreak, Price: price, Change: price - l, Level: l, Session: name})
+	}
+	return events
+}
+
+// close makes the levels of the session tracked the last session's, once.
+func (ss *session) close() {
+	if ss.ended {
+		return
+	}
+	ss.ended = true
+	if ss.partial || ss.levels.High == 0 {
+		return
+	}
+	ss.levels.LastHigh, ss.levels.LastLow = ss.levels.High, ss.levels.Low
+	ss.brokeHigh, ss.brokeLow = false, false
+}
+
+// Levels returns the levels of the session name of symbol, false before a
+// price.
+func (s *Sessions) Levels(symbol, name string) (SessionLevels, bool) {
+	if ss, ok := s.symbols[symbol][name]; ok {
+		return ss.levels, true
+	}
+	return SessionLevels{}, false
+}
+
+// Retain drops the sessions tracked, with their highs and lows, of the
+// symbols for which keep returns false.
+func (s *Sessions) Retain(keep func(symbol string) bool) {
+	for symbol := range s.symbols {
+		if !keep(symbol) {
+			delete(s.symbols, symbol)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package alert
+
+// Severity is how loud an event is, which decides the notifiers it reaches.
+// The zero value stands for the default of the event's kind.
+type Severity int
+
+const (
+	Info     Severity = iota + 1 // the log only, unless a notifier asks for it
+	Warn                         // the default of alerts
+	Critical                     // the rarest alerts, let through quiet hours
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warn:
+		return "warn"
+	case Critical:
+		return "critical"
+	}
+	return "unknown"
+}
+
+// ParseSeverity returns the severity named s, Warn for an empty one.
+func ParseSeverity(s string) (Severity, bool) {
+	for _, sev := range []Severity{Info, Warn, Critical} {
+		if s == sev.String() {
+			return sev, true
+		}
+	}
+	return Warn, s == ""
+}
+
+// Severity returns the default severity of events of k: Critical for new
+// all-time highs and depegs, Info for the starting price, otherwise Warn.
+func (k Kind) Severity() Severity {
+	switch k {
+	case ATH, Depeg:
+		return Critical
+	case Start:
+		return Info
+	}
+	return Warn
+}
@@ -0,0 +1,79 @@
+package alert
+
+import (
+	"math"
+	"time"
+)
+
+// Spreads keeps the normal bid-ask spread of each symbol and raises an
+// event when the spread stays wider than a multiple of it.
+type Spreads struct {
+	symbols map[string]*spread
+}
+
+type spread struct {
+	start  time.Time // of the first quote
+	last   time.Time // of the last quote
+	normal float64   // time-weighted average spread in percent
+	since  time.Time // the spread turned wide, zero while normal
+	fired  bool      // alerted and not yet re-armed
+}
+
+func NewSpreads() *Spreads {
+	return &Spreads{symbols: make(map[string]*spread)}
+}
+
+// Update feeds a quote of symbol at time at. It returns an event with the
+// spread in percent of the midpoint as Level and ask minus bid as Change,
+// and how long the spread has lasted, once it has been multiple times its
+// normal or wider for hold; it alerts again only after the spread has come
+// back under halfway to normal. The normal spread is the average over
+// window, weighted by time, of the spreads not wide, and alerts wait until
+// it covers a window.
+func (s *Spreads) Update(symbol string, bid, ask float64, at time.Time, multiple float64, hold, window time.Duration) (Event, time.Duration, bool) {
+	st, ok := s.symbols[symbol]
+	mid := (bid + ask) / 2
+	pct := (ask - bid) / mid * 100
+	if !ok {
+		s.symbols[symbol] = &spread{start: at, last: at, normal: pct}
+		return Event{}, 0, false
+	}
+	dt := at.Sub(st.last)
+	st.last = at
+	if pct <= st.normal*(1+multiple)/2 {
+		st.fired = false
+	}
+	if pct < st.normal*multiple || st.normal <= 0 {
+		st.normal += (pct - st.normal) * (1 - math.Exp(-dt.Seconds()/window.Seconds()))
+		st.since = time.Time{}
+		return Event{}, 0, false
+	}
+	if st.since.IsZero() {
+		st.since = at
+	}
+	lasted := at.Sub(st.since)
+	if st.fired || lasted < hold || at.Sub(st.start) < window {
+		return Event{}, 0, false
+	}
+	st.fired = true
+	return Event{Symbol: symbol, Kind: Spread, Price: mid, Change: ask - bid, Level: pct}, lasted, true
+}
+
+// Value returns the normal spread of symbol in percent, false before a
+// quote.
+func (s *Spreads) Value(symbol string) (float64, bool) {
+	if st, ok := s.symbols[symbol]; ok {
+		return st.normal, true
+	}
+	return 0, false
+}
+
+// Retain drops the normal spread, and since when the spread is wide, of
+// the symbols for which keep returns false.
+func (s *Spreads) Retain(keep func(symbol string) bool) {
+	for symbol := range s.symbols {
+		if !keep(symbol) {
+			delete(s.symbols, symbol)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+// Package alert decides when a price move is worth announcing.
+package alert
+
+import (
+	"math"
+	"time"
+)
+
+// Kind classifies an alert event.
+type Kind int
+
+const (
+	Start        Kind = iota // first price seen, checkpoint established
+	Up                       // price rose a full step above the checkpoint
+	Down                     // price fell a full step below the checkpoint
+	Target                   // price crossed a registered target level
+	Change24h                // 24h change reached another multiple of its step
+	High24h                  // price set a new 24h high
+	Low24h                   // price set a new 24h low
+	Fill                     // an order of the account was filled
+	Liquidation              // liquidations within a window reached a threshold
+	FundingRate              // the predicted funding rate crossed a level
+	OpenInterest             // open interest and price both moved within a window
+	GasBelow                 // the gas price fell below a level
+	Divergence               // the price drifted apart from a reference price
+	Basis                    // a futures contract's basis over the price blew out
+	Correlation              // the correlation with a reference symbol broke down
+	Premium                  // a perpetual's mark traded far from its index
+	RoundNumber              // price crossed a multiple of a round increment
+	TrailingStop             // price retraced a distance from its high or low
+	Rule                     // the expression of a rule turned true
+	Crossover                // the price or a moving average crossed another
+	RSI                      // the RSI turned overbought or oversold
+	MACDSignal               // the MACD crossed its signal line
+	MACDZero                 // the MACD crossed zero
+	Bollinger                // a candle closed outside the Bollinger bands
+	Squeeze                  // the Bollinger bandwidth contracted
+	VWAP                     // the price strayed from its session VWAP
+	Velocity                 // the price moved fast
+	SessionBreak             // the price broke the high or low of a session
+	ATH                      // the price set a new all-time high
+	DailyChange              // the price moved a percentage from the day's open
+	Whale                    // a single large trade
+	Volume                   // the volume of a candle ran far above its average
+	VolumeDelta              // takers bought or sold far more than the other side
+	Arbitrage                // the markets of an aggregated symbol drifted apart
+	Depeg                    // a stablecoin strayed from its peg, or returned
+	Spread                   // the bid-ask spread stayed wide
+	Depth                    // one side of the book outweighed the other
+	Stale                    // a connected feed went silent, or spoke again
+	Approach                 // the price came near a support or resistance
+	Breakout                 // the price broke a support or resistance
+	Pivot                    // the price crossed a daily or weekly pivot point
+	VolSpike                 // the realized volatility rose above its threshold
+	VolCollapse              // the realized volatility fell below its threshold
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Start:
+		return "start"
+	case Up:
+		return "up"
+	case Down:
+		return "down"
+	case Target:
+		return "target"
+	case Change24h:
+		return "change_24h"
+	case High24h:
+		return "high_24h"
+	case Low24h:
+		return "low_24h"
+	case Fill:
+		return "fill"
+	case Liquidation:
+		return "liquidations"
+	case FundingRate:
+		return "funding"
+	case OpenInterest:
+		return "open_interest"
+	case GasBelow:
+		return "gas_below"
+	case Divergence:
+		return "divergence"
+	case Basis:
+		return "basis"
+	case Correlation:
+		return "correlation"
+	case Premium:
+		return "premium"
+	case RoundNumber:
+		return "round_number"
+	case TrailingStop:
+		return "trailing"
+	case Rule:
+		return "rule"
+	case Crossover:
+		return "crossover"
+	case RSI:
+		return "rsi"
+	case MACDSignal:
+		return "macd_signal"
+	case MACDZero:
+		return "macd_zero"
+	case Bollinger:
+		return "bollinger"
+	case Squeeze:
+		return "squeeze"
+	case VWAP:
+		return "vwap"
+	case Velocity:
+		return "velocity"
+	case SessionBreak:
+		return "session"
+	case ATH:
+		return "ath"
+	case DailyChange:
+		return "daily_change"
+	case Whale:
+		return "whale"
+	case Volume:
+		return "volume"
+	case VolumeDelta:
+		return "volume_delta"
+	case Arbitrage:
+		return "arbitrage"
+	case Depeg:
+		return "depeg"
+	case Spread:
+		return "spread"
+	case Depth:
+		return "depth"
+	case Stale:
+		return "stale"
+	case Approach:
+		return "approach"
+	case Breakout:
+		return "breakout"
+	case Pivot:
+		return "pivot"
+	case VolSpike:
+		return "volatility_spike"
+	case VolCollapse:
+		return "volatility_collapse"
+	}
+	return "unknown"
+}
+
+// Event is an alert raised for a symbol.
+type Event struct {
+	Symbol string
+	Kind   Kind
+	Price  float64
+	// Change is the move from the previous checkpoint, or the price move
+	// within the window of an OpenInterest event.
+	Change float64
+	// Level is the crossed level of a Target event, the change in percent
+	// of a Change24h event, the new extreme of a High24h or Low24h one or
+	// the window's notional of a Liquidation one, the crossed level of a
+	// FundingRate one, the open interest change in percent of an
+	// OpenInterest one, the level a GasBelow one fell below, the gap to
+	// the reference in percent of a Divergence one, the basis in percent of
+	// a Basis one, the correlation of a Correlation one, the premium in
+	// percent of a Premium one, the crossed level of a RoundNumber one,
+	// the high or low retraced from of a TrailingStop one, the crossed
+	// average of a Crossover one, the RSI of an RSI one, the MACD of a
+	// MACDSignal or MACDZero one, the band of a Bollinger one, the
+	// bandwidth of a Squeeze one, the VWAP of a VWAP one, the price the
+	// move of a Velocity one started from, the broken high or low of a
+	// SessionBreak one, the previous all-time high of an ATH one, the
+	// day's open of a DailyChange one, the notional of a Whale one, the
+	// average volume of a Volume one, the imbalance of a VolumeDelta one,
+	// the spread in percent of an Arbitrage or Spread one, the deviation
+	// from the peg in percent of a Depeg one, the ratio of the heavier side
+	// of the book to the lighter of a Depth one, the seconds without a
+	// price of a Stale one, the support or resistance of an Approach or
+	// Breakout one, the crossed level of a Pivot one or the volatility in
+	// percent of a VolSpike or VolCollapse one.
+	Level float64
+	Time  time.Time
+	// Text is the human-readable announcement, e.g. "ETHUSDT up to 3100".
+	Text string
+	// Source is the exchange the price came from, set for symbols with
+	// failover markets.
+	Source string
+	// Size is the quantity of the tick that raised the event, 0 if the
+	// source does not report one.
+	Size float64
+	// Spread is the ask minus the bid when the price is a book ticker
+	// midpoint.
+	Spread float64
+	// Rule is the name of the rule that raised a Rule event.
+	Rule string
+	// Session is the name of the session whose level a SessionBreak event
+	// broke.
+	Session string
+	// Streak is the number of steps in a row of an Up or Down event that
+	// made a streak, 0 otherwise.
+	Streak int
+	// Side is the taker side of the trade behind a Whale event, buy or
+	// sell, or the support or resistance of an Approach or Breakout one.
+	Side string
+	// Severity overrides the default of Kind, if set.
+	Severity Severity
+	// Pivot names the level of a Pivot event, such as R1.
+	Pivot string
+	// Window names the window of a VolSpike or VolCollapse event, such
+	// as 1h.
+	Window string
+	// Repeats is how often an escalated alert fired within the
+	// escalation window, 0 for one that was not.
+	Repeats int
+}
+
+// Stepper tracks one checkpoint per symbol and raises an event whenever the
+// price moves a full step away from it.
+type Stepper struct {
+	checkpoints map[string]float64
+}
+
+func NewStepper() *Stepper {
+	return &Stepper{checkpoints: make(map[string]float64)}
+}
+
+// Grid is the checkpoint grid of a symbol, the multiples of Size from
+// Origin, which the first checkpoint snaps to. With Snap the checkpoint
+// also moves to the grid level a step reached, the last one at or short of
+// the price, rather than the price itself.
+type Grid struct {
+	Size, Origin float64
+	Snap         bool
+}
+
+// Update feeds a price for symbol. The first price snaps the checkpoint to
+// grid and yields a Start event; afterwards a move of at least step yields
+// Up or Down and moves the checkpoint on, to the price or with grid.Snap to
+// the grid, or to the price after all when the last level short of it is
+// not past the checkpoint. ok is false when the price stayed within the
+// step; change is always the move from the checkpoint.
+func (s *Stepper) Update(symbol string, price, step float64, grid Grid) (ev Event, change float64, ok bool) {
+	checkpoint, seen := s.checkpoints[symbol]
+	if !seen {
+		s.checkpoints[symbol] = grid.Origin + math.Round((price-grid.Origin)/grid.Size)*grid.Size
+		return Event{Symbol: symbol, Kind: Start, Price: price}, 0, true
+	}
+
+	change = price - checkpoint
+	// Levels are counted in sizes from the origin, with a hair of slack
+	// for float noise on a price right on one
+	level := func(round func(float64) float64) float64 {
+		return grid.Origin + round((price-grid.Origin)/grid.Size)*grid.Size
+	}
+	next := price
+	switch {
+	case change >= step:
+		ev = Event{Symbol: symbol, Kind: Up, Price: price, Change: change}
+		if l := level(func(n float64) float64 { return math.Floor(n + 1e-9) }); grid.Snap && l > checkpoint {
+			next = l
+		}
+	case change <= -step:
+		ev = Event{Symbol: symbol, Kind: Down, Price: price, Change: change}
+		if l := level(func(n float64) float64 { return math.Ceil(n - 1e-9) }); grid.Snap && l < checkpoint {
+			next = l
+		}
+	default:
+		return Event{}, change, false
+	}
+	s.checkpoints[symbol] = next
+	return ev, change, true
+}
+
+// Checkpoint returns the current checkpoint of symbol.
+func (s *Stepper) Checkpoint(symbol string) (float64, bool) {
+	c, ok := s.checkpoints[symbol]
+	return c, ok
+}
+
+// SetCheckpoint moves the checkpoint of symbol to price.
+func (s *Stepper) SetCheckpoint(symbol string, price float64) {
+	s.checkpoints[symbol] = price
+}
+
+// Retain drops the checkpoints of the symbols for which keep returns
+// false, so each starts over from its next price.
+func (s *Stepper) Retain(keep func(symbol string) bool) {
+	for symbol := range s.checkpoints {
+		if !keep(symbol) {
+			delete(s.checkpoints, symbol)
+		}
+	}
+}
+
+// RoundTo snaps val to the nearest multiple of step.
+func RoundTo(val, step float64) float64 {
+	return float64(int(val/step+0.5)) * step
+}
@@ -0,0 +1,74 @@
+package alert
+
+import "testing"
+
+func TestStepperUpdate(t *testing.T) {
+	type step struct {
+		price      float64
+		kind       Kind
+		ok         bool
+		checkpoint float64 // after the price
+	}
+	seven, four, tenth := 0.7, 0.4, 0.1 // variables, as constants are exact
+	tests := []struct {
+		name  string
+		step  float64
+		grid  Grid
+		steps []step
+	}{
+		{"to the price", 10, Grid{Size: 10}, []step{
+			{3004, Start, true, 3000},
+			{3009.9, 0, false, 3000},
+			{3013, Up, true, 3013},
+			{3022, 0, false, 3013},
+			{3003, Down, true, 3003},
+		}},
+		{"snapped", 10, Grid{Size: 10, Snap: true}, []step{
+			{3004, Start, true, 3000},
+			{3013, Up, true, 3010},
+			{3027, Up, true, 3020},
+			{3009, Down, true, 3010},
+		}},
+		{"snapped on a level", 10, Grid{Size: 10, Snap: true}, []step{
+			{3000, Start, true, 3000},
+			{3010, Up, true, 3010},
+			{3030, Up, true, 3030},
+			{3020, Down, true, 3020},
+		}},
+		// 0.7-0.4 is a hair below 0.3 and 3*0.1 a hair above
+		{"snapped on a level with float noise", 0.05, Grid{Size: 0.1, Snap: true}, []step{
+			{0.1, Start, true, 0.1},
+			{seven - four, Up, true, 0.3},
+			{0.5, Up, true, 0.5},
+			{3 * tenth, Down, true, 0.3},
+		}},
+		{"snapped to an origin", 10, Grid{Size: 10, Origin: 5, Snap: true}, []step{
+			{3001, Start, true, 3005},
+			{3016, Up, true, 3015},
+			{3004, Down, true, 3005},
+		}},
+		// The step outgrows the grid, and the last level short of the price
+		// is not past the checkpoint
+		{"snapped short of the checkpoint", 80, Grid{Size: 100, Snap: true}, []step{
+			{3000, Start, true, 3000},
+			{3080, Up, true, 3080},
+			{3160, Up, true, 3100},
+		}},
+	}
+	for _, tt := range tests {
+		s := NewStepper()
+		for _, st := range tt.steps {
+			ev, _, ok := s.Update("ethusdt", st.price, tt.step, tt.grid)
+			checkpoint, _ := s.Checkpoint("ethusdt")
+			if ok != st.ok || ok && ev.Kind != st.kind || !near(checkpoint, st.checkpoint) {
+				t.Errorf("%s: Update(%g) = %v, %v, checkpoint %g, want %v, %v, checkpoint %g",
+					tt.name, st.price, ev.Kind, ok, checkpoint, st.kind, st.ok, st.checkpoint)
+			}
+		}
+	}
+}
+
+// near reports whether a and b are equal but for float noise.
+func near(a, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
@@ -0,0 +1,47 @@
+package alert
+
+import "time"
+
+// Streaks counts the steps in a row each symbol moved in one direction.
+type Streaks struct {
+	symbols map[string]*streak
+}
+
+type streak struct {
+	kind  Kind        // Up or Down
+	steps []time.Time // of the steps in a row, oldest first
+}
+
+func NewStreaks() *Streaks {
+	return &Streaks{symbols: make(map[string]*streak)}
+}
+
+// Update feeds a step event of symbol and returns how many Up or Down
+// steps in a row it makes and when the first of them was; a step the other
+// way, or a Start, starts over. With window set, steps older than window
+// before ev drop out of the count.
+func (s *Streaks) Update(ev Event, window time.Duration) (n int, since time.Time) {
+	st, ok := s.symbols[ev.Symbol]
+	if !ok || st.kind != ev.Kind {
+		st = &streak{kind: ev.Kind}
+		s.symbols[ev.Symbol] = st
+	}
+	if ev.Kind != Up && ev.Kind != Down {
+		return 0, time.Time{}
+	}
+	st.steps = append(st.steps, ev.Time)
+	for window > 0 && ev.Time.Sub(st.steps[0]) > window {
+		st.steps = st.steps[1:]
+	}
+	return len(st.steps), st.steps[0]
+}
+
+// Retain drops the steps in a row counted for the symbols for which keep
+// returns false.
+func (s *Streaks) Retain(keep func(symbol string) bool) {
+	for symbol := range s.symbols {
+		if !keep(symbol) {
+			delete(s.symbols, symbol)
+		}
+	}
+}
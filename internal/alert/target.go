@@ -0,0 +1,141 @@
+package alert
+
+import "sort"
+
+// Targets raises an event when the price of a symbol crosses a registered
+// level in either direction. A one-shot level is then removed; a repeating
+// one re-arms once the price has moved its rearm distance away from it.
+type Targets struct {
+	levels map[string][]target
+	last   map[string]float64
+}
+
+type target struct {
+	level      float64
+	repeat     bool
+	rearm      float64
+	fired      bool // a repeating level waiting to re-arm
+	configured bool // from the config file, replaced by Configure
+}
+
+func NewTargets() *Targets {
+	return &Targets{levels: make(map[string][]target), last: make(map[string]float64)}
+}
+
+// Add registers the one-shot level for symbol.
+func (t *Targets) Add(symbol string, level float64) {
+	t.add(symbol, target{level: level})
+}
+
+// Every registers level for symbol as repeating: it alerts on every
+// crossing, once the price has been at least rearm away from it since the
+// last one.
+func (t *Targets) Every(symbol string, level, rearm float64) {
+	t.add(symbol, target{level: level, repeat: true, rearm: rearm})
+}
+
+func (t *Targets) add(symbol string, tg target) {
+	levels := append(t.levels[symbol], tg)
+	sort.SliceStable(levels, func(i, j int) bool { return levels[i].level < levels[j].level })
+	t.levels[symbol] = levels
+}
+
+// Remove drops every level of symbol at level, false if there was none.
+func (t *Targets) Remove(symbol string, level float64) bool {
+	return t.keep(symbol, func(tg target) bool { return tg.level != level })
+}
+
+// Configure replaces the configured levels of symbol with once and every,
+// the repeating ones re-arming at rearm, and keeps those added at run time.
+// A repeating level configured before stays disarmed if it was.
+func (t *Targets) Configure(symbol string, once, every []float64, rearm float64) {
+	fired := make(map[float64]bool)
+	for _, tg := range t.levels[symbol] {
+		if tg.configured && tg.repeat && tg.fired {
+			fired[tg.level] = true
+		}
+	}
+	t.keep(symbol, func(tg target) bool { return !tg.configured })
+	for _, level := range once {
+		t.add(symbol, target{level: level, configured: true})
+	}
+	for _, level := range every {
+		t.add(symbol, target{level: level, repeat: true, rearm: rearm, fired: fired[level], configured: true})
+	}
+}
+
+// keep drops the levels of symbol for which keep returns false and reports
+// whether any was dropped.
+func (t *Targets) keep(symbol string, keep func(target) bool) bool {
+	var kept []target
+	for _, tg := range t.levels[symbol] {
+		if keep(tg) {
+			kept = append(kept, tg)
+		}
+	}
+	dropped := len(kept) < len(t.levels[symbol])
+	t.levels[symbol] = kept
+	return dropped
+}
+
+// Levels returns the pending levels of symbol in ascending order.
+func (t *Targets) Levels(symbol string) []float64 {
+	return t.filter(symbol, func(target) bool { return true })
+}
+
+// Repeating returns the repeating levels of symbol in ascending order.
+func (t *Targets) Repeating(symbol string) []float64 {
+	return t.filter(symbol, func(tg target) bool { return tg.repeat })
+}
+
+func (t *Targets) filter(symbol string, match func(target) bool) []float64 {
+	var levels []float64
+	for _, tg := range t.levels[symbol] {
+		if match(tg) {
+			levels = append(levels, tg.level)
+		}
+	}
+	return levels
+}
+
+// Update feeds a price and returns an event for every armed level crossed
+// since the previous price. Crossed one-shot levels are removed.
+func (t *Targets) Update(symbol string, price float64) []Event {
+	prev, seen := t.last[symbol]
+	t.last[symbol] = price
+	if !seen {
+		return nil
+	}
+	var events []Event
+	var pending []target
+	for _, tg := range t.levels[symbol] {
+		if tg.fired && (price >= tg.level+tg.rearm || price <= tg.level-tg.rearm) {
+			tg.fired = false
+		}
+		if !tg.fired && ((prev < tg.level && price >= tg.level) || (prev > tg.level && price <= tg.level)) {
+			events = append(events, Event{Symbol: symbol, Kind: Target, Price: price, Change: price - prev, Level: tg.level})
+			if !tg.repeat {
+				continue
+			}
+			tg.fired = true
+		}
+		pending = append(pending, tg)
+	}
+	t.levels[symbol] = pending
+	return events
+}
+
+// Retain drops the levels, and the last price checked against them, of
+// the symbols for which keep returns false.
+func (t *Targets) Retain(keep func(symbol string) bool) {
+	for symbol := range t.last {
+		if !keep(symbol) {
+			delete(t.last, symbol)
+		}
+	}
+	for symbol := range t.levels {
+		if !keep(symbol) {
+			delete(t.levels, symbol)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package alert
+
+// Trailing raises an event when the price of a symbol retraces a distance
+// from its high or low since arming, like a trailing stop.
+type Trailing struct {
+	symbols map[string]*trail
+}
+
+type trail struct {
+	high, low float64
+}
+
+func NewTrailing() *Trailing {
+	return &Trailing{symbols: make(map[string]*trail)}
+}
+
+// Update feeds a price for symbol, whose first price arms it. It returns
+// an event when the price has fallen amount, or percent of the high, from
+// the high since arming (with high), or risen as much from the low (with
+// low), with the extreme as Level; a retrace re-arms both extremes at the
+// price.
+func (t *Trailing) Update(symbol string, price, amount, percent float64, high, low bool) (Event, bool) {
+	s, ok := t.symbols[symbol]
+	if !ok {
+		t.symbols[symbol] = &trail{high: price, low: price}
+		return Event{}, false
+	}
+	s.high, s.low = max(s.high, price), min(s.low, price)
+	distance := func(extreme float64) float64 {
+		if percent > 0 {
+			return extreme * percent / 100
+		}
+		return amount
+	}
+	var extreme float64
+	switch {
+	case high && price <= s.high-distance(s.high):
+		extreme = s.high
+	case low && price >= s.low+distance(s.low):
+		extreme = s.low
+	default:
+		return Event{}, false
+	}
+	s.high, s.low = price, price
+	return Event{Symbol: symbol, Kind: TrailingStop, Price: price, Change: price - extreme, Level: extreme}, true
+}
+
+// Extremes returns the high and low of symbol since arming.
+func (t *Trailing) Extremes(symbol string) (high, low float64, ok bool) {
+	if s, ok := t.symbols[symbol]; ok {
+		return s.high, s.low, true
+	}
+	return 0, 0, false
+}
+
+// Retain drops the high and low trailed from of the symbols for which keep
+// returns false.
+func (t *Trailing) Retain(keep func(symbol string) bool) {
+	for symbol := range t.symbols {
+		if !keep(symbol) {
+			delete(t.symbols, symbol)
+		}
+	}
+}
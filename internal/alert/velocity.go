@@ -0,0 +1,79 @@
+package alert
+
+import "time"
+
+// Velocities raises an event when the price of a symbol moves a distance
+// within a rolling window, however it sits against the checkpoint grid.
+type Velocities struct {
+	symbols map[string]*velocity
+}
+
+type velocity struct {
+	// lows and highs hold the prices of the window that no later price
+	// undercuts or exceeds, oldest first, so their heads are its low and
+	// high
+	lows, highs []sample
+}
+
+type sample struct {
+	at    time.Time
+	price float64
+}
+
+func NewVelocities() *Velocities {
+	return &Velocities{symbols: make(map[string]*velocity)}
+}
+
+// Update feeds a price of symbol at time at. It returns an event when the
+// price is amount, or percent of the low or high, above the low or below
+// the high of the last window, with that extreme as Level, and how long
+// the move took; the window then starts over at the price.
+func (v *Velocities) Update(symbol string, price float64, at time.Time, amount, percent float64, window time.Duration) (Event, time.Duration, bool) {
+	s, ok := v.symbols[symbol]
+	if !ok {
+		s = &velocity{}
+		v.symbols[symbol] = s
+	}
+	for len(s.lows) > 0 && s.lows[len(s.lows)-1].price >= price {
+		s.lows = s.lows[:len(s.lows)-1]
+	}
+	for len(s.highs) > 0 && s.highs[len(s.highs)-1].price <= price {
+		s.highs = s.highs[:len(s.highs)-1]
+	}
+	now := sample{at, price}
+	s.lows, s.highs = append(s.lows, now), append(s.highs, now)
+	from := at.Add(-window)
+	for s.lows[0].at.Before(from) {
+		s.lows = s.lows[1:]
+	}
+	for s.highs[0].at.Before(from) {
+		s.highs = s.highs[1:]
+	}
+	distance := func(extreme float64) float64 {
+		if percent > 0 {
+			return extreme * percent / 100
+		}
+		return amount
+	}
+	var extreme sample
+	switch low, high := s.lows[0], s.highs[0]; {
+	case price >= low.price+distance(low.price):
+		extreme = low
+	case price <= high.price-distance(high.price):
+		extreme = high
+	default:
+		return Event{}, 0, false
+	}
+	s.lows, s.highs = []sample{now}, []sample{now}
+	return Event{Symbol: symbol, Kind: Velocity, Price: price, Change: price - extreme.price, Level: extreme.price}, at.Sub(extreme.at), true
+}
+
+// Retain drops the window lows and highs of the symbols for which keep
+// returns false.
+func (v *Velocities) Retain(keep func(symbol string) bool) {
+	for symbol := range v.symbols {
+		if !keep(symbol) {
+			delete(v.symbols, symbol)
+		}
+	}
+}
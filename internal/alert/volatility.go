@@ -0,0 +1,127 @@
+package alert
+
+import (
+	"math"
+	"time"
+)
+
+// VOLATILITY_REARM is how far, as a fraction of the threshold, the
+// volatility has to come back inside it before the same alert fires again.
+const VOLATILITY_REARM = 0.1
+
+// Volatilities keeps the realized volatility of each symbol, from the
+// close-to-close log returns of its candles over rolling windows,
+// annualized over 365 days in percent, and raises an event when it spikes
+// above or collapses below a threshold.
+type Volatilities struct {
+	symbols map[string]*volatility
+}
+
+type volatility struct {
+	closes []closeAt // oldest first
+	// values are the volatility of each window, once it spans one; alerted
+	// is VolSpike or VolCollapse while that alert holds, Start otherwise
+	values  map[string]float64
+	alerted map[string]Kind
+}
+
+// closeAt is the close of a candle and when it opened.
+type closeAt struct {
+	start time.Time
+	close float64
+}
+
+// VolWindow is a rolling window of Volatilities by name, such as 1h.
+type VolWindow struct {
+	Name   string
+	Length time.Duration
+}
+
+func NewVolatilities() *Volatilities {
+	return &Volatilities{symbols: make(map[string]*volatility)}
+}
+
+// Add feeds the close of a candle of symbol of interval opening at start,
+// ignoring one not after the last, and returns an event, with the
+// volatility as Level and the window's name as Window, for each of windows
+// whose volatility rose to above or fell to below, 0 for none. Each window
+// has a value once the candles span it, a gap counting as one return; an
+// alert fires again once the volatility has been VOLATILITY_REARM inside
+// its threshold.
+func (v *Volatilities) Add(symbol string, start time.Time, close float64, interval time.Duration, windows []VolWindow, above, below float64) []Event {
+	s, ok := v.symbols[symbol]
+	if !ok {
+		s = &volatility{values: make(map[string]float64), alerted: make(map[string]Kind)}
+		v.symbols[symbol] = s
+	}
+	if n := len(s.closes); close <= 0 || n > 0 && !start.After(s.closes[n-1].start) {
+		return nil
+	}
+	s.closes = append(s.closes, closeAt{start, close})
+	longest := time.Duration(0)
+	for _, w := range windows {
+		longest = max(longest, w.Length)
+	}
+	// The candle before the longest window is the first return's base
+	end := start.Add(interval)
+	for len(s.closes) > 1 && !s.closes[1].start.After(end.Add(-longest-interval)) {
+		s.closes = s.closes[1:]
+	}
+	var events []Event
+	for _, w := range windows {
+		from := end.Add(-w.Length)
+		if s.closes[0].start.After(from.Add(-interval)) {
+			continue
+		}
+		sum, n := 0.0, 0
+		for i := 1; i < len(s.closes); i++ {
+			if s.closes[i].start.Before(from) {
+				continue
+			}
+			r := math.Log(s.closes[i].close / s.closes[i-1].close)
+			sum, n = sum+r*r, n+1
+		}
+		if n == 0 {
+			continue
+		}
+		value := math.Sqrt(sum/float64(n)*float64(365*24*time.Hour/interval)) * 100
+		s.values[w.Name] = value
+		kind := s.alerted[w.Name]
+		switch {
+		case kind == VolSpike && value < above*(1-VOLATILITY_REARM),
+			kind == VolCollapse && value > below*(1+VOLATILITY_REARM):
+			kind = Start
+		}
+		switch {
+		case kind != VolSpike && above > 0 && value > above:
+			kind = VolSpike
+			events = append(events, Event{Symbol: symbol, Kind: VolSpike, Price: close, Level: value, Window: w.Name})
+		case kind != VolCollapse && below > 0 && value < below:
+			kind = VolCollapse
+			events = append(events, Event{Symbol: symbol, Kind: VolCollapse, Price: close, Level: value, Window: w.Name})
+		}
+		s.alerted[w.Name] = kind
+	}
+	return events
+}
+
+// Value returns the volatility of symbol over the window named window,
+// false before the candles spanned it.
+func (v *Volatilities) Value(symbol, window string) (float64, bool) {
+	s, ok := v.symbols[symbol]
+	if !ok {
+		return 0, false
+	}
+	value, ok := s.values[window]
+	return value, ok
+}
+
+// Retain drops the closes, the volatility of each window and the spike or
+// collapse alerts held of the symbols for which keep returns false.
+func (v *Volatilities) Retain(keep func(symbol string) bool) {
+	for symbol := range v.symbols {
+		if !keep(symbol) {
+			delete(v.symbols, symbol)
+		}
+	}
+}
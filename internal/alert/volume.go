@@ -0,0 +1,115 @@
+package alert
+
+import "time"
+
+// Volumes keeps the volume traded in candles of each symbol and raises an
+// event when a candle's volume runs far above the average of the last
+// ones.
+type Volumes struct {
+	symbols map[string]*volume
+}
+
+type volume struct {
+	start   time.Time // of the open candle
+	current float64   // volume of the open candle
+	past    []float64 // volumes of the closed candles, at most the period
+	period  int
+	alerted bool // the open candle has alerted
+}
+
+func NewVolumes() *Volumes {
+	return &Volumes{symbols: make(map[string]*volume)}
+}
+
+// Update adds a trade of size to the open candle of interval of symbol at
+// time at, candles without a trade counting as 0, and reports like Add.
+func (v *Volumes) Update(symbol string, price, size float64, at time.Time, interval time.Duration, period int, multiple float64) (Event, bool) {
+	s := v.open(symbol, at.Truncate(interval), interval, period)
+	if s == nil {
+		return Event{}, false
+	}
+	s.current += size
+	return v.check(symbol, s, price, multiple)
+}
+
+// Add feeds a candle of symbol opening at start with its volume, such as a
+// historical kline; one opening before the open candle is ignored, one
+// opening with it replaces its volume. Once period candles have closed it
+// returns an event with their average volume as Level and the open
+// candle's as Change when that reaches multiple times the average, once a
+// candle.
+func (v *Volumes) Add(symbol string, start time.Time, vol, price float64, interval time.Duration, period int, multiple float64) (Event, bool) {
+	s := v.open(symbol, start, interval, period)
+	if s == nil {
+		return Event{}, false
+	}
+	s.current = vol
+	return v.check(symbol, s, price, multiple)
+}
+
+// open returns the state of symbol with the candle opening at start open,
+// nil for a start before the open candle.
+func (v *Volumes) open(symbol string, start time.Time, interval time.Duration, period int) *volume {
+	s, ok := v.symbols[symbol]
+	switch {
+	case !ok:
+		s = &volume{start: start}
+		v.symbols[symbol] = s
+	case start.Before(s.start):
+		return nil
+	case start.After(s.start):
+		s.past = append(s.past, s.current)
+		// Candles without a trade in between
+		for range min(int(start.Sub(s.start)/interval)-1, period) {
+			s.past = append(s.past, 0)
+		}
+		s.start, s.current, s.alerted = start, 0, false
+	}
+	if s.period = period; len(s.past) > period {
+		s.past = s.past[len(s.past)-period:]
+	}
+	return s
+}
+
+func (v *Volumes) check(symbol string, s *volume, price, multiple float64) (Event, bool) {
+	avg, ok := s.average()
+	if !ok || s.alerted || avg <= 0 || s.current < multiple*avg {
+		return Event{}, false
+	}
+	s.alerted = true
+	return Event{Symbol: symbol, Kind: Volume, Price: price, Change: s.current, Level: avg}, true
+}
+
+// average returns the average volume of the closed candles, false before
+// period of them.
+func (s *volume) average() (float64, bool) {
+	if len(s.past) < s.period {
+		return 0, false
+	}
+	sum := 0.0
+	for _, p := range s.past {
+		sum += p
+	}
+	return sum / float64(len(s.past)), true
+}
+
+// Value returns the volume of the open candle of symbol and the average of
+// the closed ones, false before period of them.
+func (v *Volumes) Value(symbol string) (current, average float64, ok bool) {
+	if s, ok := v.symbols[symbol]; ok {
+		if avg, ok := s.average(); ok {
+			return s.current, avg, true
+		}
+	}
+	return 0, 0, false
+}
+
+// Retain drops the candle volumes of the symbols for which keep returns
+// false.
+func (v *Volumes) Retain(keep func(symbol string) bool) {
+	for symbol := range v.symbols {
+		if !keep(symbol) {
+			delete(v.symbols, symbol)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package alert
+
+import "time"
+
+// VolumeDeltas keeps the volume takers bought and sold of each symbol over
+// a rolling window and raises an event when one side dominates.
+type VolumeDeltas struct {
+	symbols map[string]*volumeDelta
+}
+
+type volumeDelta struct {
+	seconds   []flow  // of the window, oldest first
+	buy, sell float64 // summed over seconds
+	bought    bool    // a buying event waits for its re-arm
+	sold      bool    // and a selling one
+}
+
+// flow is the volume bought and sold within one second.
+type flow struct {
+	at        time.Time
+	buy, sell float64
+}
+
+func NewVolumeDeltas() *VolumeDeltas {
+	return &VolumeDeltas{symbols: make(map[string]*volumeDelta)}
+}
+
+// Update adds a trade of size at time at to the window of symbol, bought
+// by the taker when buy is set. Once the window has traded minVolume it
+// returns an event with the imbalance, the bought minus the sold volume in
+// percent of both, as Level and the bought minus the sold volume as Change
+// when the imbalance reaches imbalance either way, and alerts that way
+// again only after it has been back below half that.
+func (v *VolumeDeltas) Update(symbol string, price, size float64, buy bool, at time.Time, window time.Duration, imbalance, minVolume float64) (Event, bool) {
+	s, ok := v.symbols[symbol]
+	if !ok {
+		s = &volumeDelta{}
+		v.symbols[symbol] = s
+	}
+	sec := at.Truncate(time.Second)
+	if n := len(s.seconds); n == 0 || s.seconds[n-1].at.Before(sec) {
+		s.seconds = append(s.seconds, flow{at: sec})
+	}
+	last := &s.seconds[len(s.seconds)-1]
+	if buy {
+		last.buy += size
+		s.buy += size
+	} else {
+		last.sell += size
+		s.sell += size
+	}
+	from := at.Add(-window)
+	for len(s.seconds) > 1 && !s.seconds[0].at.After(from) {
+		s.buy -= s.seconds[0].buy
+		s.sell -= s.seconds[0].sell
+		s.seconds = s.seconds[1:]
+	}
+	total := s.buy + s.sell
+	if total <= 0 || total < minVolume {
+		return Event{}, false
+	}
+	pct := (s.buy - s.sell) / total * 100
+	if pct < imbalance/2 {
+		s.bought = false
+	}
+	if pct > -imbalance/2 {
+		s.sold = false
+	}
+	switch {
+	case pct >= imbalance && !s.bought:
+		s.bought = true
+	case pct <= -imbalance && !s.sold:
+		s.sold = true
+	default:
+		return Event{}, false
+	}
+	return Event{Symbol: symbol, Kind: VolumeDelta, Price: price, Change: s.buy - s.sell, Level: pct}, true
+}
+
+// Value returns the volume bought and sold within the window of symbol as
+// of its last trade, false before one.
+func (v *VolumeDeltas) Value(symbol string) (buy, sell float64, ok bool) {
+	if s, ok := v.symbols[symbol]; ok {
+		return max(s.buy, 0), max(s.sell, 0), true
+	}
+	return 0, 0, false
+}
+
+// Retain drops the bought and sold volume of the window of the symbols for
+// which keep returns false.
+func (v *VolumeDeltas) Retain(keep func(symbol string) bool) {
+	for symbol := range v.symbols {
+		if !keep(symbol) {
+			delete(v.symbols, symbol)
+		}
+	}
+}
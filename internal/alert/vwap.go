@@ -0,0 +1,88 @@
+package alert
+
+import (
+	"math"
+	"time"
+)
+
+// VWAPs keeps the volume-weighted average price of each symbol over its
+// current session and raises an event when the price strays from it.
+type VWAPs struct {
+	symbols map[string]*vwap
+}
+
+type vwap struct {
+	session          time.Time
+	volume, turnover float64 // size, and price times size, summed
+	squares          float64 // price squared times size, summed
+	value, deviation float64 // the VWAP and standard deviation around it
+	above, below     bool    // an event of that side waits for its re-arm
+}
+
+func NewVWAPs() *VWAPs {
+	return &VWAPs{symbols: make(map[string]*vwap)}
+}
+
+// Update feeds a tick of symbol in the session starting at session, a new
+// one starting the sums over; a tick without a size counts as one. It
+// returns an event with the VWAP as Level and the price minus it as Change
+// when the price got percent percent, or deviations standard deviations,
+// above or below the VWAP, each 0 for none, and alerts that side again only
+// after the price has come back within half of both.
+func (v *VWAPs) Update(symbol string, price, size float64, session time.Time, percent, deviations float64) (Event, bool) {
+	s, ok := v.symbols[symbol]
+	if !ok || !s.session.Equal(session) {
+		s = &vwap{session: session}
+		v.symbols[symbol] = s
+	}
+	if size <= 0 {
+		size = 1
+	}
+	s.volume += size
+	s.turnover += price * size
+	s.squares += price * price * size
+	s.value = s.turnover / s.volume
+	s.deviation = math.Sqrt(max(s.squares/s.volume-s.value*s.value, 0))
+	// How far the price is from the VWAP in multiples of each threshold
+	// set, the largest counting
+	dist := 0.0
+	if percent > 0 {
+		dist = math.Abs(price-s.value) / s.value * 100 / percent
+	}
+	if deviations > 0 && s.deviation > 0 {
+		dist = max(dist, math.Abs(price-s.value)/s.deviation/deviations)
+	}
+	if dist < 0.5 {
+		s.above, s.below = false, false
+	}
+	switch {
+	case dist < 1:
+		return Event{}, false
+	case price > s.value && !s.above:
+		s.above = true
+	case price < s.value && !s.below:
+		s.below = true
+	default:
+		return Event{}, false
+	}
+	return Event{Symbol: symbol, Kind: VWAP, Price: price, Change: price - s.value, Level: s.value}, true
+}
+
+// Value returns the VWAP of symbol over its session and the standard
+// deviation of its prices around it, false before a tick.
+func (v *VWAPs) Value(symbol string) (value, deviation float64, ok bool) {
+	if s, ok := v.symbols[symbol]; ok {
+		return s.value, s.deviation, true
+	}
+	return 0, 0, false
+}
+
+// Retain drops the session sums, the VWAP and its deviation of the
+// symbols for which keep returns false.
+func (v *VWAPs) Retain(keep func(symbol string) bool) {
+	for symbol := range v.symbols {
+		if !keep(symbol) {
+			delete(v.symbols, symbol)
+		}
+	}
+}
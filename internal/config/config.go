@@ -0,0 +1,1370 @@
+// Package config loads the writer configuration from defaults, a YAML file,
+// command-line flags and PRICE_ALERT_* environment variables.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/qqubb/tts_price_alert/internal/logging"
+	"github.com/qqubb/tts_price_alert/internal/numfmt"
+)
+
+// Defaults used when neither the file, flags nor environment set a value.
+const (
+	SHM_PATH       = "/dev/shm/eth_price_shm"
+	PIPE_PATH      = "/tmp/eth_price_pipe"
+	ALERT_PATH     = "/dev/shm/eth_price_alerts"
+	CONTROL_SOCKET = "/tmp/price_alert.sock"
+	BINANCE_WS     = "wss://stream.binance.com:9443"
+	BINANCE_REST   = "https://api.binance.com"
+
+	BINANCE_TESTNET_WS   = "wss://stream.testnet.binance.vision"
+	BINANCE_TESTNET_REST = "https://testnet.binance.vision"
+	// IPC paths of testnet runs, so they never clobber live data
+	TESTNET_SHM_PATH       = "/dev/shm/price_alert_testnet_shm"
+	TESTNET_PIPE_PATH      = "/tmp/price_alert_testnet_pipe"
+	TESTNET_ALERT_PATH     = "/dev/shm/price_alert_testnet_alerts"
+	TESTNET_CONTROL_SOCKET = "/tmp/price_alert_testnet.sock"
+	BINANCE_US_WS          = "wss://stream.binance.us:9443"
+	BINANCE_US_REST        = "https://api.binance.us"
+	// USDⓈ-M futures
+	BINANCE_FUTURES_WS           = "wss://fstream.binance.com"
+	BINANCE_FUTURES_REST         = "https://fapi.binance.com"
+	BINANCE_FUTURES_TESTNET_WS   = "wss://stream.binancefuture.com"
+	BINANCE_FUTURES_TESTNET_REST = "https://testnet.binancefuture.com"
+	COINBASE_WS                  = "wss://ws-feed.exchange.coinbase.com"
+	COINBASE_REST                = "https://api.exchange.coinbase.com"
+	KRAKEN_WS                    = "wss://ws.kraken.com/v2"
+	KRAKEN_REST                  = "https://api.kraken.com"
+	BYBIT_SPOT_WS                = "wss://stream.bybit.com/v5/public/spot"
+	BYBIT_LINEAR_WS              = "wss://stream.bybit.com/v5/public/linear"
+	BYBIT_REST                   = "https://api.bybit.com"
+	OKX_WS                       = "wss://ws.okx.com:8443/ws/v5/public"
+	OKX_REST                     = "https://www.okx.com"
+	POLYGON_REST                 = "https://api.polygon.io"
+	ALPHA_VANTAGE_REST           = "https://www.alphavantage.co"
+	TWELVE_DATA_REST             = "https://api.twelvedata.com"
+	COINGECKO_REST               = "https://api.coingecko.com/api/v3"
+	COINMARKETCAP_REST           = "https://pro-api.coinmarketcap.com"
+	ETHEREUM_RPC                 = "https://ethereum-rpc.publicnode.com"
+
+	SYMBOL          = "ethusdt"
+	STEP            = 12.5
+	MAX_BACKOFF     = 60 * time.Second
+	PING_PERIOD     = 20 * time.Second
+	STATUS_INTERVAL = 5 * time.Second
+	// OKX_IDLE_TIMEOUT is how long OKX keeps a connection without traffic.
+	OKX_IDLE_TIMEOUT = 30 * time.Second
+	// AGGREGATE_WINDOW is the default aggregate.window.
+	AGGREGATE_WINDOW = 10 * time.Second
+	// ARBITRAGE_FOR is the default time arbitrage spreads have to last.
+	ARBITRAGE_FOR = 10 * time.Second
+	// LIQUIDATION_WINDOW is the default liquidations.window.
+	LIQUIDATION_WINDOW = time.Minute
+	// OUTLIER_SAMPLES is the default outliers.samples.
+	OUTLIER_SAMPLES = 9
+	// DEPTH_LEVELS and DEPTH_FOR are the defaults of depth.
+	DEPTH_LEVELS = 10
+	DEPTH_FOR    = 10 * time.Second
+	// OPEN_INTEREST_WINDOW is the default open_interest.window.
+	OPEN_INTEREST_WINDOW = 5 * time.Minute
+	// CORRELATION_WINDOW and CORRELATION_BELOW are the default
+	// correlation.window and below; MIN_CORRELATION_WINDOW keeps its 30
+	// samples 10s apart.
+	CORRELATION_WINDOW     = time.Hour
+	CORRELATION_BELOW      = 0.5
+	MIN_CORRELATION_WINDOW = 5 * time.Minute
+	// ATR_INTERVAL and ATR_PERIOD are the default atr.interval and period.
+	ATR_INTERVAL = "1h"
+	ATR_PERIOD   = 14
+	// AVERAGES_INTERVAL is the default moving_averages.interval;
+	// MAX_AVERAGE_PERIOD keeps their backfill to one kline request.
+	AVERAGES_INTERVAL  = "1h"
+	MAX_AVERAGE_PERIOD = 500
+	// RSI_* are the defaults of rsi.
+	RSI_INTERVAL   = "1h"
+	RSI_PERIOD     = 14
+	RSI_OVERBOUGHT = 70
+	RSI_OVERSOLD   = 30
+	RSI_REARM      = 5
+	// MACD_* are the defaults of macd; MACD_SIGNAL_CROSS and
+	// MACD_ZERO_CROSS are the crosses of macd.crosses.
+	MACD_INTERVAL     = "1h"
+	MACD_FAST         = 12
+	MACD_SLOW         = 26
+	MACD_SIGNAL       = 9
+	MACD_SIGNAL_CROSS = "signal"
+	MACD_ZERO_CROSS   = "zero"
+	// BOLLINGER_* are the defaults of bollinger.
+	BOLLINGER_INTERVAL   = "1h"
+	BOLLINGER_PERIOD     = 20
+	BOLLINGER_DEVIATIONS = 2
+	// VOLUME_* are the defaults of volume.
+	VOLUME_INTERVAL = "1m"
+	VOLUME_PERIOD   = 20
+	VOLUME_MULTIPLE = 3
+	// VWAP_ANCHOR is the default time of day a vwap session starts.
+	VWAP_ANCHOR = "00:00"
+	// DAILY_CHANGE_ANCHOR is the default time of day a daily_change day
+	// starts.
+	DAILY_CHANGE_ANCHOR = "00:00"
+	// VELOCITY_WINDOW is the default window of velocity.
+	VELOCITY_WINDOW = time.Minute
+	// PEG_* are the defaults of peg.
+	PEG_TARGET = 1
+	PEG_BAND   = 0.5
+	PEG_REPEAT = time.Minute
+	// LEVELS_* are the defaults of levels: a week of 1h candles, swings
+	// of 3 candles each side, alerting within 0.3 percent.
+	LEVELS_INTERVAL = "1h"
+	LEVELS_LOOKBACK = 168
+	LEVELS_SWING    = 3
+	LEVELS_APPROACH = 0.3
+	// PIVOTS_PERIOD and PIVOTS_REARM are the defaults of pivots.
+	PIVOTS_PERIOD = "1d"
+	PIVOTS_REARM  = 0.1
+	// VOLATILITY_INTERVAL is the default candle interval of volatility.
+	VOLATILITY_INTERVAL = "5m"
+	// SPREAD_FOR and SPREAD_WINDOW are the defaults of spread.
+	SPREAD_FOR    = 10 * time.Second
+	SPREAD_WINDOW = 15 * time.Minute
+	// VOLUME_DELTA_WINDOW is the default window of volume_delta.
+	VOLUME_DELTA_WINDOW = 5 * time.Minute
+	// TRAILING_* are the extremes trailing.from retraces from.
+	TRAILING_HIGH = "high"
+	TRAILING_LOW  = "low"
+	TRAILING_BOTH = "both"
+	// RULE_* are when rules[].on evaluates a rule.
+	RULE_TICK   = "tick"
+	RULE_CANDLE = "candle"
+	// ESCALATION_WINDOW is the default escalation.window.
+	ESCALATION_WINDOW = 30 * time.Minute
+	// ACTION_TIMEOUT is the default actions.timeout.
+	ACTION_TIMEOUT = 10 * time.Second
+	// QUOTES_INTERVAL is the default stocks.interval and forex.interval.
+	QUOTES_INTERVAL = 15 * time.Second
+	// COINS_INTERVAL is the default coins.interval; MIN_COINS_INTERVAL
+	// keeps the free aggregator plans, a few calls a minute, from
+	// throttling.
+	COINS_INTERVAL     = time.Minute
+	MIN_COINS_INTERVAL = 10 * time.Second
+	// GAS_INTERVAL is the default ethereum.interval, about a block.
+	GAS_INTERVAL = 12 * time.Second
+	// CHAINLINK_INTERVAL and UNISWAP_INTERVAL are the default
+	// chainlink.interval and uniswap.interval, the block an update lands
+	// in.
+	CHAINLINK_INTERVAL = 12 * time.Second
+	UNISWAP_INTERVAL   = 12 * time.Second
+	// CUSTOM_INTERVAL is the default custom.interval.
+	CUSTOM_INTERVAL = 30 * time.Second
+	// BACKFILL_INTERVAL is the default backfill.interval.
+	BACKFILL_INTERVAL = "15m"
+	// MAX_BACKFILL_KLINES is the most klines one Binance request returns.
+	MAX_BACKFILL_KLINES = 1000
+	// OPEN_INTEREST_INTERVAL is how often open interest is polled.
+	OPEN_INTEREST_INTERVAL = 30 * time.Second
+	// FAILOVER_AFTER is the default reconnect.failover_after.
+	FAILOVER_AFTER = 15 * time.Second
+	// MIN_POLL_INTERVAL keeps REST polling well inside exchange rate limits.
+	MIN_POLL_INTERVAL = time.Second
+	BUFFER_SIZE       = 32  // bytes per SHM slot
+	STATS_SIZE        = 192 // bytes per stats_path slot
+	ALERT_SIZE        = 256 // bytes per alert_path record
+	SLOTS             = 16  // SHM slots mapped up front so a reload can add symbols
+)
+
+// Limits on the SHM geometry. A slot must hold the longest record the
+// format can produce: up to MAX_PRICE_DIGITS integer digits, a decimal
+// point and precision decimals, the symbol, and two NULs.
+const (
+	MIN_BUFFER_SIZE  = 16
+	MIN_ALERT_SIZE   = 128 // room for the fields before the text
+	MAX_SLOTS        = 255 // the pipe signal is one byte: slot index + 1
+	MAX_PRICE_DIGITS = 8
+	MAX_PRECISION    = 8
+)
+
+// Widths of the stats record fields other than prices, for
+// StatsRecordSize: a volume as strconv writes it, a percent with two
+// decimals, a funding rate with four and a time in milliseconds.
+const (
+	MAX_VOLUME_DIGITS  = 20
+	MAX_PERCENT_DIGITS = 9
+	MAX_FUNDING_DIGITS = 8
+	MAX_MILLIS_DIGITS  = 13
+)
+
+// DEFAULT_STEP_TICKS is how many price ticks make up a derived alert step;
+// with ETHUSDT's 0.01 tick this reproduces the historical 12.5 step.
+const DEFAULT_STEP_TICKS = 1250
+
+// Price sources selectable with the source key. The exchanges can also be
+// chosen per symbol.
+const (
+	SOURCE_BINANCE         = "binance"
+	SOURCE_BINANCE_FUTURES = "binance-futures" // USDⓈ-M perpetuals and futures
+	SOURCE_COINBASE        = "coinbase"
+	SOURCE_KRAKEN          = "kraken"
+	SOURCE_BYBIT           = "bybit"        // spot
+	SOURCE_BYBIT_LINEAR    = "bybit-linear" // USDT/USDC perpetuals
+	SOURCE_OKX             = "okx"
+	SOURCE_STOCKS          = "stocks"    // US equities, polled from stocks.provider
+	SOURCE_FOREX           = "forex"     // currency pairs and metals, from forex.provider
+	SOURCE_ETHEREUM        = "ethereum"  // the base fee in gwei, from ethereum.rpc_url
+	SOURCE_COINS           = "coins"     // any coin, from the coins.provider aggregator
+	SOURCE_CHAINLINK       = "chainlink" // Chainlink price feeds, from chainlink.rpc_url
+	SOURCE_UNISWAP         = "uniswap"   // Uniswap v3 pool prices, from uniswap.rpc_url
+	SOURCE_CUSTOM          = "custom"    // any JSON API, from custom.sources
+	SOURCE_PLUGIN          = "plugin"    // external programs, from plugins
+	SOURCE_BASKET          = "basket"    // symbols priced from others, from basket or ratio
+	SOURCE_REPLAY          = "replay"
+)
+
+// Checkpoint reset strategies selectable per symbol with reset:, where the
+// checkpoint moves once a step fired.
+const (
+	RESET_PRICE    = "price"    // to the price, after a start snapped to rounding
+	RESET_GRID     = "grid"     // to the multiples of rounding the move reached
+	RESET_ANCHORED = "anchored" // likewise, counted from origin
+)
+
+// RESETS are the valid reset strategies.
+var RESETS = []string{RESET_PRICE, RESET_GRID, RESET_ANCHORED}
+
+// Quote providers selectable with stocks.provider and forex.provider, and
+// the aggregators selectable with coins.provider.
+const (
+	QUOTES_POLYGON       = "polygon"
+	QUOTES_ALPHA_VANTAGE = "alphavantage"
+	QUOTES_TWELVE_DATA   = "twelvedata"
+	QUOTES_COINGECKO     = "coingecko"
+	QUOTES_COINMARKETCAP = "coinmarketcap"
+)
+
+// quotesREST is the default rest_endpoint of each provider.
+var quotesREST = map[string]string{
+	QUOTES_POLYGON:       POLYGON_REST,
+	QUOTES_ALPHA_VANTAGE: ALPHA_VANTAGE_REST,
+	QUOTES_TWELVE_DATA:   TWELVE_DATA_REST,
+	QUOTES_COINGECKO:     COINGECKO_REST,
+	QUOTES_COINMARKETCAP: COINMARKETCAP_REST,
+}
+
+// Binance stream types selectable per symbol with stream:.
+const (
+	STREAM_TRADE         = "trade"
+	STREAM_AGG_TRADE     = "aggTrade"     // trades at one price and taker, summed
+	STREAM_BOOK_TICKER   = "bookTicker"   // best bid/ask, priced at the midpoint
+	STREAM_MARK_PRICE    = "markPrice"    // futures only, every 3s
+	STREAM_MARK_PRICE_1S = "markPrice@1s" // futures only, every second
+	STREAM_MINI_TICKER   = "miniTicker"   // last price and 24h stats, every second
+	STREAM_TICKER        = "ticker"       // miniTicker plus the best bid/ask
+	// STREAM_KLINE prefixes candle streams: kline_1m, kline_5m, ...
+	STREAM_KLINE = "kline_"
+)
+
+// KLINE_INTERVALS are the candle intervals Binance streams; 1s is spot only.
+var KLINE_INTERVALS = []string{"1s", "1m", "3m", "5m", "15m", "30m", "1h", "2h", "4h", "6h", "8h", "12h", "1d", "3d", "1w", "1M"}
+
+// StatsStream reports whether stream carries 24h statistics.
+func StatsStream(stream string) bool {
+	return stream == STREAM_MINI_TICKER || stream == STREAM_TICKER
+}
+
+// REST_SOURCE is the SHM source field of prices polled from a REST ticker
+// while the stream is down.
+const REST_SOURCE = "rest"
+
+// Config is the writer configuration, loaded from YAML and refined by flags.
+type Config struct {
+	// Profile is the name of the applied profile, if any.
+	Profile string `yaml:"-"`
+
+	Source string `yaml:"source"`
+	// Testnet runs against the Binance spot testnet: endpoints and IPC
+	// paths left at their defaults switch to the testnet ones, and every
+	// market must be on Binance so nothing touches a live exchange.
+	Testnet      bool   `yaml:"testnet"`
+	Endpoint     string `yaml:"endpoint"`
+	RESTEndpoint string `yaml:"rest_endpoint"`
+	SHMPath      string `yaml:"shm_path"`
+	PipePath     string `yaml:"pipe_path"`
+	BufferSize   int    `yaml:"buffer_size"` // bytes per SHM slot
+	Slots        int    `yaml:"slots"`       // SHM slots mapped
+	// ControlSocket is the Unix socket for runtime commands; "off" disables it.
+	ControlSocket string `yaml:"control_socket"`
+	// StatsPath, when set, maps a second region with one StatsSize slot
+	// per SHM slot, holding the 24h statistics of ticker-streamed symbols.
+	StatsPath string `yaml:"stats_path"`
+	StatsSize int    `yaml:"stats_size"` // bytes per stats slot
+	// AlertPath maps a region with a ring of AlertSize records per SHM
+	// slot, holding the latest alerts of its symbol for readers to speak;
+	// "off" disables it.
+	AlertPath string `yaml:"alert_path"`
+	AlertSize int    `yaml:"alert_size"` // bytes per alert record
+	// AlertSeverity is the least severity (one of SEVERITIES, default
+	// info) of the alerts written to AlertPath, and so spoken.
+	AlertSeverity string `yaml:"alert_severity"`
+	// PIDFile, when set, receives the writer's PID for service managers.
+	PIDFile string `yaml:"pid_file"`
+	// ATHFile, when set, keeps the all-time highs of symbols with ath
+	// across restarts.
+	ATHFile string `yaml:"ath_file"`
+	// CandleFile, when set, has the closed candles of symbols with
+	// candles appended to it as CSV.
+	CandleFile string `yaml:"candle_file"`
+	// StatusFile, when set, is rewritten every StatusInterval with a JSON
+	// health summary for monitoring scripts.
+	StatusFile     string          `yaml:"status_file"`
+	StatusInterval time.Duration   `yaml:"status_interval"`
+	Symbols        []SymbolConfig  `yaml:"symbols"`
+	BinanceFutures ExchangeConfig  `yaml:"binance_futures"`
+	Coinbase       ExchangeConfig  `yaml:"coinbase"`
+	Kraken         ExchangeConfig  `yaml:"kraken"`
+	Bybit          ExchangeConfig  `yaml:"bybit"`
+	BybitLinear    ExchangeConfig  `yaml:"bybit_linear"`
+	OKX            ExchangeConfig  `yaml:"okx"`
+	Stocks         QuotesConfig    `yaml:"stocks"`
+	Forex          QuotesConfig    `yaml:"forex"`
+	Coins          QuotesConfig    `yaml:"coins"`
+	Ethereum       EthereumConfig  `yaml:"ethereum"`
+	Chainlink      EthereumConfig  `yaml:"chainlink"`
+	Uniswap        EthereumConfig  `yaml:"uniswap"`
+	Custom         CustomConfig    `yaml:"custom"`
+	Reconnect      ReconnectConfig `yaml:"reconnect"`
+	Replay         ReplayConfig    `yaml:"replay"`
+	Backfill       BackfillConfig  `yaml:"backfill"`
+	Secrets        SecretsConfig   `yaml:"secrets"`
+	UserData       UserDataConfig  `yaml:"user_data"`
+	// Permissions applies to the SHM file and FIFO the writer creates.
+	Permissions PermissionsConfig `yaml:"permissions"`
+	Log         LogConfig         `yaml:"log"`
+	// Locale sets the decimal separator and digit grouping of prices in log
+	// lines and alert texts, e.g. "de_DE" or "auto" for $LANG. Empty keeps
+	// the plain "1234.56" that SHM always uses.
+	Locale string `yaml:"locale"`
+	// Plugins are the external price sources of the plugin exchange by
+	// name, whose markets are written <name>:<symbol>.
+	Plugins map[string]PluginConfig `yaml:"plugins"`
+	// Actions are the commands rules and whale trades run when they
+	// alert, by name.
+	Actions map[string]ActionConfig `yaml:"actions"`
+	// Sessions are the trading sessions symbols track the high and low
+	// of, by name.
+	Sessions map[string]SessionConfig `yaml:"sessions"`
+	// QuietHours holds back all but critical alerts at night.
+	QuietHours QuietHoursConfig `yaml:"quiet_hours"`
+	// Notifiers are the sinks alerts reach beside the log, by severity.
+	Notifiers []NotifierConfig `yaml:"notifiers"`
+	// Escalation turns an alert that keeps firing into a warning.
+	Escalation EscalationConfig `yaml:"escalation"`
+	// DedupWindow drops an alert repeating the last one of the same kind,
+	// rule and level within it; 0 keeps them all.
+	DedupWindow time.Duration `yaml:"dedup_window"`
+
+	// Profiles are named partial configs merged over the base with -profile.
+	Profiles map[string]Config `yaml:"profiles"`
+}
+
+// ReplayConfig selects the recording played back by the replay source.
+type ReplayConfig struct {
+	File string `yaml:"file"`
+	// Speed scales recorded gaps: 1 is real time, 0 replays without delay.
+	Speed float64 `yaml:"speed"`
+}
+
+// BackfillConfig seeds symbols from the Binance klines of the last Window
+// at startup instead of from their first tick; 0 disables it.
+type BackfillConfig struct {
+	Window   time.Duration `yaml:"window"`
+	Interval string        `yaml:"interval"`
+}
+
+// KlineDuration returns the length of a kline interval, a month counted
+// as 30 days, or 0 for an unknown one.
+func KlineDuration(interval string) time.Duration {
+	if !slices.Contains(KLINE_INTERVALS, interval) {
+		return 0
+	}
+	n, _ := strconv.Atoi(interval[:len(interval)-1])
+	unit := map[byte]time.Duration{'s': time.Second, 'm': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour, 'M': 30 * 24 * time.Hour}
+	return time.Duration(n) * unit[interval[len(interval)-1]]
+}
+
+// ReconnectConfig controls websocket keepalive and reconnect backoff.
+type ReconnectConfig struct {
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	PingPeriod     time.Duration `yaml:"ping_period"`
+	// FailoverAfter is how long a market may stay silent before a symbol's
+	// next failover market takes over.
+	FailoverAfter time.Duration `yaml:"failover_after"`
+	// PollInterval, when set, polls the REST ticker of symbols whose stream
+	// is down at this interval until it reconnects.
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// StaleAfter, when set, is the default stale_after of the symbols.
+	StaleAfter time.Duration `yaml:"stale_after"`
+}
+
+// validate checks the backoff and keepalive settings; okx keeps the ping
+// period within OKX's idle timeout.
+func (r ReconnectConfig) validate(okx bool) []error {
+	var errs []error
+	if r.InitialBackoff <= 0 || r.MaxBackoff < r.InitialBackoff {
+		errs = append(errs, errors.New("reconnect: need 0 < initial_backoff <= max_backoff"))
+	}
+	if r.PingPeriod <= 0 {
+		errs = append(errs, errors.New("reconnect: ping_period must be positive"))
+	}
+	if r.FailoverAfter <= 0 {
+		errs = append(errs, errors.New("reconnect: failover_after must be positive"))
+	}
+	if r.PollInterval != 0 && r.PollInterval < MIN_POLL_INTERVAL {
+		errs = append(errs, fmt.Errorf("reconnect: poll_interval must be 0 (off) or at least %v", MIN_POLL_INTERVAL))
+	}
+	if okx && r.PingPeriod >= OKX_IDLE_TIMEOUT {
+		errs = append(errs, fmt.Errorf("reconnect: okx drops connections idle for %v, ping_period must be shorter", OKX_IDLE_TIMEOUT))
+	}
+	return errs
+}
+
+// SecretsConfig locates credentials referenced as "secret:<name>" values.
+// They are looked up in the OS keyring under KeyringService, then in
+// PRICE_ALERT_SECRET_<NAME> environment variables, then as files in Dir.
+type SecretsConfig struct {
+	KeyringService string `yaml:"keyring_service"`
+	Dir            string `yaml:"dir"`
+}
+
+// UserDataConfig turns the fills of the account's own orders into alerts,
+// from the listen key user data stream of Exchange (binance or
+// binance-futures). It is off unless APIKey is set, normally as a
+// "secret:<name>" reference; the stream needs no signature, so a key
+// without trading permission is enough.
+type UserDataConfig struct {
+	Exchange string `yaml:"exchange"`
+	APIKey   string `yaml:"api_key"`
+	// PartialFills also alerts on every partial fill, not just the one
+	// that completes the order.
+	PartialFills bool `yaml:"partial_fills"`
+}
+
+// Log file rotation defaults: megabytes per file and rotated files kept.
+const (
+	LOG_MAX_SIZE    = 100
+	LOG_MAX_BACKUPS = 5
+)
+
+// Log color modes.
+
+const (
+	COLOR_AUTO   = "auto" // when stderr is a terminal and NO_COLOR is unset
+	COLOR_ALWAYS = "always"
+	COLOR_NEVER  = "never"
+)
+
+// LogConfig controls diagnostics. Level is one of debug (every tick), info
+// (alerts and lifecycle), warn (reconnects) or error. Format is console,
+// json, journal or auto (journal when stderr is the journal, otherwise
+// console); Color only applies to console.
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+	Color  string `yaml:"color"`
+	// File, when set, replaces stderr; it is rotated at MaxSize megabytes
+	// keeping MaxBackups old files, gzipped with Compress.
+	File       string `yaml:"file"`
+	MaxSize    int    `yaml:"max_size"`
+	MaxBackups int    `yaml:"max_backups"`
+	Compress   bool   `yaml:"compress"`
+}
+
+// validate checks the level, format, rotation and color.
+func (l LogConfig) validate() []error {
+	var errs []error
+	if _, err := logging.ParseLevel(l.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	switch l.Format {
+	case logging.FORMAT_AUTO, logging.FORMAT_CONSOLE, logging.FORMAT_JSON, logging.FORMAT_JOURNAL:
+	default:
+		errs = append(errs, fmt.Errorf("log.format must be auto, console, json or journal, not %q", l.Format))
+	}
+	if l.MaxSize < 0 || l.MaxBackups < 0 {
+		errs = append(errs, errors.New("log: max_size and max_backups must not be negative"))
+	}
+	switch l.Color {
+	case COLOR_AUTO, COLOR_ALWAYS, COLOR_NEVER:
+	default:
+		errs = append(errs, fmt.Errorf("log.color must be auto, always or never, not %q", l.Color))
+	}
+	return errs
+}
+
+// PermissionsConfig sets access to the IPC objects for multi-user machines.
+// Unset fields leave the process defaults (0666 minus umask, the creating
+// user's group, no privilege drop).
+type PermissionsConfig struct {
+	Mode  string `yaml:"mode"`  // octal, e.g. "0640"
+	Group string `yaml:"group"` // group name or numeric gid
+	// User is the account switched to once the IPC objects and the control
+	// socket exist; it needs starting as root.
+	User string `yaml:"user"`
+}
+
+// FileMode parses Mode. It reports false when no mode is configured.
+func (p PermissionsConfig) FileMode() (os.FileMode, bool, error) {
+	if p.Mode == "" {
+		return 0, false, nil
+	}
+	mode, err := strconv.ParseUint(p.Mode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, false, fmt.Errorf("permissions.mode %q is not an octal mode like 0640", p.Mode)
+	}
+	return os.FileMode(mode), true, nil
+}
+
+// SECRET_ENV_PREFIX prefixes environment variables holding secrets.
+const SECRET_ENV_PREFIX = ENV_PREFIX + "SECRET_"
+
+// Default returns the built-in single-symbol ETHUSDT configuration.
+func Default() Config {
+	return Config{
+		Source:         SOURCE_BINANCE,
+		Endpoint:       BINANCE_WS,
+		RESTEndpoint:   BINANCE_REST,
+		SHMPath:        SHM_PATH,
+		PipePath:       PIPE_PATH,
+		BufferSize:     BUFFER_SIZE,
+		StatsSize:      STATS_SIZE,
+		AlertPath:      ALERT_PATH,
+		AlertSize:      ALERT_SIZE,
+		Slots:          SLOTS,
+		ControlSocket:  CONTROL_SOCKET,
+		StatusInterval: STATUS_INTERVAL,
+		Symbols:        []SymbolConfig{{Symbol: SYMBOL, Step: STEP}},
+		Coinbase:       ExchangeConfig{Endpoint: COINBASE_WS, RESTEndpoint: COINBASE_REST},
+		Kraken:         ExchangeConfig{Endpoint: KRAKEN_WS, RESTEndpoint: KRAKEN_REST},
+		Bybit:          ExchangeConfig{Endpoint: BYBIT_SPOT_WS, RESTEndpoint: BYBIT_REST},
+		BybitLinear:    ExchangeConfig{Endpoint: BYBIT_LINEAR_WS, RESTEndpoint: BYBIT_REST},
+		OKX:            ExchangeConfig{Endpoint: OKX_WS, RESTEndpoint: OKX_REST},
+		BinanceFutures: ExchangeConfig{Endpoint: BINANCE_FUTURES_WS, RESTEndpoint: BINANCE_FUTURES_REST},
+		Reconnect: ReconnectConfig{
+			InitialBackoff: time.Second,
+			MaxBackoff:     MAX_BACKOFF,
+			PingPeriod:     PING_PERIOD,
+			FailoverAfter:  FAILOVER_AFTER,
+		},
+		Replay:    ReplayConfig{Speed: 1},
+		Backfill:  BackfillConfig{Interval: BACKFILL_INTERVAL},
+		UserData:  UserDataConfig{Exchange: SOURCE_BINANCE},
+		Stocks:    QuotesConfig{Provider: QUOTES_POLYGON, Interval: QUOTES_INTERVAL},
+		Forex:     QuotesConfig{Provider: QUOTES_TWELVE_DATA, Interval: QUOTES_INTERVAL},
+		Coins:     QuotesConfig{Provider: QUOTES_COINGECKO, Interval: COINS_INTERVAL},
+		Ethereum:  EthereumConfig{RPCURL: ETHEREUM_RPC, Interval: GAS_INTERVAL},
+		Chainlink: EthereumConfig{RPCURL: ETHEREUM_RPC, Interval: CHAINLINK_INTERVAL},
+		Uniswap:   EthereumConfig{RPCURL: ETHEREUM_RPC, Interval: UNISWAP_INTERVAL},
+		Custom:    CustomConfig{Interval: CUSTOM_INTERVAL},
+		Log: LogConfig{
+			Level:      "info",
+			Format:     logging.FORMAT_AUTO,
+			Color:      COLOR_AUTO,
+			MaxSize:    LOG_MAX_SIZE,
+			MaxBackups: LOG_MAX_BACKUPS,
+		},
+		Secrets: SecretsConfig{
+			KeyringService: "price-alert",
+			// Set by systemd for units using LoadCredential=
+			Dir: os.Getenv("CREDENTIALS_DIRECTORY"),
+		},
+		Profiles: builtinProfiles(),
+	}
+}
+
+// builtinProfiles are always available; a profile of the same name in the
+// config file is merged over them. The test profiles use their own IPC
+// paths so they never clobber live data; binance-us is live data for US
+// users and only swaps the endpoints.
+func builtinProfiles() map[string]Config {
+	return map[string]Config{
+		"prod": {},
+		"binance-us": {
+			Endpoint:     BINANCE_US_WS,
+			RESTEndpoint: BINANCE_US_REST,
+		},
+		"testnet": {Testnet: true},
+		"replay": {
+			Source:        SOURCE_REPLAY,
+			SHMPath:       "/dev/shm/price_alert_replay_shm",
+			PipePath:      "/tmp/price_alert_replay_pipe",
+			ControlSocket: "/tmp/price_alert_replay.sock",
+			AlertPath:     "/dev/shm/price_alert_replay_alerts",
+			Replay:        ReplayConfig{File: "ticks.csv"},
+		},
+	}
+}
+
+// ApplyProfile merges the named profile over c.
+func (c *Config) ApplyProfile(name string) error {
+	p, ok := c.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(c.Profiles))
+		for n := range c.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown profile %q (have %s)", name, strings.Join(names, ", "))
+	}
+	c.merge(p)
+	c.Profile = name
+	return nil
+}
+
+// LoadFile reads a YAML file on top of the defaults. Missing keys keep
+// their default values.
+func LoadFile(path string) (Config, error) {
+	cfg := Default()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	var file Config
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return cfg, fmt.Errorf("parse %s: %w", path, err)
+	}
+	cfg.merge(file)
+	return cfg, nil
+}
+
+// merge copies every non-zero field of o into c. Profiles are merged by
+// name.
+func (c *Config) merge(o Config) {
+	if o.Source != "" {
+		c.Source = o.Source
+	}
+	if o.Testnet {
+		c.Testnet = true
+	}
+	if o.Endpoint != "" {
+		c.Endpoint = o.Endpoint
+	}
+	if o.RESTEndpoint != "" {
+		c.RESTEndpoint = o.RESTEndpoint
+	}
+	if o.SHMPath != "" {
+		c.SHMPath = o.SHMPath
+	}
+	if o.PipePath != "" {
+		c.PipePath = o.PipePath
+	}
+	if o.ControlSocket != "" {
+		c.ControlSocket = o.ControlSocket
+	}
+	if o.StatsPath != "" {
+		c.StatsPath = o.StatsPath
+	}
+	if o.PIDFile != "" {
+		c.PIDFile = o.PIDFile
+	}
+	if o.Locale != "" {
+		c.Locale = o.Locale
+	}
+	if o.StatusFile != "" {
+		c.StatusFile = o.StatusFile
+	}
+	if o.ATHFile != "" {
+		c.ATHFile = o.ATHFile
+	}
+	if o.CandleFile != "" {
+		c.CandleFile = o.CandleFile
+	}
+	if o.StatusInterval > 0 {
+		c.StatusInterval = o.StatusInterval
+	}
+	if o.BufferSize > 0 {
+		c.BufferSize = o.BufferSize
+	}
+	if o.StatsSize > 0 {
+		c.StatsSize = o.StatsSize
+	}
+	if o.AlertPath != "" {
+		c.AlertPath = o.AlertPath
+	}
+	if o.AlertSize > 0 {
+		c.AlertSize = o.AlertSize
+	}
+	if o.AlertSeverity != "" {
+		c.AlertSeverity = o.AlertSeverity
+	}
+	if o.Slots > 0 {
+		c.Slots = o.Slots
+	}
+	if len(o.Symbols) > 0 {
+		c.Symbols = o.Symbols
+	}
+	if o.Coinbase.Endpoint != "" {
+		c.Coinbase.Endpoint = o.Coinbase.Endpoint
+	}
+	if o.Coinbase.RESTEndpoint != "" {
+		c.Coinbase.RESTEndpoint = o.Coinbase.RESTEndpoint
+	}
+	if o.Kraken.Endpoint != "" {
+		c.Kraken.Endpoint = o.Kraken.Endpoint
+	}
+	if o.Kraken.RESTEndpoint != "" {
+		c.Kraken.RESTEndpoint = o.Kraken.RESTEndpoint
+	}
+	if o.Bybit.Endpoint != "" {
+		c.Bybit.Endpoint = o.Bybit.Endpoint
+	}
+	if o.Bybit.RESTEndpoint != "" {
+		c.Bybit.RESTEndpoint = o.Bybit.RESTEndpoint
+	}
+	if o.BybitLinear.Endpoint != "" {
+		c.BybitLinear.Endpoint = o.BybitLinear.Endpoint
+	}
+	if o.BybitLinear.RESTEndpoint != "" {
+		c.BybitLinear.RESTEndpoint = o.BybitLinear.RESTEndpoint
+	}
+	if o.BinanceFutures.Endpoint != "" {
+		c.BinanceFutures.Endpoint = o.BinanceFutures.Endpoint
+	}
+	if o.BinanceFutures.RESTEndpoint != "" {
+		c.BinanceFutures.RESTEndpoint = o.BinanceFutures.RESTEndpoint
+	}
+	if o.OKX.Endpoint != "" {
+		c.OKX.Endpoint = o.OKX.Endpoint
+	}
+	if o.OKX.RESTEndpoint != "" {
+		c.OKX.RESTEndpoint = o.OKX.RESTEndpoint
+	}
+	c.Stocks.merge(o.Stocks)
+	c.Forex.merge(o.Forex)
+	c.Coins.merge(o.Coins)
+	c.Ethereum.merge(o.Ethereum)
+	c.Chainlink.merge(o.Chainlink)
+	c.Uniswap.merge(o.Uniswap)
+	c.Custom.merge(o.Custom)
+	if len(o.Plugins) > 0 {
+		plugins := maps.Clone(c.Plugins)
+		if plugins == nil {
+			plugins = make(map[string]PluginConfig)
+		}
+		maps.Copy(plugins, o.Plugins)
+		c.Plugins = plugins
+	}
+	if len(o.Actions) > 0 {
+		actions := maps.Clone(c.Actions)
+		if actions == nil {
+			actions = make(map[string]ActionConfig)
+		}
+		maps.Copy(actions, o.Actions)
+		c.Actions = actions
+	}
+	if len(o.Sessions) > 0 {
+		sessions := maps.Clone(c.Sessions)
+		if sessions == nil {
+			sessions = make(map[string]SessionConfig)
+		}
+		maps.Copy(sessions, o.Sessions)
+		c.Sessions = sessions
+	}
+	if len(o.Notifiers) > 0 {
+		c.Notifiers = o.Notifiers
+	}
+	if o.QuietHours.Enabled() {
+		c.QuietHours = o.QuietHours
+	}
+	if o.Escalation.Count > 0 {
+		c.Escalation = o.Escalation
+	}
+	if o.DedupWindow > 0 {
+		c.DedupWindow = o.DedupWindow
+	}
+	if o.Reconnect.InitialBackoff > 0 {
+		c.Reconnect.InitialBackoff = o.Reconnect.InitialBackoff
+	}
+	if o.Reconnect.MaxBackoff > 0 {
+		c.Reconnect.MaxBackoff = o.Reconnect.MaxBackoff
+	}
+	if o.Reconnect.PingPeriod > 0 {
+		c.Reconnect.PingPeriod = o.Reconnect.PingPeriod
+	}
+	if o.Reconnect.FailoverAfter > 0 {
+		c.Reconnect.FailoverAfter = o.Reconnect.FailoverAfter
+	}
+	if o.Reconnect.PollInterval > 0 {
+		c.Reconnect.PollInterval = o.Reconnect.PollInterval
+	}
+	if o.Reconnect.StaleAfter > 0 {
+		c.Reconnect.StaleAfter = o.Reconnect.StaleAfter
+	}
+	if o.Secrets.KeyringService != "" {
+		c.Secrets.KeyringService = o.Secrets.KeyringService
+	}
+	if o.Secrets.Dir != "" {
+		c.Secrets.Dir = o.Secrets.Dir
+	}
+	if o.UserData.Exchange != "" {
+		c.UserData.Exchange = o.UserData.Exchange
+	}
+	if o.UserData.APIKey != "" {
+		c.UserData.APIKey = o.UserData.APIKey
+	}
+	if o.UserData.PartialFills {
+		c.UserData.PartialFills = true
+	}
+	if o.Replay.File != "" {
+		c.Replay.File = o.Replay.File
+	}
+	if o.Log.Level != "" {
+		c.Log.Level = o.Log.Level
+	}
+	if o.Log.Format != "" {
+		c.Log.Format = o.Log.Format
+	}
+	if o.Log.Color != "" {
+		c.Log.Color = o.Log.Color
+	}
+	if o.Log.File != "" {
+		c.Log.File = o.Log.File
+	}
+	if o.Log.MaxSize > 0 {
+		c.Log.MaxSize = o.Log.MaxSize
+	}
+	if o.Log.MaxBackups > 0 {
+		c.Log.MaxBackups = o.Log.MaxBackups
+	}
+	if o.Log.Compress {
+		c.Log.Compress = true
+	}
+	if o.Permissions.Mode != "" {
+		c.Permissions.Mode = o.Permissions.Mode
+	}
+	if o.Permissions.Group != "" {
+		c.Permissions.Group = o.Permissions.Group
+	}
+	if o.Permissions.User != "" {
+		c.Permissions.User = o.Permissions.User
+	}
+	if o.Replay.Speed > 0 {
+		c.Replay.Speed = o.Replay.Speed
+	}
+	if o.Backfill.Window > 0 {
+		c.Backfill.Window = o.Backfill.Window
+	}
+	if o.Backfill.Interval != "" {
+		c.Backfill.Interval = o.Backfill.Interval
+	}
+	for name, p := range o.Profiles {
+		if c.Profiles == nil {
+			c.Profiles = make(map[string]Config)
+		}
+		base := c.Profiles[name]
+		base.merge(p)
+		c.Profiles[name] = base
+	}
+}
+
+// applyTestnet moves the Binance endpoints and IPC paths still at their
+// defaults to the testnet ones, so a testnet run neither streams live
+// prices nor clobbers the live SHM region. Explicit settings, e.g. a local
+// proxy, are kept.
+func (c *Config) applyTestnet() {
+	for _, s := range []struct {
+		dst           *string
+		live, testnet string
+	}{
+		{&c.Endpoint, BINANCE_WS, BINANCE_TESTNET_WS},
+		{&c.RESTEndpoint, BINANCE_REST, BINANCE_TESTNET_REST},
+		{&c.BinanceFutures.Endpoint, BINANCE_FUTURES_WS, BINANCE_FUTURES_TESTNET_WS},
+		{&c.BinanceFutures.RESTEndpoint, BINANCE_FUTURES_REST, BINANCE_FUTURES_TESTNET_REST},
+		{&c.SHMPath, SHM_PATH, TESTNET_SHM_PATH},
+		{&c.PipePath, PIPE_PATH, TESTNET_PIPE_PATH},
+		{&c.AlertPath, ALERT_PATH, TESTNET_ALERT_PATH},
+		{&c.ControlSocket, CONTROL_SOCKET, TESTNET_CONTROL_SOCKET},
+	} {
+		if *s.dst == s.live {
+			*s.dst = s.testnet
+		}
+	}
+}
+
+// Validate normalises symbol names and reports every invalid setting.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Testnet {
+		c.applyTestnet()
+	}
+	switch c.Source {
+	case SOURCE_BINANCE, SOURCE_BINANCE_FUTURES, SOURCE_COINBASE, SOURCE_KRAKEN, SOURCE_BYBIT, SOURCE_BYBIT_LINEAR, SOURCE_OKX, SOURCE_STOCKS, SOURCE_FOREX, SOURCE_ETHEREUM, SOURCE_COINS, SOURCE_CHAINLINK, SOURCE_UNISWAP, SOURCE_CUSTOM, SOURCE_PLUGIN:
+	case SOURCE_REPLAY:
+		if c.Replay.File == "" {
+			errs = append(errs, errors.New("replay.file is required for the replay source"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown source %q", c.Source))
+	}
+	if c.Replay.Speed < 0 {
+		errs = append(errs, errors.New("replay.speed must not be negative"))
+	}
+	if c.Endpoint == "" {
+		errs = append(errs, errors.New("endpoint must not be empty"))
+	}
+	if c.RESTEndpoint == "" {
+		errs = append(errs, errors.New("rest_endpoint must not be empty"))
+	}
+	if c.BinanceFutures.Endpoint == "" || c.BinanceFutures.RESTEndpoint == "" {
+		errs = append(errs, errors.New("binance_futures: endpoint and rest_endpoint must not be empty"))
+	}
+	if c.Coinbase.Endpoint == "" || c.Coinbase.RESTEndpoint == "" {
+		errs = append(errs, errors.New("coinbase: endpoint and rest_endpoint must not be empty"))
+	}
+	if c.Kraken.Endpoint == "" || c.Kraken.RESTEndpoint == "" {
+		errs = append(errs, errors.New("kraken: endpoint and rest_endpoint must not be empty"))
+	}
+	if c.Bybit.Endpoint == "" || c.Bybit.RESTEndpoint == "" {
+		errs = append(errs, errors.New("bybit: endpoint and rest_endpoint must not be empty"))
+	}
+	if c.BybitLinear.Endpoint == "" || c.BybitLinear.RESTEndpoint == "" {
+		errs = append(errs, errors.New("bybit_linear: endpoint and rest_endpoint must not be empty"))
+	}
+	if c.OKX.Endpoint == "" || c.OKX.RESTEndpoint == "" {
+		errs = append(errs, errors.New("okx: endpoint and rest_endpoint must not be empty"))
+	}
+	if c.SHMPath == "" {
+		errs = append(errs, errors.New("shm_path must not be empty"))
+	}
+	if c.PipePath == "" {
+		errs = append(errs, errors.New("pipe_path must not be empty"))
+	}
+	if c.BufferSize < MIN_BUFFER_SIZE {
+		errs = append(errs, fmt.Errorf("buffer_size %d is below the minimum of %d", c.BufferSize, MIN_BUFFER_SIZE))
+	}
+	if c.StatsSize < STATS_SIZE {
+		errs = append(errs, fmt.Errorf("stats_size %d is below the minimum of %d", c.StatsSize, STATS_SIZE))
+	}
+	if c.AlertPath == "" {
+		errs = append(errs, errors.New(`alert_path must not be empty, use "off" to disable it`))
+	}
+	if c.AlertSize < MIN_ALERT_SIZE {
+		errs = append(errs, fmt.Errorf("alert_size %d is below the minimum of %d", c.AlertSize, MIN_ALERT_SIZE))
+	}
+	if c.AlertSeverity = strings.ToLower(c.AlertSeverity); c.AlertSeverity == "" {
+		c.AlertSeverity = SEVERITIES[0]
+	}
+	if !slices.Contains(SEVERITIES, c.AlertSeverity) {
+		errs = append(errs, fmt.Errorf("alert_severity must be one of %s", strings.Join(SEVERITIES, ", ")))
+	}
+	if c.Slots < 1 || c.Slots > MAX_SLOTS {
+		errs = append(errs, fmt.Errorf("slots must be between 1 and %d", MAX_SLOTS))
+	}
+	switch n := len(c.Symbols); {
+	case n == 0:
+		errs = append(errs, errors.New("at least one symbol is required"))
+	case n > c.Slots:
+		errs = append(errs, fmt.Errorf("%d symbols configured but only %d SHM slots", n, c.Slots))
+	}
+	// Sources are markets, so spelled like symbols
+	sources := make(map[string]CustomSource, len(c.Custom.Sources))
+	for name, src := range c.Custom.Sources {
+		sources[strings.ToLower(strings.TrimSpace(name))] = src
+	}
+	c.Custom.Sources = sources
+	plugins := make(map[string]PluginConfig, len(c.Plugins))
+	for name, p := range c.Plugins {
+		name = strings.ToLower(strings.TrimSpace(name))
+		errs = append(errs, p.validate(name)...)
+		plugins[name] = p
+	}
+	c.Plugins = plugins
+	for name, a := range c.Actions {
+		errs = append(errs, a.validate(name)...)
+		c.Actions[name] = a
+	}
+	for i := range c.Notifiers {
+		errs = append(errs, within(fmt.Sprintf("notifiers[%d]", i), c.Notifiers[i].validate(c.Actions))...)
+	}
+	errs = append(errs, c.Escalation.validate(c.Actions)...)
+	for name, ss := range c.Sessions {
+		errs = append(errs, ss.validate(name)...)
+		c.Sessions[name] = ss
+	}
+	errs = append(errs, c.QuietHours.validate()...)
+	seen := make(map[string]bool)
+	streamed := make(map[string]bool) // "exchange symbol"
+	used := make(map[string]bool)     // exchanges
+	for i := range c.Symbols {
+		s := &c.Symbols[i]
+		at := fmt.Sprintf("symbols[%d]", i)
+		s.Symbol = strings.ToLower(strings.TrimSpace(s.Symbol))
+		if s.Symbol == "" {
+			errs = append(errs, fmt.Errorf("%s: symbol must not be empty", at))
+		} else if seen[s.Symbol] {
+			errs = append(errs, fmt.Errorf("%s: duplicate symbol %s", at, s.Symbol))
+		}
+		seen[s.Symbol] = true
+		errs = append(errs, within(at, c.validateSymbol(s))...)
+		for _, mk := range c.Markets(*s) {
+			if key := mk.Exchange + " " + mk.Symbol; streamed[key] {
+				errs = append(errs, fmt.Errorf("%s: %s on %s is already streamed", at, mk.Symbol, mk.Exchange))
+			} else {
+				streamed[key] = true
+			}
+			used[mk.Exchange] = true
+			errs = append(errs, within(at, c.validateMarket(*s, mk))...)
+		}
+	}
+	errs = append(errs, c.Reconnect.validate(used[SOURCE_OKX])...)
+	errs = append(errs, c.Stocks.validate(SOURCE_STOCKS, used[SOURCE_STOCKS] && c.Source != SOURCE_REPLAY)...)
+	errs = append(errs, c.Forex.validate(SOURCE_FOREX, used[SOURCE_FOREX] && c.Source != SOURCE_REPLAY)...)
+	errs = append(errs, c.Coins.validate(SOURCE_COINS, used[SOURCE_COINS] && c.Source != SOURCE_REPLAY)...)
+	errs = append(errs, c.Ethereum.validate(SOURCE_ETHEREUM)...)
+	errs = append(errs, c.Chainlink.validate(SOURCE_CHAINLINK)...)
+	errs = append(errs, c.Uniswap.validate(SOURCE_UNISWAP)...)
+	errs = append(errs, c.Custom.validate()...)
+	for i := range c.Symbols {
+		errs = append(errs, within(fmt.Sprintf("symbols[%d]", i), c.validateLinks(&c.Symbols[i]))...)
+	}
+	if d := KlineDuration(c.Backfill.Interval); d == 0 {
+		errs = append(errs, fmt.Errorf("backfill: unknown interval %q (have %s)", c.Backfill.Interval, strings.Join(KLINE_INTERVALS, ", ")))
+	} else if c.Backfill.Window < 0 || c.Backfill.Window > MAX_BACKFILL_KLINES*d {
+		errs = append(errs, fmt.Errorf("backfill: window must be between 0 and %d klines of %s", MAX_BACKFILL_KLINES, c.Backfill.Interval))
+	}
+	if c.StatusInterval <= 0 {
+		errs = append(errs, errors.New("status_interval must be positive"))
+	}
+	if c.DedupWindow < 0 {
+		errs = append(errs, errors.New("dedup_window must not be negative"))
+	}
+	switch c.UserData.Exchange {
+	case SOURCE_BINANCE, SOURCE_BINANCE_FUTURES:
+	default:
+		errs = append(errs, fmt.Errorf("user_data: exchange must be %s or %s", SOURCE_BINANCE, SOURCE_BINANCE_FUTURES))
+	}
+	if _, err := numfmt.Parse(c.Locale); err != nil {
+		errs = append(errs, fmt.Errorf("locale: %w", err))
+	}
+	if _, _, err := c.Permissions.FileMode(); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, c.Log.validate()...)
+	return errors.Join(errs...)
+}
+
+// within prefixes each of errs with at, where the setting they report is,
+// such as symbols[2].
+func within(at string, errs []error) []error {
+	for i, err := range errs {
+		errs[i] = fmt.Errorf("%s: %w", at, err)
+	}
+	return errs
+}
+
+// ControlPath returns the control socket path, or "" when it is disabled.
+func (c Config) ControlPath() string {
+	if c.ControlSocket == "off" {
+		return ""
+	}
+	return c.ControlSocket
+}
+
+// AlertRegion returns the alert region path, or "" when it is disabled.
+func (c Config) AlertRegion() string {
+	if c.AlertPath == "off" {
+		return ""
+	}
+	return c.AlertPath
+}
+
+// SymbolNames returns the configured symbols in slot order.
+func (c Config) SymbolNames() []string {
+	names := make([]string, len(c.Symbols))
+	for i, s := range c.Symbols {
+		names[i] = s.Symbol
+	}
+	return names
+}
+
+// Slot returns the SHM slot of symbol, or -1 when it is not configured.
+func (c Config) Slot(symbol string) int {
+	for i, s := range c.Symbols {
+		if strings.EqualFold(s.Symbol, symbol) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Symbol returns the config of symbol, or nil when it is not configured.
+func (c Config) Symbol(symbol string) *SymbolConfig {
+	if i := c.Slot(symbol); i >= 0 {
+		return &c.Symbols[i]
+	}
+	return nil
+}
+
+// validateMarket checks mk, one of the markets of s: its spelling, the
+// custom source or plugin it names, the stream of s on it and testnet.
+func (c *Config) validateMarket(s SymbolConfig, mk MarketConfig) []error {
+	var errs []error
+	if err := checkMarket(mk); err != nil {
+		errs = append(errs, err)
+	}
+	if _, ok := c.Custom.Sources[mk.Symbol]; mk.Exchange == SOURCE_CUSTOM && !ok {
+		errs = append(errs, fmt.Errorf("%s is not one of custom.sources", mk.Symbol))
+	}
+	if plugin, _ := PluginMarket(mk.Symbol); mk.Exchange == SOURCE_PLUGIN && c.Plugins[plugin].Command == "" {
+		errs = append(errs, fmt.Errorf("%s is not one of plugins", plugin))
+	}
+	if err := checkStream(s.Stream, mk.Exchange); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Testnet && c.Source != SOURCE_REPLAY && mk.Exchange != SOURCE_BINANCE && mk.Exchange != SOURCE_BINANCE_FUTURES && mk.Exchange != SOURCE_BASKET {
+		errs = append(errs, fmt.Errorf("testnet covers Binance only, %s streams from %s", mk.Symbol, mk.Exchange))
+	}
+	return errs
+}
+
+// checkMarket checks that m names a known exchange and is written the way
+// that exchange spells its markets.
+func checkMarket(m MarketConfig) error {
+	switch m.Exchange {
+	case SOURCE_BINANCE, SOURCE_BINANCE_FUTURES, SOURCE_BYBIT, SOURCE_BYBIT_LINEAR, SOURCE_ETHEREUM, SOURCE_CUSTOM, SOURCE_BASKET:
+	case SOURCE_COINBASE:
+		if !strings.Contains(m.Symbol, "-") {
+			return fmt.Errorf("coinbase products are written like eth-usd, not %s", m.Symbol)
+		}
+	case SOURCE_OKX:
+		if !strings.Contains(m.Symbol, "-") {
+			return fmt.Errorf("okx instruments are written like btc-usdt, not %s", m.Symbol)
+		}
+	case SOURCE_KRAKEN:
+		if !strings.Contains(m.Symbol, "/") {
+			return fmt.Errorf("kraken pairs are written like eth/usd, not %s", m.Symbol)
+		}
+	case SOURCE_STOCKS:
+		if strings.ContainsAny(m.Symbol, "-/") {
+			return fmt.Errorf("stocks are written as tickers like spy or brk.b, not %s", m.Symbol)
+		}
+	case SOURCE_FOREX:
+		if base, quote, ok := strings.Cut(m.Symbol, "/"); !ok || len(base) != 3 || len(quote) != 3 {
+			return fmt.Errorf("forex pairs are written like eur/usd or xau/usd, not %s", m.Symbol)
+		}
+	case SOURCE_COINS:
+		if coin, currency, _ := strings.Cut(m.Symbol, "/"); coin == "" || strings.Contains(currency, "/") {
+			return fmt.Errorf("coins are written as the provider's coin, optionally with a currency, like pepe or bitcoin/eur, not %s", m.Symbol)
+		}
+	case SOURCE_CHAINLINK:
+		base, quote, pair := strings.Cut(m.Symbol, "/")
+		address := strings.HasPrefix(m.Symbol, "0x") && len(m.Symbol) == 42
+		if !address && (!pair || base == "" || quote == "") {
+			return fmt.Errorf("chainlink feeds are written as their address or a pair like eth/usd, not %s", m.Symbol)
+		}
+	case SOURCE_UNISWAP:
+		pool := strings.TrimPrefix(m.Symbol, "1/")
+		base, quote, pair := strings.Cut(m.Symbol, "/")
+		address := strings.HasPrefix(pool, "0x") && len(pool) == 42
+		if !address && (!pair || base == "" || quote == "") {
+			return fmt.Errorf("uniswap pools are written as their address, 1/<address> for the inverse price, or a pair like eth/usdc, not %s", m.Symbol)
+		}
+	case SOURCE_PLUGIN:
+		if plugin, symbol := PluginMarket(m.Symbol); plugin == "" || symbol == "" {
+			return fmt.Errorf("plugin markets are written as <plugin>:<symbol>, like myfeed:btc-usd, not %s", m.Symbol)
+		}
+	default:
+		return fmt.Errorf("unknown exchange %q", m.Exchange)
+	}
+	return nil
+}
+
+// canonicalStream spells a stream type the way Binance expects it,
+// whatever its case.
+func canonicalStream(stream string) string {
+	// Interval units are case-sensitive: 1m is a minute, 1M a month
+	if len(stream) > len(STREAM_KLINE) && strings.EqualFold(stream[:len(STREAM_KLINE)], STREAM_KLINE) {
+		return STREAM_KLINE + stream[len(STREAM_KLINE):]
+	}
+	for _, known := range []string{STREAM_TRADE, STREAM_AGG_TRADE, STREAM_BOOK_TICKER, STREAM_MARK_PRICE, STREAM_MARK_PRICE_1S, STREAM_MINI_TICKER, STREAM_TICKER} {
+		if strings.EqualFold(stream, known) {
+			return known
+		}
+	}
+	return stream
+}
+
+// checkStream checks that a market on exchange offers stream.
+func checkStream(stream, exchange string) error {
+	switch stream {
+	case "", STREAM_TRADE:
+	case STREAM_AGG_TRADE, STREAM_BOOK_TICKER, STREAM_MINI_TICKER, STREAM_TICKER:
+		if exchange != SOURCE_BINANCE && exchange != SOURCE_BINANCE_FUTURES {
+			return fmt.Errorf("stream %s needs binance or binance-futures, not %s", stream, exchange)
+		}
+	case STREAM_MARK_PRICE, STREAM_MARK_PRICE_1S:
+		if exchange != SOURCE_BINANCE_FUTURES {
+			return fmt.Errorf("stream %s needs binance-futures, not %s", stream, exchange)
+		}
+	default:
+		if interval, ok := strings.CutPrefix(stream, STREAM_KLINE); ok {
+			switch {
+			case !slices.Contains(KLINE_INTERVALS, interval):
+				return fmt.Errorf("unknown kline interval %q (have %s)", interval, strings.Join(KLINE_INTERVALS, ", "))
+			case exchange != SOURCE_BINANCE && exchange != SOURCE_BINANCE_FUTURES:
+				return fmt.Errorf("stream %s needs binance or binance-futures, not %s", stream, exchange)
+			case interval == "1s" && exchange != SOURCE_BINANCE:
+				return fmt.Errorf("stream %s is spot only", stream)
+			}
+			return nil
+		}
+		return fmt.Errorf("unknown stream %q (have trade, aggTrade, bookTicker, miniTicker, ticker, kline_<interval>, markPrice, markPrice@1s)", stream)
+	}
+	return nil
+}
+
+// Markets returns the exchange markets s is streamed from: its markets
+// list, or the symbol itself on its exchange followed by its failover
+// markets. Each defaults to the symbol's exchange and name.
+func (c Config) Markets(s SymbolConfig) []MarketConfig {
+	list := s.Markets
+	if len(list) == 0 {
+		list = append([]MarketConfig{{}}, s.Failover...)
+	}
+	out := make([]MarketConfig, len(list))
+	for i, m := range list {
+		if m.Exchange == "" {
+			m.Exchange = c.ExchangeOf(s)
+		}
+		if m.Symbol == "" {
+			m.Symbol = s.Symbol
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// MarketSlot returns the SHM slot of the symbol streamed from market on
+// exchange, or -1.
+func (c Config) MarketSlot(exchange, market string) int {
+	for i, s := range c.Symbols {
+		for _, m := range c.Markets(s) {
+			if m.Exchange == exchange && m.Symbol == market {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// ExchangeOf returns the exchange streaming s: its own exchange, basket for
+// a basket or ratio, else the source. Replays count as Binance, whose tick sizes
+// they use.
+func (c Config) ExchangeOf(s SymbolConfig) string {
+	switch {
+	case s.Exchange != "":
+		return s.Exchange
+	case s.Derived():
+		return SOURCE_BASKET
+	case c.Source == SOURCE_REPLAY:
+		return SOURCE_BINANCE
+	}
+	return c.Source
+}
+
+// Endpoints returns the websocket and REST endpoints of exchange.
+func (c Config) Endpoints(exchange string) (ws, rest string) {
+	switch exchange {
+	case SOURCE_COINBASE:
+		return c.Coinbase.Endpoint, c.Coinbase.RESTEndpoint
+	case SOURCE_KRAKEN:
+		return c.Kraken.Endpoint, c.Kraken.RESTEndpoint
+	case SOURCE_BYBIT:
+		return c.Bybit.Endpoint, c.Bybit.RESTEndpoint
+	case SOURCE_BYBIT_LINEAR:
+		return c.BybitLinear.Endpoint, c.BybitLinear.RESTEndpoint
+	case SOURCE_OKX:
+		return c.OKX.Endpoint, c.OKX.RESTEndpoint
+	case SOURCE_BINANCE_FUTURES:
+		return c.BinanceFutures.Endpoint, c.BinanceFutures.RESTEndpoint
+	case SOURCE_STOCKS, SOURCE_FOREX, SOURCE_COINS:
+		return "", c.Quotes(exchange).RESTEndpoint
+	case SOURCE_ETHEREUM:
+		return "", c.Ethereum.RPCURL
+	case SOURCE_CHAINLINK:
+		return "", c.Chainlink.RPCURL
+	case SOURCE_UNISWAP:
+		return "", c.Uniswap.RPCURL
+	case SOURCE_CUSTOM, SOURCE_PLUGIN, SOURCE_BASKET:
+		return "", ""
+	}
+	return c.Endpoint, c.RESTEndpoint
+}
+
+// SelectSymbols replaces the configured symbols with a comma-separated list,
+// keeping the settings of symbols that were already configured.
+func (c *Config) SelectSymbols(list string) {
+	var symbols []SymbolConfig
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if s := c.Symbol(name); s != nil {
+			symbols = append(symbols, *s)
+		} else {
+			symbols = append(symbols, SymbolConfig{Symbol: name})
+		}
+	}
+	c.Symbols = symbols
+}
+
+// TickLookup returns the tick size of a symbol and its number of decimals.
+type TickLookup func(symbol string) (tick float64, decimals int, err error)
+
+// ResolveDefaults fills in step, rounding and precision for symbols that
+// leave them unset, deriving them from the tick size returned by lookup.
+// When the lookup fails the historical defaults are used.
+func (c *Config) ResolveDefaults(lookup TickLookup) {
+	for i := range c.Symbols {
+		s := &c.Symbols[i]
+		if s.Step > 0 && s.Precision != nil {
+			if s.Rounding == 0 {
+				s.Rounding = s.Step
+			}
+			continue
+		}
+
+		tick, decimals, err := lookup(s.Symbol)
+		if err != nil || tick <= 0 {
+			logging.Warnf("%s: no tick size (%v), using default step/precision", s.Label(), err)
+			tick, decimals = 0, 2
+		}
+
+		if s.Step == 0 {
+			if tick > 0 {
+				// Round away float noise from tick * DEFAULT_STEP_TICKS
+				scale := math.Pow10(decimals)
+				s.Step = math.Round(tick*DEFAULT_STEP_TICKS*scale) / scale
+			} else {
+				s.Step = STEP
+			}
+		}
+		if s.Rounding == 0 {
+			s.Rounding = s.Step
+		}
+		if s.Precision == nil {
+			s.Precision = &decimals
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ENV_PREFIX prefixes every environment variable read by ApplyEnv.
+const ENV_PREFIX = "PRICE_ALERT_"
+
+// ApplyEnv overrides c with any PRICE_ALERT_* variables that are set.
+// Environment values take precedence over both the config file and flags.
+func (c *Config) ApplyEnv() error {
+	var errs []error
+	str := func(name string, dst *string) {
+		if v, ok := os.LookupEnv(ENV_PREFIX + name); ok {
+			*dst = v
+		}
+	}
+	boolean := func(name string, dst *bool) {
+		if v, ok := os.LookupEnv(ENV_PREFIX + name); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s%s: %w", ENV_PREFIX, name, err))
+				return
+			}
+			*dst = b
+		}
+	}
+	dur := func(name string, dst *time.Duration) {
+		if v, ok := os.LookupEnv(ENV_PREFIX + name); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s%s: %w", ENV_PREFIX, name, err))
+				return
+			}
+			*dst = d
+		}
+	}
+
+	str("SOURCE", &c.Source)
+	boolean("TESTNET", &c.Testnet)
+	str("ENDPOINT", &c.Endpoint)
+	str("REST_ENDPOINT", &c.RESTEndpoint)
+	str("BINANCE_FUTURES_ENDPOINT", &c.BinanceFutures.Endpoint)
+	str("BINANCE_FUTURES_REST_ENDPOINT", &c.BinanceFutures.RESTEndpoint)
+	str("COINBASE_ENDPOINT", &c.Coinbase.Endpoint)
+	str("COINBASE_REST_ENDPOINT", &c.Coinbase.RESTEndpoint)
+	str("KRAKEN_ENDPOINT", &c.Kraken.Endpoint)
+	str("KRAKEN_REST_ENDPOINT", &c.Kraken.RESTEndpoint)
+	str("BYBIT_ENDPOINT", &c.Bybit.Endpoint)
+	str("BYBIT_REST_ENDPOINT", &c.Bybit.RESTEndpoint)
+	str("BYBIT_LINEAR_ENDPOINT", &c.BybitLinear.Endpoint)
+	str("BYBIT_LINEAR_REST_ENDPOINT", &c.BybitLinear.RESTEndpoint)
+	str("OKX_ENDPOINT", &c.OKX.Endpoint)
+	str("OKX_REST_ENDPOINT", &c.OKX.RESTEndpoint)
+	str("STOCKS_PROVIDER", &c.Stocks.Provider)
+	str("STOCKS_REST_ENDPOINT", &c.Stocks.RESTEndpoint)
+	str("STOCKS_API_KEY", &c.Stocks.APIKey)
+	dur("STOCKS_INTERVAL", &c.Stocks.Interval)
+	str("FOREX_PROVIDER", &c.Forex.Provider)
+	str("FOREX_REST_ENDPOINT", &c.Forex.RESTEndpoint)
+	str("FOREX_API_KEY", &c.Forex.APIKey)
+	dur("FOREX_INTERVAL", &c.Forex.Interval)
+	str("COINS_PROVIDER", &c.Coins.Provider)
+	str("COINS_REST_ENDPOINT", &c.Coins.RESTEndpoint)
+	str("COINS_API_KEY", &c.Coins.APIKey)
+	dur("COINS_INTERVAL", &c.Coins.Interval)
+	str("ETHEREUM_RPC_URL", &c.Ethereum.RPCURL)
+	dur("ETHEREUM_INTERVAL", &c.Ethereum.Interval)
+	str("CHAINLINK_RPC_URL", &c.Chainlink.RPCURL)
+	dur("CHAINLINK_INTERVAL", &c.Chainlink.Interval)
+	str("UNISWAP_RPC_URL", &c.Uniswap.RPCURL)
+	dur("UNISWAP_INTERVAL", &c.Uniswap.Interval)
+	dur("CUSTOM_INTERVAL", &c.Custom.Interval)
+	str("SHM_PATH", &c.SHMPath)
+	str("PIPE_PATH", &c.PipePath)
+	str("CONTROL_SOCKET", &c.ControlSocket)
+	str("STATS_PATH", &c.StatsPath)
+	str("ALERT_PATH", &c.AlertPath)
+	str("ALERT_SEVERITY", &c.AlertSeverity)
+	str("PID_FILE", &c.PIDFile)
+	str("STATUS_FILE", &c.StatusFile)
+	str("ATH_FILE", &c.ATHFile)
+	str("CANDLE_FILE", &c.CandleFile)
+	str("LOCALE", &c.Locale)
+	if v, ok := os.LookupEnv(ENV_PREFIX + "SYMBOL"); ok {
+		c.SelectSymbols(v)
+	}
+	if v, ok := os.LookupEnv(ENV_PREFIX + "STEP"); ok {
+		step, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%sSTEP: %w", ENV_PREFIX, err))
+		} else {
+			for i := range c.Symbols {
+				c.Symbols[i].Step = step
+			}
+		}
+	}
+	num := func(name string, dst *int) {
+		if v, ok := os.LookupEnv(ENV_PREFIX + name); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s%s: %w", ENV_PREFIX, name, err))
+				return
+			}
+			*dst = n
+		}
+	}
+	num("BUFFER_SIZE", &c.BufferSize)
+	num("STATS_SIZE", &c.StatsSize)
+	num("ALERT_SIZE", &c.AlertSize)
+	num("SLOTS", &c.Slots)
+	dur("INITIAL_BACKOFF", &c.Reconnect.InitialBackoff)
+	dur("MAX_BACKOFF", &c.Reconnect.MaxBackoff)
+	dur("PING_PERIOD", &c.Reconnect.PingPeriod)
+	dur("FAILOVER_AFTER", &c.Reconnect.FailoverAfter)
+	dur("POLL_INTERVAL", &c.Reconnect.PollInterval)
+	dur("STALE_AFTER", &c.Reconnect.StaleAfter)
+	dur("STATUS_INTERVAL", &c.StatusInterval)
+	dur("DEDUP_WINDOW", &c.DedupWindow)
+	str("KEYRING_SERVICE", &c.Secrets.KeyringService)
+	str("SECRETS_DIR", &c.Secrets.Dir)
+	str("USER_DATA_EXCHANGE", &c.UserData.Exchange)
+	str("USER_DATA_API_KEY", &c.UserData.APIKey)
+	str("REPLAY_FILE", &c.Replay.File)
+	dur("BACKFILL_WINDOW", &c.Backfill.Window)
+	str("BACKFILL_INTERVAL", &c.Backfill.Interval)
+	str("IPC_MODE", &c.Permissions.Mode)
+	str("IPC_GROUP", &c.Permissions.Group)
+	str("USER", &c.Permissions.User)
+	str("LOG_LEVEL", &c.Log.Level)
+	str("LOG_FORMAT", &c.Log.Format)
+	str("LOG_COLOR", &c.Log.Color)
+	str("LOG_FILE", &c.Log.File)
+	num("LOG_MAX_SIZE", &c.Log.MaxSize)
+	num("LOG_MAX_BACKUPS", &c.Log.MaxBackups)
+	boolean("LOG_COMPRESS", &c.Log.Compress)
+
+	return errors.Join(errs...)
+}
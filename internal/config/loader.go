@@ -0,0 +1,122 @@
+package config
+
+import (
+	"flag"
+	"os"
+)
+
+// Loader remembers where the configuration came from so it can be rebuilt
+// identically on SIGHUP.
+type Loader struct {
+	Path    string
+	Profile string
+	flags   []func(*Config)
+}
+
+// Load builds the config from defaults, the file, the selected profile,
+// explicitly set flags and finally the environment, then validates it.
+func (l *Loader) Load() (Config, error) {
+	cfg := Default()
+	if l.Path != "" {
+		var err error
+		if cfg, err = LoadFile(l.Path); err != nil {
+			return cfg, err
+		}
+	}
+	if l.Profile != "" {
+		if err := cfg.ApplyProfile(l.Profile); err != nil {
+			return cfg, err
+		}
+	}
+	for _, apply := range l.flags {
+		apply(&cfg)
+	}
+	if err := cfg.ApplyEnv(); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// Override registers a change applied after the config flags, e.g. for a
+// subcommand's own flags. It survives reloads like any other flag.
+func (l *Loader) Override(apply func(*Config)) {
+	l.flags = append(l.flags, apply)
+}
+
+// ParseFlags registers the config flags on fs, parses args and records the
+// optional -config file (or PRICE_ALERT_CONFIG) and every explicitly set
+// flag. Subcommands register their own flags on fs before calling it.
+func ParseFlags(fs *flag.FlagSet, args []string) *Loader {
+	configPath := fs.String("config", os.Getenv(ENV_PREFIX+"CONFIG"), "YAML config file (env PRICE_ALERT_CONFIG)")
+	profile := fs.String("profile", os.Getenv(ENV_PREFIX+"PROFILE"), "named profile to apply, e.g. prod, binance-us, testnet, replay (env PRICE_ALERT_PROFILE)")
+	testnet := fs.Bool("testnet", false, "use the Binance spot testnet endpoints and IPC paths (env PRICE_ALERT_TESTNET)")
+	endpoint := fs.String("endpoint", BINANCE_WS, "Binance websocket base URL, e.g. "+BINANCE_US_WS)
+	restEndpoint := fs.String("rest-endpoint", BINANCE_REST, "Binance REST base URL, e.g. "+BINANCE_US_REST)
+	symbol := fs.String("symbol", SYMBOL, "comma-separated Binance symbols to stream (e.g. ethusdt,btcusdt)")
+	step := fs.Float64("step", STEP, "price move from checkpoint that triggers an alert, for every symbol")
+	shmPath := fs.String("shm", SHM_PATH, "shared memory file the price is written to")
+	pipePath := fs.String("pipe", PIPE_PATH, "named pipe used to signal the reader")
+	bufferSize := fs.Int("buffer-size", BUFFER_SIZE, "bytes per SHM slot")
+	statsPath := fs.String("stats", "", "shared memory file the 24h statistics of ticker streams are written to")
+	alertPath := fs.String("alerts", ALERT_PATH, `shared memory file the alert texts are written to, "off" to disable`)
+	pidFile := fs.String("pid-file", "", "write the process ID to this file")
+	statusFile := fs.String("status-file", "", "rewrite this JSON health file every status_interval")
+	quiet := fs.Bool("q", false, "quiet: only log warnings and errors")
+	verbose := fs.Bool("v", false, "verbose: also log every tick")
+	color := fs.String("color", COLOR_AUTO, "color log levels: auto, always or never")
+	logFormat := fs.String("log-format", "auto", "log format: auto, console, json or journal")
+	logFile := fs.String("log-file", "", "write logs to this file instead of stderr, rotated by size")
+	locale := fs.String("locale", "", `number format of log lines and alerts, e.g. de_DE or "auto" for $LANG`)
+	fs.Parse(args)
+
+	loader := &Loader{Path: *configPath, Profile: *profile}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "symbol":
+			loader.flags = append(loader.flags, func(c *Config) { c.SelectSymbols(*symbol) })
+		case "step":
+			loader.flags = append(loader.flags, func(c *Config) {
+				for i := range c.Symbols {
+					c.Symbols[i].Step = *step
+				}
+			})
+		case "testnet":
+			loader.flags = append(loader.flags, func(c *Config) { c.Testnet = *testnet })
+		case "endpoint":
+			loader.flags = append(loader.flags, func(c *Config) { c.Endpoint = *endpoint })
+		case "rest-endpoint":
+			loader.flags = append(loader.flags, func(c *Config) { c.RESTEndpoint = *restEndpoint })
+		case "shm":
+			loader.flags = append(loader.flags, func(c *Config) { c.SHMPath = *shmPath })
+		case "pipe":
+			loader.flags = append(loader.flags, func(c *Config) { c.PipePath = *pipePath })
+		case "stats":
+			loader.flags = append(loader.flags, func(c *Config) { c.StatsPath = *statsPath })
+		case "alerts":
+			loader.flags = append(loader.flags, func(c *Config) { c.AlertPath = *alertPath })
+		case "pid-file":
+			loader.flags = append(loader.flags, func(c *Config) { c.PIDFile = *pidFile })
+		case "status-file":
+			loader.flags = append(loader.flags, func(c *Config) { c.StatusFile = *statusFile })
+		case "buffer-size":
+			loader.flags = append(loader.flags, func(c *Config) { c.BufferSize = *bufferSize })
+		case "q":
+			if *quiet {
+				loader.flags = append(loader.flags, func(c *Config) { c.Log.Level = "warn" })
+			}
+		case "v":
+			if *verbose {
+				loader.flags = append(loader.flags, func(c *Config) { c.Log.Level = "debug" })
+			}
+		case "color":
+			loader.flags = append(loader.flags, func(c *Config) { c.Log.Color = *color })
+		case "log-format":
+			loader.flags = append(loader.flags, func(c *Config) { c.Log.Format = *logFormat })
+		case "log-file":
+			loader.flags = append(loader.flags, func(c *Config) { c.Log.File = *logFile })
+		case "locale":
+			loader.flags = append(loader.flags, func(c *Config) { c.Locale = *locale })
+		}
+	})
+	return loader
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ActionConfig runs Command with Args when a rule or whale trade alerts,
+// with the alert in PRICE_ALERT_EVENT, _RULE, _SYMBOL, _PRICE and _TEXT,
+// and kills it after Timeout.
+type ActionConfig struct {
+	Command string        `yaml:"command"`
+	Args    []string      `yaml:"args"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// validate checks the action called name and fills in its timeout.
+func (a *ActionConfig) validate(name string) []error {
+	switch {
+	case a.Command == "":
+		return []error{fmt.Errorf("actions: %s: command is required", name)}
+	case a.Timeout < 0:
+		return []error{fmt.Errorf("actions: %s: timeout must not be negative", name)}
+	case a.Timeout == 0:
+		a.Timeout = ACTION_TIMEOUT
+	}
+	return nil
+}
+
+// SessionConfig is a trading session from Start to End, times of day in
+// Timezone (default UTC), on the Days it starts on (default every day); an
+// End not after Start ends it the next day, so 00:00 to 00:00 is a whole
+// day.
+type SessionConfig struct {
+	Start    string   `yaml:"start"`
+	End      string   `yaml:"end"`
+	Timezone string   `yaml:"timezone"`
+	Days     []string `yaml:"days"`
+	// From and To are Start and End as offsets from midnight, Location
+	// Timezone and Weekdays Days, filled in by Validate.
+	From, To time.Duration  `yaml:"-"`
+	Location *time.Location `yaml:"-"`
+	Weekdays []time.Weekday `yaml:"-"`
+}
+
+// validate checks the session called name and fills in From, To,
+// Location and Weekdays.
+func (s *SessionConfig) validate(name string) []error {
+	var errs []error
+	var err error
+	if s.From, err = parseClock(s.Start); err != nil {
+		errs = append(errs, fmt.Errorf("sessions: %s: start %w", name, err))
+	}
+	if s.To, err = parseClock(s.End); err != nil {
+		errs = append(errs, fmt.Errorf("sessions: %s: end %w", name, err))
+	}
+	if s.Location = time.UTC; s.Timezone != "" {
+		if s.Location, err = time.LoadLocation(s.Timezone); err != nil {
+			errs = append(errs, fmt.Errorf("sessions: %s: timezone: %w", name, err))
+		}
+	}
+	s.Weekdays = nil
+	for _, d := range s.Days {
+		day := slices.Index(WEEKDAYS, strings.ToLower(d))
+		if day < 0 {
+			errs = append(errs, fmt.Errorf("sessions: %s: days: %q is not one of %s", name, d, strings.Join(WEEKDAYS, ", ")))
+			continue
+		}
+		s.Weekdays = append(s.Weekdays, time.Weekday(day))
+	}
+	return errs
+}
+
+// SEVERITIES are the severities of alerts, from the quietest.
+var SEVERITIES = []string{"info", "warn", "critical"}
+
+// NotifierConfig delivers the alerts of Severity (one of SEVERITIES,
+// default warn) and above to a sink beside the log: the command of the
+// named Action, with the alert in its environment.
+type NotifierConfig struct {
+	Action   string `yaml:"action"`
+	Severity string `yaml:"severity"`
+}
+
+// validate checks the action and severity against actions, filling in the
+// default severity.
+func (n *NotifierConfig) validate(actions map[string]ActionConfig) []error {
+	var errs []error
+	if n.Severity = strings.ToLower(n.Severity); n.Severity == "" {
+		n.Severity = SEVERITIES[1]
+	}
+	if _, ok := actions[n.Action]; !ok {
+		errs = append(errs, fmt.Errorf("unknown action %q", n.Action))
+	}
+	if !slices.Contains(SEVERITIES, n.Severity) {
+		errs = append(errs, fmt.Errorf("severity must be one of %s", strings.Join(SEVERITIES, ", ")))
+	}
+	return errs
+}
+
+// EscalationConfig escalates an alert firing the Count-th time within
+// Window (default ESCALATION_WINDOW), and each time after, counting those
+// of one symbol, kind and rule at any level: it is announced as a warning,
+// "Warning, ETHUSDT down to 3400, fifth time in 24 minutes", one severity
+// up, and also runs Actions. Off while Count is 0.
+type EscalationConfig struct {
+	Count   int           `yaml:"count"`
+	Window  time.Duration `yaml:"window"`
+	Actions []string      `yaml:"actions"`
+}
+
+// validate checks the count, window and actions of an enabled escalation,
+// filling in the default window.
+func (e *EscalationConfig) validate(actions map[string]ActionConfig) []error {
+	if e.Count == 0 {
+		return nil
+	}
+	var errs []error
+	if e.Window == 0 {
+		e.Window = ESCALATION_WINDOW
+	}
+	switch {
+	case e.Count < 2:
+		errs = append(errs, errors.New("escalation: count must be at least 2"))
+	case e.Window < 0:
+		errs = append(errs, errors.New("escalation: window must not be negative"))
+	}
+	for _, name := range e.Actions {
+		if _, ok := actions[name]; !ok {
+			errs = append(errs, fmt.Errorf("escalation: unknown action %s", name))
+		}
+	}
+	return errs
+}
+
+// QuietHoursConfig holds back alerts below critical severity from Start
+// to End, times of day on the wall clock of Timezone (default the local
+// one), so they follow DST; an End not after Start ends them the next day. Held alerts are only logged, or with Queue delivered
+// once the quiet hours are over.
+type QuietHoursConfig struct {
+	Start    string `yaml:"start"`
+	End      string `yaml:"end"`
+	Timezone string `yaml:"timezone"`
+	Queue    bool   `yaml:"queue"`
+	// From and To are Start and End as offsets from midnight and Location
+	// Timezone, filled in by Validate.
+	From, To time.Duration  `yaml:"-"`
+	Location *time.Location `yaml:"-"`
+}
+
+func (q QuietHoursConfig) Enabled() bool { return q.Start != "" || q.End != "" }
+
+// validate checks the times and timezone of enabled quiet hours, filling
+// in From, To and Location.
+func (q *QuietHoursConfig) validate() []error {
+	if !q.Enabled() {
+		return nil
+	}
+	var errs []error
+	var err error
+	if q.From, err = parseClock(q.Start); err != nil {
+		errs = append(errs, fmt.Errorf("quiet_hours: start %w", err))
+	}
+	if q.To, err = parseClock(q.End); err != nil {
+		errs = append(errs, fmt.Errorf("quiet_hours: end %w", err))
+	}
+	if q.Location = time.Local; q.Timezone != "" {
+		if q.Location, err = time.LoadLocation(q.Timezone); err != nil {
+			errs = append(errs, fmt.Errorf("quiet_hours: timezone: %w", err))
+		}
+	}
+	return errs
+}
+
+// Contains reports whether at falls in the quiet hours. The time of day is
+// read off the local clock, so a start skipped by a DST change begins them
+// at the first time after it.
+func (q QuietHoursConfig) Contains(at time.Time) bool {
+	if !q.Enabled() {
+		return false
+	}
+	local := at.In(q.Location)
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+	if q.From < q.To {
+		return clock >= q.From && clock < q.To
+	}
+	return clock >= q.From || clock < q.To
+}
+
+// WEEKDAYS are the names of session days, from Sunday.
+var WEEKDAYS = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Last returns the start and end of the latest session that started by
+// at, which may still be on.
+func (s SessionConfig) Last(at time.Time) (start, end time.Time) {
+	local := at.In(s.Location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, s.Location)
+	for back := 0; back <= 7; back++ {
+		day := midnight.AddDate(0, 0, -back)
+		if len(s.Weekdays) > 0 && !slices.Contains(s.Weekdays, day.Weekday()) {
+			continue
+		}
+		start, end = day.Add(s.From), day.Add(s.To)
+		if s.To <= s.From {
+			end = day.AddDate(0, 0, 1).Add(s.To)
+		}
+		if !start.After(at) {
+			return start, end
+		}
+	}
+	return start, end
+}
+
+// parseClock parses a time of day such as 13:30 into its offset from
+// midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("must be a time of day such as 13:30, not %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
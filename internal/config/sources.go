@@ -0,0 +1,216 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/jsonpath"
+)
+
+// ExchangeConfig locates an exchange other than Binance, whose endpoints
+// are the top-level endpoint and rest_endpoint.
+type ExchangeConfig struct {
+	Endpoint     string `yaml:"endpoint"`
+	RESTEndpoint string `yaml:"rest_endpoint"`
+}
+
+// QuotesConfig selects the REST quote provider of the stocks, forex or
+// coins exchange. It is polled every Interval while the market is open;
+// APIKey is normally a "secret:<name>" reference.
+type QuotesConfig struct {
+	Provider     string        `yaml:"provider"`      // polygon, alphavantage or twelvedata; coingecko or coinmarketcap
+	RESTEndpoint string        `yaml:"rest_endpoint"` // default: the provider's
+	APIKey       string        `yaml:"api_key"`
+	Interval     time.Duration `yaml:"interval"`
+	// Key is APIKey resolved, filled in by the writer before it drops
+	// privileges.
+	Key string `yaml:"-"`
+}
+
+// EthereumConfig selects the JSON-RPC node the ethereum exchange polls
+// for the base fee, the chainlink exchange for feed answers or the uniswap
+// exchange for pool prices, every Interval. Hosted node URLs often embed
+// a key, so RPCURL is kept out of error messages.
+type EthereumConfig struct {
+	RPCURL   string        `yaml:"rpc_url"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// CustomConfig names the JSON APIs of the custom exchange, whose markets
+// are those names; each is polled every Interval.
+type CustomConfig struct {
+	Interval time.Duration           `yaml:"interval"`
+	Sources  map[string]CustomSource `yaml:"sources"`
+}
+
+// CustomSource is one JSON API of the custom exchange: a GET of URL, sent
+// with Headers, answering the price at Path, such as data.0.price or
+// $.data[0].price. A header value may be a "secret:<name>" reference.
+type CustomSource struct {
+	URL     string            `yaml:"url"`
+	Path    string            `yaml:"path"`
+	Headers map[string]string `yaml:"headers"`
+	// Header is Headers resolved, filled in by the writer before it drops
+	// privileges.
+	Header map[string]string `yaml:"-"`
+}
+
+// merge overrides c with the fields o sets, source by source.
+func (c *CustomConfig) merge(o CustomConfig) {
+	if o.Interval > 0 {
+		c.Interval = o.Interval
+	}
+	if len(o.Sources) > 0 {
+		sources := maps.Clone(c.Sources)
+		if sources == nil {
+			sources = make(map[string]CustomSource)
+		}
+		maps.Copy(sources, o.Sources)
+		c.Sources = sources
+	}
+}
+
+// validate checks the interval and every source.
+func (c CustomConfig) validate() []error {
+	var errs []error
+	if c.Interval < MIN_POLL_INTERVAL {
+		errs = append(errs, fmt.Errorf("custom: interval must be at least %v", MIN_POLL_INTERVAL))
+	}
+	names := make([]string, 0, len(c.Sources))
+	for name := range c.Sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		src := c.Sources[name]
+		if u, err := url.Parse(src.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("custom: sources.%s: url must be an http or https URL", name))
+		}
+		if _, err := jsonpath.Parse(src.Path); err != nil {
+			errs = append(errs, fmt.Errorf("custom: sources.%s: %w", name, err))
+		}
+	}
+	return errs
+}
+
+// PluginConfig is an external price source of the plugin exchange: the
+// program Command, run with Args and Env, which writes ticks to stdout.
+// An Env value may be a "secret:<name>" reference.
+type PluginConfig struct {
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args"`
+	Env     map[string]string `yaml:"env"`
+	// Environ is Env resolved, filled in by the writer before it drops
+	// privileges.
+	Environ map[string]string `yaml:"-"`
+}
+
+// validate checks the plugin called name.
+func (p PluginConfig) validate(name string) []error {
+	var errs []error
+	if name == "" || strings.Contains(name, ":") {
+		errs = append(errs, fmt.Errorf("plugins: bad name %q", name))
+	}
+	if p.Command == "" {
+		errs = append(errs, fmt.Errorf("plugins: %s: command is required", name))
+	}
+	return errs
+}
+
+// PluginMarket splits a plugin market into the plugin's name and the
+// symbol it knows the market by.
+func PluginMarket(market string) (plugin, symbol string) {
+	plugin, symbol, _ = strings.Cut(market, ":")
+	return plugin, symbol
+}
+
+// Polled reports whether exchange is polled over REST or JSON-RPC rather
+// than streamed.
+func Polled(exchange string) bool {
+	switch exchange {
+	case SOURCE_STOCKS, SOURCE_FOREX, SOURCE_ETHEREUM, SOURCE_COINS, SOURCE_CHAINLINK, SOURCE_UNISWAP, SOURCE_CUSTOM:
+		return true
+	}
+	return false
+}
+
+// Quotes returns the quote provider settings of the stocks, forex or
+// coins exchange, or nil for a websocket exchange.
+func (c *Config) Quotes(exchange string) *QuotesConfig {
+	switch exchange {
+	case SOURCE_STOCKS:
+		return &c.Stocks
+	case SOURCE_FOREX:
+		return &c.Forex
+	case SOURCE_COINS:
+		return &c.Coins
+	}
+	return nil
+}
+
+// merge overrides q with the fields o sets.
+func (q *QuotesConfig) merge(o QuotesConfig) {
+	if o.Provider != "" {
+		q.Provider = o.Provider
+	}
+	if o.RESTEndpoint != "" {
+		q.RESTEndpoint = o.RESTEndpoint
+	}
+	if o.APIKey != "" {
+		q.APIKey = o.APIKey
+	}
+	if o.Interval > 0 {
+		q.Interval = o.Interval
+	}
+}
+
+// merge overrides e with the fields o sets.
+func (e *EthereumConfig) merge(o EthereumConfig) {
+	if o.RPCURL != "" {
+		e.RPCURL = o.RPCURL
+	}
+	if o.Interval > 0 {
+		e.Interval = o.Interval
+	}
+}
+
+// validate checks the node settings of exchange.
+func (e EthereumConfig) validate(exchange string) []error {
+	var errs []error
+	if e.RPCURL == "" {
+		errs = append(errs, fmt.Errorf("%s: rpc_url must not be empty", exchange))
+	}
+	if e.Interval < MIN_POLL_INTERVAL {
+		errs = append(errs, fmt.Errorf("%s: interval must be at least %v", exchange, MIN_POLL_INTERVAL))
+	}
+	return errs
+}
+
+// validate checks the provider settings of exchange and fills in the
+// provider's endpoint; used makes the API key mandatory.
+func (q *QuotesConfig) validate(exchange string, used bool) []error {
+	var errs []error
+	providers, least := []string{QUOTES_POLYGON, QUOTES_ALPHA_VANTAGE, QUOTES_TWELVE_DATA}, MIN_POLL_INTERVAL
+	if exchange == SOURCE_COINS {
+		providers, least = []string{QUOTES_COINGECKO, QUOTES_COINMARKETCAP}, MIN_COINS_INTERVAL
+	}
+	if !slices.Contains(providers, q.Provider) {
+		last := len(providers) - 1
+		errs = append(errs, fmt.Errorf("%s: provider must be %s or %s", exchange, strings.Join(providers[:last], ", "), providers[last]))
+	} else if q.RESTEndpoint == "" {
+		q.RESTEndpoint = quotesREST[q.Provider]
+	}
+	// CoinGecko's public API works without a key, at a lower rate limit
+	if used && q.APIKey == "" && q.Provider != QUOTES_COINGECKO {
+		errs = append(errs, fmt.Errorf("%s: api_key is required for %s symbols", exchange, exchange))
+	}
+	if q.Interval < least {
+		errs = append(errs, fmt.Errorf("%s: interval must be at least %v", exchange, least))
+	}
+	return errs
+}
@@ -0,0 +1,1727 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/aggregate"
+	"github.com/qqubb/tts_price_alert/internal/expr"
+	"github.com/qqubb/tts_price_alert/internal/numfmt"
+)
+
+// SymbolConfig describes one streamed market and how it is alerted on.
+// Zero/unset step, rounding and precision are derived from the tick size.
+type SymbolConfig struct {
+	Symbol string  `yaml:"symbol"`
+	Step   float64 `yaml:"step"`
+	// StepPercent, when set, alerts on moves of that many percent of the
+	// checkpoint, e.g. 0.5, instead of a fixed Step.
+	StepPercent float64 `yaml:"step_percent"`
+	// ATR, when set, scales the step with volatility instead: a multiple
+	// of the average true range of recent candles.
+	ATR ATRConfig `yaml:"atr"`
+	// Streak escalates the announcement of several steps in a row in one
+	// direction.
+	Streak StreakConfig `yaml:"streak"`
+	// Targets are fixed price levels alerted on independently of the
+	// step checkpoint.
+	Targets TargetsConfig `yaml:"targets"`
+	// RoundNumbers alerts whenever the price crosses a multiple of it,
+	// e.g. 100; 0 is off.
+	RoundNumbers float64 `yaml:"round_numbers"`
+	// Trailing alerts when the price retraces from its high or low, like
+	// a trailing stop.
+	Trailing TrailingConfig `yaml:"trailing"`
+	// Rules alert when an expression over the price and its recent
+	// history becomes true.
+	Rules []RuleConfig `yaml:"rules"`
+	// Candles are the intervals, from 1m to 1d, whose candles are built
+	// from the prices, resampled from 1m ones, and reported as they close.
+	Candles []string `yaml:"candles"`
+	// MovingAverages keeps averages of candle closes and alerts when the
+	// price or an average crosses another.
+	MovingAverages MovingAveragesConfig `yaml:"moving_averages"`
+	// RSI alerts when the relative strength index turns overbought or
+	// oversold.
+	RSI RSIConfig `yaml:"rsi"`
+	// MACD alerts when the MACD crosses its signal line or zero.
+	MACD MACDConfig `yaml:"macd"`
+	// Bollinger alerts on closes outside the Bollinger bands and on
+	// squeezes.
+	Bollinger BollingerConfig `yaml:"bollinger"`
+	// VWAP alerts when the price strays from its session VWAP.
+	VWAP VWAPConfig `yaml:"vwap"`
+	// Volume alerts on unusual traded volume.
+	Volume VolumeConfig `yaml:"volume"`
+	// VolumeDelta alerts on lopsided buying or selling.
+	VolumeDelta VolumeDeltaConfig `yaml:"volume_delta"`
+	// Spread alerts when the bid-ask spread widens.
+	Spread SpreadConfig `yaml:"spread"`
+	// Velocity alerts on fast moves, whatever the checkpoint.
+	Velocity VelocityConfig `yaml:"velocity"`
+	// Peg alerts when a stablecoin strays from its peg.
+	Peg PegConfig `yaml:"peg"`
+	// DailyChange alerts on the change of the day from its open.
+	DailyChange DailyChangeConfig `yaml:"daily_change"`
+	// Sessions name the sessions whose high and low the symbol tracks and
+	// alerts on breaks of.
+	Sessions []string `yaml:"sessions"`
+	// ATH alerts on new all-time highs, once per step.
+	ATH bool `yaml:"ath"`
+	// Levels alerts near and through swing highs and lows.
+	Levels LevelsConfig `yaml:"levels"`
+	// Pivots alerts on touches and crossings of daily or weekly pivot points.
+	Pivots PivotsConfig `yaml:"pivots"`
+	// Volatility tracks the realized volatility and alerts on spikes and
+	// collapses.
+	Volatility VolatilityConfig `yaml:"volatility"`
+	// Exchange streams the symbol (binance, binance-futures, coinbase,
+	// kraken, bybit, bybit-linear, okx, stocks, forex, ethereum, coins,
+	// chainlink, uniswap, custom or plugin); empty uses source, or basket
+	// with Basket or Ratio set.
+	Exchange string `yaml:"exchange"`
+	// Stream picks the Binance stream of the symbol's Binance markets:
+	// trade (the default), aggTrade, bookTicker (the bid/ask midpoint),
+	// miniTicker or ticker (with 24h statistics), kline_<interval>
+	// (candles) or, on binance-futures, markPrice or markPrice@1s.
+	Stream string `yaml:"stream"`
+	// CloseOnly evaluates step and target alerts on closed candles only,
+	// so single-trade wicks never alert; it needs a kline stream. Prices
+	// are still published on every update.
+	CloseOnly bool `yaml:"close_only"`
+	// Alerts24h raises alerts on the 24h statistics of a miniTicker or
+	// ticker stream.
+	Alerts24h Alerts24hConfig `yaml:"alerts_24h"`
+	// Liquidations raises alerts on the forced orders of a binance-futures
+	// symbol.
+	Liquidations LiquidationsConfig `yaml:"liquidations"`
+	// Whales alerts on single large trades.
+	Whales WhalesConfig `yaml:"whales"`
+	// Depth alerts when one side of the order book outweighs the other.
+	Depth DepthConfig `yaml:"depth"`
+	// Funding raises alerts on the funding rate of a binance-futures
+	// perpetual.
+	Funding FundingConfig `yaml:"funding"`
+	// Premium raises alerts on the premium of a binance-futures
+	// perpetual's mark price over its index.
+	Premium PremiumConfig `yaml:"premium"`
+	// OpenInterest raises alerts on the polled open interest of a
+	// binance-futures perpetual.
+	OpenInterest OpenInterestConfig `yaml:"open_interest"`
+	// Gas raises alerts on the base fee of an ethereum symbol.
+	Gas GasConfig `yaml:"gas"`
+	// Divergence raises alerts when the price drifts apart from another
+	// symbol's, such as a chainlink oracle from the exchange price.
+	Divergence DivergenceConfig `yaml:"divergence"`
+	// Basis tracks the basis of a binance-futures contract over the price.
+	Basis BasisConfig `yaml:"basis"`
+	// Correlation alerts when the price stops moving with another
+	// symbol's.
+	Correlation CorrelationConfig `yaml:"correlation"`
+	// StaleAfter raises a stale alert and marks the SHM record stale once
+	// the connected stream has delivered no price for this long (default
+	// reconnect.stale_after, 0 for none).
+	StaleAfter time.Duration `yaml:"stale_after"`
+	// Outliers drops single bad prints before they are published.
+	Outliers OutliersConfig `yaml:"outliers"`
+	// Rounding is the grid the starting checkpoint snaps to (default: step).
+	Rounding float64 `yaml:"rounding"`
+	// Reset is where the checkpoint moves once a step fired (price,
+	// grid or anchored, default price), and Origin the level the
+	// anchored grid counts from.
+	Reset  string  `yaml:"reset"`
+	Origin float64 `yaml:"origin"`
+	// Precision is the number of decimals written to SHM and logs.
+	Precision *int `yaml:"precision"`
+	// Markets, when set, streams the symbol from several exchanges and
+	// publishes their composite price.
+	Markets   []MarketConfig  `yaml:"markets"`
+	Aggregate AggregateConfig `yaml:"aggregate"`
+	// Arbitrage alerts when the prices of Markets drift apart.
+	Arbitrage ArbitrageConfig `yaml:"arbitrage"`
+	// Failover lists backup markets, most preferred first. One takes over
+	// while every market before it has been silent for
+	// reconnect.failover_after.
+	Failover []MarketConfig `yaml:"failover"`
+	// Basket makes the symbol a synthetic index priced from other symbols
+	// instead of a market of its own.
+	Basket []BasketConfig `yaml:"basket"`
+	// Ratio makes the symbol the price of one symbol in another, such as
+	// ETH in BTC from ethusdt and btcusdt.
+	Ratio RatioConfig `yaml:"ratio"`
+}
+
+// validateSymbol normalises s, fills in the defaults of the features it
+// turns on and checks them. It leaves out the checks against other
+// symbols, which validateLinks makes once every symbol is normalised.
+func (c *Config) validateSymbol(s *SymbolConfig) []error {
+	var errs []error
+	if s.Step < 0 {
+		errs = append(errs, errors.New("step must not be negative"))
+	}
+	if s.StepPercent < 0 || s.StepPercent >= 100 {
+		errs = append(errs, errors.New("step_percent must be between 0 and 100"))
+	}
+	errs = append(errs, s.ATR.validate(s.StepPercent)...)
+	errs = append(errs, s.MovingAverages.validate()...)
+	errs = append(errs, s.RSI.validate()...)
+	errs = append(errs, s.Volume.validate()...)
+	errs = append(errs, s.Bollinger.validate()...)
+	errs = append(errs, s.VWAP.validate()...)
+	errs = append(errs, s.Peg.validate()...)
+	errs = append(errs, s.Levels.validate()...)
+	errs = append(errs, s.Volatility.validate()...)
+	errs = append(errs, s.Pivots.validate()...)
+	errs = append(errs, s.DailyChange.validate()...)
+	errs = append(errs, s.MACD.validate()...)
+	errs = append(errs, s.Trailing.validate()...)
+	errs = append(errs, s.Spread.validate()...)
+	errs = append(errs, s.VolumeDelta.validate()...)
+	errs = append(errs, s.Velocity.validate()...)
+	names := make(map[string]bool)
+	for j := range s.Rules {
+		r := &s.Rules[j]
+		rule := r.validate(c.Actions)
+		switch {
+		case r.Name == "":
+			rule = []error{errors.New("name is required")}
+		case names[r.Name]:
+			rule = []error{fmt.Errorf("duplicate name %q", r.Name)}
+		}
+		errs = append(errs, within(fmt.Sprintf("rules[%d]", j), rule)...)
+		names[r.Name] = true
+	}
+	for j, interval := range s.Candles {
+		if d := KlineDuration(interval); d < time.Minute || d > 24*time.Hour || slices.Contains(s.Candles[:j], interval) {
+			errs = append(errs, fmt.Errorf("candles: %q must be a kline interval from 1m to 1d, listed once", interval))
+		}
+	}
+	for _, name := range s.Sessions {
+		if _, ok := c.Sessions[name]; !ok {
+			errs = append(errs, fmt.Errorf("sessions: no session %q", name))
+		}
+	}
+	errs = append(errs, s.Streak.validate()...)
+	if s.RoundNumbers < 0 {
+		errs = append(errs, errors.New("round_numbers must not be negative"))
+	}
+	errs = append(errs, s.Targets.validate()...)
+	if s.Rounding < 0 {
+		errs = append(errs, errors.New("rounding must not be negative"))
+	}
+	if s.Reset = strings.ToLower(s.Reset); s.Reset == "" {
+		s.Reset = RESET_PRICE
+	}
+	switch {
+	case !slices.Contains(RESETS, s.Reset):
+		errs = append(errs, fmt.Errorf("reset: %q is not one of %s", s.Reset, strings.Join(RESETS, ", ")))
+	case s.Reset == RESET_ANCHORED && s.Origin <= 0:
+		errs = append(errs, errors.New("reset: anchored needs a positive origin"))
+	case s.Reset != RESET_ANCHORED && s.Origin != 0:
+		errs = append(errs, errors.New("origin only applies to reset: anchored"))
+	}
+	if s.Precision != nil && (*s.Precision < 0 || *s.Precision > MAX_PRECISION) {
+		errs = append(errs, fmt.Errorf("precision must be between 0 and %d", MAX_PRECISION))
+	}
+	if need := c.RecordSize(*s); c.BufferSize >= MIN_BUFFER_SIZE && need > c.BufferSize {
+		errs = append(errs, fmt.Errorf("%s needs %d-byte slots, buffer_size is %d", s.Label(), need, c.BufferSize))
+	}
+	if need := c.StatsRecordSize(*s); c.StatsPath != "" && c.StatsSize >= STATS_SIZE && need > c.StatsSize {
+		errs = append(errs, fmt.Errorf("%s needs %d-byte stats slots, stats_size is %d", s.Label(), need, c.StatsSize))
+	}
+	s.Exchange = strings.ToLower(s.Exchange)
+	for _, list := range [][]MarketConfig{s.Markets, s.Failover} {
+		for j := range list {
+			mk := &list[j]
+			mk.Exchange = strings.ToLower(strings.TrimSpace(mk.Exchange))
+			mk.Symbol = strings.ToLower(strings.TrimSpace(mk.Symbol))
+		}
+	}
+	s.Stream = canonicalStream(s.Stream)
+	if len(s.Markets) > 0 && len(s.Failover) > 0 {
+		errs = append(errs, errors.New("use either markets or failover, an aggregate already drops silent markets"))
+	}
+	if len(s.Markets) > 0 {
+		errs = append(errs, s.Aggregate.validate()...)
+	}
+	errs = append(errs, s.Arbitrage.validate(len(s.Markets))...)
+	errs = append(errs, s.Alerts24h.validate(s.Stream, len(s.Markets))...)
+	if s.CloseOnly && (!strings.HasPrefix(s.Stream, STREAM_KLINE) || len(s.Markets) > 0) {
+		errs = append(errs, errors.New("close_only needs a kline stream and a single market"))
+	}
+	errs = append(errs, s.Whales.validate(c.Actions)...)
+	if s.StaleAfter == 0 {
+		s.StaleAfter = c.Reconnect.StaleAfter
+	}
+	if s.StaleAfter != 0 && s.StaleAfter < time.Second {
+		errs = append(errs, errors.New("stale_after must be at least 1s"))
+	}
+	errs = append(errs, s.Outliers.validate()...)
+	// The exchange-only features need the symbol on that one market
+	exchange, single := c.ExchangeOf(*s), len(s.Markets) == 0 && len(s.Failover) == 0
+	errs = append(errs, s.Depth.validate(exchange, single)...)
+	errs = append(errs, s.Liquidations.validate(exchange, single)...)
+	errs = append(errs, s.Funding.validate(exchange, single)...)
+	errs = append(errs, s.Premium.validate(exchange, single)...)
+	errs = append(errs, s.Gas.validate(exchange, single)...)
+	errs = append(errs, s.OpenInterest.validate(exchange, single)...)
+	return errs
+}
+
+// validateLinks checks what s takes from other symbols: the basis
+// contract, the correlation and divergence symbols and, for a basket or
+// ratio, its components.
+func (c *Config) validateLinks(s *SymbolConfig) []error {
+	var errs []error
+	errs = append(errs, s.Basis.validate(c.ExchangeOf(*s), s.Symbol, len(s.Markets))...)
+	errs = append(errs, s.Correlation.validate(c, s.Symbol)...)
+	errs = append(errs, s.Divergence.validate(c, s.Symbol)...)
+	switch {
+	case !s.Derived():
+		if s.Exchange == SOURCE_BASKET {
+			errs = append(errs, errors.New("exchange basket needs a basket or ratio"))
+		}
+		return errs
+	case len(s.Basket) > 0 && s.Ratio.Enabled():
+		errs = append(errs, errors.New("set either basket or ratio, not both"))
+	case s.Exchange != "" && s.Exchange != SOURCE_BASKET || len(s.Markets) > 0 || len(s.Failover) > 0:
+		errs = append(errs, errors.New("a basket or ratio has no exchange, markets or failover of its own"))
+	}
+	errs = append(errs, s.Ratio.validate(c, s.Symbol)...)
+	held := make(map[string]bool)
+	for j := range s.Basket {
+		b := &s.Basket[j]
+		errs = append(errs, b.validate(fmt.Sprintf("basket[%d]", j), c, s.Symbol, held)...)
+		held[b.Symbol] = true
+	}
+	return errs
+}
+
+// RatioConfig prices a symbol as the latest price of Base over that of
+// Quote, both other configured symbols.
+type RatioConfig struct {
+	Base  string `yaml:"base"`
+	Quote string `yaml:"quote"`
+}
+
+// Enabled reports whether the ratio is set.
+func (r RatioConfig) Enabled() bool { return r.Base != "" || r.Quote != "" }
+
+// validate checks that an enabled ratio divides two configured symbols
+// other than symbol, neither of them derived itself.
+func (r *RatioConfig) validate(c *Config, symbol string) []error {
+	if !r.Enabled() {
+		return nil
+	}
+	var errs []error
+	r.Base = strings.ToLower(strings.TrimSpace(r.Base))
+	r.Quote = strings.ToLower(strings.TrimSpace(r.Quote))
+	for _, side := range []struct{ name, symbol string }{{"base", r.Base}, {"quote", r.Quote}} {
+		switch from := c.Symbol(side.symbol); {
+		case from == nil || side.symbol == symbol:
+			errs = append(errs, fmt.Errorf("ratio.%s must name another configured symbol, not %q", side.name, side.symbol))
+		case from.Derived():
+			errs = append(errs, fmt.Errorf("ratio.%s: %s is a basket or ratio itself", side.name, side.symbol))
+		}
+	}
+	if r.Base == r.Quote {
+		errs = append(errs, errors.New("ratio.base and ratio.quote must differ"))
+	}
+	return errs
+}
+
+// Derived reports whether s is priced from other symbols, as a basket or
+// a ratio, rather than streamed.
+func (s SymbolConfig) Derived() bool { return len(s.Basket) > 0 || s.Ratio.Enabled() }
+
+// Components lists the symbols s is priced from, if it is derived.
+func (s SymbolConfig) Components() []string {
+	if s.Ratio.Enabled() {
+		return []string{s.Ratio.Base, s.Ratio.Quote}
+	}
+	symbols := make([]string, len(s.Basket))
+	for i, b := range s.Basket {
+		symbols[i] = b.Symbol
+	}
+	return symbols
+}
+
+// BasketConfig is one component of a basket: Weight times the latest
+// price of Symbol, another configured symbol. A negative weight subtracts.
+type BasketConfig struct {
+	Symbol string  `yaml:"symbol"`
+	Weight float64 `yaml:"weight"`
+}
+
+// validate checks that b, the component at of the basket of symbol, weighs
+// another configured symbol, not derived itself nor already held.
+func (b *BasketConfig) validate(at string, c *Config, symbol string, held map[string]bool) []error {
+	var errs []error
+	b.Symbol = strings.ToLower(strings.TrimSpace(b.Symbol))
+	switch from := c.Symbol(b.Symbol); {
+	case from == nil || b.Symbol == symbol:
+		errs = append(errs, fmt.Errorf("%s must name another configured symbol, not %q", at, b.Symbol))
+	case from.Derived():
+		errs = append(errs, fmt.Errorf("%s: %s is a basket or ratio itself", at, b.Symbol))
+	case held[b.Symbol]:
+		errs = append(errs, fmt.Errorf("%s: %s is already in the basket", at, b.Symbol))
+	}
+	if b.Weight == 0 {
+		errs = append(errs, fmt.Errorf("%s: weight must not be 0", at))
+	}
+	return errs
+}
+
+// MarketConfig is one exchange's market of an aggregated or failover
+// symbol.
+type MarketConfig struct {
+	Exchange string `yaml:"exchange"` // default: the symbol's exchange
+	Symbol   string `yaml:"symbol"`   // default: the symbol's name
+}
+
+// AggregateConfig selects how market prices are combined: median of the
+// latest price per market, or vwap over every trade. Markets without a
+// trade within Window are left out.
+type AggregateConfig struct {
+	Method string        `yaml:"method"`
+	Window time.Duration `yaml:"window"`
+}
+
+// validate checks the method and window, filling in their defaults.
+func (a *AggregateConfig) validate() []error {
+	var errs []error
+	if a.Method == "" {
+		a.Method = aggregate.MEDIAN
+	}
+	if a.Window == 0 {
+		a.Window = AGGREGATE_WINDOW
+	}
+	if a.Method != aggregate.MEDIAN && a.Method != aggregate.VWAP {
+		errs = append(errs, errors.New("aggregate.method must be median or vwap"))
+	}
+	if a.Window < 0 {
+		errs = append(errs, errors.New("aggregate.window must not be negative"))
+	}
+	return errs
+}
+
+// ArbitrageConfig alerts when the cheapest and dearest of the markets of
+// an aggregated symbol with a trade within aggregate.window are Percent or
+// more apart, in percent of the cheapest, for For (default 10s); 0 is off.
+type ArbitrageConfig struct {
+	Percent float64       `yaml:"percent"`
+	For     time.Duration `yaml:"for"`
+}
+
+// validate checks an enabled arbitrage against the number of markets it
+// compares, filling in the default for.
+func (a *ArbitrageConfig) validate(markets int) []error {
+	if *a == (ArbitrageConfig{}) {
+		return nil
+	}
+	var errs []error
+	if a.For == 0 {
+		a.For = ARBITRAGE_FOR
+	}
+	switch {
+	case a.Percent <= 0 || a.For < 0:
+		errs = append(errs, errors.New("arbitrage needs a positive percent and a for not negative"))
+	case markets < 2:
+		errs = append(errs, errors.New("arbitrage needs two or more markets"))
+	}
+	return errs
+}
+
+// Alerts24hConfig selects the alerts of a symbol's rolling 24h window,
+// the exchange's for miniTicker and ticker streams and otherwise one kept
+// from the prices.
+type Alerts24hConfig struct {
+	// ChangeStep alerts each time the 24h change reaches another multiple
+	// of this many percent, up or down; 0 is off.
+	ChangeStep float64 `yaml:"change_step"`
+	// HighLow alerts when the price sets a new 24h high or low.
+	HighLow bool `yaml:"high_low"`
+	// Rearm is how far beyond the extreme last alerted on a new one must
+	// be to alert again; 0 uses the step.
+	Rearm float64 `yaml:"rearm"`
+}
+
+// Enabled reports whether a alerts.
+func (a Alerts24hConfig) Enabled() bool { return a.ChangeStep > 0 || a.HighLow }
+
+// validate checks a against the symbol's stream and number of markets.
+func (a Alerts24hConfig) validate(stream string, markets int) []error {
+	var errs []error
+	if a.ChangeStep < 0 || a.Rearm < 0 {
+		errs = append(errs, errors.New("alerts_24h.change_step and rearm must not be negative"))
+	}
+	if a.Enabled() && StatsStream(stream) && markets > 0 {
+		errs = append(errs, errors.New("alerts_24h on a miniTicker or ticker stream needs a single market"))
+	}
+	return errs
+}
+
+// LiquidationsConfig alerts when the notional of the forced orders of a
+// symbol within Window reaches Threshold, in the quote asset; 0 is off.
+type LiquidationsConfig struct {
+	Threshold float64       `yaml:"threshold"`
+	Window    time.Duration `yaml:"window"`
+}
+
+// validate checks l against the symbol's exchange and whether it streams
+// a single market, filling in the default window.
+func (l *LiquidationsConfig) validate(exchange string, single bool) []error {
+	var errs []error
+	if l.Threshold < 0 || l.Window < 0 {
+		errs = append(errs, errors.New("liquidations.threshold and window must not be negative"))
+	}
+	if l.Threshold > 0 {
+		if l.Window == 0 {
+			l.Window = LIQUIDATION_WINDOW
+		}
+		if exchange != SOURCE_BINANCE_FUTURES || !single {
+			errs = append(errs, fmt.Errorf("liquidations needs a single %s market", SOURCE_BINANCE_FUTURES))
+		}
+	}
+	return errs
+}
+
+// OutliersConfig drops a price more than Percent percent from the median
+// of the symbol's last Samples prices (default 9) before it reaches SHM,
+// the checkpoint or any alert. Dropped prices count towards the median, so
+// a real move passes once it has lasted half the samples; 0 is off.
+type OutliersConfig struct {
+	Percent float64 `yaml:"percent"`
+	Samples int     `yaml:"samples"`
+}
+
+// validate checks enabled outlier filtering, filling in the default
+// samples.
+func (o *OutliersConfig) validate() []error {
+	if *o == (OutliersConfig{}) {
+		return nil
+	}
+	var errs []error
+	if o.Samples == 0 {
+		o.Samples = OUTLIER_SAMPLES
+	}
+	switch {
+	case o.Percent <= 0:
+		errs = append(errs, errors.New("outliers.percent must be positive"))
+	case o.Samples < 3 || o.Samples > 100:
+		errs = append(errs, errors.New("outliers.samples must be 3 to 100"))
+	}
+	return errs
+}
+
+// DepthConfig subscribes a Binance symbol to the partial book depth
+// stream of its top Levels (5, 10 or 20, default 10) price levels and
+// alerts when the notional of the bids outweighs that of the asks, or the
+// other way round, Ratio times or more for For (default 10s); 0 is off.
+type DepthConfig struct {
+	Levels int           `yaml:"levels"`
+	Ratio  float64       `yaml:"ratio"`
+	For    time.Duration `yaml:"for"`
+}
+
+// Enabled reports whether d alerts.
+func (d DepthConfig) Enabled() bool { return d.Ratio > 0 }
+
+// validate checks an enabled depth against the symbol's exchange and
+// whether it streams a single market, filling in the default levels and
+// for.
+func (d *DepthConfig) validate(exchange string, single bool) []error {
+	if *d == (DepthConfig{}) {
+		return nil
+	}
+	var errs []error
+	if d.Levels == 0 {
+		d.Levels = DEPTH_LEVELS
+	}
+	if d.For == 0 {
+		d.For = DEPTH_FOR
+	}
+	switch {
+	case d.Ratio <= 1 || d.For < 0:
+		errs = append(errs, errors.New("depth.ratio must be above 1 and for not negative"))
+	case d.Levels != 5 && d.Levels != 10 && d.Levels != 20:
+		errs = append(errs, errors.New("depth.levels must be 5, 10 or 20"))
+	case exchange != SOURCE_BINANCE && exchange != SOURCE_BINANCE_FUTURES || !single:
+		errs = append(errs, fmt.Errorf("depth needs a single %s or %s market", SOURCE_BINANCE, SOURCE_BINANCE_FUTURES))
+	}
+	return errs
+}
+
+// WhalesConfig alerts on each trade of a notional of Notional or more, in
+// the quote asset, and runs Actions with it, such as a pager; 0 is off.
+type WhalesConfig struct {
+	Notional float64  `yaml:"notional"`
+	Actions  []string `yaml:"actions"`
+}
+
+// validate checks the notional and the actions against actions.
+func (w WhalesConfig) validate(actions map[string]ActionConfig) []error {
+	var errs []error
+	switch {
+	case w.Notional < 0:
+		errs = append(errs, errors.New("whales.notional must not be negative"))
+	case w.Notional == 0 && len(w.Actions) > 0:
+		errs = append(errs, errors.New("whales.actions needs whales.notional"))
+	default:
+		for _, name := range w.Actions {
+			if _, ok := actions[name]; !ok {
+				errs = append(errs, fmt.Errorf("whales: unknown action %s", name))
+			}
+		}
+	}
+	return errs
+}
+
+// FundingConfig alerts on the predicted funding rate of a perpetual, in
+// percent per funding interval: whenever it crosses one of Levels, e.g.
+// 0.05 or -0.01, and with SignFlip whenever it changes sign.
+type FundingConfig struct {
+	Levels   []float64 `yaml:"levels"`
+	SignFlip bool      `yaml:"sign_flip"`
+}
+
+// PremiumConfig alerts when the mark price of a perpetual trades Percent
+// or more above its index price (a premium) or below it (a discount); 0 is
+// off.
+type PremiumConfig struct {
+	Percent float64 `yaml:"percent"`
+}
+
+// validate checks p against the symbol's exchange and whether it streams
+// a single market.
+func (p PremiumConfig) validate(exchange string, single bool) []error {
+	switch {
+	case p.Percent < 0:
+		return []error{errors.New("premium.percent must not be negative")}
+	case p.Percent > 0 && (exchange != SOURCE_BINANCE_FUTURES || !single):
+		return []error{fmt.Errorf("premium needs a single %s market", SOURCE_BINANCE_FUTURES)}
+	}
+	return nil
+}
+
+// OpenInterestConfig alerts when the open interest of a perpetual moves by
+// Change percent within Window while its price moves at least a step,
+// either way; 0 is off.
+type OpenInterestConfig struct {
+	Change float64       `yaml:"change"`
+	Window time.Duration `yaml:"window"`
+}
+
+// validate checks o against the symbol's exchange and whether it streams
+// a single market, filling in the default window.
+func (o *OpenInterestConfig) validate(exchange string, single bool) []error {
+	var errs []error
+	if o.Change < 0 || o.Window < 0 {
+		errs = append(errs, errors.New("open_interest.change and window must not be negative"))
+	}
+	if o.Change > 0 {
+		if o.Window == 0 {
+			o.Window = OPEN_INTEREST_WINDOW
+		}
+		if o.Window < OPEN_INTEREST_INTERVAL {
+			errs = append(errs, fmt.Errorf("open_interest.window must be at least the %v poll interval", OPEN_INTEREST_INTERVAL))
+		}
+		if exchange != SOURCE_BINANCE_FUTURES || !single {
+			errs = append(errs, fmt.Errorf("open_interest needs a single %s market", SOURCE_BINANCE_FUTURES))
+		}
+	}
+	return errs
+}
+
+// GasConfig alerts when the base fee drops below one of Below, in gwei.
+type GasConfig struct {
+	Below []float64 `yaml:"below"`
+}
+
+// validate checks the levels of g against the symbol's exchange and
+// whether it streams a single market.
+func (g GasConfig) validate(exchange string, single bool) []error {
+	var errs []error
+	if len(g.Below) > 0 && (exchange != SOURCE_ETHEREUM || !single) {
+		errs = append(errs, fmt.Errorf("gas needs a single %s market", SOURCE_ETHEREUM))
+	}
+	for _, level := range g.Below {
+		if level <= 0 {
+			errs = append(errs, errors.New("gas.below levels must be positive"))
+			break
+		}
+	}
+	return errs
+}
+
+// DivergenceConfig alerts when the price differs from the latest price of
+// symbol From by Percent or more, either way; 0 is off.
+type DivergenceConfig struct {
+	From    string  `yaml:"from"`
+	Percent float64 `yaml:"percent"`
+}
+
+// validate checks that an enabled divergence compares symbol with another
+// configured symbol.
+func (d *DivergenceConfig) validate(c *Config, symbol string) []error {
+	d.From = strings.ToLower(strings.TrimSpace(d.From))
+	switch {
+	case d.Percent < 0:
+		return []error{errors.New("divergence.percent must not be negative")}
+	case d.Percent == 0:
+	case d.From == symbol || c.Symbol(d.From) == nil:
+		return []error{fmt.Errorf("divergence.from must name another configured symbol, not %q", d.From)}
+	}
+	return nil
+}
+
+// BasisConfig tracks the basis of the binance-futures contract Market (by
+// default the symbol's own Binance market) over the symbol's price, from
+// the contract's mark price. It alerts when the basis reaches Percent of
+// the price, or Annualized percent a year, either way; 0 is off.
+type BasisConfig struct {
+	Market     string  `yaml:"market"`
+	Percent    float64 `yaml:"percent"`
+	Annualized float64 `yaml:"annualized"`
+}
+
+// validate checks an enabled basis, defaulting its market to symbol when
+// that streams a single binance market.
+func (b *BasisConfig) validate(exchange, symbol string, markets int) []error {
+	if !b.Enabled() {
+		return nil
+	}
+	b.Market = strings.ToLower(strings.TrimSpace(b.Market))
+	switch {
+	case b.Percent < 0 || b.Annualized < 0:
+		return []error{errors.New("basis.percent and annualized must not be negative")}
+	case b.Market == "" && exchange == SOURCE_BINANCE && markets == 0:
+		b.Market = symbol
+	case b.Market == "":
+		return []error{fmt.Errorf("basis.market must name the %s contract unless the symbol streams from %s", SOURCE_BINANCE_FUTURES, SOURCE_BINANCE)}
+	}
+	return nil
+}
+
+// TargetsConfig lists price levels to alert on when crossed either way:
+// Once alerts a single time, Every on each crossing once the price has
+// moved Rearm (default: the step) away from the level since the last one.
+type TargetsConfig struct {
+	Once  []float64 `yaml:"once"`
+	Every []float64 `yaml:"every"`
+	Rearm float64   `yaml:"rearm"`
+}
+
+// validate checks the rearm distance.
+func (t TargetsConfig) validate() []error {
+	if t.Rearm < 0 {
+		return []error{errors.New("targets.rearm must not be negative")}
+	}
+	return nil
+}
+
+// TrailingConfig alerts when the price retraces Amount, or Percent of the
+// extreme, from its high since arming (From high, the default), from its
+// low (low) or from either (both). It arms at the first price and again
+// after each alert; off while Amount and Percent are 0.
+type TrailingConfig struct {
+	Amount  float64 `yaml:"amount"`
+	Percent float64 `yaml:"percent"`
+	From    string  `yaml:"from"`
+}
+
+// Enabled reports whether t alerts.
+func (t TrailingConfig) Enabled() bool { return t.Amount > 0 || t.Percent > 0 }
+
+// validate checks t once its amount or percent is set, filling in the
+// default from.
+func (t *TrailingConfig) validate() []error {
+	if !t.Enabled() && t.Amount >= 0 && t.Percent >= 0 {
+		return nil
+	}
+	var errs []error
+	t.From = strings.ToLower(t.From)
+	if t.From == "" {
+		t.From = TRAILING_HIGH
+	}
+	switch {
+	case t.Amount < 0 || t.Percent < 0:
+		errs = append(errs, errors.New("trailing.amount and percent must not be negative"))
+	case t.Amount > 0 && t.Percent > 0:
+		errs = append(errs, errors.New("set either trailing.amount or percent, not both"))
+	case t.Percent >= 100:
+		errs = append(errs, errors.New("trailing.percent must be below 100"))
+	case t.From != TRAILING_HIGH && t.From != TRAILING_LOW && t.From != TRAILING_BOTH:
+		errs = append(errs, fmt.Errorf("trailing.from must be %s, %s or %s", TRAILING_HIGH, TRAILING_LOW, TRAILING_BOTH))
+	}
+	return errs
+}
+
+// StreakConfig escalates the announcement of the Count-th step in a row
+// in one direction, and of each one after it, e.g. "ETHUSDT third step down
+// in 20 minutes"; off while Count is 0. With Window set only the steps
+// within it count.
+type StreakConfig struct {
+	Count  int           `yaml:"count"`
+	Window time.Duration `yaml:"window"`
+}
+
+// validate checks the count and window.
+func (s StreakConfig) validate() []error {
+	if s.Count < 0 || s.Count == 1 || s.Window < 0 {
+		return []error{errors.New("streak.count must be 0 or at least 2, and streak.window not negative")}
+	}
+	return nil
+}
+
+// VelocityConfig alerts when the price moves Amount, or Percent of where
+// it started, within Window (default 1m), then starts the window over; off
+// while Amount and Percent are 0.
+type VelocityConfig struct {
+	Amount  float64       `yaml:"amount"`
+	Percent float64       `yaml:"percent"`
+	Window  time.Duration `yaml:"window"`
+}
+
+// Enabled reports whether v alerts.
+func (v VelocityConfig) Enabled() bool { return v.Amount > 0 || v.Percent > 0 }
+
+// validate checks v once any field is set, filling in the default window.
+func (v *VelocityConfig) validate() []error {
+	if *v == (VelocityConfig{}) {
+		return nil
+	}
+	var errs []error
+	if v.Window == 0 {
+		v.Window = VELOCITY_WINDOW
+	}
+	switch {
+	case v.Amount < 0 || v.Percent < 0 || v.Window < 0:
+		errs = append(errs, errors.New("velocity.amount, percent and window must not be negative"))
+	case v.Amount > 0 && v.Percent > 0:
+		errs = append(errs, errors.New("set either velocity.amount or percent, not both"))
+	case !v.Enabled():
+		errs = append(errs, errors.New("velocity needs an amount or percent"))
+	}
+	return errs
+}
+
+// RuleConfig alerts when the expression When turns true, e.g.
+// "price > 3500 && change_1h < -2%", evaluated on every tick (On tick, the
+// default) or as each 1m candle closes (candle), at most once per
+// Cooldown and, with Rearm set, again only once When was false with the
+// price at least Rearm away from the last alert's. For and Candles, which
+// implies candle, make it wait until When has held that long or at that
+// many candle closes in a row. Text, in
+// which {price} stands for the price, replaces the default
+// "<label> <name> at <price>".
+type RuleConfig struct {
+	Name     string        `yaml:"name"`
+	When     string        `yaml:"when"`
+	On       string        `yaml:"on"`
+	Cooldown time.Duration `yaml:"cooldown"`
+	Rearm    float64       `yaml:"rearm"`
+	Text     string        `yaml:"text"`
+	For      time.Duration `yaml:"for"`
+	Candles  int           `yaml:"candles"`
+	// Confirm, e.g. 1m or 5m, alerts only if When still holds when the
+	// candle of that length it turned true in closes.
+	Confirm time.Duration `yaml:"confirm"`
+	// All and Any are further conditions, which must all hold and of
+	// which one must hold, along with When.
+	All []string `yaml:"all"`
+	Any []string `yaml:"any"`
+	// Actions name the actions run when the rule alerts; Quiet runs them
+	// without announcing the alert.
+	Actions []string `yaml:"actions"`
+	Quiet   bool     `yaml:"quiet"`
+	// Severity is one of SEVERITIES, default warn.
+	Severity string `yaml:"severity"`
+	// Expr is When, All and Any parsed into one expression, filled in by
+	// Validate.
+	Expr *expr.Expr `yaml:"-"`
+}
+
+// validate normalises r, parses it into Expr and checks it, its actions
+// against actions and its variables; the caller checks its name.
+func (r *RuleConfig) validate(actions map[string]ActionConfig) []error {
+	var errs []error
+	r.On, r.Severity = strings.ToLower(r.On), strings.ToLower(r.Severity)
+	switch {
+	case r.On == "" && r.Candles > 0:
+		r.On = RULE_CANDLE
+	case r.On == "":
+		r.On = RULE_TICK
+	}
+	var err error
+	switch r.Expr, err = parseRule(*r); {
+	case err != nil:
+		errs = append(errs, err)
+	case r.On != RULE_TICK && r.On != RULE_CANDLE:
+		errs = append(errs, fmt.Errorf("on must be %s or %s", RULE_TICK, RULE_CANDLE))
+	case r.Cooldown < 0 || r.Rearm < 0 || r.For < 0 || r.Candles < 0:
+		errs = append(errs, errors.New("cooldown, rearm, for and candles must not be negative"))
+	case r.Confirm < 0 || r.Confirm > 0 && (r.Confirm%time.Minute != 0 || time.Hour%r.Confirm != 0):
+		errs = append(errs, errors.New("confirm must be a candle length dividing 1h, such as 1m, 5m or 15m"))
+	case r.Candles > 0 && r.On != RULE_CANDLE:
+		errs = append(errs, fmt.Errorf("candles needs on: %s", RULE_CANDLE))
+	case r.Quiet && len(r.Actions) == 0:
+		errs = append(errs, errors.New("a quiet rule needs actions"))
+	case r.Severity != "" && !slices.Contains(SEVERITIES, r.Severity):
+		errs = append(errs, fmt.Errorf("severity must be one of %s", strings.Join(SEVERITIES, ", ")))
+	default:
+		for _, name := range r.Actions {
+			if _, ok := actions[name]; !ok {
+				errs = append(errs, fmt.Errorf("unknown action %s", name))
+			}
+		}
+		for _, name := range r.Expr.Idents() {
+			if _, _, ok := expr.Variable(name); !ok {
+				errs = append(errs, fmt.Errorf("unknown variable %s", name))
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// MovingAveragesConfig keeps Averages, exponential (ema20) or simple
+// (sma200), of the closes of Interval candles, built from the prices and
+// seeded from Binance klines, and alerts on Crossovers of the price or an
+// average with another, such as price/ema200 or ema20/ema50, as those
+// candles close.
+type MovingAveragesConfig struct {
+	Interval   string   `yaml:"interval"`
+	Averages   []string `yaml:"averages"`
+	Crossovers []string `yaml:"crossovers"`
+}
+
+// validate normalises and checks the averages and crossovers, filling in
+// the default interval once either is set.
+func (ma *MovingAveragesConfig) validate() []error {
+	if len(ma.Averages) == 0 && len(ma.Crossovers) == 0 {
+		return nil
+	}
+	var errs []error
+	if ma.Interval == "" {
+		ma.Interval = AVERAGES_INTERVAL
+	}
+	if d := KlineDuration(ma.Interval); d < time.Minute || d > 24*time.Hour {
+		errs = append(errs, fmt.Errorf("moving_averages.interval must be a kline interval from 1m to 1d, not %q", ma.Interval))
+	}
+	for j, name := range ma.Averages {
+		name = strings.ToLower(strings.TrimSpace(name))
+		ma.Averages[j] = name
+		switch _, period, ok := MovingAverage(name); {
+		case !ok:
+			errs = append(errs, fmt.Errorf("moving_averages.averages: bad average %q, want ema or sma and a period, e.g. ema20", name))
+		case period < 2 || period > MAX_AVERAGE_PERIOD:
+			errs = append(errs, fmt.Errorf("moving_averages.averages: %s: period must be between 2 and %d", name, MAX_AVERAGE_PERIOD))
+		case slices.Contains(ma.Averages[:j], name):
+			errs = append(errs, fmt.Errorf("moving_averages.averages: duplicate %s", name))
+		}
+	}
+	for j, cross := range ma.Crossovers {
+		cross = strings.ToLower(strings.ReplaceAll(cross, " ", ""))
+		ma.Crossovers[j] = cross
+		x, y, ok := strings.Cut(cross, "/")
+		if !ok {
+			errs = append(errs, fmt.Errorf("moving_averages.crossovers: want two sides, e.g. price/ema200, not %q", cross))
+			continue
+		}
+		for _, side := range []string{x, y} {
+			if side != "price" && !slices.Contains(ma.Averages, side) {
+				errs = append(errs, fmt.Errorf("moving_averages.crossovers: %q: %q is neither price nor one of the averages", cross, side))
+			}
+		}
+		if x == y {
+			errs = append(errs, fmt.Errorf("moving_averages.crossovers: %q crosses itself", cross))
+		}
+	}
+	return errs
+}
+
+// RSIConfig alerts when the RSI over Period candles of Interval, built
+// from the prices and seeded from Binance klines, closes at or above
+// Overbought or at or below Oversold, and again once it has been Rearm
+// back inside. Setting any field turns it on with the others' defaults.
+type RSIConfig struct {
+	Interval   string  `yaml:"interval"`
+	Period     int     `yaml:"period"`
+	Overbought float64 `yaml:"overbought"`
+	Oversold   float64 `yaml:"oversold"`
+	Rearm      float64 `yaml:"rearm"`
+}
+
+// Enabled reports whether r alerts.
+func (r RSIConfig) Enabled() bool { return r != RSIConfig{} }
+
+// validate checks an enabled RSI, filling in its defaults.
+func (r *RSIConfig) validate() []error {
+	if !r.Enabled() {
+		return nil
+	}
+	if r.Interval == "" {
+		r.Interval = RSI_INTERVAL
+	}
+	if r.Period == 0 {
+		r.Period = RSI_PERIOD
+	}
+	if r.Overbought == 0 {
+		r.Overbought = RSI_OVERBOUGHT
+	}
+	if r.Oversold == 0 {
+		r.Oversold = RSI_OVERSOLD
+	}
+	if r.Rearm == 0 {
+		r.Rearm = RSI_REARM
+	}
+	switch d := KlineDuration(r.Interval); {
+	case d < time.Minute || d > 24*time.Hour:
+		return []error{fmt.Errorf("rsi.interval must be a kline interval from 1m to 1d, not %q", r.Interval)}
+	case r.Period < 2 || r.Period > MAX_AVERAGE_PERIOD:
+		return []error{fmt.Errorf("rsi.period must be between 2 and %d", MAX_AVERAGE_PERIOD)}
+	case r.Oversold < 0 || r.Overbought > 100 || r.Oversold >= r.Overbought:
+		return []error{errors.New("rsi.oversold must be below overbought, both between 0 and 100")}
+	case r.Rearm < 0:
+		return []error{errors.New("rsi.rearm must not be negative")}
+	}
+	return nil
+}
+
+// MACDConfig alerts as candles of Interval close with the MACD, the EMA
+// of Fast closes minus that of Slow, crossing its signal line, the EMA of
+// Signal MACDs, or zero, as listed in Crosses (default both). The candles
+// are built from the prices and seeded from Binance klines. Setting any
+// field turns it on with the others' defaults.
+type MACDConfig struct {
+	Interval string   `yaml:"interval"`
+	Fast     int      `yaml:"fast"`
+	Slow     int      `yaml:"slow"`
+	Signal   int      `yaml:"signal"`
+	Crosses  []string `yaml:"crosses"`
+}
+
+// Enabled reports whether m alerts.
+func (m MACDConfig) Enabled() bool {
+	return m.Interval != "" || m.Fast != 0 || m.Slow != 0 || m.Signal != 0 || len(m.Crosses) > 0
+}
+
+// validate checks an enabled MACD, filling in its defaults.
+func (m *MACDConfig) validate() []error {
+	if !m.Enabled() {
+		return nil
+	}
+	var errs []error
+	if m.Interval == "" {
+		m.Interval = MACD_INTERVAL
+	}
+	if m.Fast == 0 {
+		m.Fast = MACD_FAST
+	}
+	if m.Slow == 0 {
+		m.Slow = MACD_SLOW
+	}
+	if m.Signal == 0 {
+		m.Signal = MACD_SIGNAL
+	}
+	if len(m.Crosses) == 0 {
+		m.Crosses = []string{MACD_SIGNAL_CROSS, MACD_ZERO_CROSS}
+	}
+	for j := range m.Crosses {
+		m.Crosses[j] = strings.ToLower(m.Crosses[j])
+		if c := m.Crosses[j]; c != MACD_SIGNAL_CROSS && c != MACD_ZERO_CROSS {
+			errs = append(errs, fmt.Errorf("macd.crosses: %q is neither %s nor %s", c, MACD_SIGNAL_CROSS, MACD_ZERO_CROSS))
+		}
+	}
+	switch d := KlineDuration(m.Interval); {
+	case d < time.Minute || d > 24*time.Hour:
+		errs = append(errs, fmt.Errorf("macd.interval must be a kline interval from 1m to 1d, not %q", m.Interval))
+	case m.Fast < 1 || m.Signal < 1 || m.Slow > MAX_AVERAGE_PERIOD:
+		errs = append(errs, fmt.Errorf("macd.fast, slow and signal must be between 1 and %d", MAX_AVERAGE_PERIOD))
+	case m.Fast >= m.Slow:
+		errs = append(errs, errors.New("macd.fast must be below slow"))
+	}
+	return errs
+}
+
+// BollingerConfig alerts when a candle of Interval, built from the prices
+// and seeded from Binance klines, closes outside the bands Deviations
+// standard deviations around the mean of the last Period closes, and, with
+// Squeeze set, when the bandwidth, the width of the bands in percent of the
+// mean, contracts below Squeeze. Setting any field turns it on with the
+// others' defaults.
+type BollingerConfig struct {
+	Interval   string  `yaml:"interval"`
+	Period     int     `yaml:"period"`
+	Deviations float64 `yaml:"deviations"`
+	Squeeze    float64 `yaml:"squeeze"`
+}
+
+// Enabled reports whether b alerts.
+func (b BollingerConfig) Enabled() bool { return b != BollingerConfig{} }
+
+// validate checks enabled bands, filling in their defaults.
+func (b *BollingerConfig) validate() []error {
+	if !b.Enabled() {
+		return nil
+	}
+	if b.Interval == "" {
+		b.Interval = BOLLINGER_INTERVAL
+	}
+	if b.Period == 0 {
+		b.Period = BOLLINGER_PERIOD
+	}
+	if b.Deviations == 0 {
+		b.Deviations = BOLLINGER_DEVIATIONS
+	}
+	switch d := KlineDuration(b.Interval); {
+	case d < time.Minute || d > 24*time.Hour:
+		return []error{fmt.Errorf("bollinger.interval must be a kline interval from 1m to 1d, not %q", b.Interval)}
+	case b.Period < 2 || b.Period > MAX_AVERAGE_PERIOD:
+		return []error{fmt.Errorf("bollinger.period must be between 2 and %d", MAX_AVERAGE_PERIOD)}
+	case b.Deviations < 0 || b.Squeeze < 0:
+		return []error{errors.New("bollinger.deviations and squeeze must not be negative")}
+	}
+	return nil
+}
+
+// VolumeConfig alerts when the volume traded in a candle of Interval,
+// built from the trades and seeded from Binance klines, reaches Multiple
+// times the average volume of the last Period candles, once a candle.
+// Setting any field turns it on with the others' defaults.
+type VolumeConfig struct {
+	Interval string  `yaml:"interval"`
+	Period   int     `yaml:"period"`
+	Multiple float64 `yaml:"multiple"`
+}
+
+// Enabled reports whether v alerts.
+func (v VolumeConfig) Enabled() bool { return v != VolumeConfig{} }
+
+// validate checks enabled volume alerts, filling in their defaults.
+func (v *VolumeConfig) validate() []error {
+	if !v.Enabled() {
+		return nil
+	}
+	if v.Interval == "" {
+		v.Interval = VOLUME_INTERVAL
+	}
+	if v.Period == 0 {
+		v.Period = VOLUME_PERIOD
+	}
+	if v.Multiple == 0 {
+		v.Multiple = VOLUME_MULTIPLE
+	}
+	switch d := KlineDuration(v.Interval); {
+	case d < time.Minute || d > 24*time.Hour:
+		return []error{fmt.Errorf("volume.interval must be a kline interval from 1m to 1d, not %q", v.Interval)}
+	case v.Period < 1 || v.Period > MAX_AVERAGE_PERIOD:
+		return []error{fmt.Errorf("volume.period must be between 1 and %d", MAX_AVERAGE_PERIOD)}
+	case v.Multiple <= 1:
+		return []error{errors.New("volume.multiple must be above 1")}
+	}
+	return nil
+}
+
+// VolumeDeltaConfig alerts when the volume bought by takers minus the
+// volume sold within a rolling Window (default 5m) reaches Imbalance
+// percent of the volume traded, either way, once the window has traded
+// MinVolume; off while Imbalance is 0.
+type VolumeDeltaConfig struct {
+	Imbalance float64       `yaml:"imbalance"`
+	Window    time.Duration `yaml:"window"`
+	MinVolume float64       `yaml:"min_volume"`
+}
+
+// Enabled reports whether d alerts.
+func (d VolumeDeltaConfig) Enabled() bool { return d.Imbalance > 0 }
+
+// validate checks d once any field is set, filling in the default window.
+func (d *VolumeDeltaConfig) validate() []error {
+	if *d == (VolumeDeltaConfig{}) {
+		return nil
+	}
+	if d.Window == 0 {
+		d.Window = VOLUME_DELTA_WINDOW
+	}
+	switch {
+	case d.Imbalance <= 0 || d.Imbalance > 100:
+		return []error{errors.New("volume_delta.imbalance must be above 0 and at most 100")}
+	case d.Window < time.Second:
+		return []error{errors.New("volume_delta.window must be at least 1s")}
+	case d.MinVolume < 0:
+		return []error{errors.New("volume_delta.min_volume must not be negative")}
+	}
+	return nil
+}
+
+// SpreadConfig alerts when the bid-ask spread of a quoting stream, in
+// percent of the midpoint, has stayed Multiple times its normal or wider
+// for For (default 10s); normal is its time-weighted average over Window
+// (default 15m), leaving out wide spreads. Off while Multiple is 0.
+type SpreadConfig struct {
+	Multiple float64       `yaml:"multiple"`
+	For      time.Duration `yaml:"for"`
+	Window   time.Duration `yaml:"window"`
+}
+
+// Enabled reports whether s alerts.
+func (s SpreadConfig) Enabled() bool { return s.Multiple > 0 }
+
+// validate checks s once any field is set, filling in the default for and
+// window.
+func (s *SpreadConfig) validate() []error {
+	if *s == (SpreadConfig{}) {
+		return nil
+	}
+	if s.For == 0 {
+		s.For = SPREAD_FOR
+	}
+	if s.Window == 0 {
+		s.Window = SPREAD_WINDOW
+	}
+	switch {
+	case s.Multiple <= 1:
+		return []error{errors.New("spread.multiple must be above 1")}
+	case s.For < 0 || s.Window < time.Minute:
+		return []error{errors.New("spread.for must not be negative and window must be at least 1m")}
+	}
+	return nil
+}
+
+// VWAPConfig keeps the VWAP of sessions starting daily at Anchor, a time
+// of day in Timezone (default UTC), and alerts when the price strays
+// Percent percent or Deviations volume-weighted standard deviations from
+// it, and again once it has come back within half that. Setting any field
+// turns it on; without Percent and Deviations it only tracks the VWAP.
+type VWAPConfig struct {
+	Anchor     string  `yaml:"anchor"`
+	Timezone   string  `yaml:"timezone"`
+	Percent    float64 `yaml:"percent"`
+	Deviations float64 `yaml:"deviations"`
+	// Start is Anchor as an offset from midnight and Location Timezone,
+	// filled in by Validate.
+	Start    time.Duration  `yaml:"-"`
+	Location *time.Location `yaml:"-"`
+}
+
+// Enabled reports whether v tracks the VWAP.
+func (v VWAPConfig) Enabled() bool {
+	return v.Anchor != "" || v.Timezone != "" || v.Percent != 0 || v.Deviations != 0
+}
+
+// Session returns the start of the session at falls in.
+func (v VWAPConfig) Session(at time.Time) time.Time { return dayStart(at, v.Start, v.Location) }
+
+// validate checks an enabled VWAP, filling in the default anchor, Start
+// and Location.
+func (v *VWAPConfig) validate() []error {
+	if !v.Enabled() {
+		return nil
+	}
+	var errs []error
+	if v.Anchor == "" {
+		v.Anchor = VWAP_ANCHOR
+	}
+	if v.Location = time.UTC; v.Timezone != "" {
+		loc, err := time.LoadLocation(v.Timezone)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("vwap.timezone: %w", err))
+		} else {
+			v.Location = loc
+		}
+	}
+	start, err := parseClock(v.Anchor)
+	switch {
+	case err != nil:
+		errs = append(errs, fmt.Errorf("vwap.anchor %w", err))
+	case v.Percent < 0 || v.Deviations < 0:
+		errs = append(errs, errors.New("vwap.percent and deviations must not be negative"))
+	default:
+		v.Start = start
+	}
+	return errs
+}
+
+// PegConfig watches a stablecoin pair, such as usdcusdt, for depegs: it
+// alerts when the price strays Band percent (default 0.5) or more from
+// Target (default 1), every further Band deeper and every Repeat (default
+// 1m) while it stays out, and once it is back within half of Band. Setting
+// any field turns it on.
+type PegConfig struct {
+	Target float64       `yaml:"target"`
+	Band   float64       `yaml:"band"`
+	Repeat time.Duration `yaml:"repeat"`
+}
+
+// Enabled reports whether p alerts.
+func (p PegConfig) Enabled() bool { return p != PegConfig{} }
+
+// validate checks an enabled peg, filling in its defaults.
+func (p *PegConfig) validate() []error {
+	if !p.Enabled() {
+		return nil
+	}
+	if p.Target == 0 {
+		p.Target = PEG_TARGET
+	}
+	if p.Band == 0 {
+		p.Band = PEG_BAND
+	}
+	if p.Repeat == 0 {
+		p.Repeat = PEG_REPEAT
+	}
+	if p.Target < 0 || p.Band < 0 || p.Repeat < 0 {
+		return []error{errors.New("peg.target, band and repeat must not be negative")}
+	}
+	return nil
+}
+
+// LevelsConfig finds support and resistance in the last Lookback candles
+// of Interval, built from the prices and seeded from Binance klines: the
+// swing highs and lows, candles whose high or low Swing candles on either
+// side do not reach, that no later candle went past. It alerts when the
+// price comes within Approach percent of the nearest level above or below,
+// again once it has been twice that away, and when it breaks one. Setting
+// any field turns it on with the others' defaults.
+type LevelsConfig struct {
+	Interval string  `yaml:"interval"`
+	Lookback int     `yaml:"lookback"`
+	Swing    int     `yaml:"swing"`
+	Approach float64 `yaml:"approach"`
+}
+
+// Enabled reports whether l alerts.
+func (l LevelsConfig) Enabled() bool { return l != LevelsConfig{} }
+
+// validate checks enabled levels, filling in their defaults.
+func (l *LevelsConfig) validate() []error {
+	if !l.Enabled() {
+		return nil
+	}
+	if l.Interval == "" {
+		l.Interval = LEVELS_INTERVAL
+	}
+	if l.Lookback == 0 {
+		l.Lookback = LEVELS_LOOKBACK
+	}
+	if l.Swing == 0 {
+		l.Swing = LEVELS_SWING
+	}
+	if l.Approach == 0 {
+		l.Approach = LEVELS_APPROACH
+	}
+	switch d := KlineDuration(l.Interval); {
+	case d < time.Minute || d > 7*24*time.Hour:
+		return []error{fmt.Errorf("levels.interval must be a kline interval from 1m to 1w, not %q", l.Interval)}
+	case l.Swing < 1 || l.Approach < 0:
+		return []error{errors.New("levels.swing must be at least 1 and approach not negative")}
+	case l.Lookback < 2*l.Swing+1 || l.Lookback >= MAX_BACKFILL_KLINES:
+		return []error{fmt.Errorf("levels.lookback must be between %d and %d candles", 2*l.Swing+1, MAX_BACKFILL_KLINES-1)}
+	}
+	return nil
+}
+
+// PIVOT_PERIODS are the periods pivots are computed from, and
+// PIVOT_LEVELS their levels.
+var (
+	PIVOT_PERIODS = []string{"1d", "1w"}
+	PIVOT_LEVELS  = []string{"S3", "S2", "S1", "P", "R1", "R2", "R3"}
+)
+
+// PivotsConfig alerts when the price touches or crosses the classic pivots of
+// the previous period of each of Periods (default 1d), days and weeks in
+// UTC like Binance's klines, the previous one seeded from them: the pivot
+// P (high + low + close) / 3, R1 2P - low, S1 2P - high, R2 and S2 P plus
+// and minus the range, R3 high + 2(P - low) and S3 low - 2(high - P), of
+// which Levels picks (default all). A level crossed alerts again once the
+// price has been Rearm percent (default 0.1) away from it. Setting any
+// field turns it on.
+type PivotsConfig struct {
+	Periods []string `yaml:"periods"`
+	Levels  []string `yaml:"levels"`
+	Rearm   float64  `yaml:"rearm"`
+}
+
+// Enabled reports whether p alerts.
+func (p PivotsConfig) Enabled() bool {
+	return len(p.Periods) > 0 || len(p.Levels) > 0 || p.Rearm != 0
+}
+
+// validate normalises and checks enabled pivots, filling in their
+// defaults.
+func (p *PivotsConfig) validate() []error {
+	if !p.Enabled() {
+		return nil
+	}
+	var errs []error
+	if len(p.Periods) == 0 {
+		p.Periods = []string{PIVOTS_PERIOD}
+	}
+	if len(p.Levels) == 0 {
+		p.Levels = slices.Clone(PIVOT_LEVELS)
+	}
+	if p.Rearm == 0 {
+		p.Rearm = PIVOTS_REARM
+	}
+	for _, period := range p.Periods {
+		if !slices.Contains(PIVOT_PERIODS, period) {
+			errs = append(errs, fmt.Errorf("pivots.periods: %q is not one of %s", period, strings.Join(PIVOT_PERIODS, ", ")))
+		}
+	}
+	for j, level := range p.Levels {
+		if p.Levels[j] = strings.ToUpper(level); !slices.Contains(PIVOT_LEVELS, p.Levels[j]) {
+			errs = append(errs, fmt.Errorf("pivots.levels: %q is not one of %s", level, strings.Join(PIVOT_LEVELS, ", ")))
+		}
+	}
+	if p.Rearm < 0 {
+		errs = append(errs, errors.New("pivots.rearm must not be negative"))
+	}
+	return errs
+}
+
+// VOLATILITY_WINDOWS are the default windows of volatility.
+var VOLATILITY_WINDOWS = []string{"1h", "1d"}
+
+// VolatilityConfig keeps the realized volatility of the close-to-close log
+// returns of Interval (default 5m) candles, built from the prices, over
+// each of Windows (default 1h and 1d), annualized over 365 days in
+// percent, and alerts when one rises above Above or falls below Below,
+// again once it has come back 10% inside. Setting any field turns it on.
+type VolatilityConfig struct {
+	Interval string   `yaml:"interval"`
+	Windows  []string `yaml:"windows"`
+	Above    float64  `yaml:"above"`
+	Below    float64  `yaml:"below"`
+}
+
+// Enabled reports whether v is tracked.
+func (v VolatilityConfig) Enabled() bool {
+	return v.Interval != "" || len(v.Windows) > 0 || v.Above != 0 || v.Below != 0
+}
+
+// validate checks tracked volatility and its windows, filling in the
+// default interval and windows.
+func (v *VolatilityConfig) validate() []error {
+	if !v.Enabled() {
+		return nil
+	}
+	var errs []error
+	if v.Interval == "" {
+		v.Interval = VOLATILITY_INTERVAL
+	}
+	if len(v.Windows) == 0 {
+		v.Windows = slices.Clone(VOLATILITY_WINDOWS)
+	}
+	interval := KlineDuration(v.Interval)
+	switch {
+	case interval < time.Minute || interval > 24*time.Hour:
+		errs = append(errs, fmt.Errorf("volatility.interval must be a kline interval from 1m to 1d, not %q", v.Interval))
+	case v.Above < 0 || v.Below < 0 || v.Above > 0 && v.Below >= v.Above:
+		errs = append(errs, errors.New("volatility.above and below must not be negative, below under above"))
+	}
+	for j, window := range v.Windows {
+		d := KlineDuration(window)
+		if interval >= time.Minute && (d == 0 || d%interval != 0 || d < 3*interval || d/interval >= MAX_BACKFILL_KLINES || slices.Contains(v.Windows[:j], window)) {
+			errs = append(errs, fmt.Errorf("volatility.windows: %q must be a kline interval of 3 to %d %s candles, listed once", window, MAX_BACKFILL_KLINES-1, v.Interval))
+		}
+	}
+	return errs
+}
+
+// DailyChangeConfig alerts when the price of a day starting at Anchor, a
+// time of day in Timezone (default UTC), such as an exchange's open, has
+// moved each of Percents (default 1, 2 and 5) up or down from its open,
+// once a day each. Setting any field turns it on.
+type DailyChangeConfig struct {
+	Anchor   string    `yaml:"anchor"`
+	Timezone string    `yaml:"timezone"`
+	Percents []float64 `yaml:"percents"`
+	// Start is Anchor as an offset from midnight and Location Timezone,
+	// filled in by Validate.
+	Start    time.Duration  `yaml:"-"`
+	Location *time.Location `yaml:"-"`
+}
+
+// DAILY_CHANGE_PERCENTS are the default daily_change.percents.
+var DAILY_CHANGE_PERCENTS = []float64{1, 2, 5}
+
+// Enabled reports whether d alerts.
+func (d DailyChangeConfig) Enabled() bool {
+	return d.Anchor != "" || d.Timezone != "" || len(d.Percents) > 0
+}
+
+// Day returns the start of the day at falls in.
+func (d DailyChangeConfig) Day(at time.Time) time.Time { return dayStart(at, d.Start, d.Location) }
+
+// validate checks an enabled daily change, sorting its percents and
+// filling in the default anchor, percents, Start and Location.
+func (d *DailyChangeConfig) validate() []error {
+	if !d.Enabled() {
+		return nil
+	}
+	var errs []error
+	if d.Anchor == "" {
+		d.Anchor = DAILY_CHANGE_ANCHOR
+	}
+	if len(d.Percents) == 0 {
+		d.Percents = DAILY_CHANGE_PERCENTS
+	}
+	if d.Location = time.UTC; d.Timezone != "" {
+		loc, err := time.LoadLocation(d.Timezone)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("daily_change.timezone: %w", err))
+		} else {
+			d.Location = loc
+		}
+	}
+	// Ascending, so the thresholds passed are a prefix
+	d.Percents = slices.Clone(d.Percents)
+	slices.Sort(d.Percents)
+	d.Percents = slices.Compact(d.Percents)
+	start, err := parseClock(d.Anchor)
+	switch {
+	case err != nil:
+		errs = append(errs, fmt.Errorf("daily_change.anchor %w", err))
+	case d.Percents[0] <= 0:
+		errs = append(errs, errors.New("daily_change.percents must be positive"))
+	default:
+		d.Start = start
+	}
+	return errs
+}
+
+// Equal reports whether d and o are the same settings.
+func (d DailyChangeConfig) Equal(o DailyChangeConfig) bool {
+	return d.Anchor == o.Anchor && d.Timezone == o.Timezone && slices.Equal(d.Percents, o.Percents)
+}
+
+// dayStart returns the last time at or before at that is start past
+// midnight in loc.
+func dayStart(at time.Time, start time.Duration, loc *time.Location) time.Time {
+	local := at.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc).Add(start)
+	if day.After(local) {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+// MovingAverage parses the name of an average, such as ema20, into whether
+// it is exponential and its period; ok is false for a bad name.
+func MovingAverage(name string) (exponential bool, period int, ok bool) {
+	kind, digits := name[:min(3, len(name))], name[min(3, len(name)):]
+	period, err := strconv.Atoi(digits)
+	if err != nil || (kind != "ema" && kind != "sma") || strconv.Itoa(period) != digits {
+		return false, 0, false
+	}
+	return kind == "ema", period, true
+}
+
+// parseRule parses When, All and Any of r into one expression, failing
+// with the first of them that does not parse.
+func parseRule(r RuleConfig) (*expr.Expr, error) {
+	if len(r.All) == 0 && len(r.Any) == 0 {
+		if r.When == "" {
+			return nil, errors.New("when, all or any is required")
+		}
+		return expr.Parse(r.When)
+	}
+	var parts []string
+	for _, cond := range append([]string{r.When}, r.All...) {
+		if cond != "" {
+			parts = append(parts, "("+cond+")")
+		}
+	}
+	if len(r.Any) > 0 {
+		parts = append(parts, "(("+strings.Join(r.Any, ") || (")+"))")
+	}
+	for _, cond := range slices.Concat([]string{r.When}, r.All, r.Any) {
+		if _, err := expr.Parse(cond); cond != "" && err != nil {
+			return nil, err
+		}
+	}
+	return expr.Parse(strings.Join(parts, " && "))
+}
+
+// ATRConfig makes the step Multiple times the average true range of the
+// last Period candles of Interval, built from the prices and seeded from
+// Binance klines; the fixed step stands in until Period candles closed.
+// Off while Multiple is 0.
+type ATRConfig struct {
+	Multiple float64 `yaml:"multiple"`
+	Interval string  `yaml:"interval"`
+	Period   int     `yaml:"period"`
+}
+
+// validate checks an enabled ATR against the symbol's stepPercent,
+// filling in the default interval and period.
+func (a *ATRConfig) validate(stepPercent float64) []error {
+	if a.Multiple == 0 {
+		return nil
+	}
+	if a.Interval == "" {
+		a.Interval = ATR_INTERVAL
+	}
+	if a.Period == 0 {
+		a.Period = ATR_PERIOD
+	}
+	switch d := KlineDuration(a.Interval); {
+	case a.Multiple < 0 || a.Period < 0:
+		return []error{errors.New("atr.multiple and period must be positive")}
+	case d < time.Minute || d > 24*time.Hour:
+		return []error{fmt.Errorf("atr.interval must be a kline interval from 1m to 1d, not %q", a.Interval)}
+	case stepPercent > 0:
+		return []error{errors.New("set either step_percent or atr, not both")}
+	}
+	return nil
+}
+
+// CorrelationConfig alerts when the rolling correlation of the symbol's
+// returns with those of symbol With over Window falls below Below; off
+// without With.
+type CorrelationConfig struct {
+	With   string        `yaml:"with"`
+	Window time.Duration `yaml:"window"`
+	Below  float64       `yaml:"below"`
+}
+
+// validate checks that an enabled correlation compares symbol with
+// another configured symbol, filling in the default window and below.
+func (r *CorrelationConfig) validate(c *Config, symbol string) []error {
+	if r.With == "" {
+		return nil
+	}
+	r.With = strings.ToLower(strings.TrimSpace(r.With))
+	if r.Window == 0 {
+		r.Window = CORRELATION_WINDOW
+	}
+	if r.Below == 0 {
+		r.Below = CORRELATION_BELOW
+	}
+	switch {
+	case r.With == symbol || c.Symbol(r.With) == nil:
+		return []error{fmt.Errorf("correlation.with must name another configured symbol, not %q", r.With)}
+	case r.Window < MIN_CORRELATION_WINDOW:
+		return []error{fmt.Errorf("correlation.window must be at least %v", MIN_CORRELATION_WINDOW)}
+	case r.Below <= -1 || r.Below >= 1:
+		return []error{errors.New("correlation.below must be between -1 and 1")}
+	}
+	return nil
+}
+
+// StepAt returns the alert step of s at checkpoint: StepPercent percent of
+// it when set, else Step, which also stands in while the checkpoint is 0.
+func (s SymbolConfig) StepAt(checkpoint float64) float64 {
+	if step := math.Abs(checkpoint) * s.StepPercent / 100; step > 0 {
+		return step
+	}
+	return s.Step
+}
+
+// Enabled reports whether f raises any alert.
+func (f FundingConfig) Enabled() bool {
+	return len(f.Levels) > 0 || f.SignFlip
+}
+
+// validate checks f against the symbol's exchange and whether it streams
+// a single market.
+func (f FundingConfig) validate(exchange string, single bool) []error {
+	var errs []error
+	if f.Enabled() && (exchange != SOURCE_BINANCE_FUTURES || !single) {
+		errs = append(errs, fmt.Errorf("funding needs a single %s market", SOURCE_BINANCE_FUTURES))
+	}
+	if slices.Contains(f.Levels, 0) {
+		errs = append(errs, errors.New("funding.levels must not be 0, use sign_flip"))
+	}
+	return errs
+}
+
+// Enabled reports whether b tracks a basis.
+func (b BasisConfig) Enabled() bool {
+	return b.Market != "" || b.Percent != 0 || b.Annualized != 0
+}
+
+// Format renders price with the symbol's precision in the plain format SHM
+// readers parse.
+func (s SymbolConfig) Format(price float64) string {
+	return s.Display(numfmt.Plain, price)
+}
+
+// Display renders price with the symbol's precision for people.
+func (s SymbolConfig) Display(loc numfmt.Locale, price float64) string {
+	precision := 2
+	if s.Precision != nil {
+		precision = *s.Precision
+	}
+	return loc.Format(price, precision)
+}
+
+// DisplayAlert renders price for an alert: whole units when the step is at
+// least 1, otherwise with the symbol's precision.
+func (s SymbolConfig) DisplayAlert(loc numfmt.Locale, price float64) string {
+	if s.Step >= 1 {
+		return loc.Format(math.Trunc(price), 0)
+	}
+	return s.Display(loc, price)
+}
+
+// RecordSize is the largest SHM record s can produce. An unset precision
+// counts as MAX_PRECISION.
+func (c Config) RecordSize(s SymbolConfig) int {
+	precision := MAX_PRECISION
+	if s.Precision != nil {
+		precision = *s.Precision
+	}
+	price := MAX_PRICE_DIGITS
+	if precision > 0 {
+		price += 1 + precision
+	}
+	size := price + 1 + len(s.Symbol) + 1
+	switch {
+	case len(s.Failover) > 0:
+		size += len(SOURCE_BINANCE_FUTURES) + 1 // the active source, longest exchange name
+	case c.Reconnect.PollInterval > 0:
+		size += len(REST_SOURCE) + 1
+	}
+	return size
+}
+
+// StatsRecordSize is the largest stats_path record s can produce: the
+// label, the 24h and funding fields, one per moving average, the VWAP, two
+// per session, five per candles interval and one per volatility window,
+// each NUL-terminated. An unset precision counts as MAX_PRECISION.
+func (c Config) StatsRecordSize(s SymbolConfig) int {
+	precision := MAX_PRECISION
+	if s.Precision != nil {
+		precision = *s.Precision
+	}
+	price := MAX_PRICE_DIGITS
+	if precision > 0 {
+		price += 1 + precision
+	}
+	size := len(s.Symbol) + 1
+	size += 3*(price+1) + 2*(MAX_VOLUME_DIGITS+1) + MAX_PERCENT_DIGITS + 1 // 24h
+	size += 2*(MAX_FUNDING_DIGITS+1) + MAX_MILLIS_DIGITS + 1               // funding
+	size += len(s.MovingAverages.Averages) * (price + 1)
+	if s.VWAP.Enabled() {
+		size += price + 1
+	}
+	size += 2 * len(s.Sessions) * (price + 1)
+	size += len(s.Candles) * (4*(price+1) + MAX_VOLUME_DIGITS + 1)
+	if s.Volatility.Enabled() {
+		size += len(s.Volatility.Windows) * (MAX_PERCENT_DIGITS + 1)
+	}
+	return size
+}
+
+// Label is the upper-case symbol used in logs and SHM records.
+func (s SymbolConfig) Label() string {
+	return strings.ToUpper(s.Symbol)
+}
@@ -0,0 +1,126 @@
+// Package control implements the line-based admin protocol spoken over the
+// writer's Unix control socket. Each request line is split into words and
+// answered with one or more lines of text.
+package control
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Request is one command line received from a control client.
+type Request struct {
+	Args  []string
+	reply chan string
+}
+
+// Reply answers the request. It must be called exactly once.
+func (r Request) Reply(text string) {
+	r.reply <- text
+}
+
+// Server accepts control connections and sends their commands to reqs, so
+// the commands run on the goroutine that drains it.
+type Server struct {
+	ln   net.Listener
+	path string
+	reqs chan<- Request
+}
+
+// Listen creates the socket at path, replacing a stale one left by a
+// crashed process, and restricts it to the current user.
+func Listen(path string, reqs chan<- Request) (*Server, error) {
+	if _, err := os.Stat(path); err == nil {
+		if c, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("%s: already in use by another process", path)
+		}
+		os.Remove(path)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	s := &Server{ln: ln, path: path, reqs: reqs}
+	go s.accept()
+	return s, nil
+}
+
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	os.Remove(s.path)
+	return err
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		go s.serve(conn)
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	Serve(conn, conn, "", s.reqs)
+}
+
+// Serve reads command lines from r, sends each to reqs and writes the reply
+// to w until r is exhausted. A non-empty prompt is written before every
+// line, for interactive use.
+func Serve(r io.Reader, w io.Writer, prompt string, reqs chan<- Request) {
+	sc := bufio.NewScanner(r)
+	for {
+		if prompt != "" {
+			io.WriteString(w, prompt)
+		}
+		if !sc.Scan() {
+			return
+		}
+		args := strings.Fields(sc.Text())
+		if len(args) == 0 {
+			continue
+		}
+		req := Request{Args: args, reply: make(chan string, 1)}
+		reqs <- req
+		text := <-req.reply
+		if !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		if _, err := io.WriteString(w, text); err != nil {
+			return
+		}
+	}
+}
+
+// Send runs one command against the socket at path and copies the answer
+// to w.
+func Send(path, command string, w io.Writer) error {
+	conn, err := net.DialTimeout("unix", path, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, command+"\n"); err != nil {
+		return err
+	}
+	// Half-close so the server sees EOF after answering
+	conn.(*net.UnixConn).CloseWrite()
+	_, err = io.Copy(w, conn)
+	return err
+}
@@ -0,0 +1,371 @@
+// Package expr parses and evaluates the expressions of rules, such as
+// price > 3500 && change_1h < -2% && volume_5m > 2*avg_volume_5m.
+//
+// Expressions combine numbers, variables and the functions abs, min and
+// max with + - * /, the comparisons < <= > >= == != and the logic
+// operators && || !, with the usual precedence. Comparisons and logic
+// yield 1 or 0, and any non-zero value is true. A % after a number only
+// marks it as a percentage, so 2% is 2.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrNoData is returned by a lookup, and passed on by Eval, for a variable
+// that has no value yet, such as a change over more history than seen.
+var ErrNoData = errors.New("no data")
+
+// Expr is a parsed expression.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Lookup returns the value of a variable.
+type Lookup func(name string) (float64, error)
+
+type node interface {
+	eval(Lookup) (float64, error)
+}
+
+type (
+	number float64
+	ident  string
+	unary  struct {
+		op string
+		x  node
+	}
+	binary struct {
+		op   string
+		x, y node
+	}
+	call struct {
+		fn   string
+		args []node
+	}
+)
+
+// Parse parses src.
+func Parse(src string) (*Expr, error) {
+	p := &parser{src: src}
+	p.next()
+	root, err := p.or()
+	if err == nil && p.tok != "" {
+		err = fmt.Errorf("unexpected %q", p.tok)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", src, err)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// String returns the source of e.
+func (e *Expr) String() string { return e.src }
+
+// Eval evaluates e with the variables of lookup.
+func (e *Expr) Eval(lookup Lookup) (float64, error) {
+	return e.root.eval(lookup)
+}
+
+// Idents returns the variables e refers to, in order of appearance.
+func (e *Expr) Idents() []string {
+	var names []string
+	var walk func(node)
+	walk = func(n node) {
+		switch n := n.(type) {
+		case ident:
+			names = append(names, string(n))
+		case unary:
+			walk(n.x)
+		case binary:
+			walk(n.x)
+			walk(n.y)
+		case call:
+			for _, a := range n.args {
+				walk(a)
+			}
+		}
+	}
+	walk(e.root)
+	return names
+}
+
+func (n number) eval(Lookup) (float64, error) { return float64(n), nil }
+
+func (n ident) eval(lookup Lookup) (float64, error) { return lookup(string(n)) }
+
+func (n unary) eval(lookup Lookup) (float64, error) {
+	x, err := n.x.eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	if n.op == "!" {
+		return truth(x == 0), nil
+	}
+	return -x, nil
+}
+
+func (n binary) eval(lookup Lookup) (float64, error) {
+	x, err := n.x.eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	// && and || short-circuit, so a false guard spares missing data
+	switch {
+	case n.op == "&&" && x == 0:
+		return 0, nil
+	case n.op == "||" && x != 0:
+		return 1, nil
+	}
+	y, err := n.y.eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		if y == 0 {
+			return 0, ErrNoData
+		}
+		return x / y, nil
+	case "<":
+		return truth(x < y), nil
+	case "<=":
+		return truth(x <= y), nil
+	case ">":
+		return truth(x > y), nil
+	case ">=":
+		return truth(x >= y), nil
+	case "==":
+		return truth(x == y), nil
+	case "!=":
+		return truth(x != y), nil
+	}
+	return truth(y != 0), nil // && and ||
+}
+
+func (n call) eval(lookup Lookup) (float64, error) {
+	args := make([]float64, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(lookup)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+	switch n.fn {
+	case "abs":
+		return math.Abs(args[0]), nil
+	case "min":
+		return min(args[0], args[1]), nil
+	}
+	return max(args[0], args[1]), nil
+}
+
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// arity is the number of arguments of each function.
+var arity = map[string]int{"abs": 1, "min": 2, "max": 2}
+
+// OPERATORS are the operators of two characters.
+var OPERATORS = []string{"<=", ">=", "==", "!=", "&&", "||"}
+
+type parser struct {
+	src string
+	pos int
+	tok string // "" at the end
+}
+
+// next moves to the next token.
+func (p *parser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos == len(p.src) {
+		p.tok = ""
+		return
+	}
+	switch c := p.src[p.pos]; {
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+			p.pos++
+		}
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || unicode.IsLetter(rune(p.src[p.pos])) || unicode.IsDigit(rune(p.src[p.pos]))) {
+			p.pos++
+		}
+	default:
+		p.pos++
+		if p.pos < len(p.src) && slices.Contains(OPERATORS, p.src[start:p.pos+1]) {
+			p.pos++
+		}
+	}
+	p.tok = p.src[start:p.pos]
+}
+
+func (p *parser) or() (node, error) {
+	return p.binary(p.and, "||")
+}
+
+func (p *parser) and() (node, error) {
+	return p.binary(p.comparison, "&&")
+}
+
+func (p *parser) comparison() (node, error) {
+	x, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	switch op := p.tok; op {
+	case "<", "<=", ">", ">=", "==", "!=":
+		p.next()
+		y, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		return binary{op, x, y}, nil
+	}
+	return x, nil
+}
+
+func (p *parser) sum() (node, error) {
+	return p.binary(p.product, "+", "-")
+}
+
+func (p *parser) product() (node, error) {
+	return p.binary(p.unary, "*", "/")
+}
+
+// binary parses a left-associative chain of operand joined by ops.
+func (p *parser) binary(operand func() (node, error), ops ...string) (node, error) {
+	x, err := operand()
+	for err == nil && slices.Contains(ops, p.tok) {
+		op := p.tok
+		p.next()
+		var y node
+		y, err = operand()
+		x = binary{op, x, y}
+	}
+	return x, err
+}
+
+func (p *parser) unary() (node, error) {
+	if op := p.tok; op == "-" || op == "!" {
+		p.next()
+		x, err := p.unary()
+		return unary{op, x}, err
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	tok := p.tok
+	switch {
+	case tok == "":
+		return nil, errors.New("unexpected end")
+	case tok == "(":
+		p.next()
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok != ")" {
+			return nil, errors.New("missing )")
+		}
+		p.next()
+		return x, nil
+	case tok[0] >= '0' && tok[0] <= '9' || tok[0] == '.':
+		v, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q", tok)
+		}
+		if p.next(); p.tok == "%" {
+			p.next()
+		}
+		return number(v), nil
+	case tok[0] == '_' || unicode.IsLetter(rune(tok[0])):
+		if p.next(); p.tok != "(" {
+			return ident(tok), nil
+		}
+		n, ok := arity[tok]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %s", tok)
+		}
+		var args []node
+		for p.next(); len(args) < n; {
+			a, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, a)
+			if len(args) < n {
+				if p.tok != "," {
+					return nil, fmt.Errorf("%s takes %d arguments", tok, n)
+				}
+				p.next()
+			}
+		}
+		if p.tok != ")" {
+			return nil, fmt.Errorf("%s takes %d arguments", tok, n)
+		}
+		p.next()
+		return call{tok, args}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", tok)
+}
+
+// WINDOWS are the windows of the windowed variables, as in change_1h.
+var WINDOWS = []string{"1m", "5m", "15m", "30m", "1h", "4h", "12h", "24h"}
+
+// VARIABLES are the plain variables; WINDOWED are the names the windowed
+// ones put before one of WINDOWS.
+var (
+	VARIABLES = []string{"price", "bid", "ask", "spread", "hour", "minute", "weekday"}
+	WINDOWED  = []string{"change", "high", "low", "volume", "avg_volume", "vwap",
+		"candle_open", "candle_high", "candle_low", "candle_close", "candle_volume"}
+)
+
+// Variable splits the name of a variable into its base name and window,
+// 0 for a plain variable; ok is false for an unknown name.
+func Variable(name string) (base string, window time.Duration, ok bool) {
+	for _, v := range VARIABLES {
+		if name == v {
+			return name, 0, true
+		}
+	}
+	i := strings.LastIndexByte(name, '_')
+	if i < 0 {
+		return "", 0, false
+	}
+	base, suffix := name[:i], name[i+1:]
+	known := false
+	for _, w := range WINDOWED {
+		known = known || base == w
+	}
+	for _, w := range WINDOWS {
+		if known && suffix == w {
+			d, _ := time.ParseDuration(w)
+			return base, d, true
+		}
+	}
+	return "", 0, false
+}
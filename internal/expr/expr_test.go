@@ -0,0 +1,178 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// vars is the lookup of the tests: price 3500, change_1h -2.5, and no
+// data for anything else.
+func vars(name string) (float64, error) {
+	switch name {
+	case "price":
+		return 3500, nil
+	case "change_1h":
+		return -2.5, nil
+	}
+	return 0, fmt.Errorf("%s: %w", name, ErrNoData)
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"2 * 3 + 1", 7},
+		{"1 + 6 / 2", 4},
+		{"10 - 4 - 3", 3},
+		{"10 - (4 - 3)", 9},
+		{"16 / 4 / 2", 2},
+		{"-2 * 3", -6},
+		{"--2", 2},
+		{"-price + 1", -3499},
+		{"!0", 1},
+		{"!2", 0},
+		{"!!0.5", 1},
+		{"1 + 1 > 1", 1},
+		{"2 * 2 <= 4", 1},
+		{"1 == 1 && 0", 0},
+		{"1 || 0 && 0", 1},
+		{"(1 || 0) && 0", 0},
+		{"0 && 1 || 1", 1},
+		{"0 || 0 || 3", 1},
+		{"1 && 1 && 2", 1},
+		{"3 != 3", 0},
+		{"change_1h < -2%", 1},
+		{"2%", 2},
+		{".5 * 4", 2},
+		{"abs(change_1h)", 2.5},
+		{"min(price, 4000) - max(1, 2)", 3498},
+		{"max(abs(-3), min(1, 2) * 2)", 3},
+		{"abs(1 - 3) * 2", 4},
+		{"price > 3000 && change_1h < 0", 1},
+		{"  price\t>=\n3500 ", 1},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.src, err)
+			continue
+		}
+		got, err := e.Eval(vars)
+		if err != nil {
+			t.Errorf("Eval(%q): %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Eval(%q) = %g, want %g", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestEvalNoData(t *testing.T) {
+	tests := []struct {
+		src  string
+		want float64 // when the short circuit spares the missing variable
+		err  bool
+	}{
+		{src: "volume_5m > 1", err: true},
+		{src: "unknown", err: true},
+		{src: "price > 1 && volume_5m > 1", err: true},
+		{src: "price < 1 && volume_5m > 1", want: 0},
+		{src: "price > 1 || volume_5m > 1", want: 1},
+		{src: "abs(volume_5m)", err: true},
+		{src: "1 / 0", err: true},
+		{src: "price / (1 - 1)", err: true},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.src, err)
+			continue
+		}
+		got, err := e.Eval(vars)
+		switch {
+		case tt.err && !errors.Is(err, ErrNoData):
+			t.Errorf("Eval(%q) = %g, %v, want ErrNoData", tt.src, got, err)
+		case !tt.err && (err != nil || got != tt.want):
+			t.Errorf("Eval(%q) = %g, %v, want %g", tt.src, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"   ",
+		"1 +",
+		"* 2",
+		"(1 + 2",
+		"1 + 2)",
+		"()",
+		"1 2",
+		"price price",
+		"1 < 2 < 3",
+		"1 == 2 != 3",
+		"1..2",
+		"1 = 2",
+		"1 & 2",
+		"1 | 2",
+		"$price",
+		"sqrt(4)",
+		"abs()",
+		"abs(1, 2)",
+		"min(1)",
+		"max(1, 2, 3)",
+		"min(1 2)",
+		"abs 1",
+		"1 % 2",
+	} {
+		if e, err := Parse(src); err == nil {
+			t.Errorf("Parse(%q) = %v, want an error", src, e)
+		}
+	}
+}
+
+func TestIdents(t *testing.T) {
+	e, err := Parse("price > max(high_1h, vwap_5m) && !(change_1h < -2% || price < low_24h)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"price", "high_1h", "vwap_5m", "change_1h", "price", "low_24h"}
+	if got := e.Idents(); !slices.Equal(got, want) {
+		t.Errorf("Idents() = %v, want %v", got, want)
+	}
+}
+
+func TestVariable(t *testing.T) {
+	tests := []struct {
+		name   string
+		base   string
+		window time.Duration
+		ok     bool
+	}{
+		{"price", "price", 0, true},
+		{"weekday", "weekday", 0, true},
+		{"change_1h", "change", time.Hour, true},
+		{"avg_volume_5m", "avg_volume", 5 * time.Minute, true},
+		{"candle_close_24h", "candle_close", 24 * time.Hour, true},
+		{"change_2h", "", 0, false},
+		{"change", "", 0, false},
+		{"price_1h", "", 0, false},
+		{"volume_", "", 0, false},
+		{"Price", "", 0, false},
+		{"unknown", "", 0, false},
+		{"abs", "", 0, false},
+	}
+	for _, tt := range tests {
+		base, window, ok := Variable(tt.name)
+		if base != tt.base || window != tt.window || ok != tt.ok {
+			t.Errorf("Variable(%q) = %q, %v, %v, want %q, %v, %v", tt.name, base, window, ok, tt.base, tt.window, tt.ok)
+		}
+	}
+}
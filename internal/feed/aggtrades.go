@@ -0,0 +1,82 @@
+package feed
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AGG_TRADES_LIMIT is the most aggregate trades one request returns.
+const AGG_TRADES_LIMIT = 1000
+
+// AggTrade is one aggregate trade: the fills of one taker order at one
+// price, trade IDs FirstID to LastID.
+type AggTrade struct {
+	ID, FirstID, LastID int64
+	Price, Qty          float64
+	Side                string // of the taker
+	Time                time.Time
+}
+
+// AggTradesFetcher asks an exchange's REST API for up to AGG_TRADES_LIMIT
+// aggregate trades of a market, oldest first, from aggregate ID fromID or,
+// when that is 0, from start.
+type AggTradesFetcher func(client *http.Client, restEndpoint, symbol string, fromID int64, start time.Time) ([]AggTrade, error)
+
+// FetchAggTrades reads /api/v3/aggTrades.
+func FetchAggTrades(client *http.Client, restEndpoint, symbol string, fromID int64, start time.Time) ([]AggTrade, error) {
+	return fetchBinanceAggTrades(client, strings.TrimRight(restEndpoint, "/")+"/api/v3/aggTrades", symbol, fromID, start)
+}
+
+// FetchFuturesAggTrades reads /fapi/v1/aggTrades.
+func FetchFuturesAggTrades(client *http.Client, restEndpoint, symbol string, fromID int64, start time.Time) ([]AggTrade, error) {
+	return fetchBinanceAggTrades(client, strings.TrimRight(restEndpoint, "/")+"/fapi/v1/aggTrades", symbol, fromID, start)
+}
+
+func fetchBinanceAggTrades(client *http.Client, base, symbol string, fromID int64, start time.Time) ([]AggTrade, error) {
+	u := fmt.Sprintf("%s?symbol=%s&limit=%d", base, url.QueryEscape(strings.ToUpper(symbol)), AGG_TRADES_LIMIT)
+	if fromID > 0 {
+		u += "&fromId=" + strconv.FormatInt(fromID, 10)
+	} else {
+		u += "&startTime=" + strconv.FormatInt(start.UnixMilli(), 10)
+	}
+	var raw json.RawMessage
+	status, err := getJSON(client, u, "aggTrades", &raw)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		var e struct {
+			Msg string `json:"msg"`
+		}
+		json.Unmarshal(raw, &e)
+		return nil, fmt.Errorf("aggTrades: HTTP %d: %s", status, e.Msg)
+	}
+	var trades []struct {
+		A int64  `json:"a"`
+		P string `json:"p"`
+		Q string `json:"q"`
+		F int64  `json:"f"`
+		L int64  `json:"l"`
+		T int64  `json:"T"`
+		M bool   `json:"m"`
+		B bool   `json:"M"` // keeps "M" from being read into M
+	}
+	if err := json.Unmarshal(raw, &trades); err != nil {
+		return nil, fmt.Errorf("aggTrades: %w", err)
+	}
+	out := make([]AggTrade, 0, len(trades))
+	for _, t := range trades {
+		price, err := parsePrice("aggTrades", t.P)
+		if err != nil {
+			return nil, err
+		}
+		qty, _ := strconv.ParseFloat(t.Q, 64)
+		out = append(out, AggTrade{ID: t.A, FirstID: t.F, LastID: t.L, Price: price, Qty: qty, Side: takerSide(t.M), Time: time.UnixMilli(t.T)})
+	}
+	return out, nil
+}
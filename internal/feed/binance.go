@@ -0,0 +1,347 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Binance caps the streams of one combined connection; futures allow fewer
+// than spot.
+const (
+	BINANCE_MAX_STREAMS         = 1024
+	BINANCE_FUTURES_MAX_STREAMS = 200
+)
+
+// Binance streams several symbols over one combined-stream connection and
+// routes every message by the stream it names. Besides trades it reads
+// aggregated trades, whose size is their total quantity, book tickers,
+// priced at the midpoint, 24h (mini) tickers, priced at their last trade
+// with the day's statistics, candles, priced at their running close,
+// USDⓈ-M futures mark price updates, whose size is 0, and futures
+// liquidation orders.
+type Binance struct {
+	// Endpoint is the websocket base, e.g. wss://stream.binance.com:9443.
+	// A trailing /ws or /stream (the old single-stream form) is accepted.
+	Endpoint string
+	// Symbols are subscribed to their trade stream, unless they name a
+	// stream themselves, e.g. btcusdt@markPrice@1s.
+	Symbols    []string
+	PingPeriod time.Duration
+	// Methods, when set, is attached to every connection so streams can
+	// be changed while it is up.
+	Methods *BinanceMethods
+}
+
+// URL returns the combined stream of every symbol.
+func (b *Binance) URL() string {
+	base := strings.TrimRight(b.Endpoint, "/")
+	base = strings.TrimSuffix(strings.TrimSuffix(base, "/ws"), "/stream")
+	return fmt.Sprintf("%s/stream?streams=%s", base, strings.Join(b.streams(), "/"))
+}
+
+// streams returns the stream name of every symbol, in order.
+func (b *Binance) streams() []string {
+	streams := make([]string, len(b.Symbols))
+	for i, s := range b.Symbols {
+		symbol, stream, ok := strings.Cut(s, "@")
+		if !ok {
+			stream = "trade"
+		}
+		streams[i] = strings.ToLower(symbol) + "@" + stream
+	}
+	return streams
+}
+
+// SplitBinance spreads the symbols of b over as many connections as max
+// streams per connection require. Methods go to the last one, which has
+// room left if any does.
+func SplitBinance(b Binance, max int) Source {
+	if len(b.Symbols) <= max {
+		return &b
+	}
+	var multi Multi
+	for start := 0; start < len(b.Symbols); start += max {
+		c := b
+		c.Symbols = b.Symbols[start:min(start+max, len(b.Symbols))]
+		if start+max < len(b.Symbols) {
+			c.Methods = nil
+		}
+		multi = append(multi, &c)
+	}
+	return multi
+}
+
+func (b *Binance) Run(ctx context.Context, out chan<- Tick) error {
+	c, err := dial(ctx, b.URL())
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	defer keepalive(ctx, c, b.PingPeriod, pingFrame)()
+
+	// Ticks take the symbol of the stream they arrived on; anything
+	// else on the socket is a method reply or skipped
+	methods := b.Methods
+	if methods == nil {
+		methods = &BinanceMethods{}
+	}
+	methods.attach(c, b.streams())
+	defer methods.detach()
+	for {
+		_, msg, err := c.ReadMessage()
+		if err != nil {
+			return readErr(ctx, err)
+		}
+
+		var envelope struct {
+			Stream string          `json:"stream"`
+			Data   json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(msg, &envelope); err != nil {
+			continue
+		}
+		if envelope.Stream == "" {
+			methods.deliver(msg)
+			continue
+		}
+		parse := parseBinanceTrade
+		switch {
+		case strings.HasSuffix(envelope.Stream, "@bookTicker"):
+			parse = parseBinanceBook
+		case strings.HasSuffix(envelope.Stream, "@miniTicker"), strings.HasSuffix(envelope.Stream, "@ticker"):
+			parse = parseBinanceTicker
+		case strings.Contains(envelope.Stream, "@kline_"):
+			parse = parseBinanceKline
+		case strings.HasSuffix(envelope.Stream, "@forceOrder"):
+			parse = parseBinanceForceOrder
+		case strings.Contains(envelope.Stream, "@depth"):
+			parse = parseBinanceDepth
+		}
+		symbol, subscribed := methods.symbol(envelope.Stream)
+		if !subscribed {
+			continue
+		}
+		tick, ok := parse(envelope.Data)
+		if !ok {
+			continue
+		}
+		tick.Symbol = symbol
+		if !send(ctx, out, tick) {
+			return nil
+		}
+	}
+}
+
+// parseBinanceTrade reads a trade, aggTrade or markPriceUpdate event.
+func parseBinanceTrade(data []byte) (Tick, bool) {
+	var d struct {
+		Event string `json:"e"`
+		E     int64  `json:"E"`
+		S     string `json:"s"`
+		P     string `json:"p"`
+		Q     string `json:"q"`
+		T     int64  `json:"T"`
+		// Declared so encoding/json's case-insensitive matching does not
+		// read them into T and P
+		TradeID int64  `json:"t"`
+		Settle  string `json:"P"` // markPriceUpdate's estimated settle price
+		Rate    string `json:"r"` // markPriceUpdate's funding rate, "" on delivery contracts
+		Index   string `json:"i"` // markPriceUpdate's index price
+		AggID   int64  `json:"a"` // aggTrade's aggregate trade ID
+		Maker   bool   `json:"m"` // the buyer was the maker
+		Best    bool   `json:"M"` // keeps "M" from being read into Maker
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return Tick{}, false
+	}
+	price, err := strconv.ParseFloat(d.P, 64)
+	if err != nil {
+		return Tick{}, false
+	}
+	size, _ := strconv.ParseFloat(d.Q, 64)
+	tick := Tick{Symbol: strings.ToLower(d.S), Price: price, Size: size, Time: time.UnixMilli(d.T)}
+	switch d.Event {
+	case "trade":
+		tick.TradeID, tick.Side = d.TradeID, takerSide(d.Maker)
+	case "aggTrade":
+		tick.TradeID, tick.Side = d.AggID, takerSide(d.Maker)
+	case "markPriceUpdate":
+		// T is the next funding time there
+		tick.Time, tick.Size = time.UnixMilli(d.E), 0
+		if rate, err := strconv.ParseFloat(d.Rate, 64); err == nil {
+			index, _ := strconv.ParseFloat(d.Index, 64)
+			tick.Funding = &Funding{Rate: rate, Next: time.UnixMilli(d.T), Index: index}
+		}
+	}
+	return tick, true
+}
+
+// parseBinanceBook reads a bookTicker update into a tick priced at the
+// bid/ask midpoint. Spot updates carry no timestamp, so they are stamped
+// on arrival.
+func parseBinanceBook(data []byte) (Tick, bool) {
+	var d struct {
+		S   string `json:"s"`
+		Bid string `json:"b"`
+		Ask string `json:"a"`
+		T   int64  `json:"T"` // futures only
+		// The quantities, declared so they are not read into Bid and Ask
+		BidQty string `json:"B"`
+		AskQty string `json:"A"`
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return Tick{}, false
+	}
+	bid, err := strconv.ParseFloat(d.Bid, 64)
+	if err != nil || bid <= 0 {
+		return Tick{}, false
+	}
+	ask, err := strconv.ParseFloat(d.Ask, 64)
+	if err != nil || ask <= 0 {
+		return Tick{}, false
+	}
+	at := time.Now()
+	if d.T > 0 {
+		at = time.UnixMilli(d.T)
+	}
+	return Tick{Symbol: strings.ToLower(d.S), Price: (bid + ask) / 2, Bid: bid, Ask: ask, Time: at}, true
+}
+
+// parseBinanceTicker reads a 24hrMiniTicker or 24hrTicker event. Both reuse
+// letters in upper and lower case for different fields, which
+// encoding/json would match case-insensitively, so the fields are picked
+// by their exact key.
+func parseBinanceTicker(data []byte) (Tick, bool) {
+	var d map[string]json.RawMessage
+	if err := json.Unmarshal(data, &d); err != nil {
+		return Tick{}, false
+	}
+	num := func(key string) float64 {
+		var s string
+		if json.Unmarshal(d[key], &s) != nil {
+			return 0
+		}
+		v, _ := strconv.ParseFloat(s, 64)
+		return v
+	}
+	var symbol string
+	var at int64
+	json.Unmarshal(d["s"], &symbol)
+	json.Unmarshal(d["E"], &at)
+	price := num("c")
+	if symbol == "" || price <= 0 {
+		return Tick{}, false
+	}
+	return Tick{
+		Symbol: strings.ToLower(symbol),
+		Price:  price,
+		Time:   time.UnixMilli(at),
+		Bid:    num("b"), // ticker only
+		Ask:    num("a"),
+		Day:    &Stats{Open: num("o"), High: num("h"), Low: num("l"), Volume: num("v"), QuoteVolume: num("q")},
+	}, true
+}
+
+// parseBinanceForceOrder reads a forceOrder event into a Liquidation tick
+// priced at the order's average fill price and sized with its filled
+// quantity. The order object names the symbol s and the side S, which
+// encoding/json would match case-insensitively, so its fields are picked
+// by exact key.
+func parseBinanceForceOrder(data []byte) (Tick, bool) {
+	var d struct {
+		Event string                     `json:"e"`
+		E     int64                      `json:"E"`
+		O     map[string]json.RawMessage `json:"o"`
+	}
+	if err := json.Unmarshal(data, &d); err != nil || d.O == nil {
+		return Tick{}, false
+	}
+	var symbol, side, avg, filled string
+	var at int64
+	json.Unmarshal(d.O["s"], &symbol)
+	json.Unmarshal(d.O["S"], &side)
+	json.Unmarshal(d.O["ap"], &avg)
+	json.Unmarshal(d.O["z"], &filled)
+	json.Unmarshal(d.O["T"], &at)
+	price, err := strconv.ParseFloat(avg, 64)
+	if err != nil || price <= 0 {
+		return Tick{}, false
+	}
+	size, _ := strconv.ParseFloat(filled, 64)
+	return Tick{Symbol: strings.ToLower(symbol), Price: price, Size: size, Time: time.UnixMilli(at), Liquidation: &Liquidation{Side: side}}, true
+}
+
+// parseBinanceDepth reads a partial book depth snapshot, spot's bids and
+// asks or futures' depthUpdate b and a, into a Depth tick. Spot snapshots
+// carry no time, so they take the time of arrival.
+func parseBinanceDepth(data []byte) (Tick, bool) {
+	var d struct {
+		T    int64       `json:"T"`
+		Bids [][2]string `json:"bids"`
+		Asks [][2]string `json:"asks"`
+		B    [][2]string `json:"b"`
+		A    [][2]string `json:"a"`
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return Tick{}, false
+	}
+	if d.Bids == nil {
+		d.Bids, d.Asks = d.B, d.A
+	}
+	if len(d.Bids) == 0 || len(d.Asks) == 0 {
+		return Tick{}, false
+	}
+	sum := func(levels [][2]string) float64 {
+		var notional float64
+		for _, l := range levels {
+			price, _ := strconv.ParseFloat(l[0], 64)
+			qty, _ := strconv.ParseFloat(l[1], 64)
+			notional += price * qty
+		}
+		return notional
+	}
+	bid, err1 := strconv.ParseFloat(d.Bids[0][0], 64)
+	ask, err2 := strconv.ParseFloat(d.Asks[0][0], 64)
+	if err1 != nil || err2 != nil {
+		return Tick{}, false
+	}
+	at := time.Now()
+	if d.T > 0 {
+		at = time.UnixMilli(d.T)
+	}
+	return Tick{Price: (bid + ask) / 2, Time: at, Depth: &Depth{Bids: sum(d.Bids), Asks: sum(d.Asks)}}, true
+}
+
+// parseBinanceKline reads a kline event, priced at the candle's close so
+// far. The final update of a candle is marked Closed and sized with its
+// volume. The candle fields clash case-insensitively too (t and T, v and
+// V, ...), so they are picked by exact key.
+func parseBinanceKline(data []byte) (Tick, bool) {
+	var d struct {
+		Event string                     `json:"e"`
+		E     int64                      `json:"E"`
+		S     string                     `json:"s"`
+		K     map[string]json.RawMessage `json:"k"`
+	}
+	if err := json.Unmarshal(data, &d); err != nil || d.K == nil {
+		return Tick{}, false
+	}
+	var close, volume string
+	var closed bool
+	json.Unmarshal(d.K["c"], &close)
+	json.Unmarshal(d.K["v"], &volume)
+	json.Unmarshal(d.K["x"], &closed)
+	price, err := strconv.ParseFloat(close, 64)
+	if err != nil || price <= 0 {
+		return Tick{}, false
+	}
+	tick := Tick{Symbol: strings.ToLower(d.S), Price: price, Time: time.UnixMilli(d.E), Closed: closed}
+	if closed {
+		tick.Size, _ = strconv.ParseFloat(volume, 64)
+	}
+	return tick, true
+}
@@ -0,0 +1,101 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// BYBIT_SUBSCRIBE_BATCH is the most topics Bybit's spot stream accepts in
+// one subscribe request; longer symbol lists are split.
+const BYBIT_SUBSCRIBE_BATCH = 10
+
+// Bybit streams trades from one Bybit v5 public stream (spot or linear
+// futures) via its publicTrade topic. Symbols are written as on Binance,
+// e.g. "btcusdt".
+type Bybit struct {
+	// Endpoint is the public stream of the category, e.g.
+	// wss://stream.bybit.com/v5/public/spot or .../v5/public/linear.
+	Endpoint   string
+	Symbols    []string
+	PingPeriod time.Duration
+}
+
+// bybitMessage is the part of a v5 message we use: an op response
+// (subscribe, pong) or topic data.
+type bybitMessage struct {
+	Op      string `json:"op"`
+	Success *bool  `json:"success"`
+	RetMsg  string `json:"ret_msg"`
+	Topic   string `json:"topic"`
+	Data    []struct {
+		Symbol string `json:"s"`
+		Price  string `json:"p"`
+		Size   string `json:"v"`
+		Side   string `json:"S"` // of the taker, Buy or Sell
+		Time   int64  `json:"T"`
+	} `json:"data"`
+}
+
+// bybitPing is the {"op":"ping"} Bybit expects about every 20 seconds; it
+// drops connections that only send websocket ping frames.
+func bybitPing(c *websocket.Conn) error {
+	return c.WriteJSON(map[string]string{"op": "ping"})
+}
+
+func (b *Bybit) Run(ctx context.Context, out chan<- Tick) error {
+	c, err := dial(ctx, b.Endpoint)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	for i := 0; i < len(b.Symbols); i += BYBIT_SUBSCRIBE_BATCH {
+		var topics []string
+		for _, s := range b.Symbols[i:min(i+BYBIT_SUBSCRIBE_BATCH, len(b.Symbols))] {
+			topics = append(topics, "publicTrade."+strings.ToUpper(s))
+		}
+		if err := c.WriteJSON(map[string]any{"op": "subscribe", "args": topics}); err != nil {
+			return fmt.Errorf("subscribe: %w", err)
+		}
+	}
+	defer keepalive(ctx, c, b.PingPeriod, bybitPing)()
+
+	// Quiet symbols may not trade for minutes, but every ping is answered
+	silence := 2*b.PingPeriod + CLOSE_TIMEOUT
+	for {
+		expectWithin(ctx, c, silence)
+		_, msg, err := c.ReadMessage()
+		if err != nil {
+			return silentErr(ctx, err, silence)
+		}
+
+		var m bybitMessage
+		if err := json.Unmarshal(msg, &m); err != nil {
+			continue
+		}
+		// Spot answers pings with op "ping" and ret_msg "pong", linear
+		// with op "pong"; only a failed subscribe matters
+		if m.Op == "subscribe" && m.Success != nil && !*m.Success {
+			return fmt.Errorf("bybit: subscribe: %s", m.RetMsg)
+		}
+		if !strings.HasPrefix(m.Topic, "publicTrade.") {
+			continue
+		}
+		for _, d := range m.Data {
+			price, err := strconv.ParseFloat(d.Price, 64)
+			if err != nil {
+				continue
+			}
+			size, _ := strconv.ParseFloat(d.Size, 64)
+			if !send(ctx, out, Tick{Symbol: strings.ToLower(d.Symbol), Price: price, Size: size, Side: strings.ToLower(d.Side), Time: time.UnixMilli(d.Time)}) {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package feed
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Function selectors of the Chainlink AggregatorV3Interface.
+const (
+	LATEST_ROUND_DATA = "0xfeaf968c"
+	DECIMALS          = "0x313ce567"
+)
+
+// chainlinkFeeds are the Ethereum mainnet proxies of common price feeds by
+// pair; any other feed, or one on another chain, goes by its address.
+var chainlinkFeeds = map[string]string{
+	"eth/usd":  "0x5f4ec3df9cbd43714fe2740f5e3616155c5b8419",
+	"btc/usd":  "0xf4030086522a5beea4988f8ca5b36dbc97bee88c",
+	"link/usd": "0x2c1d072e956affc0d435cb7ac38ef18d24d9127c",
+	"usdc/usd": "0x8fffffd4afb6115b954bd326cbe7b4ba576818f6",
+	"usdt/usd": "0x3e7d1eab13ad0104d2750b8863b489d65364e32d",
+	"dai/usd":  "0xaed0c38402a5d19df6e4c03f4e2dced6e29c1ee9",
+}
+
+// ChainlinkQuoter returns a Quoter for Chainlink price feeds over an
+// Ethereum JSON-RPC node: the answer of the feed's latest round, scaled by
+// its decimals, which are read once per feed. symbol is the feed's proxy
+// address or a pair of chainlinkFeeds; restEndpoint is the RPC URL and
+// apiKey is unused. The price is the one on chain at the poll, however
+// long ago the feed last updated, so at is zero.
+func ChainlinkQuoter() Quoter {
+	scales := make(map[string]*big.Float)
+	return func(client *http.Client, restEndpoint, apiKey, symbol string) (float64, time.Time, error) {
+		feed := symbol
+		if !strings.HasPrefix(symbol, "0x") {
+			var ok bool
+			if feed, ok = chainlinkFeeds[symbol]; !ok {
+				return 0, time.Time{}, fmt.Errorf("unknown chainlink feed %s, use its address", symbol)
+			}
+		}
+		scale, ok := scales[feed]
+		if !ok {
+			words, err := ethCall(client, restEndpoint, feed, DECIMALS, 1)
+			if err != nil {
+				return 0, time.Time{}, err
+			}
+			exp := new(big.Int).Exp(big.NewInt(10), words[0], nil)
+			scale = new(big.Float).SetInt(exp)
+			scales[feed] = scale
+		}
+		// roundId, answer, startedAt, updatedAt, answeredInRound
+		words, err := ethCall(client, restEndpoint, feed, LATEST_ROUND_DATA, 5)
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		answer := words[1]
+		if answer.Bit(255) == 1 {
+			answer.Sub(answer, new(big.Int).Lsh(big.NewInt(1), 256)) // int256
+		}
+		if answer.Sign() <= 0 || words[3].Sign() == 0 {
+			return 0, time.Time{}, fmt.Errorf("latestRoundData: no answer (%s)", answer)
+		}
+		price, _ := new(big.Float).Quo(new(big.Float).SetInt(answer), scale).Float64()
+		return price, time.Time{}, nil
+	}
+}
+
+// ethCall calls a view function of contract without arguments and splits
+// its result into n 32-byte words.
+func ethCall(client *http.Client, endpoint, contract, selector string, n int) ([]*big.Int, error) {
+	var result string
+	if err := rpcCall(client, endpoint, "eth_call", []any{map[string]string{"to": contract, "data": selector}, "latest"}, &result); err != nil {
+		return nil, err
+	}
+	hex := strings.TrimPrefix(result, "0x")
+	if len(hex) < n*64 {
+		if hex == "" {
+			return nil, errors.New("eth_call: empty result, not a price feed")
+		}
+		return nil, fmt.Errorf("eth_call: short result of %d bytes", len(hex)/2)
+	}
+	words := make([]*big.Int, n)
+	for i := range words {
+		w, ok := new(big.Int).SetString(hex[i*64:(i+1)*64], 16)
+		if !ok {
+			return nil, fmt.Errorf("eth_call: bad result %q", result)
+		}
+		words[i] = w
+	}
+	return words, nil
+}
+
+// ChainlinkInfo stands in for an exchange lookup of a feed, deriving its
+// tick size from the current answer as CoinInfo does.
+func ChainlinkInfo(client *http.Client, restEndpoint, symbol string) (SymbolInfo, error) {
+	price, _, err := ChainlinkQuoter()(client, restEndpoint, "", symbol)
+	if err != nil {
+		return SymbolInfo{}, err
+	}
+	return CoinInfo(symbol, price), nil
+}
@@ -0,0 +1,89 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Coinbase streams trades from the Coinbase Exchange websocket feed's
+// matches channel. Symbols are product IDs such as "eth-usd".
+type Coinbase struct {
+	// Endpoint is the feed URL, e.g. wss://ws-feed.exchange.coinbase.com.
+	Endpoint   string
+	Symbols    []string
+	PingPeriod time.Duration
+}
+
+// coinbaseMessage is the part of a feed message we use. Subscribing yields
+// one last_match per product, then a match for every trade; the heartbeat
+// channel keeps quiet products from timing the connection out.
+type coinbaseMessage struct {
+	Type      string `json:"type"`
+	ProductID string `json:"product_id"`
+	Price     string `json:"price"`
+	Size      string `json:"size"`
+	Side      string `json:"side"` // of the maker order
+	Time      string `json:"time"`
+	Message   string `json:"message"`
+	Reason    string `json:"reason"`
+}
+
+func (cb *Coinbase) Run(ctx context.Context, out chan<- Tick) error {
+	c, err := dial(ctx, cb.Endpoint)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	products := make([]string, len(cb.Symbols))
+	for i, s := range cb.Symbols {
+		products[i] = strings.ToUpper(s)
+	}
+	sub := map[string]any{"type": "subscribe", "product_ids": products, "channels": []string{"matches", "heartbeat"}}
+	if err := c.WriteJSON(sub); err != nil {
+		return fmt.Errorf("subscribe: %w", err)
+	}
+	defer keepalive(ctx, c, cb.PingPeriod, pingFrame)()
+
+	for {
+		_, msg, err := c.ReadMessage()
+		if err != nil {
+			return readErr(ctx, err)
+		}
+
+		var m coinbaseMessage
+		if err := json.Unmarshal(msg, &m); err != nil {
+			continue
+		}
+		switch m.Type {
+		case "match", "last_match":
+		case "error":
+			return fmt.Errorf("coinbase: %s: %s", m.Message, m.Reason)
+		default:
+			continue
+		}
+		price, err := strconv.ParseFloat(m.Price, 64)
+		if err != nil {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, m.Time)
+		if err != nil {
+			t = time.Now()
+		}
+		size, _ := strconv.ParseFloat(m.Size, 64)
+		tick := Tick{Symbol: strings.ToLower(m.ProductID), Price: price, Size: size, Time: t}
+		switch m.Side {
+		case "buy":
+			tick.Side = SIDE_SELL
+		case "sell":
+			tick.Side = SIDE_BUY
+		}
+		if !send(ctx, out, tick) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package feed
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// COIN_DIGITS is the significant digits a coin's derived tick size keeps,
+// about what exchanges list for a major pair.
+const COIN_DIGITS = 6
+
+// Price is one quote of a BatchFetcher; At is zero when the provider does
+// not say when it was quoted.
+type Price struct {
+	Price float64
+	At    time.Time
+}
+
+// BatchFetcher asks a market data aggregator for the prices of several
+// coins, written "<coin>" (in USD) or "<coin>/<currency>", at once. A coin
+// the provider does not know is left out of the result.
+type BatchFetcher func(client *http.Client, restEndpoint, apiKey string, symbols []string) (map[string]Price, error)
+
+// CachedQuoter turns fetch into a Quoter that asks for all of symbols in
+// one request and answers from that response, or its error, for ttl. A
+// round of Quotes then costs one request against the aggregator's rate
+// limit however many coins it polls. A symbol outside symbols joins them.
+func CachedQuoter(fetch BatchFetcher, symbols []string, ttl time.Duration) Quoter {
+	var prices map[string]Price
+	var err error
+	var fetched time.Time
+	return func(client *http.Client, restEndpoint, apiKey, symbol string) (float64, time.Time, error) {
+		added := !slices.Contains(symbols, symbol)
+		if added {
+			symbols = append(slices.Clone(symbols), symbol)
+		}
+		if added || time.Since(fetched) >= ttl {
+			prices, err = fetch(client, restEndpoint, apiKey, symbols)
+			fetched = time.Now()
+		}
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		p, ok := prices[symbol]
+		if !ok {
+			return 0, time.Time{}, fmt.Errorf("unknown coin %s", symbol)
+		}
+		return p.Price, p.At, nil
+	}
+}
+
+// byCurrency groups coins by the currency they are priced in.
+func byCurrency(symbols []string) map[string][]string {
+	groups := make(map[string][]string)
+	for _, s := range symbols {
+		coin, currency, ok := strings.Cut(s, "/")
+		if !ok {
+			currency = "usd"
+		}
+		groups[currency] = append(groups[currency], coin)
+	}
+	return groups
+}
+
+// coinSymbol is the symbol a quote of coin in currency answers.
+func coinSymbol(coin, currency string, symbols []string) string {
+	if currency == "usd" && slices.Contains(symbols, coin) {
+		return coin
+	}
+	return coin + "/" + currency
+}
+
+// FetchCoinGeckoPrices reads /simple/price, one request per currency. The
+// coins are CoinGecko API IDs such as bitcoin or the-open-network. apiKey
+// is optional; a demo key raises the public rate limit, and a pro key
+// needs rest_endpoint https://pro-api.coingecko.com/api/v3.
+func FetchCoinGeckoPrices(client *http.Client, restEndpoint, apiKey string, symbols []string) (map[string]Price, error) {
+	prices := make(map[string]Price)
+	for currency, coins := range byCurrency(symbols) {
+		u := strings.TrimRight(restEndpoint, "/") + "/simple/price?ids=" + url.QueryEscape(strings.Join(coins, ",")) +
+			"&vs_currencies=" + url.QueryEscape(currency) + "&include_last_updated_at=true"
+		switch {
+		case apiKey == "":
+		case strings.Contains(restEndpoint, "pro-api"):
+			u += "&x_cg_pro_api_key=" + url.QueryEscape(apiKey)
+		default:
+			u += "&x_cg_demo_api_key=" + url.QueryEscape(apiKey)
+		}
+		// Every coin is an object of currency prices, except on errors
+		var r map[string]map[string]any
+		status, err := quoteJSON(client, u, "simple/price", &r)
+		if status != http.StatusOK {
+			if err == nil {
+				err = fmt.Errorf("simple/price: HTTP %d", status)
+			}
+			if e, ok := r["status"]["error_message"].(string); ok {
+				err = fmt.Errorf("simple/price: HTTP %d: %s", status, e)
+			}
+			return nil, err
+		}
+		if err != nil {
+			return nil, err
+		}
+		for coin, quote := range r {
+			price, ok := quote[currency].(float64)
+			if !ok || price <= 0 {
+				continue
+			}
+			p := Price{Price: price}
+			if at, ok := quote["last_updated_at"].(float64); ok {
+				p.At = time.Unix(int64(at), 0)
+			}
+			prices[coinSymbol(coin, currency, symbols)] = p
+		}
+	}
+	return prices, nil
+}
+
+// FetchCoinMarketCapPrices reads /v2/cryptocurrency/quotes/latest, one
+// request per currency. The coins are tickers such as pepe; of several
+// coins sharing one, the first listed is taken.
+func FetchCoinMarketCapPrices(client *http.Client, restEndpoint, apiKey string, symbols []string) (map[string]Price, error) {
+	prices := make(map[string]Price)
+	for currency, coins := range byCurrency(symbols) {
+		u := strings.TrimRight(restEndpoint, "/") + "/v2/cryptocurrency/quotes/latest?symbol=" + url.QueryEscape(strings.ToUpper(strings.Join(coins, ","))) +
+			"&convert=" + url.QueryEscape(strings.ToUpper(currency)) + "&skip_invalid=true&CMC_PRO_API_KEY=" + url.QueryEscape(apiKey)
+		var r struct {
+			Status struct {
+				ErrorCode    int    `json:"error_code"`
+				ErrorMessage string `json:"error_message"`
+			} `json:"status"`
+			Data map[string][]struct {
+				Quote map[string]struct {
+					Price       float64   `json:"price"`
+					LastUpdated time.Time `json:"last_updated"`
+				} `json:"quote"`
+			} `json:"data"`
+		}
+		status, err := quoteJSON(client, u, "quotes/latest", &r)
+		if err != nil {
+			return nil, err
+		}
+		if status != http.StatusOK || r.Status.ErrorCode != 0 {
+			return nil, fmt.Errorf("quotes/latest: HTTP %d: %s (code %d)", status, r.Status.ErrorMessage, r.Status.ErrorCode)
+		}
+		for coin, listings := range r.Data {
+			if len(listings) == 0 {
+				continue
+			}
+			q, ok := listings[0].Quote[strings.ToUpper(currency)]
+			if !ok || q.Price <= 0 {
+				continue
+			}
+			prices[coinSymbol(strings.ToLower(coin), currency, symbols)] = Price{Price: q.Price, At: q.LastUpdated}
+		}
+	}
+	return prices, nil
+}
+
+// CoinInfo stands in for an exchange lookup of a coin quoted at price: a
+// tick of COIN_DIGITS significant digits, and at least cents.
+func CoinInfo(symbol string, price float64) SymbolInfo {
+	decimals := 2
+	if price > 0 {
+		decimals = max(2, min(12, COIN_DIGITS-1-int(math.Floor(math.Log10(price)))))
+	}
+	return SymbolInfo{Symbol: strings.ToUpper(symbol), Trading: true, TickSize: math.Pow(10, -float64(decimals)), Decimals: decimals}
+}
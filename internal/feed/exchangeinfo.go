@@ -0,0 +1,263 @@
+package feed
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SymbolInfo is what an exchange reports about one market.
+type SymbolInfo struct {
+	Symbol string
+	Status string // as reported, e.g. TRADING or online
+	// Trading is set when the market currently accepts orders.
+	Trading  bool
+	TickSize float64
+	// Decimals is the number of decimals in the tick size.
+	Decimals int
+}
+
+// FetchSymbolInfo looks one symbol up in the exchangeInfo endpoint.
+func FetchSymbolInfo(client *http.Client, restEndpoint, symbol string) (SymbolInfo, error) {
+	u := strings.TrimRight(restEndpoint, "/") + "/api/v3/exchangeInfo?symbol=" + url.QueryEscape(strings.ToUpper(symbol))
+	return fetchBinanceInfo(client, u, symbol)
+}
+
+// FetchFuturesSymbolInfo looks one USDⓈ-M futures contract up in
+// /fapi/v1/exchangeInfo, which always lists every contract.
+func FetchFuturesSymbolInfo(client *http.Client, restEndpoint, symbol string) (SymbolInfo, error) {
+	return fetchBinanceInfo(client, strings.TrimRight(restEndpoint, "/")+"/fapi/v1/exchangeInfo", symbol)
+}
+
+// fetchBinanceInfo picks symbol out of the exchangeInfo response at u.
+func fetchBinanceInfo(client *http.Client, u, symbol string) (SymbolInfo, error) {
+	resp, err := client.Get(u)
+	if err != nil {
+		return SymbolInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	var info struct {
+		Msg     string `json:"msg"`
+		Symbols []struct {
+			Symbol  string `json:"symbol"`
+			Status  string `json:"status"`
+			Filters []struct {
+				FilterType string `json:"filterType"`
+				TickSize   string `json:"tickSize"`
+			} `json:"filters"`
+		} `json:"symbols"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return SymbolInfo{}, fmt.Errorf("exchangeInfo: HTTP %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return SymbolInfo{}, fmt.Errorf("exchangeInfo: HTTP %d: %s", resp.StatusCode, info.Msg)
+	}
+	i := 0
+	for i < len(info.Symbols) && !strings.EqualFold(info.Symbols[i].Symbol, symbol) {
+		i++
+	}
+	if i == len(info.Symbols) {
+		return SymbolInfo{}, fmt.Errorf("unknown symbol %s", symbol)
+	}
+
+	s := info.Symbols[i]
+	out := SymbolInfo{Symbol: s.Symbol, Status: s.Status, Trading: s.Status == "TRADING"}
+	for _, f := range s.Filters {
+		if f.FilterType != "PRICE_FILTER" {
+			continue
+		}
+		tick, err := strconv.ParseFloat(f.TickSize, 64)
+		if err != nil || tick <= 0 {
+			break
+		}
+		out.TickSize, out.Decimals = tick, decimals(f.TickSize)
+	}
+	return out, nil
+}
+
+// FetchCoinbaseProduct looks one product up in the Coinbase Exchange REST
+// API; its quote_increment is the tick size.
+func FetchCoinbaseProduct(client *http.Client, restEndpoint, product string) (SymbolInfo, error) {
+	u := strings.TrimRight(restEndpoint, "/") + "/products/" + url.PathEscape(strings.ToUpper(product))
+	resp, err := client.Get(u)
+	if err != nil {
+		return SymbolInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	var info struct {
+		Message         string `json:"message"`
+		ID              string `json:"id"`
+		Status          string `json:"status"`
+		TradingDisabled bool   `json:"trading_disabled"`
+		QuoteIncrement  string `json:"quote_increment"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return SymbolInfo{}, fmt.Errorf("products: HTTP %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return SymbolInfo{}, fmt.Errorf("unknown product %s", strings.ToUpper(product))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return SymbolInfo{}, fmt.Errorf("products: HTTP %d: %s", resp.StatusCode, info.Message)
+	}
+	out := SymbolInfo{Symbol: info.ID, Status: info.Status, Trading: info.Status == "online" && !info.TradingDisabled}
+	if tick, err := strconv.ParseFloat(info.QuoteIncrement, 64); err == nil && tick > 0 {
+		out.TickSize, out.Decimals = tick, decimals(info.QuoteIncrement)
+	}
+	return out, nil
+}
+
+// FetchKrakenPair looks one pair up in Kraken's AssetPairs endpoint.
+func FetchKrakenPair(client *http.Client, restEndpoint, pair string) (SymbolInfo, error) {
+	u := strings.TrimRight(restEndpoint, "/") + "/0/public/AssetPairs?pair=" + url.QueryEscape(strings.ToUpper(pair))
+	resp, err := client.Get(u)
+	if err != nil {
+		return SymbolInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	var info struct {
+		Error  []string `json:"error"`
+		Result map[string]struct {
+			WSName       string `json:"wsname"`
+			Status       string `json:"status"`
+			TickSize     string `json:"tick_size"`
+			PairDecimals int    `json:"pair_decimals"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return SymbolInfo{}, fmt.Errorf("AssetPairs: HTTP %d: %w", resp.StatusCode, err)
+	}
+	if len(info.Error) > 0 {
+		return SymbolInfo{}, fmt.Errorf("AssetPairs: %s", strings.Join(info.Error, "; "))
+	}
+	for _, p := range info.Result {
+		out := SymbolInfo{Symbol: p.WSName, Status: p.Status, Trading: p.Status == "online"}
+		if tick, err := strconv.ParseFloat(p.TickSize, 64); err == nil && tick > 0 {
+			out.TickSize, out.Decimals = tick, decimals(p.TickSize)
+		} else {
+			out.TickSize, out.Decimals = math.Pow10(-p.PairDecimals), p.PairDecimals
+		}
+		return out, nil
+	}
+	return SymbolInfo{}, fmt.Errorf("unknown pair %s", strings.ToUpper(pair))
+}
+
+// BybitInstrument returns the lookup of one Bybit category ("spot" or
+// "linear") in the v5 instruments-info endpoint.
+func BybitInstrument(category string) InfoFetcher {
+	return func(client *http.Client, restEndpoint, symbol string) (SymbolInfo, error) {
+		u := fmt.Sprintf("%s/v5/market/instruments-info?category=%s&symbol=%s",
+			strings.TrimRight(restEndpoint, "/"), category, url.QueryEscape(strings.ToUpper(symbol)))
+		resp, err := client.Get(u)
+		if err != nil {
+			return SymbolInfo{}, err
+		}
+		defer resp.Body.Close()
+
+		var info struct {
+			RetCode int    `json:"retCode"`
+			RetMsg  string `json:"retMsg"`
+			Result  struct {
+				List []struct {
+					Symbol      string `json:"symbol"`
+					Status      string `json:"status"`
+					PriceFilter struct {
+						TickSize string `json:"tickSize"`
+					} `json:"priceFilter"`
+				} `json:"list"`
+			} `json:"result"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+			return SymbolInfo{}, fmt.Errorf("instruments-info: HTTP %d: %w", resp.StatusCode, err)
+		}
+		if info.RetCode != 0 {
+			return SymbolInfo{}, fmt.Errorf("instruments-info: %s", info.RetMsg)
+		}
+		if len(info.Result.List) == 0 {
+			return SymbolInfo{}, fmt.Errorf("unknown %s symbol %s", category, strings.ToUpper(symbol))
+		}
+		s := info.Result.List[0]
+		out := SymbolInfo{Symbol: s.Symbol, Status: s.Status, Trading: s.Status == "Trading"}
+		if tick, err := strconv.ParseFloat(s.PriceFilter.TickSize, 64); err == nil && tick > 0 {
+			out.TickSize, out.Decimals = tick, decimals(s.PriceFilter.TickSize)
+		}
+		return out, nil
+	}
+}
+
+// FetchOKXInstrument looks one instrument up in OKX's public instruments
+// endpoint. The instrument type follows from the ID: "-swap" perpetuals,
+// dated futures like btc-usd-250627, spot otherwise.
+func FetchOKXInstrument(client *http.Client, restEndpoint, instID string) (SymbolInfo, error) {
+	id := strings.ToUpper(instID)
+	instType := "SPOT"
+	parts := strings.Split(id, "-")
+	switch last := parts[len(parts)-1]; {
+	case last == "SWAP":
+		instType = "SWAP"
+	case len(parts) == 3 && strings.Trim(last, "0123456789") == "":
+		instType = "FUTURES"
+	}
+	u := fmt.Sprintf("%s/api/v5/public/instruments?instType=%s&instId=%s",
+		strings.TrimRight(restEndpoint, "/"), instType, url.QueryEscape(id))
+	resp, err := client.Get(u)
+	if err != nil {
+		return SymbolInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	var info struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data []struct {
+			InstID string `json:"instId"`
+			State  string `json:"state"`
+			TickSz string `json:"tickSz"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return SymbolInfo{}, fmt.Errorf("instruments: HTTP %d: %w", resp.StatusCode, err)
+	}
+	if info.Code != "0" {
+		return SymbolInfo{}, fmt.Errorf("instruments: %s (code %s)", info.Msg, info.Code)
+	}
+	if len(info.Data) == 0 {
+		return SymbolInfo{}, fmt.Errorf("unknown instrument %s", id)
+	}
+	d := info.Data[0]
+	out := SymbolInfo{Symbol: d.InstID, Status: d.State, Trading: d.State == "live"}
+	if tick, err := strconv.ParseFloat(d.TickSz, 64); err == nil && tick > 0 {
+		out.TickSize, out.Decimals = tick, decimals(d.TickSz)
+	}
+	return out, nil
+}
+
+// decimals counts the significant decimals of a tick size like "0.01000".
+func decimals(tick string) int {
+	if i := strings.IndexByte(tick, '.'); i >= 0 {
+		return len(strings.TrimRight(tick[i+1:], "0"))
+	}
+	return 0
+}
+
+// InfoFetcher looks a market up in an exchange's REST API.
+type InfoFetcher func(client *http.Client, restEndpoint, symbol string) (SymbolInfo, error)
+
+// TickLookup returns a config.TickLookup-compatible function backed by
+// fetch, e.g. FetchSymbolInfo for Binance's exchangeInfo endpoint.
+func TickLookup(fetch InfoFetcher, restEndpoint string) func(string) (float64, int, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return func(symbol string) (float64, int, error) {
+		info, err := fetch(client, restEndpoint, symbol)
+		return info.TickSize, info.Decimals, err
+	}
+}
@@ -0,0 +1,205 @@
+// Package feed turns exchange streams and recordings into a common Tick
+// stream.
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// SIDE_BUY and SIDE_SELL are the Side of a trade: the taker bought, lifting
+// the ask, or sold, hitting the bid.
+const (
+	SIDE_BUY  = "buy"
+	SIDE_SELL = "sell"
+)
+
+// takerSide returns SIDE_SELL when the buyer of a trade was the maker,
+// else SIDE_BUY.
+func takerSide(buyerMaker bool) string {
+	if buyerMaker {
+		return SIDE_SELL
+	}
+	return SIDE_BUY
+}
+
+// Tick is one trade price observed for a symbol.
+type Tick struct {
+	// Symbol is the lower-case market name, e.g. "ethusdt".
+	Symbol string
+	Price  float64
+	// Size is the traded quantity, summed over the trades of a Binance
+	// aggTrade; 0 when the source does not report it.
+	Size float64
+	// Side is the side of the taker of a trade, SIDE_BUY or SIDE_SELL;
+	// empty when the source does not report it.
+	Side string
+	Time time.Time
+	// Bid and Ask are the best quotes of a book ticker, whose Price is
+	// their midpoint, or of a ticker; 0 for trades.
+	Bid, Ask float64
+	// Day holds the rolling 24h statistics of ticker streams, nil
+	// otherwise.
+	Day *Stats
+	// Closed marks the final update of a kline candle, whose Price is the
+	// candle's close and Size its volume.
+	Closed bool
+	// Fill is set on ticks of the user data stream, which report an
+	// execution of one of the account's orders rather than a market price.
+	Fill *Fill
+	// Liquidation is set on ticks of a futures liquidation stream, which
+	// report a forced order rather than a market price.
+	Liquidation *Liquidation
+	// Funding is set on the mark price ticks of futures perpetuals.
+	Funding *Funding
+	// Depth is set on ticks of a partial book depth stream, priced at the
+	// midpoint of the best bid and ask.
+	Depth *Depth
+	// Exchange names the live source the tick came from (see Tagged);
+	// empty for replays.
+	Exchange string
+	// Degraded marks a price polled from a REST ticker while the stream
+	// was down.
+	Degraded bool
+	// TradeID is the ID of a Binance trade, or the aggregate trade ID of
+	// an aggTrade; 0 for other ticks.
+	TradeID int64
+	// Backfilled marks a trade a reconnect missed, fetched from REST
+	// after the fact.
+	Backfilled bool
+}
+
+// Liquidation is a forced order of a futures liquidation, carried by a
+// Tick whose Price and Size are its average price and filled quantity.
+type Liquidation struct {
+	Side string // SELL closes a long position, BUY a short one
+}
+
+// Depth sums the top levels of a book snapshot.
+type Depth struct {
+	Bids, Asks float64 // notional, price times quantity, of each side
+}
+
+// Funding is the funding of a perpetual as of a mark price update.
+type Funding struct {
+	Rate  float64   // predicted rate of the next settlement, e.g. 0.0001
+	Next  time.Time // next settlement
+	Index float64   // index price the mark and the premium index track
+}
+
+// Stats are an exchange's statistics over the last 24 hours.
+type Stats struct {
+	Open, High, Low float64
+	Volume          float64 // in the base asset
+	QuoteVolume     float64
+}
+
+// ChangePct is the move from Open to p in percent.
+func (s Stats) ChangePct(p float64) float64 {
+	if s.Open == 0 {
+		return 0
+	}
+	return (p - s.Open) / s.Open * 100
+}
+
+// Source produces ticks until its context is cancelled or it fails.
+type Source interface {
+	// Run sends ticks to out. It returns nil when ctx is cancelled or the
+	// source is exhausted, and an error when the connection drops.
+	Run(ctx context.Context, out chan<- Tick) error
+}
+
+// Tagged stamps every tick of Source with Exchange, so the same market
+// name on two exchanges stays apart.
+type Tagged struct {
+	Source
+	Exchange string
+}
+
+func (t Tagged) Run(ctx context.Context, out chan<- Tick) error {
+	in := make(chan Tick)
+	errc := make(chan error, 1)
+	go func() { errc <- t.Source.Run(ctx, in) }()
+	for {
+		select {
+		case tick := <-in:
+			tick.Exchange = t.Exchange
+			if !send(ctx, out, tick) {
+				return <-errc
+			}
+		case err := <-errc:
+			return err
+		}
+	}
+}
+
+// send delivers t unless ctx is cancelled first.
+func send(ctx context.Context, out chan<- Tick, t Tick) bool {
+	select {
+	case out <- t:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Multi runs several sources into one tick stream, e.g. one connection per
+// exchange. The first source to fail stops the others and its error is
+// returned once all of them have.
+type Multi []Source
+
+func (m Multi) Run(ctx context.Context, out chan<- Tick) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errc := make(chan error, len(m))
+	for _, src := range m {
+		go func() { errc <- src.Run(ctx, out) }()
+	}
+	var first error
+	for range m {
+		if err := <-errc; err != nil && first == nil {
+			first = err
+			cancel()
+		}
+	}
+	return first
+}
+
+// Retry keeps Source running on its own, redialling after a failure with
+// exponential backoff, so that inside a Multi a dead exchange does not take
+// the healthy ones down with it. It only returns once ctx is cancelled.
+type Retry struct {
+	Source
+	Name                       string // for log lines, e.g. the exchange
+	InitialBackoff, MaxBackoff time.Duration
+}
+
+func (r Retry) Run(ctx context.Context, out chan<- Tick) error {
+	backoff := r.InitialBackoff
+	for {
+		started := time.Now()
+		err := r.Source.Run(ctx, out)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil {
+			err = errors.New("stream ended")
+		}
+		// A connection that stayed up a while starts over from the initial delay
+		if time.Since(started) > r.MaxBackoff {
+			backoff = r.InitialBackoff
+		}
+		logging.Warn(fmt.Sprintf("%s error: %v, reconnecting in %v", r.Name, err, backoff),
+			"event", "reconnect", "exchange", r.Name, "backoff", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil
+		}
+		backoff = min(backoff*2, r.MaxBackoff)
+	}
+}
@@ -0,0 +1,94 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FetchBaseFee is a Quoter for an Ethereum JSON-RPC node: the base fee of
+// the next block in gwei, from eth_feeHistory, or eth_gasPrice on nodes
+// without it. restEndpoint is the RPC URL; apiKey and symbol are unused.
+func FetchBaseFee(client *http.Client, restEndpoint, apiKey, symbol string) (float64, time.Time, error) {
+	var history struct {
+		BaseFees []string `json:"baseFeePerGas"` // the last is the next block's
+	}
+	err := rpcCall(client, restEndpoint, "eth_feeHistory", []any{1, "latest", []int{}}, &history)
+	var rpcErr *rpcError
+	if errors.As(err, &rpcErr) {
+		var price string
+		if err := rpcCall(client, restEndpoint, "eth_gasPrice", []any{}, &price); err != nil {
+			return 0, time.Time{}, err
+		}
+		return gwei("eth_gasPrice", price)
+	}
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if len(history.BaseFees) == 0 {
+		return 0, time.Time{}, errors.New("eth_feeHistory: no base fee")
+	}
+	return gwei("eth_feeHistory", history.BaseFees[len(history.BaseFees)-1])
+}
+
+// rpcError is an error object returned by the node.
+type rpcError struct {
+	Method  string
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s: %s (code %d)", e.Method, e.Message, e.Code)
+}
+
+// rpcCall posts a JSON-RPC 2.0 request and decodes its result into v. The
+// URL stays out of transport errors.
+func rpcCall(client *http.Client, endpoint, method string, params []any, v any) error {
+	body, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	var r struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("%s: HTTP %d: %w", method, resp.StatusCode, err)
+	}
+	if r.Error != nil {
+		r.Error.Method = method
+		return r.Error
+	}
+	if resp.StatusCode != http.StatusOK || len(r.Result) == 0 {
+		return fmt.Errorf("%s: HTTP %d: no result", method, resp.StatusCode)
+	}
+	return json.Unmarshal(r.Result, v)
+}
+
+// gwei converts a hex wei quantity to gwei.
+func gwei(method, hex string) (float64, time.Time, error) {
+	wei, err := strconv.ParseUint(strings.TrimPrefix(hex, "0x"), 16, 64)
+	if err != nil || wei == 0 {
+		return 0, time.Time{}, fmt.Errorf("%s: bad quantity %q", method, hex)
+	}
+	return float64(wei) / 1e9, time.Time{}, nil
+}
+
+// GasInfo stands in for an exchange lookup: base fees are shown in gwei
+// to two decimals.
+func GasInfo(client *http.Client, restEndpoint, symbol string) (SymbolInfo, error) {
+	return SymbolInfo{Symbol: strings.ToUpper(symbol), Trading: true, TickSize: 0.01, Decimals: 2}, nil
+}
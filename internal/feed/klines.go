@@ -0,0 +1,77 @@
+package feed
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kline is one candle of a symbol's history.
+type Kline struct {
+	Open, High, Low, Close float64
+	Volume                 float64
+	OpenTime, CloseTime    time.Time
+}
+
+// KlineFetcher asks an exchange's REST API for the last limit klines of a
+// market, oldest first; the last one is still open.
+type KlineFetcher func(client *http.Client, restEndpoint, symbol, interval string, limit int) ([]Kline, error)
+
+// FetchKlines reads /api/v3/klines.
+func FetchKlines(client *http.Client, restEndpoint, symbol, interval string, limit int) ([]Kline, error) {
+	return fetchBinanceKlines(client, strings.TrimRight(restEndpoint, "/")+"/api/v3/klines", symbol, interval, limit)
+}
+
+// FetchFuturesKlines reads /fapi/v1/klines.
+func FetchFuturesKlines(client *http.Client, restEndpoint, symbol, interval string, limit int) ([]Kline, error) {
+	return fetchBinanceKlines(client, strings.TrimRight(restEndpoint, "/")+"/fapi/v1/klines", symbol, interval, limit)
+}
+
+// fetchBinanceKlines decodes the klines at base. Each kline is an array of
+// open time, open, high, low, close, volume, close time and more.
+func fetchBinanceKlines(client *http.Client, base, symbol, interval string, limit int) ([]Kline, error) {
+	u := fmt.Sprintf("%s?symbol=%s&interval=%s&limit=%d", base, url.QueryEscape(strings.ToUpper(symbol)), url.QueryEscape(interval), limit)
+	var raw json.RawMessage
+	status, err := getJSON(client, u, "klines", &raw)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		var e struct {
+			Msg string `json:"msg"`
+		}
+		json.Unmarshal(raw, &e)
+		return nil, fmt.Errorf("klines: HTTP %d: %s", status, e.Msg)
+	}
+	var rows [][]json.RawMessage
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, fmt.Errorf("klines: %w", err)
+	}
+	klines := make([]Kline, 0, len(rows))
+	for _, row := range rows {
+		if len(row) < 7 {
+			return nil, fmt.Errorf("klines: short row of %d fields", len(row))
+		}
+		var k Kline
+		var opened, closed int64
+		var fields [5]string
+		json.Unmarshal(row[0], &opened)
+		json.Unmarshal(row[6], &closed)
+		for i := range fields {
+			json.Unmarshal(row[i+1], &fields[i])
+		}
+		for i, p := range []*float64{&k.Open, &k.High, &k.Low, &k.Close} {
+			if *p, err = parsePrice("klines", fields[i]); err != nil {
+				return nil, err
+			}
+		}
+		k.Volume, _ = strconv.ParseFloat(fields[4], 64)
+		k.OpenTime, k.CloseTime = time.UnixMilli(opened), time.UnixMilli(closed)
+		klines = append(klines, k)
+	}
+	return klines, nil
+}
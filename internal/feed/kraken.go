@@ -0,0 +1,96 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// KRAKEN_HEARTBEAT_TIMEOUT is how long the connection may stay silent. Once
+// subscribed Kraken sends a heartbeat every second, so a longer gap means
+// the connection is dead even if TCP has not noticed.
+const KRAKEN_HEARTBEAT_TIMEOUT = 10 * time.Second
+
+// Kraken streams trades from the Kraken v2 websocket API's trade channel.
+// Symbols are pairs such as "eth/usd".
+type Kraken struct {
+	// Endpoint is the feed URL, e.g. wss://ws.kraken.com/v2.
+	Endpoint   string
+	Symbols    []string
+	PingPeriod time.Duration
+}
+
+// krakenMessage is the part of a v2 message we use: either a method
+// response (subscribe, pong) or channel data.
+type krakenMessage struct {
+	Method  string `json:"method"`
+	Success *bool  `json:"success"`
+	Error   string `json:"error"`
+	Channel string `json:"channel"`
+	Data    []struct {
+		Symbol    string  `json:"symbol"`
+		Price     float64 `json:"price"`
+		Qty       float64 `json:"qty"`
+		Side      string  `json:"side"` // of the taker
+		Timestamp string  `json:"timestamp"`
+	} `json:"data"`
+}
+
+// krakenPing is Kraken's application-level ping; it answers websocket ping
+// frames too, but only a method ping proves the session is served.
+func krakenPing(c *websocket.Conn) error {
+	return c.WriteJSON(map[string]string{"method": "ping"})
+}
+
+func (k *Kraken) Run(ctx context.Context, out chan<- Tick) error {
+	c, err := dial(ctx, k.Endpoint)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	pairs := make([]string, len(k.Symbols))
+	for i, s := range k.Symbols {
+		pairs[i] = strings.ToUpper(s)
+	}
+	sub := map[string]any{
+		"method": "subscribe",
+		"params": map[string]any{"channel": "trade", "symbol": pairs, "snapshot": false},
+	}
+	if err := c.WriteJSON(sub); err != nil {
+		return fmt.Errorf("subscribe: %w", err)
+	}
+	defer keepalive(ctx, c, k.PingPeriod, krakenPing)()
+
+	for {
+		expectWithin(ctx, c, KRAKEN_HEARTBEAT_TIMEOUT)
+		_, msg, err := c.ReadMessage()
+		if err != nil {
+			return silentErr(ctx, err, KRAKEN_HEARTBEAT_TIMEOUT)
+		}
+
+		var m krakenMessage
+		if err := json.Unmarshal(msg, &m); err != nil {
+			continue
+		}
+		if m.Method == "subscribe" && m.Success != nil && !*m.Success {
+			return fmt.Errorf("kraken: subscribe: %s", m.Error)
+		}
+		if m.Channel != "trade" {
+			continue
+		}
+		for _, d := range m.Data {
+			t, err := time.Parse(time.RFC3339Nano, d.Timestamp)
+			if err != nil {
+				t = time.Now()
+			}
+			if !send(ctx, out, Tick{Symbol: strings.ToLower(d.Symbol), Price: d.Price, Size: d.Qty, Side: d.Side, Time: t}) {
+				return nil
+			}
+		}
+	}
+}
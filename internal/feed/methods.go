@@ -0,0 +1,176 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// METHOD_TIMEOUT bounds the wait for Binance's reply to a websocket method.
+const METHOD_TIMEOUT = 5 * time.Second
+
+// ErrNotConnected is returned by BinanceMethods calls while no connection
+// is up; the next one subscribes from the config anyway.
+var ErrNotConnected = errors.New("not connected")
+
+// BinanceMethods changes the streams of a running Binance source with the
+// websocket SUBSCRIBE, UNSUBSCRIBE and LIST_SUBSCRIPTIONS methods, so
+// symbols come and go without a reconnect. It outlives connections: each
+// one attaches on dial and detaches when it drops.
+type BinanceMethods struct {
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	nextID  int64
+	pending map[int64]chan methodReply
+	// symbols routes stream names, lower-cased, to their symbol.
+	symbols map[string]string
+}
+
+type methodReply struct {
+	result json.RawMessage
+	err    error
+}
+
+// attach makes c the connection calls go to, subscribed to streams.
+func (m *BinanceMethods) attach(c *websocket.Conn, streams []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.conn = c
+	m.pending = make(map[int64]chan methodReply)
+	m.symbols = make(map[string]string)
+	for _, stream := range streams {
+		m.route(stream)
+	}
+}
+
+// detach fails the calls still waiting for a reply from the dropped
+// connection.
+func (m *BinanceMethods) detach() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, reply := range m.pending {
+		reply <- methodReply{err: ErrNotConnected}
+	}
+	m.conn, m.pending = nil, nil
+}
+
+// route adds stream to the routing table; m.mu must be held.
+func (m *BinanceMethods) route(stream string) {
+	symbol, _, _ := strings.Cut(stream, "@")
+	m.symbols[strings.ToLower(stream)] = symbol
+}
+
+// symbol returns the symbol stream is routed to.
+func (m *BinanceMethods) symbol(stream string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	symbol, ok := m.symbols[strings.ToLower(stream)]
+	return symbol, ok
+}
+
+// deliver hands a method reply read from the connection to its caller and
+// reports whether msg was one.
+func (m *BinanceMethods) deliver(msg []byte) bool {
+	var r struct {
+		ID     *int64          `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(msg, &r) != nil || r.ID == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reply, ok := m.pending[*r.ID]
+	if !ok {
+		return true
+	}
+	delete(m.pending, *r.ID)
+	if r.Error != nil {
+		reply <- methodReply{err: fmt.Errorf("%s (code %d)", r.Error.Msg, r.Error.Code)}
+	} else {
+		reply <- methodReply{result: r.Result}
+	}
+	return true
+}
+
+// call sends method with params and waits for its reply.
+func (m *BinanceMethods) call(ctx context.Context, method string, params []string) (json.RawMessage, error) {
+	m.mu.Lock()
+	if m.conn == nil {
+		m.mu.Unlock()
+		return nil, ErrNotConnected
+	}
+	m.nextID++
+	id := m.nextID
+	reply := make(chan methodReply, 1)
+	m.pending[id] = reply
+	req := map[string]any{"method": method, "id": id}
+	if params != nil {
+		req["params"] = params
+	}
+	err := m.conn.WriteJSON(req)
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, METHOD_TIMEOUT)
+	defer cancel()
+	select {
+	case r := <-reply:
+		return r.result, r.err
+	case <-ctx.Done():
+		m.mu.Lock()
+		delete(m.pending, id)
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%s: no reply within %v", method, METHOD_TIMEOUT)
+	}
+}
+
+// Subscribe adds the stream of symbol, e.g. solusdt or solusdt@bookTicker
+// as in Binance.Symbols, to the live connection.
+func (m *BinanceMethods) Subscribe(ctx context.Context, symbol string) error {
+	stream := (&Binance{Symbols: []string{symbol}}).streams()[0]
+	m.mu.Lock()
+	if m.conn != nil {
+		m.route(stream) // before the first tick can arrive
+	}
+	m.mu.Unlock()
+	_, err := m.call(ctx, "SUBSCRIBE", []string{stream})
+	return err
+}
+
+// Unsubscribe drops the stream of symbol from the live connection.
+func (m *BinanceMethods) Unsubscribe(ctx context.Context, symbol string) error {
+	stream := (&Binance{Symbols: []string{symbol}}).streams()[0]
+	m.mu.Lock()
+	if m.conn != nil {
+		delete(m.symbols, strings.ToLower(stream))
+	}
+	m.mu.Unlock()
+	_, err := m.call(ctx, "UNSUBSCRIBE", []string{stream})
+	return err
+}
+
+// List returns the streams the live connection is subscribed to.
+func (m *BinanceMethods) List(ctx context.Context) ([]string, error) {
+	result, err := m.call(ctx, "LIST_SUBSCRIPTIONS", nil)
+	if err != nil {
+		return nil, err
+	}
+	var streams []string
+	if err := json.Unmarshal(result, &streams); err != nil {
+		return nil, fmt.Errorf("LIST_SUBSCRIPTIONS: %w", err)
+	}
+	return streams, nil
+}
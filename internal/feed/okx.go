@@ -0,0 +1,97 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// OKX streams trades from the OKX v5 public websocket's trades channel;
+// the public endpoint needs no login. Symbols are instrument IDs such as
+// "btc-usdt" or "btc-usdt-swap".
+type OKX struct {
+	// Endpoint is the public stream, e.g. wss://ws.okx.com:8443/ws/v5/public.
+	Endpoint   string
+	Symbols    []string
+	PingPeriod time.Duration
+}
+
+// okxMessage is the part of a v5 message we use: an event (subscribe,
+// error) or channel data.
+type okxMessage struct {
+	Event string `json:"event"`
+	Code  string `json:"code"`
+	Msg   string `json:"msg"`
+	Arg   struct {
+		Channel string `json:"channel"`
+	} `json:"arg"`
+	Data []struct {
+		InstID string `json:"instId"`
+		Price  string `json:"px"`
+		Size   string `json:"sz"`
+		Side   string `json:"side"` // of the taker
+		Time   string `json:"ts"`
+	} `json:"data"`
+}
+
+// okxPing is the plain-text "ping" OKX answers with "pong". The server
+// drops connections idle for 30 seconds, so PingPeriod must stay below.
+func okxPing(c *websocket.Conn) error {
+	return c.WriteMessage(websocket.TextMessage, []byte("ping"))
+}
+
+func (o *OKX) Run(ctx context.Context, out chan<- Tick) error {
+	c, err := dial(ctx, o.Endpoint)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	args := make([]map[string]string, len(o.Symbols))
+	for i, s := range o.Symbols {
+		args[i] = map[string]string{"channel": "trades", "instId": strings.ToUpper(s)}
+	}
+	if err := c.WriteJSON(map[string]any{"op": "subscribe", "args": args}); err != nil {
+		return fmt.Errorf("subscribe: %w", err)
+	}
+	defer keepalive(ctx, c, o.PingPeriod, okxPing)()
+
+	silence := 2*o.PingPeriod + CLOSE_TIMEOUT
+	for {
+		expectWithin(ctx, c, silence)
+		_, msg, err := c.ReadMessage()
+		if err != nil {
+			return silentErr(ctx, err, silence)
+		}
+
+		var m okxMessage
+		if err := json.Unmarshal(msg, &m); err != nil {
+			continue // "pong"
+		}
+		if m.Event == "error" {
+			return fmt.Errorf("okx: %s (code %s)", m.Msg, m.Code)
+		}
+		if m.Arg.Channel != "trades" {
+			continue
+		}
+		for _, d := range m.Data {
+			price, err := strconv.ParseFloat(d.Price, 64)
+			if err != nil {
+				continue
+			}
+			t := time.Now()
+			if ms, err := strconv.ParseInt(d.Time, 10, 64); err == nil {
+				t = time.UnixMilli(ms)
+			}
+			size, _ := strconv.ParseFloat(d.Size, 64)
+			if !send(ctx, out, Tick{Symbol: strings.ToLower(d.InstID), Price: price, Size: size, Side: d.Side, Time: t}) {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package feed
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// PLUGIN_STOP_TIMEOUT is how long a plugin gets to exit after SIGTERM
+// before it is killed.
+const PLUGIN_STOP_TIMEOUT = 5 * time.Second
+
+// Plugin streams ticks from an external program. Command is run with Args
+// followed by Symbols, the plugin's own names for its markets, and with Env
+// on top of the writer's environment plus PRICE_ALERT_PLUGIN=<Name> and
+// PRICE_ALERT_SYMBOLS=<symbols, comma-separated>. It writes one JSON
+// object per line to stdout:
+//
+//	{"symbol":"btc-usd","price":64000.5,"size":0.1,"time":1700000000000}
+//
+// size, time (Unix milliseconds, default now), bid and ask are optional;
+// numbers may be quoted. {"error":"..."} reports a failure, after which the
+// plugin is restarted like a dropped connection. Lines on stderr are
+// logged.
+type Plugin struct {
+	Name    string
+	Command string
+	Args    []string
+	// Env holds KEY=value pairs.
+	Env     []string
+	Symbols []string
+}
+
+// pluginLine is a line of a plugin's stdout.
+type pluginLine struct {
+	Symbol string      `json:"symbol"`
+	Price  json.Number `json:"price"`
+	Size   json.Number `json:"size"`
+	Time   int64       `json:"time"`
+	Bid    json.Number `json:"bid"`
+	Ask    json.Number `json:"ask"`
+	Error  string      `json:"error"`
+}
+
+func (p *Plugin) Run(ctx context.Context, out chan<- Tick) error {
+	cmd := exec.CommandContext(ctx, p.Command, append(append([]string(nil), p.Args...), p.Symbols...)...)
+	cmd.Env = append(os.Environ(), p.Env...)
+	cmd.Env = append(cmd.Env, "PRICE_ALERT_PLUGIN="+p.Name, "PRICE_ALERT_SYMBOLS="+strings.Join(p.Symbols, ","))
+	cmd.Cancel = func() error { return cmd.Process.Signal(syscall.SIGTERM) }
+	cmd.WaitDelay = PLUGIN_STOP_TIMEOUT
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("plugin %s: %w", p.Name, err)
+	}
+	go p.logStderr(stderr)
+
+	err = p.read(ctx, stdout, out)
+	if err != nil {
+		// Stop a plugin that is still running but reported a failure
+		_ = cmd.Cancel()
+	}
+	waitErr := cmd.Wait()
+	switch {
+	case ctx.Err() != nil:
+		return nil
+	case err != nil:
+		return fmt.Errorf("plugin %s: %w", p.Name, err)
+	case waitErr != nil:
+		return fmt.Errorf("plugin %s: %w", p.Name, waitErr)
+	}
+	return fmt.Errorf("plugin %s exited", p.Name)
+}
+
+// read sends the ticks of the plugin's stdout to out until it closes or
+// reports an error.
+func (p *Plugin) read(ctx context.Context, stdout io.Reader, out chan<- Tick) error {
+	lines := bufio.NewScanner(stdout)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" {
+			continue
+		}
+		var l pluginLine
+		if err := json.Unmarshal([]byte(line), &l); err != nil {
+			logging.Warn(fmt.Sprintf("Plugin %s: bad line %q: %v", p.Name, line, err), "event", "plugin", "plugin", p.Name)
+			continue
+		}
+		if l.Error != "" {
+			return errors.New(l.Error)
+		}
+		price, err := l.Price.Float64()
+		if err != nil || price <= 0 || math.IsNaN(price) || math.IsInf(price, 0) || l.Symbol == "" {
+			logging.Warn(fmt.Sprintf("Plugin %s: bad tick %q", p.Name, line), "event", "plugin", "plugin", p.Name)
+			continue
+		}
+		t := time.Now()
+		if l.Time > 0 {
+			t = time.UnixMilli(l.Time)
+		}
+		tick := Tick{Symbol: p.Name + ":" + strings.ToLower(l.Symbol), Price: price, Time: t}
+		tick.Size, _ = pluginNumber(l.Size)
+		tick.Bid, _ = pluginNumber(l.Bid)
+		tick.Ask, _ = pluginNumber(l.Ask)
+		if !send(ctx, out, tick) {
+			return nil
+		}
+	}
+	return lines.Err()
+}
+
+// pluginNumber is n, 0 when the line left it out or it is not finite.
+func pluginNumber(n json.Number) (float64, error) {
+	if n == "" {
+		return 0, nil
+	}
+	f, err := n.Float64()
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("bad number %q", n)
+	}
+	return f, nil
+}
+
+// logStderr logs the lines the plugin writes to stderr.
+func (p *Plugin) logStderr(stderr io.Reader) {
+	lines := bufio.NewScanner(stderr)
+	for lines.Scan() {
+		if line := strings.TrimSpace(lines.Text()); line != "" {
+			logging.Info(fmt.Sprintf("Plugin %s: %s", p.Name, line), "event", "plugin", "plugin", p.Name)
+		}
+	}
+}
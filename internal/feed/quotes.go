@@ -0,0 +1,328 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	_ "time/tzdata" // sessions must not depend on the host's zoneinfo
+
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// Quoter asks a REST quote provider for the last price of a stock ticker
+// or currency pair and when it was quoted; at is zero when the provider
+// does not say.
+type Quoter func(client *http.Client, restEndpoint, apiKey, symbol string) (price float64, at time.Time, err error)
+
+// Session reports whether a market is open at t, and otherwise when it
+// next opens.
+type Session func(t time.Time) (open bool, next time.Time)
+
+// Quotes polls a REST quote provider for stock tickers such as "spy" or
+// pairs such as "eur/usd" while their market is open and passes each quote
+// on as a tick. A failed quote is logged and retried on the next round, so
+// a flaky provider never takes the websocket exchanges down with it.
+// Outside the session it waits for the next open without a request.
+type Quotes struct {
+	Quote        Quoter
+	Session      Session // nil for a market that never closes
+	Market       string  // for log lines, e.g. "US market"
+	RESTEndpoint string
+	APIKey       string
+	Symbols      []string
+	Interval     time.Duration
+}
+
+// newYork is the time zone both sessions are set in.
+var newYork = mustLoadLocation("America/New_York")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// USSession is the regular US equity session, 9:30 to 16:00 New York time
+// on weekdays. Exchange holidays are not known, so they poll an unchanged
+// price.
+func USSession(t time.Time) (open bool, next time.Time) {
+	t = t.In(newYork)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, newYork)
+	opens, closes := day.Add(9*time.Hour+30*time.Minute), day.Add(16*time.Hour)
+	weekday := t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+	if weekday && !t.Before(opens) && t.Before(closes) {
+		return true, time.Time{}
+	}
+	if weekday && t.Before(opens) {
+		return false, opens
+	}
+	for {
+		day = day.AddDate(0, 0, 1)
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			return false, day.Add(9*time.Hour + 30*time.Minute)
+		}
+	}
+}
+
+// ForexSession is the round-the-clock currency and metals week, from
+// Sunday to Friday 17:00 New York time.
+func ForexSession(t time.Time) (open bool, next time.Time) {
+	t = t.In(newYork)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, newYork)
+	switch {
+	case t.Weekday() == time.Friday && t.Hour() >= 17:
+		day = day.AddDate(0, 0, 2)
+	case t.Weekday() == time.Saturday:
+		day = day.AddDate(0, 0, 1)
+	case t.Weekday() == time.Sunday && t.Hour() < 17:
+	default:
+		return true, time.Time{}
+	}
+	return false, day.Add(17 * time.Hour)
+}
+
+func (q *Quotes) Run(ctx context.Context, out chan<- Tick) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	for {
+		if open, next := q.open(); !open {
+			logging.Info(fmt.Sprintf("%s closed, polling %s again at %s", q.Market, strings.ToUpper(strings.Join(q.Symbols, ",")),
+				next.Format("Mon 15:04 MST")), "event", "market_closed")
+			if !sleepUntil(ctx, next) {
+				return nil
+			}
+			continue
+		}
+		for _, symbol := range q.Symbols {
+			price, at, err := q.Quote(client, q.RESTEndpoint, q.APIKey, symbol)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil {
+				logging.Warn(fmt.Sprintf("%s quote error: %v", strings.ToUpper(symbol), err), "event", "poll", "symbol", symbol)
+				continue
+			}
+			if at.IsZero() {
+				at = time.Now()
+			}
+			if !send(ctx, out, Tick{Symbol: symbol, Price: price, Time: at}) {
+				return nil
+			}
+		}
+		if !sleepUntil(ctx, time.Now().Add(q.Interval)) {
+			return nil
+		}
+	}
+}
+
+// open asks q.Session whether the market is open now.
+func (q *Quotes) open() (bool, time.Time) {
+	if q.Session == nil {
+		return true, time.Time{}
+	}
+	return q.Session(time.Now())
+}
+
+// sleepUntil waits for t and reports false if ctx ended first.
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// quoteJSON is getJSON for URLs carrying the API key, which it keeps out of
+// transport errors.
+func quoteJSON(client *http.Client, u, endpoint string, v any) (int, error) {
+	status, err := getJSON(client, u, endpoint, v)
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		err = fmt.Errorf("%s: %w", endpoint, ue.Err)
+	}
+	return status, err
+}
+
+// FetchPolygonTrade reads Polygon's /v2/last/trade/{ticker}.
+func FetchPolygonTrade(client *http.Client, restEndpoint, apiKey, symbol string) (float64, time.Time, error) {
+	u := strings.TrimRight(restEndpoint, "/") + "/v2/last/trade/" + url.PathEscape(strings.ToUpper(symbol)) + "?apiKey=" + url.QueryEscape(apiKey)
+	var t struct {
+		Status  string `json:"status"`
+		Error   string `json:"error"`
+		Message string `json:"message"`
+		Results struct {
+			Ticker string  `json:"T"` // or it would land in Time
+			Price  float64 `json:"p"`
+			Time   int64   `json:"t"` // SIP timestamp, ns
+		} `json:"results"`
+	}
+	status, err := quoteJSON(client, u, "last/trade", &t)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if status != http.StatusOK || t.Status != "OK" {
+		msg := t.Error
+		if msg == "" {
+			msg = t.Message
+		}
+		return 0, time.Time{}, fmt.Errorf("last/trade: HTTP %d: %s %s", status, t.Status, msg)
+	}
+	if t.Results.Price <= 0 {
+		return 0, time.Time{}, fmt.Errorf("last/trade: bad price %v", t.Results.Price)
+	}
+	var at time.Time
+	if t.Results.Time > 0 {
+		at = time.Unix(0, t.Results.Time)
+	}
+	return t.Results.Price, at, nil
+}
+
+// FetchAlphaVantageQuote reads Alpha Vantage's GLOBAL_QUOTE, which carries
+// no trade time.
+func FetchAlphaVantageQuote(client *http.Client, restEndpoint, apiKey, symbol string) (float64, time.Time, error) {
+	u := strings.TrimRight(restEndpoint, "/") + "/query?function=GLOBAL_QUOTE&symbol=" + url.QueryEscape(strings.ToUpper(symbol)) + "&apikey=" + url.QueryEscape(apiKey)
+	var q struct {
+		alphaVantageError
+		Quote struct {
+			Price string `json:"05. price"`
+		} `json:"Global Quote"`
+	}
+	status, err := quoteJSON(client, u, "GLOBAL_QUOTE", &q)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if err := q.check("GLOBAL_QUOTE", status); err != nil {
+		return 0, time.Time{}, err
+	}
+	if q.Quote.Price == "" {
+		return 0, time.Time{}, fmt.Errorf("GLOBAL_QUOTE: no quote for %s", strings.ToUpper(symbol))
+	}
+	price, err := parsePrice("GLOBAL_QUOTE", q.Quote.Price)
+	return price, time.Time{}, err
+}
+
+// alphaVantageError holds the complaints Alpha Vantage answers with, on
+// HTTP 200: Note and Information carry rate limit and key problems.
+type alphaVantageError struct {
+	Error       string `json:"Error Message"`
+	Note        string `json:"Note"`
+	Information string `json:"Information"`
+}
+
+func (e alphaVantageError) check(endpoint string, status int) error {
+	if msg := e.Error + e.Note + e.Information; status != http.StatusOK || msg != "" {
+		return fmt.Errorf("%s: HTTP %d: %s", endpoint, status, msg)
+	}
+	return nil
+}
+
+// FetchPolygonForex reads Polygon's /v1/last_quote/currencies/{from}/{to}
+// and prices the pair at the midpoint of its bid and ask.
+func FetchPolygonForex(client *http.Client, restEndpoint, apiKey, symbol string) (float64, time.Time, error) {
+	from, to, _ := strings.Cut(strings.ToUpper(symbol), "/")
+	u := strings.TrimRight(restEndpoint, "/") + "/v1/last_quote/currencies/" + url.PathEscape(from) + "/" + url.PathEscape(to) + "?apiKey=" + url.QueryEscape(apiKey)
+	var q struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Error   string `json:"error"`
+		Last    struct {
+			Ask  float64 `json:"ask"`
+			Bid  float64 `json:"bid"`
+			Time int64   `json:"timestamp"` // ms
+		} `json:"last"`
+	}
+	status, err := quoteJSON(client, u, "last_quote", &q)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if status != http.StatusOK || q.Status != "success" {
+		return 0, time.Time{}, fmt.Errorf("last_quote: HTTP %d: %s %s%s", status, q.Status, q.Error, q.Message)
+	}
+	if q.Last.Bid <= 0 || q.Last.Ask <= 0 {
+		return 0, time.Time{}, fmt.Errorf("last_quote: no quote for %s", strings.ToUpper(symbol))
+	}
+	var at time.Time
+	if q.Last.Time > 0 {
+		at = time.UnixMilli(q.Last.Time)
+	}
+	return (q.Last.Bid + q.Last.Ask) / 2, at, nil
+}
+
+// FetchAlphaVantageRate reads Alpha Vantage's CURRENCY_EXCHANGE_RATE.
+func FetchAlphaVantageRate(client *http.Client, restEndpoint, apiKey, symbol string) (float64, time.Time, error) {
+	from, to, _ := strings.Cut(strings.ToUpper(symbol), "/")
+	u := strings.TrimRight(restEndpoint, "/") + "/query?function=CURRENCY_EXCHANGE_RATE&from_currency=" + url.QueryEscape(from) +
+		"&to_currency=" + url.QueryEscape(to) + "&apikey=" + url.QueryEscape(apiKey)
+	var q struct {
+		alphaVantageError
+		Rate struct {
+			Rate      string `json:"5. Exchange Rate"`
+			Refreshed string `json:"6. Last Refreshed"`
+			Zone      string `json:"7. Time Zone"`
+		} `json:"Realtime Currency Exchange Rate"`
+	}
+	status, err := quoteJSON(client, u, "CURRENCY_EXCHANGE_RATE", &q)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if err := q.check("CURRENCY_EXCHANGE_RATE", status); err != nil {
+		return 0, time.Time{}, err
+	}
+	if q.Rate.Rate == "" {
+		return 0, time.Time{}, fmt.Errorf("CURRENCY_EXCHANGE_RATE: no rate for %s", strings.ToUpper(symbol))
+	}
+	price, err := parsePrice("CURRENCY_EXCHANGE_RATE", q.Rate.Rate)
+	var at time.Time
+	if q.Rate.Zone == "UTC" {
+		at, _ = time.Parse(time.DateTime, q.Rate.Refreshed)
+	}
+	return price, at, err
+}
+
+// FetchTwelveDataPrice reads Twelve Data's /price, which takes stock
+// tickers and forex or metal pairs alike and carries no quote time.
+func FetchTwelveDataPrice(client *http.Client, restEndpoint, apiKey, symbol string) (float64, time.Time, error) {
+	u := strings.TrimRight(restEndpoint, "/") + "/price?symbol=" + url.QueryEscape(strings.ToUpper(symbol)) + "&apikey=" + url.QueryEscape(apiKey)
+	var q struct {
+		Price   string `json:"price"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	status, err := quoteJSON(client, u, "price", &q)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if status != http.StatusOK || q.Code != 0 {
+		return 0, time.Time{}, fmt.Errorf("price: HTTP %d: %s (code %d)", status, q.Message, q.Code)
+	}
+	price, err := parsePrice("price", q.Price)
+	return price, time.Time{}, err
+}
+
+// ForexInfo stands in for an exchange lookup with the customary pip of a
+// pair: 0.00001, 0.001 against the yen and for silver, 0.01 for gold.
+func ForexInfo(client *http.Client, restEndpoint, symbol string) (SymbolInfo, error) {
+	base, quote, _ := strings.Cut(strings.ToLower(symbol), "/")
+	decimals := 5
+	switch {
+	case base == "xau":
+		decimals = 2
+	case base == "xag" || quote == "jpy":
+		decimals = 3
+	}
+	return SymbolInfo{Symbol: strings.ToUpper(symbol), Trading: true, TickSize: math.Pow(10, -float64(decimals)), Decimals: decimals}, nil
+}
+
+// StockInfo stands in for an exchange lookup: US equities tick in cents.
+func StockInfo(client *http.Client, restEndpoint, symbol string) (SymbolInfo, error) {
+	return SymbolInfo{Symbol: strings.ToUpper(symbol), Trading: true, TickSize: 0.01, Decimals: 2}, nil
+}
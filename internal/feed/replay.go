@@ -0,0 +1,88 @@
+package feed
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Replay plays back ticks recorded as CSV lines of "time,symbol,price",
+// where time is RFC 3339 or Unix milliseconds. Blank lines and lines
+// starting with # are skipped.
+type Replay struct {
+	Path string
+	// Speed scales the recorded gaps between ticks: 1 is real time, 10 is
+	// ten times faster and 0 replays without any delay.
+	Speed float64
+}
+
+func (r *Replay) Run(ctx context.Context, out chan<- Tick) error {
+	f, err := os.Open(r.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var last time.Time
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		tick, err := parseReplayLine(text)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", r.Path, line, err)
+		}
+
+		if r.Speed > 0 && !last.IsZero() && tick.Time.After(last) {
+			gap := time.Duration(float64(tick.Time.Sub(last)) / r.Speed)
+			select {
+			case <-time.After(gap):
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		last = tick.Time
+
+		if !send(ctx, out, tick) {
+			return nil
+		}
+	}
+	return sc.Err()
+}
+
+func parseReplayLine(text string) (Tick, error) {
+	fields := strings.Split(text, ",")
+	if len(fields) != 3 {
+		return Tick{}, fmt.Errorf("want time,symbol,price, got %q", text)
+	}
+	ts, err := parseReplayTime(strings.TrimSpace(fields[0]))
+	if err != nil {
+		return Tick{}, err
+	}
+	price, err := strconv.ParseFloat(strings.TrimSpace(fields[2]), 64)
+	if err != nil {
+		return Tick{}, fmt.Errorf("price: %w", err)
+	}
+	return Tick{
+		Symbol: strings.ToLower(strings.TrimSpace(fields[1])),
+		Price:  price,
+		Time:   ts,
+	}, nil
+}
+
+func parseReplayTime(s string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("time: %w", err)
+	}
+	return t, nil
+}
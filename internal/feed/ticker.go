@@ -0,0 +1,182 @@
+package feed
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PriceFetcher asks an exchange's REST ticker endpoint for the last trade
+// price of a market.
+type PriceFetcher func(client *http.Client, restEndpoint, symbol string) (float64, error)
+
+// getJSON decodes the response to u into v, naming endpoint in errors.
+func getJSON(client *http.Client, u, endpoint string, v any) (int, error) {
+	resp, err := client.Get(u)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return resp.StatusCode, fmt.Errorf("%s: HTTP %d: %w", endpoint, resp.StatusCode, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// parsePrice parses a decimal price string as the exchanges send it.
+func parsePrice(endpoint, s string) (float64, error) {
+	p, err := strconv.ParseFloat(s, 64)
+	if err != nil || p <= 0 {
+		return 0, fmt.Errorf("%s: bad price %q", endpoint, s)
+	}
+	return p, nil
+}
+
+// FetchBinancePrice reads /api/v3/ticker/price.
+func FetchBinancePrice(client *http.Client, restEndpoint, symbol string) (float64, error) {
+	u := strings.TrimRight(restEndpoint, "/") + "/api/v3/ticker/price?symbol=" + url.QueryEscape(strings.ToUpper(symbol))
+	var t struct {
+		Msg   string `json:"msg"`
+		Price string `json:"price"`
+	}
+	status, err := getJSON(client, u, "ticker/price", &t)
+	if err != nil {
+		return 0, err
+	}
+	if status != http.StatusOK {
+		return 0, fmt.Errorf("ticker/price: HTTP %d: %s", status, t.Msg)
+	}
+	return parsePrice("ticker/price", t.Price)
+}
+
+// FetchFuturesPrice reads the USDⓈ-M futures /fapi/v1/ticker/price.
+func FetchFuturesPrice(client *http.Client, restEndpoint, symbol string) (float64, error) {
+	u := strings.TrimRight(restEndpoint, "/") + "/fapi/v1/ticker/price?symbol=" + url.QueryEscape(strings.ToUpper(symbol))
+	var t struct {
+		Msg   string `json:"msg"`
+		Price string `json:"price"`
+	}
+	status, err := getJSON(client, u, "ticker/price", &t)
+	if err != nil {
+		return 0, err
+	}
+	if status != http.StatusOK {
+		return 0, fmt.Errorf("ticker/price: HTTP %d: %s", status, t.Msg)
+	}
+	return parsePrice("ticker/price", t.Price)
+}
+
+// FetchOpenInterest reads the USDⓈ-M futures /fapi/v1/openInterest, the
+// open contracts of a perpetual in its base asset.
+func FetchOpenInterest(client *http.Client, restEndpoint, symbol string) (float64, error) {
+	u := strings.TrimRight(restEndpoint, "/") + "/fapi/v1/openInterest?symbol=" + url.QueryEscape(strings.ToUpper(symbol))
+	var t struct {
+		Msg          string `json:"msg"`
+		OpenInterest string `json:"openInterest"`
+	}
+	status, err := getJSON(client, u, "openInterest", &t)
+	if err != nil {
+		return 0, err
+	}
+	if status != http.StatusOK {
+		return 0, fmt.Errorf("openInterest: HTTP %d: %s", status, t.Msg)
+	}
+	oi, err := strconv.ParseFloat(t.OpenInterest, 64)
+	if err != nil || oi <= 0 {
+		return 0, fmt.Errorf("openInterest: bad value %q", t.OpenInterest)
+	}
+	return oi, nil
+}
+
+// FetchCoinbasePrice reads a product's /ticker.
+func FetchCoinbasePrice(client *http.Client, restEndpoint, product string) (float64, error) {
+	u := strings.TrimRight(restEndpoint, "/") + "/products/" + url.PathEscape(strings.ToUpper(product)) + "/ticker"
+	var t struct {
+		Message string `json:"message"`
+		Price   string `json:"price"`
+	}
+	status, err := getJSON(client, u, "ticker", &t)
+	if err != nil {
+		return 0, err
+	}
+	if status != http.StatusOK {
+		return 0, fmt.Errorf("ticker: HTTP %d: %s", status, t.Message)
+	}
+	return parsePrice("ticker", t.Price)
+}
+
+// FetchKrakenPrice reads the last trade ("c") from Kraken's Ticker
+// endpoint.
+func FetchKrakenPrice(client *http.Client, restEndpoint, pair string) (float64, error) {
+	u := strings.TrimRight(restEndpoint, "/") + "/0/public/Ticker?pair=" + url.QueryEscape(strings.ToUpper(pair))
+	var t struct {
+		Error  []string `json:"error"`
+		Result map[string]struct {
+			Last []string `json:"c"` // price, lot volume
+		} `json:"result"`
+	}
+	if _, err := getJSON(client, u, "Ticker", &t); err != nil {
+		return 0, err
+	}
+	if len(t.Error) > 0 {
+		return 0, fmt.Errorf("Ticker: %s", strings.Join(t.Error, "; "))
+	}
+	for _, r := range t.Result {
+		if len(r.Last) > 0 {
+			return parsePrice("Ticker", r.Last[0])
+		}
+	}
+	return 0, fmt.Errorf("unknown pair %s", strings.ToUpper(pair))
+}
+
+// BybitPrice returns the v5 tickers lookup of one Bybit category.
+func BybitPrice(category string) PriceFetcher {
+	return func(client *http.Client, restEndpoint, symbol string) (float64, error) {
+		u := fmt.Sprintf("%s/v5/market/tickers?category=%s&symbol=%s",
+			strings.TrimRight(restEndpoint, "/"), category, url.QueryEscape(strings.ToUpper(symbol)))
+		var t struct {
+			RetCode int    `json:"retCode"`
+			RetMsg  string `json:"retMsg"`
+			Result  struct {
+				List []struct {
+					LastPrice string `json:"lastPrice"`
+				} `json:"list"`
+			} `json:"result"`
+		}
+		if _, err := getJSON(client, u, "tickers", &t); err != nil {
+			return 0, err
+		}
+		if t.RetCode != 0 {
+			return 0, fmt.Errorf("tickers: %s", t.RetMsg)
+		}
+		if len(t.Result.List) == 0 {
+			return 0, fmt.Errorf("unknown %s symbol %s", category, strings.ToUpper(symbol))
+		}
+		return parsePrice("tickers", t.Result.List[0].LastPrice)
+	}
+}
+
+// FetchOKXPrice reads /api/v5/market/ticker.
+func FetchOKXPrice(client *http.Client, restEndpoint, instID string) (float64, error) {
+	u := strings.TrimRight(restEndpoint, "/") + "/api/v5/market/ticker?instId=" + url.QueryEscape(strings.ToUpper(instID))
+	var t struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data []struct {
+			Last string `json:"last"`
+		} `json:"data"`
+	}
+	if _, err := getJSON(client, u, "ticker", &t); err != nil {
+		return 0, err
+	}
+	if t.Code != "0" {
+		return 0, fmt.Errorf("ticker: %s (code %s)", t.Msg, t.Code)
+	}
+	if len(t.Data) == 0 {
+		return 0, fmt.Errorf("unknown instrument %s", strings.ToUpper(instID))
+	}
+	return parsePrice("ticker", t.Data[0].Last)
+}
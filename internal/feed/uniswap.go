@@ -0,0 +1,103 @@
+package feed
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Function selectors of a Uniswap v3 pool.
+const (
+	SLOT0  = "0x3850c7bd"
+	TOKEN0 = "0x0dfe1681"
+	TOKEN1 = "0xd21220a7"
+)
+
+// uniswapPools are Ethereum mainnet pools by pair, "1/" marking those
+// whose token0 is the quote.
+var uniswapPools = map[string]string{
+	"eth/usdc": "1/0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640", // USDC/WETH 0.05%
+	"eth/usdt": "0x11b815efb8f581194ae79006d24e0d814b7697f6",   // WETH/USDT 0.05%
+	"wbtc/eth": "0xcbcdf9626bc03e24f779434178a73a0b4bad62ed",   // WBTC/WETH 0.3%
+}
+
+// UniswapQuoter returns a Quoter for Uniswap v3 pools over an Ethereum
+// JSON-RPC node: the pool's current price from slot0, token0 in token1
+// adjusted for the tokens' decimals, which are read once per pool. symbol
+// is the pool address, "1/<address>" for the price of token1 in token0, or
+// a pair of uniswapPools; restEndpoint is the RPC URL and apiKey is
+// unused. The price is the pool's at the poll, so at is zero.
+func UniswapQuoter() Quoter {
+	scales := make(map[string]*big.Float)
+	return func(client *http.Client, restEndpoint, apiKey, symbol string) (float64, time.Time, error) {
+		pool, ok := uniswapPools[symbol]
+		if !ok {
+			pool = symbol
+		}
+		pool, inverse := strings.CutPrefix(pool, "1/")
+		if !strings.HasPrefix(pool, "0x") {
+			return 0, time.Time{}, fmt.Errorf("unknown uniswap pool %s, use its address", symbol)
+		}
+		scale, ok := scales[pool]
+		if !ok {
+			var err error
+			if scale, err = poolScale(client, restEndpoint, pool); err != nil {
+				return 0, time.Time{}, err
+			}
+			scales[pool] = scale
+		}
+		// sqrtPriceX96, tick, observationIndex, observationCardinality,
+		// observationCardinalityNext, feeProtocol, unlocked
+		words, err := ethCall(client, restEndpoint, pool, SLOT0, 7)
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		if words[0].Sign() == 0 {
+			return 0, time.Time{}, errors.New("slot0: pool not initialized")
+		}
+		sqrt := new(big.Float).SetPrec(256).SetInt(words[0])
+		price := new(big.Float).SetPrec(256).Mul(sqrt, sqrt)
+		price.Mul(price, scale).SetMantExp(price, -192) // sqrtPriceX96 is Q64.96
+		if inverse {
+			price.Quo(big.NewFloat(1).SetPrec(256), price)
+		}
+		p, _ := price.Float64()
+		return p, time.Time{}, nil
+	}
+}
+
+// poolScale reads the decimals of the tokens of pool and returns
+// 10^(decimals0-decimals1), which turns a raw price into whole tokens.
+func poolScale(client *http.Client, endpoint, pool string) (*big.Float, error) {
+	var decimals [2]int64
+	for i, selector := range []string{TOKEN0, TOKEN1} {
+		words, err := ethCall(client, endpoint, pool, selector, 1)
+		if err != nil {
+			return nil, err
+		}
+		token := fmt.Sprintf("0x%040x", words[0])
+		if words, err = ethCall(client, endpoint, token, DECIMALS, 1); err != nil {
+			return nil, err
+		}
+		decimals[i] = words[0].Int64()
+	}
+	diff := decimals[0] - decimals[1]
+	exp := new(big.Float).SetPrec(256).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(max(diff, -diff)), nil))
+	if diff < 0 {
+		return exp.Quo(big.NewFloat(1).SetPrec(256), exp), nil
+	}
+	return exp, nil
+}
+
+// UniswapInfo stands in for an exchange lookup of a pool, deriving its
+// tick size from the current price as CoinInfo does.
+func UniswapInfo(client *http.Client, restEndpoint, symbol string) (SymbolInfo, error) {
+	price, _, err := UniswapQuoter()(client, restEndpoint, "", symbol)
+	if err != nil {
+		return SymbolInfo{}, err
+	}
+	return CoinInfo(symbol, price), nil
+}
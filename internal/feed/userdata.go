@@ -0,0 +1,181 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// LISTEN_KEY_KEEPALIVE is how often a listen key is extended; Binance lets
+// it expire after 60 minutes without one.
+const LISTEN_KEY_KEEPALIVE = 30 * time.Minute
+
+// Fill is one execution of an order of the account, carried by a Tick
+// whose Price and Size are the execution's price and quantity.
+type Fill struct {
+	OrderID int64
+	Side    string // BUY or SELL
+	Type    string // LIMIT, MARKET, STOP_LOSS_LIMIT, ...
+	Status  string // FILLED, PARTIALLY_FILLED, ...
+	// Filled is the order's cumulative filled quantity, OrderQty its
+	// original quantity.
+	Filled, OrderQty float64
+}
+
+// UserData streams the fills of the account behind APIKey from a Binance
+// listen key: the spot executionReport or, with Futures set, the futures
+// ORDER_TRADE_UPDATE. Only trades are passed on, as ticks with Fill set.
+type UserData struct {
+	Endpoint     string // websocket base, as for Binance
+	RESTEndpoint string
+	APIKey       string
+	Futures      bool
+	PingPeriod   time.Duration
+}
+
+func (u *UserData) Run(ctx context.Context, out chan<- Tick) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	key, err := u.listenKey(client, http.MethodPost, "")
+	if err != nil {
+		return err
+	}
+	base := strings.TrimRight(u.Endpoint, "/")
+	base = strings.TrimSuffix(strings.TrimSuffix(base, "/ws"), "/stream")
+	c, err := dial(ctx, base+"/ws/"+key)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	defer keepalive(ctx, c, u.PingPeriod, pingFrame)()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(LISTEN_KEY_KEEPALIVE)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := u.listenKey(client, http.MethodPut, key); err != nil {
+					// The key dies with the next missed renewal; start over
+					logging.Warnf("Listen key keepalive error: %v", err)
+					c.Close()
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		_, msg, err := c.ReadMessage()
+		if err != nil {
+			return readErr(ctx, err)
+		}
+		var ev struct {
+			Event string `json:"e"`
+			E     int64  `json:"E"` // or it would land in Event
+		}
+		if json.Unmarshal(msg, &ev) != nil {
+			continue
+		}
+		switch ev.Event {
+		case "executionReport", "ORDER_TRADE_UPDATE":
+			if t, ok := parseExecution(msg); ok && !send(ctx, out, t) {
+				return nil
+			}
+		case "listenKeyExpired":
+			return errors.New("listen key expired")
+		}
+	}
+}
+
+// listenKey creates a listen key with POST or extends key with PUT.
+func (u *UserData) listenKey(client *http.Client, method, key string) (string, error) {
+	path := "/api/v3/userDataStream"
+	if u.Futures {
+		path = "/fapi/v1/listenKey"
+	}
+	endpoint := strings.TrimRight(u.RESTEndpoint, "/") + path
+	if key != "" {
+		endpoint += "?listenKey=" + url.QueryEscape(key)
+	}
+	req, err := http.NewRequest(method, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-MBX-APIKEY", u.APIKey)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var r struct {
+		ListenKey string `json:"listenKey"`
+		Code      int    `json:"code"`
+		Msg       string `json:"msg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return "", fmt.Errorf("listen key: HTTP %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("listen key: HTTP %d: %s (code %d)", resp.StatusCode, r.Msg, r.Code)
+	}
+	if method == http.MethodPost && r.ListenKey == "" {
+		return "", errors.New("listen key: empty reply")
+	}
+	return r.ListenKey, nil
+}
+
+// parseExecution reads the trade of a spot executionReport, or of the
+// order object "o" of a futures ORDER_TRADE_UPDATE; other executions
+// (new, cancelled, expired orders) are skipped. Nearly every field clashes
+// case-insensitively with another (l and L, x and X, ...), so they are
+// picked by exact key.
+func parseExecution(data []byte) (Tick, bool) {
+	var d map[string]json.RawMessage
+	if json.Unmarshal(data, &d) != nil {
+		return Tick{}, false
+	}
+	if o, ok := d["o"]; ok && len(o) > 0 && o[0] == '{' {
+		if json.Unmarshal(o, &d) != nil {
+			return Tick{}, false
+		}
+	}
+	str := func(key string) string {
+		var s string
+		json.Unmarshal(d[key], &s)
+		return s
+	}
+	num := func(key string) float64 {
+		f, _ := strconv.ParseFloat(str(key), 64)
+		return f
+	}
+	if str("x") != "TRADE" {
+		return Tick{}, false
+	}
+	var id, ms int64
+	json.Unmarshal(d["i"], &id)
+	json.Unmarshal(d["T"], &ms)
+	price := num("L")
+	if price <= 0 {
+		return Tick{}, false
+	}
+	fill := &Fill{
+		OrderID:  id,
+		Side:     str("S"),
+		Type:     str("o"),
+		Status:   str("X"),
+		Filled:   num("z"),
+		OrderQty: num("q"),
+	}
+	return Tick{Symbol: strings.ToLower(str("s")), Price: price, Size: num("l"), Time: time.UnixMilli(ms), Fill: fill}, true
+}
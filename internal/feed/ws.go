@@ -0,0 +1,92 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// CLOSE_TIMEOUT bounds the wait for the server's close frame on shutdown.
+const CLOSE_TIMEOUT = time.Second
+
+// dial opens a websocket connection to url.
+func dial(ctx context.Context, url string) (*websocket.Conn, error) {
+	c, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("dial error: %w", err)
+	}
+	return c, nil
+}
+
+// pingFrame is the keepalive of exchanges that answer websocket pings.
+func pingFrame(c *websocket.Conn) error {
+	return c.WriteControl(websocket.PingMessage, []byte("keepalive"), time.Now().Add(CLOSE_TIMEOUT))
+}
+
+// keepalive calls ping every period until the returned stop is called. A
+// failed ping closes c, which fails the caller's read. Cancelling ctx starts
+// the close handshake and bounds the wait for the server's reply, so the
+// read loop returns shortly after.
+func keepalive(ctx context.Context, c *websocket.Conn, period time.Duration, ping func(*websocket.Conn) error) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := ping(c); err != nil {
+					logging.Warnf("Ping error: %v", err)
+					c.Close()
+					return
+				}
+			case <-ctx.Done():
+				deadline := time.Now().Add(CLOSE_TIMEOUT)
+				msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+				if err := c.WriteControl(websocket.CloseMessage, msg, deadline); err != nil {
+					c.Close()
+					return
+				}
+				c.SetReadDeadline(deadline)
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// readErr wraps a read failure, or returns nil when it was caused by
+// cancelling ctx.
+func readErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("read error: %w", err)
+}
+
+// expectWithin arms c's read deadline for exchanges that promise traffic
+// (heartbeats or pongs) at least every d. It is skipped once ctx is
+// cancelled, so keepalive's shutdown deadline holds.
+func expectWithin(ctx context.Context, c *websocket.Conn, d time.Duration) {
+	if ctx.Err() == nil {
+		c.SetReadDeadline(time.Now().Add(d))
+	}
+}
+
+// silentErr is readErr for a connection armed with expectWithin: a timeout
+// reports the silence instead of a bare i/o error.
+func silentErr(ctx context.Context, err error, d time.Duration) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() && ctx.Err() == nil {
+		return fmt.Errorf("no data for %v", d)
+	}
+	return readErr(ctx, err)
+}
@@ -0,0 +1,146 @@
+package ipc
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ALERT_RING is how many alerts each slot of an alert region keeps, so
+// the several alerts one tick can raise all reach a reader woken once.
+const ALERT_RING = 8
+
+// Alerts is the alert region: per symbol slot a ring of ALERT_RING records
+// of recordSize bytes, "<seq>\x00<field>\x00...", where seq counts the
+// alerts of the slot from 1 and picks the record, seq % ALERT_RING. A
+// reader drains a slot by taking the records past the last seq it saw.
+type Alerts struct {
+	shm        *SHM
+	recordSize int
+	seqs       []uint64 // the last seq written to each slot
+}
+
+// CreateAlerts opens or creates the alert region at path with slots slots
+// of records of recordSize bytes, applies perm, maps it read-write and
+// clears it.
+func CreateAlerts(path string, recordSize, slots int, perm Perm) (*Alerts, error) {
+	shm, err := CreateSHM(path, recordSize*ALERT_RING, slots, perm)
+	if err != nil {
+		return nil, err
+	}
+	shm.Clear()
+	return &Alerts{shm: shm, recordSize: recordSize, seqs: make([]uint64, slots)}, nil
+}
+
+// OpenAlerts maps an existing alert region of recordSize-byte records
+// read-only.
+func OpenAlerts(path string, recordSize int) (*Alerts, error) {
+	shm, err := OpenSHM(path, recordSize*ALERT_RING)
+	if err != nil {
+		return nil, err
+	}
+	return &Alerts{shm: shm, recordSize: recordSize}, nil
+}
+
+// Slots returns the number of mapped slots.
+func (a *Alerts) Slots() int {
+	return a.shm.Slots()
+}
+
+func (a *Alerts) record(slot int, seq uint64) []byte {
+	i := int(seq % ALERT_RING)
+	return a.shm.slot(slot)[i*a.recordSize : (i+1)*a.recordSize]
+}
+
+// Write stores the next alert of slot, its fields after the seq, and
+// returns its seq. The last field, the text, is cut short to fit.
+func (a *Alerts) Write(slot int, fields ...string) (uint64, error) {
+	if slot < 0 || slot >= a.Slots() {
+		return 0, fmt.Errorf("slot %d out of range", slot)
+	}
+	if len(fields) == 0 {
+		return 0, errors.New("empty alert")
+	}
+	seq := a.seqs[slot] + 1
+	head := strconv.FormatUint(seq, 10) + "\x00" + strings.Join(fields[:len(fields)-1], "\x00")
+	if len(fields) > 1 {
+		head += "\x00"
+	}
+	text := fields[len(fields)-1]
+	if room := a.recordSize - len(head) - 1; room < 0 {
+		return 0, fmt.Errorf("alert record needs %d bytes, records have %d", len(head)+1, a.recordSize)
+	} else if len(text) > room {
+		text = text[:room]
+		for !utf8.ValidString(text) {
+			text = text[:len(text)-1]
+		}
+	}
+	rec := a.record(slot, seq)
+	clear(rec[copy(rec, head+text+"\x00"):])
+	a.seqs[slot] = seq
+	return seq, nil
+}
+
+// Since returns the alerts of slot after seq, oldest first, each with its
+// seq as the first field. Alerts more than ALERT_RING behind the newest
+// are gone.
+func (a *Alerts) Since(slot int, seq uint64) [][]string {
+	if slot < 0 || slot >= a.Slots() {
+		return nil
+	}
+	type entry struct {
+		seq    uint64
+		fields []string
+	}
+	var entries []entry
+	for i := uint64(0); i < ALERT_RING; i++ {
+		record := strings.TrimRight(string(a.record(slot, i)), "\x00")
+		fields := strings.Split(record, "\x00")
+		n, err := strconv.ParseUint(fields[0], 10, 64)
+		if record == "" || err != nil || n <= seq {
+			continue
+		}
+		entries = append(entries, entry{n, fields})
+	}
+	slices.SortFunc(entries, func(x, y entry) int { return cmp.Compare(x.seq, y.seq) })
+	out := make([][]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.fields
+	}
+	return out
+}
+
+// Head returns the newest seq of slot, 0 when it has none.
+func (a *Alerts) Head(slot int) uint64 {
+	alerts := a.Since(slot, 0)
+	if len(alerts) == 0 {
+		return 0
+	}
+	n, _ := strconv.ParseUint(alerts[len(alerts)-1][0], 10, 64)
+	return n
+}
+
+// ClearSlot drops the alerts of slot, whose symbol left or moved. Its seqs
+// go on counting, so a reader past the old ones still finds the next.
+func (a *Alerts) ClearSlot(slot int) {
+	clear(a.shm.slot(slot))
+}
+
+// Clear drops every alert, for shutdown.
+func (a *Alerts) Clear() {
+	a.shm.Clear()
+	clear(a.seqs)
+}
+
+// Sync flushes the mapping to the backing file.
+func (a *Alerts) Sync() error {
+	return a.shm.Sync()
+}
+
+func (a *Alerts) Close() error {
+	return a.shm.Close()
+}
@@ -0,0 +1,108 @@
+package ipc
+
+import (
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestAlertsSince(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts")
+	a, err := CreateAlerts(path, 64, 2, DefaultPerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	r, err := OpenAlerts(path, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	texts := func(alerts [][]string) []string {
+		var out []string
+		for _, f := range alerts {
+			out = append(out, f[0]+":"+f[len(f)-1])
+		}
+		return out
+	}
+	for i := 1; i <= 3; i++ {
+		if seq, err := a.Write(1, "warn", "up", "t"+strconv.Itoa(i)); err != nil || seq != uint64(i) {
+			t.Fatalf("Write = %d, %v, want %d", seq, err, i)
+		}
+	}
+	tests := []struct {
+		slot int
+		seq  uint64
+		want []string
+	}{
+		{1, 0, []string{"1:t1", "2:t2", "3:t3"}},
+		{1, 1, []string{"2:t2", "3:t3"}},
+		{1, 3, nil},
+		{0, 0, nil},
+		{2, 0, nil},
+	}
+	for _, tt := range tests {
+		if got := texts(r.Since(tt.slot, tt.seq)); !slices.Equal(got, tt.want) {
+			t.Errorf("Since(%d, %d) = %v, want %v", tt.slot, tt.seq, got, tt.want)
+		}
+	}
+	if got := r.Head(1); got != 3 {
+		t.Errorf("Head(1) = %d, want 3", got)
+	}
+
+	// Past a full ring the oldest alerts are overwritten
+	for i := 4; i <= ALERT_RING+2; i++ {
+		a.Write(1, "warn", "up", "t"+strconv.Itoa(i))
+	}
+	got := texts(r.Since(1, 0))
+	if len(got) != ALERT_RING || got[0] != "3:t3" || got[len(got)-1] != "10:t10" {
+		t.Errorf("Since(1, 0) = %v, want t3 to t10", got)
+	}
+	if got := r.Head(0); got != 0 {
+		t.Errorf("Head(0) = %d, want 0", got)
+	}
+}
+
+func TestAlertsWriteCut(t *testing.T) {
+	a, err := CreateAlerts(filepath.Join(t.TempDir(), "alerts"), 32, 1, DefaultPerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	tests := []struct {
+		fields []string
+		want   string // the text read back; 21 bytes fit after "<seq>\x00warn\x00up\x00"
+		err    bool
+	}{
+		{[]string{"warn", "up", "short"}, "short", false},
+		{[]string{"warn", "up", strings.Repeat("x", 40)}, strings.Repeat("x", 21), false},
+		{[]string{"warn", "up", strings.Repeat("é", 20)}, strings.Repeat("é", 10), false},
+		{[]string{"warn", strings.Repeat("k", 40), "text"}, "", true},
+	}
+	for _, tt := range tests {
+		seq, err := a.Write(0, tt.fields...)
+		if tt.err {
+			if err == nil {
+				t.Errorf("Write(%q) = %d, want an error", tt.fields, seq)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Write(%q): %v", tt.fields, err)
+			continue
+		}
+		alerts := a.Since(0, seq-1)
+		if len(alerts) != 1 {
+			t.Errorf("Write(%q) then Since = %q, want one alert", tt.fields, alerts)
+			continue
+		}
+		text := alerts[0][len(alerts[0])-1]
+		if text != tt.want || !utf8.ValidString(text) {
+			t.Errorf("Write(%q) text = %q, want %q", tt.fields, text, tt.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package ipc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ErrLocked is returned when another process holds the instance lock.
+var ErrLocked = errors.New("another instance is running")
+
+// Lock is an flock(2)-based single-instance lock. The kernel releases it
+// when the process dies, so a crash never leaves a stale lock behind.
+type Lock struct {
+	files []*os.File
+}
+
+// LockPath returns the lock file guarding the SHM region at shmPath.
+func LockPath(shmPath string) string {
+	return shmPath + ".lock"
+}
+
+// AcquireLock takes an exclusive lock on each of paths without blocking
+// and records the holder's PID in them. It takes all of them or none.
+func AcquireLock(paths ...string) (*Lock, error) {
+	l := &Lock{}
+	for _, path := range paths {
+		f, err := lockFile(path)
+		if err != nil {
+			l.Release()
+			return nil, err
+		}
+		l.files = append(l.files, f)
+	}
+	return l, nil
+}
+
+// lockFile takes the lock on path of AcquireLock.
+func lockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		data, _ := os.ReadFile(path)
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			if pid := strings.TrimSpace(string(data)); pid != "" {
+				return nil, fmt.Errorf("%w: %s is held by pid %s", ErrLocked, path, pid)
+			}
+			return nil, fmt.Errorf("%w: %s is held", ErrLocked, path)
+		}
+		return nil, err
+	}
+	f.Truncate(0)
+	f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	return f, nil
+}
+
+// Release unlocks and closes the lock files. The files themselves are left
+// in place: removing one would race with a process that just opened it.
+func (l *Lock) Release() error {
+	var errs []error
+	for _, f := range l.files {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		errs = append(errs, f.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// WritePIDFile writes the current PID to path for service managers.
+func WritePIDFile(path string) error {
+	return os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644)
+}
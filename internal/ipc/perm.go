@@ -0,0 +1,26 @@
+package ipc
+
+import "os"
+
+// Perm is the access applied to the SHM file and FIFO after creating them
+// and before the first open, so a reader in the group can attach. A zero
+// Mode keeps the umask-derived mode and a negative GID keeps the group.
+type Perm struct {
+	Mode os.FileMode
+	GID  int
+}
+
+// DefaultPerm leaves mode and group as the process creates them.
+var DefaultPerm = Perm{GID: -1}
+
+func (p Perm) apply(path string) error {
+	if p.Mode != 0 {
+		if err := os.Chmod(path, p.Mode); err != nil {
+			return err
+		}
+	}
+	if p.GID >= 0 {
+		return os.Chown(path, -1, p.GID)
+	}
+	return nil
+}
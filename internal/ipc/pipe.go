@@ -0,0 +1,57 @@
+package ipc
+
+import (
+	"os"
+	"syscall"
+)
+
+// Pipe is the writer end of the named pipe that wakes readers up. Each
+// notification is a single byte: the updated slot index plus one.
+type Pipe struct {
+	f *os.File
+}
+
+// ensureFIFO creates the named pipe at path if it does not exist yet.
+func ensureFIFO(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := syscall.Mkfifo(path, 0666); err != nil && !os.IsExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreatePipe ensures the FIFO exists, applies perm and opens it for
+// writing. This blocks until a reader opens the other end.
+func CreatePipe(path string, perm Perm) (*Pipe, error) {
+	if err := ensureFIFO(path); err != nil {
+		return nil, err
+	}
+	if err := perm.apply(path); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, os.ModeNamedPipe)
+	if err != nil {
+		return nil, err
+	}
+	return &Pipe{f: f}, nil
+}
+
+// Notify signals that slot was updated.
+func (p *Pipe) Notify(slot int) error {
+	_, err := p.f.Write([]byte{byte(slot + 1)})
+	return err
+}
+
+func (p *Pipe) Close() error {
+	return p.f.Close()
+}
+
+// OpenPipeReader ensures the FIFO exists and opens it for reading. This
+// blocks until the writer opens the other end.
+func OpenPipeReader(path string) (*os.File, error) {
+	if err := ensureFIFO(path); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_RDONLY, os.ModeNamedPipe)
+}
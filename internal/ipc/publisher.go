@@ -0,0 +1,69 @@
+package ipc
+
+import "errors"
+
+// Publisher hands a formatted price for a symbol slot to readers, with the
+// source it came from when the symbol has failover markets. MarkStale
+// empties the price of a slot until the next Publish, and Notify wakes the
+// readers of a slot whose record in another region changed.
+type Publisher interface {
+	Publish(slot int, price, symbol, source string) error
+	MarkStale(slot int) error
+	Notify(slot int) error
+	Close() error
+}
+
+// Writer publishes into an SHM slot and then signals the pipe.
+type Writer struct {
+	SHM  *SHM
+	Pipe *Pipe
+}
+
+// OpenWriter maps slots SHM slots of slotSize bytes at shmPath and opens the
+// pipe, both with perm. Opening the pipe blocks until a reader attaches.
+func OpenWriter(shmPath, pipePath string, slotSize, slots int, perm Perm) (*Writer, error) {
+	shm, err := CreateSHM(shmPath, slotSize, slots, perm)
+	if err != nil {
+		return nil, err
+	}
+	pipe, err := CreatePipe(pipePath, perm)
+	if err != nil {
+		shm.Close()
+		return nil, err
+	}
+	return &Writer{SHM: shm, Pipe: pipe}, nil
+}
+
+func (w *Writer) Publish(slot int, price, symbol, source string) error {
+	if err := w.SHM.Write(slot, price, symbol, source); err != nil {
+		return err
+	}
+	return w.Pipe.Notify(slot)
+}
+
+// MarkStale clears the price of slot and wakes the readers, so they stop
+// acting on it.
+func (w *Writer) MarkStale(slot int) error {
+	w.SHM.MarkSlotStale(slot)
+	return w.Pipe.Notify(slot)
+}
+
+func (w *Writer) Notify(slot int) error {
+	return w.Pipe.Notify(slot)
+}
+
+// Close marks every slot stale and flushes the region before closing the
+// pipe, so readers woken by EOF never act on a frozen price.
+func (w *Writer) Close() error {
+	w.SHM.MarkStale()
+	return errors.Join(w.SHM.Sync(), w.Pipe.Close(), w.SHM.Close())
+}
+
+// Discard drops every update; it backs dry runs where no SHM file or FIFO
+// may be created.
+type Discard struct{}
+
+func (Discard) Publish(int, string, string, string) error { return nil }
+func (Discard) MarkStale(int) error                       { return nil }
+func (Discard) Notify(int) error                          { return nil }
+func (Discard) Close() error                              { return nil }
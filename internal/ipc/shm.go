@@ -0,0 +1,203 @@
+// Package ipc implements the shared memory record and named pipe signal that
+// hand prices from the writer to readers.
+package ipc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// BUFFER_SIZE is the default size of one symbol slot in the SHM region.
+const BUFFER_SIZE = 32
+
+// MAX_SLOTS is the most slots a region may have: the pipe signal is one
+// byte holding the slot index plus one.
+const MAX_SLOTS = 255
+
+// SHM is a memory-mapped file split into fixed-size slots, one per symbol.
+// A slot holds "<price>\x00<SYMBOL>\x00", followed by "<source>\x00" for
+// symbols with failover markets; readers that only parse up to the first
+// NUL see a plain price. RecordSize gives the bytes a record needs.
+type SHM struct {
+	f        *os.File
+	data     []byte
+	slotSize int
+}
+
+// CreateSHM opens or creates path, sizes it to slots slots of slotSize
+// bytes, applies perm and maps it read-write.
+func CreateSHM(path string, slotSize, slots int, perm Perm) (*SHM, error) {
+	if slotSize <= 0 || slots <= 0 || slots > MAX_SLOTS {
+		return nil, fmt.Errorf("invalid SHM geometry: %d slots of %d bytes", slots, slotSize)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(int64(slotSize * slots)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := perm.apply(path); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return mapSHM(f, slotSize, slotSize*slots, syscall.PROT_READ|syscall.PROT_WRITE)
+}
+
+// OpenSHM maps an existing SHM file of slotSize-byte slots read-only.
+func OpenSHM(path string, slotSize int) (*SHM, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if slotSize <= 0 || st.Size() < int64(slotSize) {
+		f.Close()
+		return nil, fmt.Errorf("%s: %d bytes, smaller than one %d-byte slot", path, st.Size(), slotSize)
+	}
+	return mapSHM(f, slotSize, int(st.Size()), syscall.PROT_READ)
+}
+
+func mapSHM(f *os.File, slotSize, size, prot int) (*SHM, error) {
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, prot, syscall.MAP_SHARED)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &SHM{f: f, data: data, slotSize: slotSize}, nil
+}
+
+// Slots returns the number of mapped slots.
+func (m *SHM) Slots() int {
+	return len(m.data) / m.slotSize
+}
+
+func (m *SHM) slot(i int) []byte {
+	return m.data[i*m.slotSize : (i+1)*m.slotSize]
+}
+
+// RecordSize returns the bytes needed to store price, symbol and an
+// optional source, including the terminating NULs.
+func RecordSize(price, symbol, source string) int {
+	n := len(price) + 1 + len(symbol) + 1
+	if source != "" {
+		n += len(source) + 1
+	}
+	return n
+}
+
+// Write fills slot i with the formatted price, the symbol and, unless
+// empty, the source the price came from. A record that does not fit the
+// slot is rejected rather than truncated.
+func (m *SHM) Write(i int, price, symbol, source string) error {
+	if need := RecordSize(price, symbol, source); need > m.slotSize {
+		return fmt.Errorf("%s record needs %d bytes, slot has %d", symbol, need, m.slotSize)
+	}
+	if source != "" {
+		return m.WriteFields(i, price, symbol, source)
+	}
+	return m.WriteFields(i, price, symbol)
+}
+
+// WriteFields fills slot i with NUL-terminated fields, the layout of
+// records other than prices, such as the 24h statistics.
+func (m *SHM) WriteFields(i int, fields ...string) error {
+	if i < 0 || i >= m.Slots() {
+		return fmt.Errorf("slot %d out of range", i)
+	}
+	record := strings.Join(fields, "\x00") + "\x00"
+	if len(record) > m.slotSize {
+		return fmt.Errorf("record needs %d bytes, slot has %d", len(record), m.slotSize)
+	}
+	slot := m.slot(i)
+	n := copy(slot, record)
+	clear(slot[n:]) // no leftover of a longer previous record
+	return nil
+}
+
+// Read returns the price, symbol and source stored in slot i.
+func (m *SHM) Read(i int) (price, symbol, source string, err error) {
+	if i < 0 || i >= m.Slots() {
+		return "", "", "", fmt.Errorf("slot %d out of range", i)
+	}
+	fields := splitNUL(m.slot(i))
+	if len(fields) == 0 || fields[0] == "" {
+		return "", "", "", errors.New("empty slot")
+	}
+	if len(fields) > 1 {
+		symbol = fields[1]
+	}
+	if len(fields) > 2 {
+		source = fields[2]
+	}
+	return fields[0], symbol, source, nil
+}
+
+// splitNUL returns the NUL-terminated strings at the start of b, at most
+// the three fields of a record.
+func splitNUL(b []byte) []string {
+	var out []string
+	start := 0
+	for i, c := range b {
+		if c == 0 {
+			out = append(out, string(b[start:i]))
+			start = i + 1
+			if len(out) == 3 {
+				break
+			}
+		}
+	}
+	return out
+}
+
+// MarkStale clears the price of every slot, keeping the symbol, so readers
+// see an empty record instead of a last price that is no longer updated.
+func (m *SHM) MarkStale() {
+	for i := 0; i < m.Slots(); i++ {
+		m.MarkSlotStale(i)
+	}
+}
+
+// MarkSlotStale clears the price of slot i like MarkStale, for a symbol
+// whose feed has stalled.
+func (m *SHM) MarkSlotStale(i int) {
+	slot := m.slot(i)
+	fields := splitNUL(slot)
+	symbol := ""
+	if len(fields) > 1 {
+		symbol = fields[1]
+	}
+	clear(slot)
+	copy(slot[1:], symbol)
+}
+
+// ClearSlot zeroes slot i, for a slot no symbol uses any more.
+func (m *SHM) ClearSlot(i int) {
+	clear(m.slot(i))
+}
+
+// Clear zeroes every slot.
+func (m *SHM) Clear() {
+	clear(m.data)
+}
+
+// Sync flushes the mapping to the backing file.
+func (m *SHM) Sync() error {
+	return m.f.Sync()
+}
+
+func (m *SHM) Close() error {
+	err := syscall.Munmap(m.data)
+	if cerr := m.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
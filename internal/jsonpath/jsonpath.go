@@ -0,0 +1,150 @@
+// Package jsonpath picks a value out of a decoded JSON document by a path
+// of object keys and array indexes: gjson's data.0.price, or the JSONPath
+// spelling $.data[0].price. A negative index counts from the end.
+package jsonpath
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Path is a parsed path, one key or index per step.
+type Path []string
+
+// Parse parses a path such as result.XXBTZUSD.c.0, $.data[0].price or
+// $["price-usd"]. A backslash escapes a dot in a dotted key. Wildcards,
+// data.* or data[*], are refused, as a price path must pick one value; a
+// key named * is written \* or ["*"].
+func Parse(s string) (Path, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(s), "$")
+	var p Path
+	var key strings.Builder
+	escaped, wildcard := false, false
+	flush := func() {
+		if key.Len() > 0 {
+			wildcard = wildcard || key.String() == "*" && !escaped
+			p = append(p, key.String())
+			key.Reset()
+		}
+		escaped = false
+	}
+	for i := 0; i < len(rest); i++ {
+		switch c := rest[i]; c {
+		case '\\':
+			if i+1 < len(rest) {
+				i++
+				key.WriteByte(rest[i])
+				escaped = true
+			}
+		case '.':
+			flush()
+		case '[':
+			flush()
+			end := strings.IndexByte(rest[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("path %q: unclosed [", s)
+			}
+			step := rest[i+1 : i+end]
+			if n := len(step); n >= 2 && (step[0] == '"' || step[0] == '\'') && step[n-1] == step[0] {
+				step = step[1 : n-1]
+			} else if step == "*" {
+				return nil, fmt.Errorf("path %q: wildcards are not supported, the path must pick one value", s)
+			} else if _, err := strconv.Atoi(step); err != nil {
+				return nil, fmt.Errorf("path %q: [%s] is neither an index nor a quoted key", s, step)
+			}
+			p = append(p, step)
+			i += end
+		default:
+			key.WriteByte(c)
+		}
+	}
+	flush()
+	if len(p) == 0 {
+		return nil, errors.New("path must not be empty")
+	}
+	if wildcard {
+		return nil, fmt.Errorf("path %q: wildcards are not supported, the path must pick one value", s)
+	}
+	return p, nil
+}
+
+// Get returns the value at p in v, as decoded by encoding/json into any.
+func (p Path) Get(v any) (any, error) {
+	for i, step := range p {
+		switch node := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = node[step]; !ok {
+				return nil, fmt.Errorf("%s: no key %q", p.at(i), step)
+			}
+		case []any:
+			n, err := strconv.Atoi(step)
+			if n < 0 {
+				n += len(node)
+			}
+			if err != nil || n < 0 || n >= len(node) {
+				return nil, fmt.Errorf("%s: no index %s of %d", p.at(i), step, len(node))
+			}
+			v = node[n]
+		default:
+			return nil, fmt.Errorf("%s: %s is not an object or array", p.at(i), kind(v))
+		}
+	}
+	return v, nil
+}
+
+// Number returns the value at p in v as a number; a string that parses
+// as one counts, as APIs often quote prices, but not "NaN" or "Inf".
+func (p Path) Number(v any) (float64, error) {
+	v, err := p.Get(v)
+	if err != nil {
+		return 0, err
+	}
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(n), 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f, nil
+		}
+		return 0, fmt.Errorf("%s: %q is not a number", p, n)
+	}
+	return 0, fmt.Errorf("%s: %s is not a number", p, kind(v))
+}
+
+// String renders p in gjson's dotted form.
+func (p Path) String() string {
+	steps := make([]string, len(p))
+	for i, step := range p {
+		steps[i] = strings.ReplaceAll(step, ".", `\.`)
+	}
+	return strings.Join(steps, ".")
+}
+
+// at renders the first n steps of p, "$" for none.
+func (p Path) at(n int) string {
+	if n == 0 {
+		return "$"
+	}
+	return p[:n].String()
+}
+
+// kind names the JSON type of v.
+func kind(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case float64:
+		return "a number"
+	case string:
+		return "a string"
+	case []any:
+		return "an array"
+	}
+	return "an object"
+}
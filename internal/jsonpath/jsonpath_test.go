@@ -0,0 +1,138 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		src  string
+		want Path
+	}{
+		{"price", Path{"price"}},
+		{"data.0.price", Path{"data", "0", "price"}},
+		{"result.XXBTZUSD.c.0", Path{"result", "XXBTZUSD", "c", "0"}},
+		{"$.data[0].price", Path{"data", "0", "price"}},
+		{"data[-1]", Path{"data", "-1"}},
+		{"$[0][1]", Path{"0", "1"}},
+		{`$["price-usd"]`, Path{"price-usd"}},
+		{`$['price usd']`, Path{"price usd"}},
+		{`$["a.b"].c`, Path{"a.b", "c"}},
+		{`a\.b.c`, Path{"a.b", "c"}},
+		{`$["*"]`, Path{"*"}},
+		{`data.\*`, Path{"data", "*"}},
+		{" $.price ", Path{"price"}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.src)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.src, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Parse(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"$",
+		"$.",
+		"data[0",
+		"data[price]",
+		`data["price]`,
+		"data[]",
+		"data[*]",
+		"data.*",
+		"data.*.price",
+		"$[*].price",
+	} {
+		if p, err := Parse(src); err == nil {
+			t.Errorf("Parse(%q) = %q, want an error", src, p)
+		}
+	}
+}
+
+const DOC = `{
+	"data": [{"price": 3500.5}, {"price": "3501.25"}, {"price": " 3502 "}],
+	"result": {"XXBTZUSD": {"c": ["64000.1", "0.5"]}},
+	"price-usd": 1.0001,
+	"a.b": {"c": 7},
+	"nan": "NaN",
+	"inf": "+Inf",
+	"ninf": "-infinity",
+	"text": "n/a",
+	"null": null,
+	"flag": true,
+	"obj": {}
+}`
+
+func TestNumber(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(DOC), &doc); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want float64
+		err  bool
+	}{
+		{path: "data.0.price", want: 3500.5},
+		{path: "$.data[1].price", want: 3501.25},
+		{path: "data[2].price", want: 3502},
+		{path: "data.-1.price", want: 3502},
+		{path: "data[-3].price", want: 3500.5},
+		{path: "result.XXBTZUSD.c.0", want: 64000.1},
+		{path: `$["price-usd"]`, want: 1.0001},
+		{path: `a\.b.c`, want: 7},
+		{path: `$['a.b']["c"]`, want: 7},
+		{path: "data[3].price", err: true},
+		{path: "data[-4].price", err: true},
+		{path: "data.price", err: true},
+		{path: "missing", err: true},
+		{path: "result.XXBTZUSD.c.0.x", err: true},
+		{path: "nan", err: true},
+		{path: "inf", err: true},
+		{path: "ninf", err: true},
+		{path: "text", err: true},
+		{path: "null", err: true},
+		{path: "flag", err: true},
+		{path: "obj", err: true},
+		{path: "data", err: true},
+	}
+	for _, tt := range tests {
+		p, err := Parse(tt.path)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.path, err)
+			continue
+		}
+		got, err := p.Number(doc)
+		switch {
+		case tt.err && err == nil:
+			t.Errorf("Number(%q) = %g, want an error", tt.path, got)
+		case !tt.err && (err != nil || got != tt.want):
+			t.Errorf("Number(%q) = %g, %v, want %g", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	for src, want := range map[string]string{
+		"$.data[0].price":   "data.0.price",
+		`$["a.b"].c`:        `a\.b.c`,
+		"result.XXBTZUSD.c": "result.XXBTZUSD.c",
+	} {
+		p, err := Parse(src)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", src, err)
+			continue
+		}
+		if got := p.String(); got != want {
+			t.Errorf("Parse(%q).String() = %q, want %q", src, got, want)
+		}
+	}
+}
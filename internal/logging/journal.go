@@ -0,0 +1,224 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// JOURNAL_SOCKET is journald's native protocol socket.
+const JOURNAL_SOCKET = "/run/systemd/journal/socket"
+
+// JOURNAL_IDENTIFIER is the SYSLOG_IDENTIFIER of every entry, so
+// `journalctl -t price-alert` selects them.
+const JOURNAL_IDENTIFIER = "price-alert"
+
+// ALERT_PRIORITY is the journal priority of step, target and 24h alerts: err, so
+// `journalctl -t price-alert -p err` shows them without the tick noise.
+const ALERT_PRIORITY = 3
+
+// CRITICAL_PRIORITY is the journal priority of the rarest alerts, new
+// all-time highs and stablecoin depegs: crit.
+const CRITICAL_PRIORITY = 2
+
+// criticalEvents are the event attribute values logged at
+// CRITICAL_PRIORITY. A severity attribute after the event overrides both
+// these and alertEvents.
+var criticalEvents = map[string]bool{"ath": true, "depeg": true}
+
+// alertEvents are the event attribute values logged at ALERT_PRIORITY.
+var alertEvents = map[string]bool{"up": true, "down": true, "target": true, "change_24h": true, "high_24h": true, "low_24h": true, "fill": true, "liquidations": true, "funding": true, "open_interest": true, "gas_below": true, "divergence": true, "basis": true, "correlation": true, "premium": true, "round_number": true, "trailing": true, "rule": true, "crossover": true, "rsi": true, "macd_signal": true, "macd_zero": true, "bollinger": true, "squeeze": true, "vwap": true, "velocity": true, "session": true, "daily_change": true, "whale": true, "volume": true, "volume_delta": true, "arbitrage": true, "spread": true, "depth": true, "stale": true, "approach": true, "breakout": true, "pivot": true, "volatility_spike": true, "volatility_collapse": true}
+
+// StderrIsJournal reports whether stderr is connected to the journal, as
+// systemd advertises through $JOURNAL_STREAM for units it started.
+func StderrIsJournal() bool {
+	dev, ino, ok := strings.Cut(os.Getenv("JOURNAL_STREAM"), ":")
+	if !ok {
+		return false
+	}
+	var st syscall.Stat_t
+	if err := syscall.Fstat(int(os.Stderr.Fd()), &st); err != nil {
+		return false
+	}
+	return strconv.FormatUint(uint64(st.Dev), 10) == dev && strconv.FormatUint(st.Ino, 10) == ino
+}
+
+// journalHandler sends each record to journald as one datagram with the
+// attributes as upper-case fields (SYMBOL=, PRICE=, DELTA=, ...).
+type journalHandler struct {
+	conn     *net.UnixConn
+	level    slog.Leveler
+	fallback slog.Handler // used when a datagram cannot be sent
+	attrs    []byte       // preformatted WithAttrs fields
+	alert    bool         // WithAttrs carried an alert event
+	critical bool         // or a critical one
+	group    string
+}
+
+// journal is the socket shared by every journalHandler, so re-running
+// Setup on reload does not leak connections.
+var journal struct {
+	sync.Mutex
+	conn *net.UnixConn
+}
+
+func newJournalHandler(level slog.Leveler, fallback slog.Handler) (*journalHandler, error) {
+	journal.Lock()
+	defer journal.Unlock()
+	if journal.conn == nil {
+		conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: JOURNAL_SOCKET, Net: "unixgram"})
+		if err != nil {
+			return nil, err
+		}
+		journal.conn = conn
+	}
+	return &journalHandler{conn: journal.conn, level: level, fallback: fallback}, nil
+}
+
+func (h *journalHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.level.Level()
+}
+
+// priority maps a slog level to a syslog priority.
+func priority(l slog.Level) int {
+	switch {
+	case l >= ERROR:
+		return 3
+	case l >= WARN:
+		return 4
+	case l >= INFO:
+		return 6
+	}
+	return 7
+}
+
+// severityPriority maps an alert severity to a syslog priority, def for
+// info.
+func severityPriority(severity string, def int) int {
+	switch severity {
+	case "critical":
+		return CRITICAL_PRIORITY
+	case "warn":
+		return ALERT_PRIORITY
+	}
+	return def
+}
+
+func (h *journalHandler) Handle(ctx context.Context, r slog.Record) error {
+	var b bytes.Buffer
+	b.Write(h.attrs)
+	prio := priority(r.Level)
+	switch {
+	case h.critical:
+		prio = CRITICAL_PRIORITY
+	case h.alert:
+		prio = ALERT_PRIORITY
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		switch {
+		case a.Key == "severity":
+			prio = severityPriority(a.Value.String(), priority(r.Level))
+		case a.Key != "event":
+		case criticalEvents[a.Value.String()]:
+			prio = CRITICAL_PRIORITY
+		case alertEvents[a.Value.String()]:
+			prio = ALERT_PRIORITY
+		}
+		appendJournalAttr(&b, h.group, a)
+		return true
+	})
+	appendJournalField(&b, "MESSAGE", r.Message)
+	appendJournalField(&b, "PRIORITY", strconv.Itoa(prio))
+	appendJournalField(&b, "SYSLOG_IDENTIFIER", JOURNAL_IDENTIFIER)
+	// One datagram per entry; concurrent writes need no lock
+	if _, err := h.conn.Write(b.Bytes()); err != nil {
+		return h.fallback.Handle(ctx, r)
+	}
+	return nil
+}
+
+func appendJournalAttr(b *bytes.Buffer, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "_"
+		}
+		for _, ga := range a.Value.Group() {
+			appendJournalAttr(b, prefix, ga)
+		}
+		return
+	}
+	v := a.Value.String()
+	if a.Value.Kind() == slog.KindDuration {
+		v = a.Value.Duration().Round(time.Millisecond).String()
+	}
+	appendJournalField(b, journalFieldName(prefix+a.Key), v)
+}
+
+// journalFieldName upper-cases key and replaces anything journald rejects;
+// field names may not start with an underscore, which marks trusted fields.
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, key)
+	return strings.TrimLeft(name, "_0123456789")
+}
+
+// appendJournalField encodes one field; values containing a newline use the
+// length-prefixed binary form of the native protocol.
+func appendJournalField(b *bytes.Buffer, name, value string) {
+	if name == "" {
+		return
+	}
+	if !strings.Contains(value, "\n") {
+		b.WriteString(name + "=" + value + "\n")
+		return
+	}
+	b.WriteString(name + "\n")
+	binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value + "\n")
+}
+
+func (h *journalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.fallback = h.fallback.WithAttrs(attrs)
+	b := bytes.NewBuffer(append([]byte(nil), h.attrs...))
+	for _, a := range attrs {
+		if a.Key == "event" && alertEvents[a.Value.String()] {
+			h2.alert = true
+		}
+		if a.Key == "event" && criticalEvents[a.Value.String()] {
+			h2.critical = true
+		}
+		appendJournalAttr(b, h.group, a)
+	}
+	h2.attrs = b.Bytes()
+	return &h2
+}
+
+func (h *journalHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.fallback = h.fallback.WithGroup(name)
+	h2.group += name + "_"
+	return &h2
+}
@@ -0,0 +1,124 @@
+// Package logging is the leveled logger shared by every command, built on
+// log/slog. Program output (reader prices, ctl replies, reports) does not
+// go through it; diagnostics and alerts do.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Levels, from per-tick output up to failures.
+const (
+	DEBUG = slog.LevelDebug // per-tick output
+	INFO  = slog.LevelInfo  // alerts and lifecycle
+	WARN  = slog.LevelWarn  // reconnects and degraded operation
+	ERROR = slog.LevelError
+)
+
+// Output formats.
+const (
+	FORMAT_AUTO    = "auto"    // journal under systemd, console otherwise
+	FORMAT_CONSOLE = "console" // aligned, optionally colored lines for people
+	FORMAT_JSON    = "json"    // one slog JSON object per line for shippers
+	FORMAT_JOURNAL = "journal" // native journald entries with fields
+)
+
+// ParseLevel accepts a level name in any case.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return DEBUG, nil
+	case "info":
+		return INFO, nil
+	case "warn", "warning":
+		return WARN, nil
+	case "error":
+		return ERROR, nil
+	}
+	return INFO, fmt.Errorf("unknown log level %q", s)
+}
+
+// Options configures Setup.
+type Options struct {
+	Level  slog.Level
+	Format string // FORMAT_CONSOLE or FORMAT_JSON
+	Color  bool   // console only
+	Output io.Writer
+}
+
+var (
+	level  slog.LevelVar
+	logger atomic.Pointer[slog.Logger]
+)
+
+func init() {
+	logger.Store(slog.New(newConsoleHandler(os.Stderr, &level, false)))
+}
+
+// Setup replaces the process logger. It may be called again on reload.
+func Setup(o Options) {
+	level.Set(o.Level)
+	w := o.Output
+	if w == nil {
+		w = os.Stderr
+	}
+	var h slog.Handler
+	switch o.Format {
+	case FORMAT_JSON:
+		h = slog.NewJSONHandler(w, &slog.HandlerOptions{Level: &level})
+	case FORMAT_JOURNAL:
+		console := newConsoleHandler(w, &level, false)
+		if jh, err := newJournalHandler(&level, console); err == nil {
+			h = jh
+		} else {
+			h = console
+			defer Warnf("journald unavailable (%v), logging to stderr", err)
+		}
+	default:
+		h = newConsoleHandler(w, &level, o.Color)
+	}
+	logger.Store(slog.New(h))
+}
+
+// Logger returns the current logger for callers that want slog directly.
+func Logger() *slog.Logger {
+	return logger.Load()
+}
+
+// Enabled reports whether messages at l are written, so callers can skip
+// building attributes for dropped messages.
+func Enabled(l slog.Level) bool {
+	return l >= level.Level()
+}
+
+// Debug, Info, Warn and Error log msg with slog key-value attributes.
+func Debug(msg string, args ...any) { Logger().Debug(msg, args...) }
+func Info(msg string, args ...any)  { Logger().Info(msg, args...) }
+func Warn(msg string, args ...any)  { Logger().Warn(msg, args...) }
+func Error(msg string, args ...any) { Logger().Error(msg, args...) }
+
+func logf(l slog.Level, format string, args ...any) {
+	if !Enabled(l) {
+		return
+	}
+	Logger().Log(context.Background(), l, strings.TrimSuffix(fmt.Sprintf(format, args...), "\n"))
+}
+
+// Debugf, Infof, Warnf and Errorf log a formatted message without
+// attributes.
+func Debugf(format string, args ...any) { logf(DEBUG, format, args...) }
+func Infof(format string, args ...any)  { logf(INFO, format, args...) }
+func Warnf(format string, args ...any)  { logf(WARN, format, args...) }
+func Errorf(format string, args ...any) { logf(ERROR, format, args...) }
+
+// Fatalf logs at ERROR and exits with status 1.
+func Fatalf(format string, args ...any) {
+	logf(ERROR, format, args...)
+	os.Exit(1)
+}
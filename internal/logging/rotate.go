@@ -0,0 +1,178 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// RotatingFile is an append-only log file that is rotated once it would
+// grow past MaxSize bytes. Rotated files are named <path>.1 (newest) to
+// <path>.<MaxBackups>, with a .gz suffix when Compress is set.
+type RotatingFile struct {
+	Path       string
+	MaxSize    int64
+	MaxBackups int
+	Compress   bool
+
+	mu   sync.Mutex
+	f    *os.File // nil after a failed reopen, retried by Write
+	size int64
+	gz   sync.WaitGroup // background compression of <path>.1
+}
+
+// OpenRotatingFile opens path for appending.
+func OpenRotatingFile(path string, maxSize int64, maxBackups int, compress bool) (*RotatingFile, error) {
+	r := &RotatingFile{Path: path, MaxSize: maxSize, MaxBackups: maxBackups, Compress: compress}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens Path and makes it the current file, closing the previous
+// one. On failure the previous file stays current.
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if r.f != nil {
+		r.f.Close()
+	}
+	r.f, r.size = f, st.Size()
+	return nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	if r.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.MaxSize {
+		if err := r.rotate(); err != nil {
+			fmt.Fprintln(os.Stderr, "log rotation failed:", err)
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) backup(i int) string {
+	name := fmt.Sprintf("%s.%d", r.Path, i)
+	if r.Compress {
+		name += ".gz"
+	}
+	return name
+}
+
+// rotate shifts the backups up by one and starts a new file. With no
+// backups kept the current file is simply truncated. The current file is
+// only closed once the new one is open, so on failure logging carries on
+// into it: renamed back to Path, or left past MaxSize.
+func (r *RotatingFile) rotate() error {
+	r.gz.Wait()
+	if r.MaxBackups <= 0 {
+		if err := r.f.Truncate(0); err != nil {
+			return err
+		}
+		r.size = 0
+		return nil
+	}
+	os.Remove(r.backup(r.MaxBackups))
+	for i := r.MaxBackups - 1; i >= 1; i-- {
+		os.Rename(r.backup(i), r.backup(i+1))
+	}
+	first := r.Path + ".1"
+	if err := os.Rename(r.Path, first); err != nil {
+		return err
+	}
+	if err := r.open(); err != nil {
+		os.Rename(first, r.Path)
+		return err
+	}
+	if r.Compress {
+		// Off the logging path; the next rotation waits for it
+		r.gz.Add(1)
+		go func() {
+			defer r.gz.Done()
+			if err := gzipFile(first, first+".gz"); err != nil {
+				fmt.Fprintln(os.Stderr, "log compression failed:", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// SetLimits changes the rotation settings of an open file.
+func (r *RotatingFile) SetLimits(maxSize int64, maxBackups int, compress bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.MaxSize, r.MaxBackups, r.Compress = maxSize, maxBackups, compress
+}
+
+// Reopen closes and reopens the file, for use after it was moved away by
+// an external tool such as logrotate. On failure Write keeps retrying it
+// rather than writing on to the moved file.
+func (r *RotatingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.open(); err != nil {
+		if r.f != nil {
+			r.f.Close()
+			r.f = nil
+		}
+		return err
+	}
+	return nil
+}
+
+// Close waits for pending compression and closes the file.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gz.Wait()
+	if r.f == nil {
+		return nil
+	}
+	return r.f.Close()
+}
+
+// gzipFile compresses src into dst and removes src.
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,92 @@
+// Package notify delivers alert events to their sinks.
+package notify
+
+import (
+	"errors"
+	"time"
+
+	"github.com/qqubb/tts_price_alert/internal/alert"
+	"github.com/qqubb/tts_price_alert/internal/logging"
+)
+
+// Notifier delivers an alert event to one sink.
+type Notifier interface {
+	Notify(ev alert.Event) error
+}
+
+// Console logs alerts at info level with the event as attributes.
+type Console struct{}
+
+func (Console) Notify(ev alert.Event) error {
+	msg := "[ALERT] " + ev.Text
+	if ev.Kind == alert.Start {
+		msg = ev.Text
+	}
+	logging.Info(msg, EventAttrs(ev)...)
+	return nil
+}
+
+// EventAttrs describes ev as slog attributes. latency_ms is the time from
+// the exchange's trade timestamp to delivery.
+func EventAttrs(ev alert.Event) []any {
+	attrs := []any{
+		"event", ev.Kind.String(),
+		"symbol", ev.Symbol,
+		"price", ev.Price,
+		"delta", ev.Change,
+	}
+	switch ev.Kind {
+	case alert.Target, alert.Change24h, alert.High24h, alert.Low24h, alert.Liquidation, alert.FundingRate, alert.OpenInterest, alert.GasBelow, alert.Divergence, alert.Basis, alert.Correlation, alert.Premium, alert.RoundNumber, alert.TrailingStop, alert.Crossover, alert.RSI, alert.MACDSignal, alert.MACDZero, alert.Bollinger, alert.Squeeze, alert.VWAP, alert.Velocity, alert.SessionBreak, alert.ATH, alert.DailyChange, alert.Whale, alert.Volume, alert.VolumeDelta, alert.Arbitrage, alert.Depeg, alert.Spread, alert.Depth, alert.Stale, alert.Approach, alert.Breakout, alert.Pivot, alert.VolSpike, alert.VolCollapse:
+		attrs = append(attrs, "level", ev.Level)
+	}
+	if ev.Source != "" {
+		attrs = append(attrs, "source", ev.Source)
+	}
+	if ev.Size > 0 {
+		attrs = append(attrs, "qty", ev.Size)
+	}
+	if ev.Spread > 0 {
+		attrs = append(attrs, "spread", ev.Spread)
+	}
+	if ev.Rule != "" {
+		attrs = append(attrs, "rule", ev.Rule)
+	}
+	if ev.Session != "" {
+		attrs = append(attrs, "session", ev.Session)
+	}
+	if ev.Streak > 0 {
+		attrs = append(attrs, "streak", ev.Streak)
+	}
+	if ev.Side != "" {
+		attrs = append(attrs, "side", ev.Side)
+	}
+	if ev.Pivot != "" {
+		attrs = append(attrs, "pivot", ev.Pivot)
+	}
+	if ev.Window != "" {
+		attrs = append(attrs, "window", ev.Window)
+	}
+	if ev.Repeats > 0 {
+		attrs = append(attrs, "repeats", ev.Repeats)
+	}
+	if ev.Severity > 0 {
+		attrs = append(attrs, "severity", ev.Severity.String())
+	}
+	if !ev.Time.IsZero() {
+		attrs = append(attrs, "latency_ms", time.Since(ev.Time).Milliseconds())
+	}
+	return attrs
+}
+
+// Multi fans an event out to every notifier and joins their errors.
+type Multi []Notifier
+
+func (m Multi) Notify(ev alert.Event) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ev); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,126 @@
+// Package numfmt renders numbers for people: the decimal separator and
+// digit grouping follow a locale. SHM records, JSON and structured log
+// attributes keep the plain machine format.
+package numfmt
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LOCALE_AUTO takes the locale from $LC_ALL, $LC_NUMERIC or $LANG.
+const LOCALE_AUTO = "auto"
+
+// Locale holds the separators of one number format.
+type Locale struct {
+	Name    string
+	Decimal string
+	Group   string // inserted between groups of three integer digits
+	// MinGroup is the number of integer digits from which grouping starts;
+	// several locales leave four-digit numbers ungrouped.
+	MinGroup int
+}
+
+// Plain is the machine format: "." and no grouping.
+var Plain = Locale{Name: "C", Decimal: "."}
+
+const (
+	nbsp  = "\u00a0"
+	nnbsp = "\u202f" // narrow, as CLDR uses for French
+)
+
+// locales maps a language, or language_TERRITORY where that differs, to its
+// separators.
+var locales = map[string]Locale{
+	"en":    {Decimal: ".", Group: ",", MinGroup: 4},
+	"ja":    {Decimal: ".", Group: ",", MinGroup: 4},
+	"ko":    {Decimal: ".", Group: ",", MinGroup: 4},
+	"zh":    {Decimal: ".", Group: ",", MinGroup: 4},
+	"de":    {Decimal: ",", Group: ".", MinGroup: 4},
+	"de_CH": {Decimal: ".", Group: "’", MinGroup: 4},
+	"it":    {Decimal: ",", Group: ".", MinGroup: 4},
+	"nl":    {Decimal: ",", Group: ".", MinGroup: 4},
+	"pt":    {Decimal: ",", Group: ".", MinGroup: 4},
+	"tr":    {Decimal: ",", Group: ".", MinGroup: 4},
+	"es":    {Decimal: ",", Group: ".", MinGroup: 5},
+	"fr":    {Decimal: ",", Group: nnbsp, MinGroup: 4},
+	"ru":    {Decimal: ",", Group: nbsp, MinGroup: 4},
+	"uk":    {Decimal: ",", Group: nbsp, MinGroup: 4},
+	"cs":    {Decimal: ",", Group: nbsp, MinGroup: 4},
+	"sv":    {Decimal: ",", Group: nbsp, MinGroup: 4},
+	"pl":    {Decimal: ",", Group: nbsp, MinGroup: 5},
+}
+
+// Parse looks up a locale name such as "de_DE.UTF-8", "fr" or "en-GB". An
+// empty name, "C" and "POSIX" give Plain; LOCALE_AUTO reads the
+// environment the way setlocale(3) does.
+func Parse(name string) (Locale, error) {
+	if name == LOCALE_AUTO {
+		name = envLocale()
+	}
+	base, _, _ := strings.Cut(name, ".")
+	base, _, _ = strings.Cut(base, "@")
+	base = strings.ReplaceAll(base, "-", "_")
+	if base == "" || base == "C" || base == "POSIX" {
+		return Plain, nil
+	}
+	lang, territory, _ := strings.Cut(base, "_")
+	lang = strings.ToLower(lang)
+	key := lang
+	if territory != "" {
+		key += "_" + strings.ToUpper(territory)
+	}
+	l, ok := locales[key]
+	if !ok {
+		l, ok = locales[lang]
+	}
+	if !ok {
+		return Plain, fmt.Errorf("unknown locale %q", name)
+	}
+	l.Name = key
+	return l, nil
+}
+
+// envLocale returns the first of $LC_ALL, $LC_NUMERIC and $LANG that is set.
+func envLocale() string {
+	for _, v := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		if s := os.Getenv(v); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// Format renders v with the given number of decimals.
+func (l Locale) Format(v float64, decimals int) string {
+	s := strconv.FormatFloat(v, 'f', decimals, 64)
+	if l.Decimal == "." && l.Group == "" {
+		return s
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	if strings.Trim(s, "0.") == "" {
+		sign = "" // -0.00
+	}
+	intPart, frac, hasFrac := strings.Cut(s, ".")
+	var b strings.Builder
+	b.WriteString(sign)
+	if l.Group != "" && len(intPart) >= l.MinGroup {
+		for i, d := range intPart {
+			if i > 0 && (len(intPart)-i)%3 == 0 {
+				b.WriteString(l.Group)
+			}
+			b.WriteRune(d)
+		}
+	} else {
+		b.WriteString(intPart)
+	}
+	if hasFrac {
+		b.WriteString(l.Decimal + frac)
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+// Package sdnotify implements the systemd service notification protocol
+// (sd_notify(3)) without linking libsystemd.
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	READY     = "READY=1"
+	STOPPING  = "STOPPING=1"
+	RELOADING = "RELOADING=1"
+	WATCHDOG  = "WATCHDOG=1"
+)
+
+// Notify sends state to the socket in $NOTIFY_SOCKET. It reports false with
+// a nil error when the process is not run by a service manager.
+func Notify(state string) (bool, error) {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return false, nil
+	}
+	// A leading @ names a socket in the abstract namespace
+	if path[0] == '@' {
+		path = "\x00" + path[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Status formats a free-form STATUS= line shown by systemctl status.
+func Status(text string) string {
+	return "STATUS=" + text
+}
+
+// WatchdogInterval returns the watchdog timeout systemd expects pings
+// within ($WATCHDOG_USEC), or 0 if the watchdog is off or meant for another
+// process ($WATCHDOG_PID).
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
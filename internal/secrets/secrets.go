@@ -0,0 +1,180 @@
+// Package secrets resolves credentials from the OS keyring, the environment
+// or a directory of files, so they never have to be written into the config.
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// REF_PREFIX marks a config value as a reference to a named secret, e.g.
+// "secret:binance_api_key".
+const REF_PREFIX = "secret:"
+
+// ErrNotFound is returned when no provider knows the secret.
+var ErrNotFound = errors.New("secret not found")
+
+// Provider looks up a named secret.
+type Provider interface {
+	Name() string
+	Lookup(name string) (string, error)
+}
+
+// Keyring reads secrets stored under Service in the OS keyring: libsecret
+// via secret-tool on Linux, the login keychain via security on macOS.
+type Keyring struct {
+	Service string
+}
+
+func (k Keyring) Name() string { return "keyring" }
+
+// Available reports whether the keyring helper binary is installed.
+func (k Keyring) Available() bool {
+	_, err := exec.LookPath(k.tool())
+	return err == nil
+}
+
+func (k Keyring) tool() string {
+	if runtime.GOOS == "darwin" {
+		return "security"
+	}
+	return "secret-tool"
+}
+
+func (k Keyring) Lookup(name string) (string, error) {
+	if !k.Available() {
+		return "", ErrNotFound
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "find-generic-password", "-s", k.Service, "-a", name, "-w")
+	} else {
+		cmd = exec.Command("secret-tool", "lookup", "service", k.Service, "key", name)
+	}
+	out, err := cmd.Output()
+	value := strings.TrimRight(string(out), "\r\n")
+	if err != nil || value == "" {
+		// Both tools exit non-zero when the item does not exist
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Store saves value under name in the keyring.
+func (k Keyring) Store(name, value string) error {
+	if !k.Available() {
+		return fmt.Errorf("%s not found in PATH", k.tool())
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "add-generic-password", "-U", "-s", k.Service, "-a", name, "-w", value)
+	} else {
+		cmd = exec.Command("secret-tool", "store", "--label", k.Service+" "+name, "service", k.Service, "key", name)
+		cmd.Stdin = strings.NewReader(value)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v: %s", k.tool(), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Env reads secrets from <Prefix><NAME> environment variables.
+type Env struct {
+	Prefix string
+}
+
+func (e Env) Name() string { return "env" }
+
+func (e Env) Lookup(name string) (string, error) {
+	if v, ok := os.LookupEnv(e.Prefix + strings.ToUpper(name)); ok && v != "" {
+		return v, nil
+	}
+	return "", ErrNotFound
+}
+
+// Dir reads a secret from the file <Path>/<name>, as laid out by systemd's
+// LoadCredential= or Docker secrets. Files readable by group or others are
+// rejected.
+type Dir struct {
+	Path string
+}
+
+func (d Dir) Name() string { return "file" }
+
+func (d Dir) Lookup(name string) (string, error) {
+	if d.Path == "" || strings.ContainsRune(name, filepath.Separator) {
+		return "", ErrNotFound
+	}
+	path := filepath.Join(d.Path, name)
+	st, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if st.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("%s: permissions %v are too open, want 0600", path, st.Mode().Perm())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Chain tries each provider in order and returns the first hit.
+type Chain []Provider
+
+func (c Chain) Name() string {
+	names := make([]string, len(c))
+	for i, p := range c {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+func (c Chain) Lookup(name string) (string, error) {
+	for _, p := range c {
+		v, err := p.Lookup(name)
+		if err == nil {
+			return v, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return "", fmt.Errorf("%s: %w", p.Name(), err)
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// New returns the default chain: keyring first, then the environment, then
+// the secrets directory when one is configured.
+func New(service, envPrefix, dir string) Chain {
+	chain := Chain{Keyring{Service: service}, Env{Prefix: envPrefix}}
+	if dir != "" {
+		chain = append(chain, Dir{Path: dir})
+	}
+	return chain
+}
+
+// IsRef reports whether value refers to a named secret.
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, REF_PREFIX)
+}
+
+// Resolve returns value unchanged unless it is a "secret:<name>" reference,
+// in which case the named secret is looked up through p.
+func Resolve(p Provider, value string) (string, error) {
+	if !IsRef(value) {
+		return value, nil
+	}
+	return p.Lookup(strings.TrimPrefix(value, REF_PREFIX))
+}
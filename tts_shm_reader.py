@@ -3,17 +3,23 @@ import os
 import threading
 import time
 from dataclasses import dataclass
-from typing import Optional
+from typing import Dict, List, Optional
 
 import numpy as np
 import sounddevice as sd
 from kokoro import KPipeline
 
 # ===================== Config =====================
-SHM_PATH = "/dev/shm/eth_price_shm"
-PIPE_PATH = "/tmp/eth_price_pipe"
-BUFFER_SIZE = 32
-THRESHOLD_VALUE = 12.5
+# Paths and slot sizes must match the Go writer; the same PRICE_ALERT_*
+# variables configure both. The writer decides what is an alert (steps,
+# checkpoints, rules, ...) and publishes it in ALERT_PATH; this only speaks.
+# Without that region ("off", or not created) it speaks moves of STEP itself.
+SHM_PATH = os.environ.get("PRICE_ALERT_SHM_PATH", "/dev/shm/eth_price_shm")
+PIPE_PATH = os.environ.get("PRICE_ALERT_PIPE_PATH", "/tmp/eth_price_pipe")
+ALERT_PATH = os.environ.get("PRICE_ALERT_ALERT_PATH", "/dev/shm/eth_price_alerts")
+BUFFER_SIZE = int(os.environ.get("PRICE_ALERT_BUFFER_SIZE", "32"))
+ALERT_SIZE = int(os.environ.get("PRICE_ALERT_ALERT_SIZE", "256"))
+STEP = float(os.environ.get("PRICE_ALERT_STEP", "12.5"))
 SAMPLE_RATE = 24000
 DEBOUNCE_SECONDS = 0.3
 FADE_OUT_MS = 300
@@ -98,49 +104,217 @@ class SpeechEngine:
             self._current_thread = t
             t.start()
 
+# ===================== Number Format =====================
+# Mirrors internal/numfmt: PRICE_ALERT_LOCALE ("de_DE", "auto" for $LANG)
+# sets the separators of printed and spoken prices; SHM is always plain.
+NBSP, NNBSP = "\u00a0", "\u202f"
+LOCALES = {
+    "en": (".", ",", 4), "ja": (".", ",", 4), "ko": (".", ",", 4), "zh": (".", ",", 4),
+    "de": (",", ".", 4), "de_CH": (".", "’", 4), "it": (",", ".", 4), "nl": (",", ".", 4),
+    "pt": (",", ".", 4), "tr": (",", ".", 4), "es": (",", ".", 5), "fr": (",", NNBSP, 4),
+    "ru": (",", NBSP, 4), "uk": (",", NBSP, 4), "cs": (",", NBSP, 4), "sv": (",", NBSP, 4),
+    "pl": (",", NBSP, 5),
+}
+
+def parse_locale(name: str):
+    """Return (decimal, group, min_group) for a locale name; unknown is plain."""
+    if name == "auto":
+        name = os.environ.get("LC_ALL") or os.environ.get("LC_NUMERIC") or os.environ.get("LANG", "")
+    base = name.split(".")[0].split("@")[0].replace("-", "_")
+    lang, _, territory = base.partition("_")
+    key = f"{lang.lower()}_{territory.upper()}" if territory else lang.lower()
+    return LOCALES.get(key) or LOCALES.get(lang.lower()) or (".", "", 0)
+
+DECIMAL, GROUP, MIN_GROUP = parse_locale(os.environ.get("PRICE_ALERT_LOCALE", ""))
+
+def format_number(value: float, decimals: int = 0) -> str:
+    """Render value with the configured separators, e.g. 3,100 or 3.100,50."""
+    s = f"{abs(value):.{decimals}f}"
+    whole, _, frac = s.partition(".")
+    if GROUP and len(whole) >= MIN_GROUP:
+        whole = f"{int(whole):,}".replace(",", GROUP)
+    sign = "-" if value < 0 and s.strip("0.") else ""
+    return sign + whole + (DECIMAL + frac if frac else "")
+
+# ===================== SHM Slots =====================
+QUOTE_ASSETS = ("USDT", "USDC", "FDUSD", "BUSD", "USD", "BTC", "ETH")
+
+def read_slot(shm: mmap.mmap, slot: int):
+    """Return (price, symbol, source) from one NUL-separated slot written by Go.
+
+    source names the exchange for symbols with failover markets, else "".
+    """
+    start = slot * BUFFER_SIZE
+    fields = shm[start:start + BUFFER_SIZE].split(b"\x00")
+    price = float(fields[0].decode("utf-8"))
+    symbol = fields[1].decode("utf-8") if len(fields) > 1 else ""
+    source = fields[2].decode("utf-8") if len(fields) > 2 else ""
+    return price, symbol, source
+
+ALERT_RING = 8  # alerts kept per slot, ipc.ALERT_RING of the writer
+
+@dataclass
+class Alert:
+    seq: int
+    severity: str
+    event: str
+    symbol: str
+    price: float
+    text: str
+
+def read_alerts(alerts: mmap.mmap, slot: int, seen: int = 0) -> List[Alert]:
+    """Return the alerts of a slot of the writer's alert region newer than
+    seq seen, oldest first.
+
+    A slot is a ring of ALERT_RING records of seq, severity, event, symbol,
+    price and text, NUL-separated; seq counts the slot's alerts from 1 and
+    picks the record, so one tick raising several alerts keeps them all.
+    """
+    base = slot * ALERT_SIZE * ALERT_RING
+    found = []
+    for i in range(ALERT_RING):
+        start = base + i * ALERT_SIZE
+        fields = alerts[start:start + ALERT_SIZE].split(b"\x00")
+        if len(fields) < 6 or not fields[0]:
+            continue
+        try:
+            seq, severity, event, symbol, price, text = (f.decode("utf-8", "replace") for f in fields[:6])
+            alert = Alert(int(seq), severity, event, symbol, float(price), text)
+        except ValueError:
+            continue
+        if alert.seq > seen:
+            found.append(alert)
+    return sorted(found, key=lambda a: a.seq)
+
+def spoken_asset(symbol: str) -> str:
+    """ETHUSDT, ETH-USD or ETH/USD -> ETH, so multi-symbol alerts say which market moved."""
+    for sep in ("-", "/"):
+        if sep in symbol:
+            return symbol.split(sep)[0]
+    for quote in QUOTE_ASSETS:
+        if symbol.endswith(quote) and len(symbol) > len(quote):
+            return symbol[: -len(quote)]
+    return symbol
+
 # ===================== Main Loop =====================
+def spoken_text(alert: Alert, several: bool) -> str:
+    """The alert as said: the symbol shortened to its asset, and only named
+    once more than one market is being watched."""
+    asset = spoken_asset(alert.symbol)
+    if alert.event == "start":
+        label = f"{asset} " if asset else ""
+        return f"Starting price checkpoint: {label}{format_number(round(alert.price))}"
+    text = alert.text
+    if alert.symbol and text.startswith(alert.symbol + " "):
+        text = text[len(alert.symbol) + 1:]
+        if several and asset:
+            text = f"{asset} {text}"
+    return text
+
+def open_alerts() -> Optional[mmap.mmap]:
+    """Map the writer's alert region read-only, None when it is off or absent."""
+    if ALERT_PATH == "off":
+        return None
+    try:
+        with open(ALERT_PATH, "rb") as a:
+            return mmap.mmap(a.fileno(), os.fstat(a.fileno()).st_size, access=mmap.ACCESS_READ)
+    except (OSError, ValueError) as e:
+        print(f"[alerts] {ALERT_PATH}: {e}, speaking moves of {format_number(STEP, 2)}")
+        return None
+
 def main():
     if not os.path.exists(SHM_PATH):
         raise FileNotFoundError(f"Shared memory not found: {SHM_PATH}")
     if not os.path.exists(PIPE_PATH):
         os.mkfifo(PIPE_PATH)
 
-    with open(SHM_PATH, "r+b") as f, open(PIPE_PATH, "rb") as pipe:
-        shm = mmap.mmap(f.fileno(), BUFFER_SIZE, access=mmap.ACCESS_READ)
-        speech = SpeechEngine()
-        checkpoint_price: Optional[float] = None
-
+    speech = SpeechEngine()
+    symbols = set()
+    checkpoints: Dict[str, float] = {}
+    # Read-only, so a group-readable SHM file (permissions.mode 0640) works
+    with open(SHM_PATH, "rb") as f:
+        size = max(os.fstat(f.fileno()).st_size, BUFFER_SIZE)
+        shm = mmap.mmap(f.fileno(), size, access=mmap.ACCESS_READ)
         while True:
-            # Block until Go writes to pipe
-            pipe.read(1)
+            # Blocks until a writer attaches; it has mapped ALERT_PATH by then
+            with open(PIPE_PATH, "rb") as pipe:
+                alerts = open_alerts()
+                # Alerts from before the reader attached are not news
+                seen = {}
+                if alerts is not None:
+                    for slot in range(len(alerts) // (ALERT_SIZE * ALERT_RING)):
+                        fresh = read_alerts(alerts, slot)
+                        seen[slot] = fresh[-1].seq if fresh else 0
+                listen(pipe, shm, size, alerts, seen, symbols, checkpoints, speech)
+                if alerts is not None:
+                    alerts.close()
+            # EOF: the writer shut down and marked its slots stale
+            print("[pipe] writer closed, waiting for it to restart")
 
-            shm.seek(0)
-            raw = shm.read(BUFFER_SIZE).split(b"\x00", 1)[0]
-            try:
-                price = float(raw.decode("utf-8"))
-            except ValueError:
-                continue
-
-            if checkpoint_price is None:
-                checkpoint_price = round(price / THRESHOLD_VALUE) * THRESHOLD_VALUE
-                alert_text = f"Starting price checkpoint: {int(round(checkpoint_price))}"
-                print("[ALERT]", alert_text)
-                speech._stream_tts(alert_text, threading.Event(), speech._match_leadin(alert_text))
-                continue
-
-            change = price - checkpoint_price
-            if change >= THRESHOLD_VALUE:
-                alert_text = f"up to {int(round(price))}"
-                print("[ALERT]", alert_text)
-                speech.say(alert_text)
-                checkpoint_price = price
-            elif change <= -THRESHOLD_VALUE:
-                alert_text = f"down to {int(round(price))}"
-                print("[ALERT]", alert_text)
-                speech.say(alert_text)
-                checkpoint_price = price
-            else:
-                print(f"ETH {price:.2f} Δ {change:.2f}")
+def listen(pipe, shm, size, alerts, seen, symbols, checkpoints, speech):
+    """Speak the writer's alerts, or without them moves of STEP, and print
+    its prices until the pipe closes."""
+    while True:
+        # Block until Go writes to pipe; the byte is the slot index + 1
+        signal = pipe.read(1)
+        if not signal:
+            return
+        slot = max(signal[0] - 1, 0)
+
+        fresh = []
+        if alerts is not None and (slot + 1) * ALERT_SIZE * ALERT_RING <= len(alerts):
+            fresh = read_alerts(alerts, slot, seen.get(slot, 0))
+        if fresh:
+            seen[slot] = fresh[-1].seq
+            texts = []
+            for alert in fresh:
+                symbols.add(alert.symbol)
+                alert_text = spoken_text(alert, len(symbols) > 1)
+                print(f"[ALERT {alert.severity}]", alert.text)
+                # The starting checkpoint is never cut short by the next alert
+                if alert.event == "start":
+                    speech._stream_tts(alert_text, threading.Event(), speech._match_leadin(alert_text))
+                else:
+                    texts.append(alert_text)
+            # Said as one, since each say cuts the previous one short
+            if texts:
+                speech.say(". ".join(texts))
+            continue
+
+        if (slot + 1) * BUFFER_SIZE > size:
+            continue
+        try:
+            price, symbol, source = read_slot(shm, slot)
+        except ValueError:
+            continue
+        symbols.add(symbol)
+        if alerts is None and speak_move(price, symbol, checkpoints, speech):
+            continue
+        via = f" ({source})" if source else ""
+        print(f"{spoken_asset(symbol) or 'ETH'} {format_number(price, 2)}{via}")
+
+def speak_move(price: float, symbol: str, checkpoints: Dict[str, float], speech) -> bool:
+    """Speak the starting checkpoint of symbol or a move of STEP from it, as
+    this did before the writer published alerts; True when it spoke."""
+    asset = spoken_asset(symbol)
+    checkpoint = checkpoints.get(symbol)
+    if checkpoint is None:
+        checkpoints[symbol] = round(price / STEP) * STEP
+        label = f"{asset} " if asset else ""
+        alert_text = f"Starting price checkpoint: {label}{format_number(round(checkpoints[symbol]))}"
+        print("[ALERT]", alert_text)
+        speech._stream_tts(alert_text, threading.Event(), speech._match_leadin(alert_text))
+        return True
+    change = price - checkpoint
+    if abs(change) < STEP:
+        return False
+    # Only name the market once more than one is being watched
+    prefix = f"{asset} " if len(checkpoints) > 1 and asset else ""
+    alert_text = f"{prefix}{'up' if change > 0 else 'down'} to {format_number(round(price))}"
+    print("[ALERT]", alert_text)
+    speech.say(alert_text)
+    checkpoints[symbol] = price
+    return True
 
 if __name__ == "__main__":
     main()